@@ -11,9 +11,12 @@ import (
 	"myesi-notification-service/internal/metrics"
 	"myesi-notification-service/internal/providers"
 	"myesi-notification-service/internal/repository"
+	"myesi-notification-service/internal/scheduler"
 	"myesi-notification-service/internal/templates"
+	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	fiber "github.com/gofiber/fiber/v2"
 )
@@ -29,20 +32,57 @@ func main() {
 		log.Printf("[METRICS] init failed: %v", err)
 	}
 
-	tplRepo := &repository.TemplateRepositoryPG{DB: db.Conn}
+	tplStore := &repository.TemplateRepositoryPG{DB: db.Conn}
+	tplRepo := repository.NewCachedTemplateRepository(tplStore, tplStore, cfg.TemplateCacheTTL)
 	prefRepo := &repository.PreferenceRepositoryPG{DB: db.Conn}
 	logRepo := &repository.LogRepositoryPG{DB: db.Conn}
 	inboxRepo := &repository.InboxRepositoryPG{DB: db.Conn}
 	orgUserRepo := &repository.OrgUserRepositoryPG{DB: db.Conn}
 	orgSettingsRepo := &repository.OrgSettingsRepositoryPG{DB: db.Conn}
+	partialRepo := &repository.PartialRepositoryPG{DB: db.Conn}
+	digestRepo := &repository.DigestRepositoryPG{DB: db.Conn}
+	quietRepo := &repository.QuietHoursRepositoryPG{DB: db.Conn}
+	scheduledRepo := &repository.ScheduledDeliveryRepositoryPG{DB: db.Conn}
+	scheduledEventRepo := &repository.ScheduledEventRepositoryPG{DB: db.Conn}
+	escalationRepo := &repository.EscalationRepositoryPG{DB: db.Conn}
+	escalationPolicyRepo := &repository.EscalationPolicyRepositoryPG{DB: db.Conn}
+	alertRepo := &repository.AlertRepositoryPG{DB: db.Conn}
+	rateLimitRepo := &repository.RateLimitRepositoryPG{DB: db.Conn}
+	groupingRuleRepo := &repository.GroupingRuleRepositoryPG{DB: db.Conn}
+	groupRepo := &repository.GroupRepositoryPG{DB: db.Conn}
+	routingRepo := &repository.RoutingRuleRepositoryPG{DB: db.Conn}
+	audienceRepo := &repository.AudienceRepositoryPG{DB: db.Conn}
+	watcherRepo := &repository.WatcherRepositoryPG{DB: db.Conn}
+	destinationRepo := &repository.DestinationRepositoryPG{DB: db.Conn}
+	snoozeRepo := &repository.SnoozeRepositoryPG{DB: db.Conn}
+
+	renderer := templates.NewRenderer(cfg.TemplateCacheSize)
 
 	svc := &domain.NotificationService{
-		Templates:   tplRepo,
-		Preferences: prefRepo,
-		Logs:        logRepo,
-		Inbox:       inboxRepo,
-		OrgUsers:    orgUserRepo,
-		OrgSettings: orgSettingsRepo,
+		Templates:          tplRepo,
+		Preferences:        prefRepo,
+		Logs:               logRepo,
+		Inbox:              inboxRepo,
+		OrgUsers:           orgUserRepo,
+		Locales:            orgUserRepo,
+		Partials:           partialRepo,
+		Digests:            digestRepo,
+		QuietHours:         quietRepo,
+		Scheduled:          scheduledRepo,
+		ScheduledEvents:    scheduledEventRepo,
+		Escalations:        escalationRepo,
+		EscalationPolicies: escalationPolicyRepo,
+		Alerts:             alertRepo,
+		RateLimits:         rateLimitRepo,
+		GroupingRules:      groupingRuleRepo,
+		Routes:             routingRepo,
+		Audiences:          audienceRepo,
+		ProjectMembers:     orgUserRepo,
+		Watchers:           watcherRepo,
+		Groups:             groupRepo,
+		Destinations:       destinationRepo,
+		Snoozes:            snoozeRepo,
+		OrgSettings:        orgSettingsRepo,
 		Email: providers.SMTPProvider{
 			Host: cfg.SMTPHost,
 			Port: cfg.SMTPPort,
@@ -52,28 +92,90 @@ func main() {
 		},
 		Slack:    providers.SlackWebhookProvider{},
 		Webhook:  providers.GenericWebhookProvider{},
-		Renderer: templates.Renderer{},
+		Teams:    providers.TeamsWebhookProvider{},
+		Renderer: renderer,
 		Metrics:  collector,
 		Defaults: domain.Defaults{
 			Emails:       cfg.DefaultEmails,
 			SlackWebhook: cfg.SlackDefaultWebhook,
 			WebhookURL:   cfg.WebhookDefaultTarget,
 		},
+		VerifyURL: cfg.DestinationVerifyURL,
 	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// "backfill-destinations" verifies the targets of existing preferences
+	// once, when destination verification is deployed, and exits.
+	if len(os.Args) > 1 && os.Args[1] == "backfill-destinations" {
+		n, err := svc.BackfillDestinations(ctx)
+		if err != nil {
+			log.Fatalf("destination backfill failed after %d: %v", n, err)
+		}
+		log.Printf("destination backfill added %d verified destinations", n)
+		return
+	}
+
 	kafka.StartConsumer(ctx, svc, cfg.KafkaBrokers, cfg.KafkaTopic, cfg.KafkaConsumerGroup)
+	scheduler.Start(ctx, "digests", cfg.DigestFlushInterval, func(ctx context.Context, now time.Time) error {
+		_, err := svc.FlushDigests(ctx, now)
+		return err
+	})
+	scheduler.Start(ctx, "scheduled deliveries", cfg.ScheduledInterval, func(ctx context.Context, now time.Time) error {
+		_, err := svc.DeliverScheduled(ctx, now)
+		return err
+	})
+	scheduler.Start(ctx, "scheduled events", cfg.ScheduledInterval, func(ctx context.Context, now time.Time) error {
+		_, err := svc.FireScheduledEvents(ctx, now)
+		return err
+	})
+	scheduler.Start(ctx, "escalations", cfg.ScheduledInterval, func(ctx context.Context, now time.Time) error {
+		_, err := svc.EscalateDue(ctx, now)
+		return err
+	})
+	scheduler.Start(ctx, "suppressed summaries", cfg.ScheduledInterval, func(ctx context.Context, now time.Time) error {
+		_, err := svc.SendSuppressedSummaries(ctx, now)
+		return err
+	})
+	scheduler.Start(ctx, "notification groups", cfg.ScheduledInterval, func(ctx context.Context, now time.Time) error {
+		_, err := svc.FlushGroups(ctx, now)
+		return err
+	})
+	scheduler.Start(ctx, "expired snoozes", cfg.ScheduledInterval, func(ctx context.Context, now time.Time) error {
+		_, err := svc.PurgeSnoozes(ctx, now)
+		return err
+	})
 
 	app := fiber.New()
 	api.RegisterRoutes(app, api.HandlerDeps{
-		Templates:    tplRepo,
-		Preferences:  prefRepo,
-		Logs:         logRepo,
-		Inbox:        inboxRepo,
-		Svc:          svc,
-		ServiceToken: cfg.ServiceToken,
+		Templates:          tplRepo,
+		Versions:           tplRepo,
+		Partials:           partialRepo,
+		Preferences:        prefRepo,
+		Logs:               logRepo,
+		Inbox:              inboxRepo,
+		QuietHours:         quietRepo,
+		ScheduledEvents:    scheduledEventRepo,
+		Escalations:        escalationRepo,
+		EscalationPolicies: escalationPolicyRepo,
+		Alerts:             alertRepo,
+		RateLimits:         rateLimitRepo,
+		GroupingRules:      groupingRuleRepo,
+		Routes:             routingRepo,
+		Audiences:          audienceRepo,
+		Watchers:           watcherRepo,
+		Destinations:       destinationRepo,
+		Snoozes:            snoozeRepo,
+		Renderer:           renderer,
+		Svc:                svc,
+		Tester:             svc,
+		Lifecycle:          svc,
+		Resolver:           svc,
+		Verifier:           svc,
+		UserSettings:       svc,
+		Channels:           svc.Channels(),
+		ServiceToken:       cfg.ServiceToken,
 	})
 
 	go func() {
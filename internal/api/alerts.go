@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"myesi-notification-service/internal/domain"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// AlertLifecycle moves alerts between states; implemented by NotificationService.
+type AlertLifecycle interface {
+	TransitionAlert(ctx context.Context, orgID int64, key, state, by string) (*domain.Alert, error)
+}
+
+// listAlerts returns the org's alerts, optionally filtered by ?state.
+func (h HandlerDeps) listAlerts(c *fiber.Ctx) error {
+	if h.Alerts == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "alerts not enabled"})
+	}
+	orgID := h.alertOrg(c)
+	if orgID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization required"})
+	}
+	state := c.Query("state")
+	switch state {
+	case "", domain.AlertOpen, domain.AlertAcknowledged, domain.AlertResolved:
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "state must be open, acknowledged or resolved"})
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+
+	res, err := h.Alerts.List(c.Context(), orgID, state, limit, offset)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(res)
+}
+
+func (h HandlerDeps) acknowledgeAlert(c *fiber.Ctx) error {
+	return h.transitionAlert(c, domain.AlertAcknowledged)
+}
+
+func (h HandlerDeps) resolveAlert(c *fiber.Ctx) error {
+	return h.transitionAlert(c, domain.AlertResolved)
+}
+
+// transitionAlert applies a state change to the alert named by :key. A change
+// the current state does not allow answers 409 with the alert as it is.
+func (h HandlerDeps) transitionAlert(c *fiber.Ctx, state string) error {
+	if h.Lifecycle == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "alerts not enabled"})
+	}
+	orgID := h.alertOrg(c)
+	if orgID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization required"})
+	}
+
+	alert, err := h.Lifecycle.TransitionAlert(c.Context(), orgID, c.Params("key"), state, requestAuthor(c))
+	switch {
+	case errors.Is(err, domain.ErrAlertsUnavailable):
+		return c.Status(501).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, domain.ErrAlertNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, domain.ErrInvalidTransition):
+		return c.Status(409).JSON(fiber.Map{"error": "alert is " + alert.State, "alert": alert})
+	case err != nil:
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(alert)
+}
+
+// alertOrg returns the caller's organization; trusted services may name one
+// with ?organization_id instead.
+func (h HandlerDeps) alertOrg(c *fiber.Ctx) int64 {
+	if orgID := extractOrgID(c); orgID != 0 {
+		return orgID
+	}
+	if !h.trustedCaller(c) {
+		return 0
+	}
+	orgID, _ := strconv.ParseInt(c.Query("organization_id"), 10, 64)
+	return orgID
+}
@@ -0,0 +1,89 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"myesi-notification-service/internal/api"
+	"myesi-notification-service/internal/domain"
+)
+
+type lifecycleMock struct {
+	alerts map[string]*domain.Alert
+	by     string
+}
+
+func (m *lifecycleMock) TransitionAlert(ctx context.Context, orgID int64, key, state, by string) (*domain.Alert, error) {
+	a, ok := m.alerts[key]
+	if !ok {
+		return nil, domain.ErrAlertNotFound
+	}
+	if a.State == state || a.State == domain.AlertResolved {
+		return a, domain.ErrInvalidTransition
+	}
+	a.State = state
+	m.by = by
+	return a, nil
+}
+
+func TestAlerts_AcknowledgeAndResolve(t *testing.T) {
+	m := &lifecycleMock{alerts: map[string]*domain.Alert{"vuln-42": {CorrelationKey: "vuln-42", State: domain.AlertOpen}}}
+	app := newApp(api.HandlerDeps{Lifecycle: m})
+
+	post := func(path string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("X-Organization-Id", "5")
+		req.Header.Set("X-User-Email", "lead@x.io")
+		resp, _ := app.Test(req)
+		return resp
+	}
+
+	if resp := post("/api/notification/alerts/vuln-42/ack"); resp.StatusCode != 200 || m.by != "lead@x.io" {
+		t.Fatalf("expected ack, got %d %q", resp.StatusCode, m.by)
+	}
+	resp := post("/api/notification/alerts/vuln-42/ack")
+	if resp.StatusCode != 409 {
+		t.Fatalf("expected 409 for double ack, got %d", resp.StatusCode)
+	}
+	if out := readJSON(t, resp); out["error"] != "alert is acknowledged" {
+		t.Fatalf("unexpected conflict body %v", out)
+	}
+	if resp := post("/api/notification/alerts/vuln-42/resolve"); resp.StatusCode != 200 {
+		t.Fatalf("expected resolve, got %d", resp.StatusCode)
+	}
+	if resp := post("/api/notification/alerts/missing/resolve"); resp.StatusCode != 404 {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestAlerts_RequiresOrganization(t *testing.T) {
+	app := newApp(api.HandlerDeps{Lifecycle: &lifecycleMock{}, ServiceToken: "secret"})
+	req, _ := http.NewRequest(http.MethodPost, "/api/notification/alerts/k/ack?organization_id=5", nil)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 400 {
+		t.Fatalf("untrusted callers cannot pick an organization, got %d", resp.StatusCode)
+	}
+}
+
+func TestAlerts_ListValidatesState(t *testing.T) {
+	app := newApp(api.HandlerDeps{Alerts: &alertsMock{}})
+	req, _ := http.NewRequest(http.MethodGet, "/api/notification/alerts?state=snoozed", nil)
+	resp, _ := app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 400 {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+type alertsMock struct{}
+
+func (alertsMock) Open(ctx domain.Context, a domain.Alert) (domain.Alert, error) { return a, nil }
+func (alertsMock) Get(ctx domain.Context, orgID int64, key string) (*domain.Alert, error) {
+	return nil, nil
+}
+func (alertsMock) List(ctx domain.Context, orgID int64, state string, limit, offset int) ([]domain.Alert, error) {
+	return nil, nil
+}
+func (alertsMock) Transition(ctx domain.Context, orgID int64, key, state, by string, from []string) (*domain.Alert, error) {
+	return nil, nil
+}
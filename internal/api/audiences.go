@@ -0,0 +1,67 @@
+package api
+
+import (
+	"strconv"
+
+	"myesi-notification-service/internal/domain"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+func (h HandlerDeps) listAudiences(c *fiber.Ctx) error {
+	if h.Audiences == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "audiences not enabled"})
+	}
+	orgID := extractOrgID(c)
+	if orgID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization required"})
+	}
+	res, err := h.Audiences.List(c.Context(), orgID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(res)
+}
+
+// saveAudience creates or replaces the org's audience for an event type glob.
+func (h HandlerDeps) saveAudience(c *fiber.Ctx) error {
+	if h.Audiences == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "audiences not enabled"})
+	}
+	orgID, err := orgAdminScope(c)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	var body domain.Audience
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+	}
+	body.OrganizationID = orgID
+	if err := body.Validate(); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	saved, err := h.Audiences.Save(c.Context(), body)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(saved)
+}
+
+func (h HandlerDeps) deleteAudience(c *fiber.Ctx) error {
+	if h.Audiences == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "audiences not enabled"})
+	}
+	orgID, err := orgAdminScope(c)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+	}
+	if err := h.Audiences.Delete(c.Context(), orgID, id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(204)
+}
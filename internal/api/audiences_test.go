@@ -0,0 +1,39 @@
+package api_test
+
+import (
+	"testing"
+
+	"myesi-notification-service/internal/api"
+	"myesi-notification-service/internal/domain"
+)
+
+type audienceMock struct{ saved *domain.Audience }
+
+func (m *audienceMock) List(ctx domain.Context, orgID int64) ([]domain.Audience, error) {
+	return nil, nil
+}
+func (m *audienceMock) Save(ctx domain.Context, a domain.Audience) (domain.Audience, error) {
+	m.saved = &a
+	return a, nil
+}
+func (m *audienceMock) Delete(ctx domain.Context, orgID, id int64) error { return nil }
+
+func TestAudiences_Save(t *testing.T) {
+	m := &audienceMock{}
+	app := newApp(api.HandlerDeps{Audiences: m})
+
+	req := putJSON(t, "/api/notification/audiences", `{"event_type":"payment.*"}`)
+	resp, _ := app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 400 {
+		t.Fatalf("expected 400 for an empty audience, got %d", resp.StatusCode)
+	}
+
+	req = putJSON(t, "/api/notification/audiences", `{"event_type":"payment.*","roles":["admin","billing"],"exclude_actor":true}`)
+	resp, _ = app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	if m.saved == nil || m.saved.OrganizationID != 5 || len(m.saved.Roles) != 2 || !m.saved.ExcludeActor {
+		t.Fatalf("unexpected saved audience %#v", m.saved)
+	}
+}
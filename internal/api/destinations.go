@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"myesi-notification-service/internal/domain"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// DestinationVerifier sends and checks destination confirmation codes;
+// implemented by NotificationService.
+type DestinationVerifier interface {
+	RequestVerification(ctx context.Context, orgID int64, channel, target string) (domain.Destination, error)
+	ResendVerification(ctx context.Context, orgID, id int64) (domain.Destination, error)
+	ConfirmDestination(ctx context.Context, orgID, id int64, code string) (domain.Destination, error)
+}
+
+// preferenceResponse is a saved preference with the verification state of
+// its target.
+type preferenceResponse struct {
+	domain.NotificationPreference
+	Verification string `json:"verification,omitempty"`
+}
+
+// verifyTarget asks for confirmation of each address of an enabled
+// preference's target. The target is verified once all of them are; a
+// failed request leaves an address pending and it can be resent.
+func (h HandlerDeps) verifyTarget(ctx context.Context, p domain.NotificationPreference) preferenceResponse {
+	out := preferenceResponse{NotificationPreference: p}
+	if h.Verifier == nil || !p.Enabled || p.Target == "" || !domain.NeedsVerification(p.Channel) {
+		return out
+	}
+	addrs := domain.VerificationTargets(p.Channel, p.Target)
+	verified := 0
+	for _, addr := range addrs {
+		if d, _ := h.Verifier.RequestVerification(ctx, p.OrganizationID, p.Channel, addr); d.Status == domain.DestinationVerified {
+			verified++
+		}
+	}
+	out.Verification = domain.DestinationPending
+	if len(addrs) > 0 && verified == len(addrs) {
+		out.Verification = domain.DestinationVerified
+	}
+	return out
+}
+
+func (h HandlerDeps) listDestinations(c *fiber.Ctx) error {
+	if h.Destinations == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "destination verification not enabled"})
+	}
+	orgID := preferenceOrg(c)
+	if orgID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization required"})
+	}
+	res, err := h.Destinations.List(c.Context(), orgID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(res)
+}
+
+// resendDestination sends a new confirmation code to a pending destination.
+func (h HandlerDeps) resendDestination(c *fiber.Ctx) error {
+	return h.verifyDestination(c, func(ctx context.Context, orgID, id int64) (domain.Destination, error) {
+		return h.Verifier.ResendVerification(ctx, orgID, id)
+	})
+}
+
+// confirmDestination verifies a destination with the code from its
+// confirmation message.
+func (h HandlerDeps) confirmDestination(c *fiber.Ctx) error {
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Code == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "code is required"})
+	}
+	return h.verifyDestination(c, func(ctx context.Context, orgID, id int64) (domain.Destination, error) {
+		return h.Verifier.ConfirmDestination(ctx, orgID, id, body.Code)
+	})
+}
+
+func (h HandlerDeps) verifyDestination(c *fiber.Ctx, fn func(ctx context.Context, orgID, id int64) (domain.Destination, error)) error {
+	if h.Verifier == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "destination verification not enabled"})
+	}
+	orgID := preferenceOrg(c)
+	if orgID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization required"})
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	d, err := fn(c.Context(), orgID, id)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "destination not found"})
+	case errors.Is(err, domain.ErrInvalidCode), errors.Is(err, domain.ErrVerificationExpired):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, domain.ErrResendTooSoon), errors.Is(err, domain.ErrTooManyAttempts):
+		return c.Status(429).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(d)
+}
@@ -0,0 +1,80 @@
+package api_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"myesi-notification-service/internal/api"
+	"myesi-notification-service/internal/domain"
+)
+
+type verifierMock struct {
+	requested []string
+}
+
+func (m *verifierMock) RequestVerification(ctx context.Context, orgID int64, channel, target string) (domain.Destination, error) {
+	m.requested = append(m.requested, channel+" "+target)
+	return domain.Destination{ID: 1, Status: domain.DestinationPending}, nil
+}
+func (m *verifierMock) ResendVerification(ctx context.Context, orgID, id int64) (domain.Destination, error) {
+	return domain.Destination{}, domain.ErrResendTooSoon
+}
+func (m *verifierMock) ConfirmDestination(ctx context.Context, orgID, id int64, code string) (domain.Destination, error) {
+	switch {
+	case id != 1:
+		return domain.Destination{}, domain.ErrNotFound
+	case code != "123456":
+		return domain.Destination{}, domain.ErrInvalidCode
+	}
+	return domain.Destination{ID: 1, OrganizationID: orgID, Status: domain.DestinationVerified}, nil
+}
+
+func TestCreatePreference_RequestsVerification(t *testing.T) {
+	m := &verifierMock{}
+	app := newApp(api.HandlerDeps{Preferences: &stubPrefs{}, Verifier: m})
+
+	resp, _ := app.Test(postJSON(t, "/api/notification/preferences",
+		`{"organization_id":1,"event_type":"*","channel":"email","target":"sec@x.io","enabled":true}`))
+	data := readJSON(t, resp)
+	if resp.StatusCode != 201 || data["verification"] != domain.DestinationPending {
+		t.Fatalf("expected pending verification, got %d %v", resp.StatusCode, data)
+	}
+	// Opt-out rows point nowhere and need no confirmation.
+	_, _ = app.Test(postJSON(t, "/api/notification/preferences",
+		`{"organization_id":1,"event_type":"*","channel":"slack","enabled":false}`))
+	if len(m.requested) != 1 || m.requested[0] != "email sec@x.io" {
+		t.Fatalf("unexpected verification requests %v", m.requested)
+	}
+}
+
+func TestCreatePreference_VerifiesEachEmailAddress(t *testing.T) {
+	m := &verifierMock{}
+	app := newApp(api.HandlerDeps{Preferences: &stubPrefs{}, Verifier: m})
+
+	resp, _ := app.Test(postJSON(t, "/api/notification/preferences",
+		`{"organization_id":1,"event_type":"*","channel":"email","target":"a@x.io,b@x.io","enabled":true}`))
+	if resp.StatusCode != 201 || strings.Join(m.requested, "|") != "email a@x.io|email b@x.io" {
+		t.Fatalf("expected one verification per address, got %d %v", resp.StatusCode, m.requested)
+	}
+}
+
+func TestConfirmDestination(t *testing.T) {
+	app := newApp(api.HandlerDeps{Verifier: &verifierMock{}})
+	for _, c := range []struct {
+		path, body string
+		want       int
+	}{
+		{"/api/notification/destinations/1/confirm", `{"code":"123456"}`, 400},
+		{"/api/notification/destinations/1/confirm?organization_id=5", `{}`, 400},
+		{"/api/notification/destinations/1/confirm?organization_id=5", `{"code":"999999"}`, 400},
+		{"/api/notification/destinations/2/confirm?organization_id=5", `{"code":"123456"}`, 404},
+		{"/api/notification/destinations/1/confirm?organization_id=5", `{"code":"123456"}`, 200},
+		{"/api/notification/destinations/1/resend?organization_id=5", `{}`, 429},
+	} {
+		resp, _ := app.Test(postJSON(t, c.path, c.body))
+		if resp.StatusCode != c.want {
+			t.Fatalf("%s %s: expected %d got %d", c.path, c.body, c.want, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"strconv"
+
+	"myesi-notification-service/internal/domain"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+func (h HandlerDeps) listEscalationPolicies(c *fiber.Ctx) error {
+	if h.EscalationPolicies == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "escalations not enabled"})
+	}
+	orgID := extractOrgID(c)
+	if orgID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization required"})
+	}
+	res, err := h.EscalationPolicies.List(c.Context(), orgID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(res)
+}
+
+// saveEscalationPolicy creates or replaces the org's policy for an event type.
+// Policies are enabled and apply to critical events unless the body says otherwise.
+func (h HandlerDeps) saveEscalationPolicy(c *fiber.Ctx) error {
+	if h.EscalationPolicies == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "escalations not enabled"})
+	}
+	orgID, err := orgAdminScope(c)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	body := domain.EscalationPolicy{Enabled: true}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+	}
+	body.OrganizationID = orgID
+	if body.SeverityMin == "" {
+		body.SeverityMin = "critical"
+	}
+	if err := body.Validate(); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	saved, err := h.EscalationPolicies.Save(c.Context(), body)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(saved)
+}
+
+func (h HandlerDeps) deleteEscalationPolicy(c *fiber.Ctx) error {
+	if h.EscalationPolicies == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "escalations not enabled"})
+	}
+	orgID, err := orgAdminScope(c)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+	}
+	if err := h.EscalationPolicies.Delete(c.Context(), orgID, id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(204)
+}
+
+// listEscalations returns the org's unacknowledged escalations.
+func (h HandlerDeps) listEscalations(c *fiber.Ctx) error {
+	if h.Escalations == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "escalations not enabled"})
+	}
+	orgID := extractOrgID(c)
+	if orgID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization required"})
+	}
+	res, err := h.Escalations.ListOpen(c.Context(), orgID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(res)
+}
+
+// acknowledgeEscalation stops an escalation chain. Any org member may
+// acknowledge; reading an escalated inbox item has the same effect.
+func (h HandlerDeps) acknowledgeEscalation(c *fiber.Ctx) error {
+	if h.Escalations == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "escalations not enabled"})
+	}
+	orgID := extractOrgID(c)
+	if orgID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization required"})
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+	}
+	ok, err := h.Escalations.Acknowledge(c.Context(), orgID, id, requestAuthor(c))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "no open escalation"})
+	}
+	return c.JSON(fiber.Map{"status": "acknowledged"})
+}
@@ -0,0 +1,112 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/api"
+	"myesi-notification-service/internal/domain"
+)
+
+type escalationPolicyMock struct {
+	saved *domain.EscalationPolicy
+}
+
+func (m *escalationPolicyMock) List(ctx domain.Context, orgID int64) ([]domain.EscalationPolicy, error) {
+	return nil, nil
+}
+func (m *escalationPolicyMock) Get(ctx domain.Context, orgID, id int64) (*domain.EscalationPolicy, error) {
+	return nil, nil
+}
+func (m *escalationPolicyMock) FindForEvent(ctx domain.Context, orgID int64, eventType string) (*domain.EscalationPolicy, error) {
+	return nil, nil
+}
+func (m *escalationPolicyMock) Save(ctx domain.Context, p domain.EscalationPolicy) (domain.EscalationPolicy, error) {
+	m.saved = &p
+	return p, nil
+}
+func (m *escalationPolicyMock) Delete(ctx domain.Context, orgID, id int64) error { return nil }
+
+type escalationMock struct {
+	open  map[int64]bool
+	ackBy string
+}
+
+func (m *escalationMock) Start(ctx domain.Context, e domain.Escalation) (domain.Escalation, error) {
+	return e, nil
+}
+func (m *escalationMock) ClaimDue(ctx domain.Context, now time.Time, lease time.Duration, limit int) ([]domain.Escalation, error) {
+	return nil, nil
+}
+func (m *escalationMock) Advance(ctx domain.Context, id int64, tier int, nextAt *time.Time, inboxIDs []int64) error {
+	return nil
+}
+func (m *escalationMock) Acknowledge(ctx domain.Context, orgID, id int64, by string) (bool, error) {
+	if !m.open[id] {
+		return false, nil
+	}
+	m.open[id] = false
+	m.ackBy = by
+	return true, nil
+}
+func (m *escalationMock) ListOpen(ctx domain.Context, orgID int64) ([]domain.Escalation, error) {
+	return nil, nil
+}
+
+func TestEscalationPolicies_SaveDefaultsAndValidates(t *testing.T) {
+	m := &escalationPolicyMock{}
+	app := newApp(api.HandlerDeps{EscalationPolicies: m})
+
+	req := putJSON(t, "/api/notification/escalation-policies", `{"event_type":"vulnerability.critical","tiers":[{"after_minutes":15,"role":"team_lead"}]}`)
+	req.Header.Set("X-Organization-Id", "5")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 403 {
+		t.Fatalf("non-admins cannot manage policies, got %d", resp.StatusCode)
+	}
+
+	req = putJSON(t, "/api/notification/escalation-policies", `{"event_type":"vulnerability.critical","tiers":[{"after_minutes":0,"role":"team_lead"}]}`)
+	resp, _ = app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 400 {
+		t.Fatalf("expected 400 for invalid tier, got %d", resp.StatusCode)
+	}
+
+	req = putJSON(t, "/api/notification/escalation-policies",
+		`{"organization_id":9,"event_type":"vulnerability.critical","tiers":[{"after_minutes":15,"role":"team_lead"},{"after_minutes":30,"channel":"email"}]}`)
+	resp, _ = app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	if m.saved == nil || m.saved.OrganizationID != 5 || !m.saved.Enabled || m.saved.SeverityMin != "critical" || len(m.saved.Tiers) != 2 {
+		t.Fatalf("unexpected saved policy %#v", m.saved)
+	}
+}
+
+func TestEscalations_Acknowledge(t *testing.T) {
+	m := &escalationMock{open: map[int64]bool{3: true}}
+	app := newApp(api.HandlerDeps{Escalations: m})
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/notification/escalations/3/ack", nil)
+	req.Header.Set("X-Organization-Id", "5")
+	req.Header.Set("X-User-Email", "lead@x.io")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 || m.ackBy != "lead@x.io" {
+		t.Fatalf("expected ack by lead, got %d %q", resp.StatusCode, m.ackBy)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, "/api/notification/escalations/3/ack", nil)
+	req.Header.Set("X-Organization-Id", "5")
+	resp, _ = app.Test(req)
+	if resp.StatusCode != 404 {
+		t.Fatalf("acknowledged escalation should be gone, got %d", resp.StatusCode)
+	}
+}
+
+func TestEscalations_NotEnabled(t *testing.T) {
+	app := newApp(api.HandlerDeps{})
+	req, _ := http.NewRequest(http.MethodGet, "/api/notification/escalations", nil)
+	resp, _ := app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 501 {
+		t.Fatalf("expected 501 got %d", resp.StatusCode)
+	}
+}
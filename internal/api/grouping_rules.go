@@ -0,0 +1,69 @@
+package api
+
+import (
+	"strconv"
+
+	"myesi-notification-service/internal/domain"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+func (h HandlerDeps) listGroupingRules(c *fiber.Ctx) error {
+	if h.GroupingRules == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "grouping not enabled"})
+	}
+	orgID := extractOrgID(c)
+	if orgID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization required"})
+	}
+	res, err := h.GroupingRules.List(c.Context(), orgID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(res)
+}
+
+// saveGroupingRule creates or replaces the org's rule for an event type; an
+// empty event type applies to all events. Rules are enabled unless the body
+// says otherwise.
+func (h HandlerDeps) saveGroupingRule(c *fiber.Ctx) error {
+	if h.GroupingRules == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "grouping not enabled"})
+	}
+	orgID, err := orgAdminScope(c)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	body := domain.GroupingRule{Enabled: true}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+	}
+	body.OrganizationID = orgID
+	if err := body.Validate(); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	saved, err := h.GroupingRules.Save(c.Context(), body)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(saved)
+}
+
+func (h HandlerDeps) deleteGroupingRule(c *fiber.Ctx) error {
+	if h.GroupingRules == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "grouping not enabled"})
+	}
+	orgID, err := orgAdminScope(c)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+	}
+	if err := h.GroupingRules.Delete(c.Context(), orgID, id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(204)
+}
@@ -0,0 +1,42 @@
+package api_test
+
+import (
+	"testing"
+
+	"myesi-notification-service/internal/api"
+	"myesi-notification-service/internal/domain"
+)
+
+type groupingRuleMock struct{ saved *domain.GroupingRule }
+
+func (m *groupingRuleMock) List(ctx domain.Context, orgID int64) ([]domain.GroupingRule, error) {
+	return nil, nil
+}
+func (m *groupingRuleMock) Save(ctx domain.Context, r domain.GroupingRule) (domain.GroupingRule, error) {
+	m.saved = &r
+	return r, nil
+}
+func (m *groupingRuleMock) Delete(ctx domain.Context, orgID, id int64) error { return nil }
+func (m *groupingRuleMock) Match(ctx domain.Context, orgID int64, eventType string) (*domain.GroupingRule, error) {
+	return nil, nil
+}
+
+func TestGroupingRules_Save(t *testing.T) {
+	m := &groupingRuleMock{}
+	app := newApp(api.HandlerDeps{GroupingRules: m})
+
+	req := putJSON(t, "/api/notification/grouping-rules", `{"event_type":"vulnerability.critical","group_by":["project"]}`)
+	resp, _ := app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 400 {
+		t.Fatalf("expected 400 without a window, got %d", resp.StatusCode)
+	}
+
+	req = putJSON(t, "/api/notification/grouping-rules", `{"event_type":"vulnerability.critical","group_by":["project"],"window_seconds":60}`)
+	resp, _ = app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	if m.saved == nil || m.saved.OrganizationID != 5 || !m.saved.Enabled || m.saved.Window != 60 {
+		t.Fatalf("unexpected saved rule %#v", m.saved)
+	}
+}
@@ -2,10 +2,14 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"myesi-notification-service/internal/domain"
+	"myesi-notification-service/internal/templates"
 
 	fiber "github.com/gofiber/fiber/v2"
 )
@@ -21,9 +25,69 @@ func RegisterRoutes(app *fiber.App, deps HandlerDeps) {
 
 	api.Get("/templates", deps.listTemplates)
 	api.Post("/templates", deps.upsertTemplate)
+	api.Post("/templates/preview", deps.previewTemplate)
+	api.Get("/templates/:id/versions", deps.listTemplateVersions)
+	api.Get("/templates/:id/versions/diff", deps.diffTemplateVersions)
+	api.Post("/templates/:id/versions/:version/rollback", deps.rollbackTemplate)
+	api.Post("/templates/:id/test", deps.testTemplate)
+
+	api.Get("/partials", deps.listPartials)
+	api.Post("/partials", deps.upsertPartial)
+	api.Delete("/partials/:id", deps.deletePartial)
 
 	api.Get("/preferences", deps.listPreferences)
+	api.Get("/preferences/effective", deps.effectivePreferences)
+	api.Post("/preferences", deps.createPreference)
+	api.Put("/preferences", deps.replacePreferences)
 	api.Put("/preferences/:id", deps.updatePreference)
+	api.Delete("/preferences/:id", deps.deletePreference)
+
+	api.Get("/destinations", deps.listDestinations)
+	api.Post("/destinations/:id/resend", deps.resendDestination)
+	api.Post("/destinations/:id/confirm", deps.confirmDestination)
+
+	api.Get("/me/settings", deps.getMySettings)
+	api.Put("/me/settings", deps.saveMySettings)
+	api.Get("/me/snoozes", deps.listSnoozes)
+	api.Post("/me/snoozes", deps.createSnooze)
+	api.Delete("/me/snoozes/:id", deps.cancelSnooze)
+	api.Post("/preferences/:id/test", deps.testPreference)
+
+	api.Get("/quiet-hours", deps.getQuietHours)
+	api.Put("/quiet-hours", deps.saveQuietHours)
+	api.Delete("/quiet-hours", deps.deleteQuietHours)
+
+	api.Get("/rate-limits", deps.listRateLimits)
+	api.Put("/rate-limits", deps.saveRateLimit)
+	api.Delete("/rate-limits/:id", deps.deleteRateLimit)
+
+	api.Get("/watches", deps.listWatches)
+	api.Put("/projects/:id/watch", deps.watchProject)
+	api.Delete("/projects/:id/watch", deps.unwatchProject)
+
+	api.Get("/audiences", deps.listAudiences)
+	api.Put("/audiences", deps.saveAudience)
+	api.Delete("/audiences/:id", deps.deleteAudience)
+
+	api.Get("/routing-rules", deps.listRoutingRules)
+	api.Post("/routing-rules", deps.saveRoutingRule)
+	api.Post("/routing-rules/evaluate", deps.evaluateRoutingRules)
+	api.Put("/routing-rules/:id", deps.saveRoutingRule)
+	api.Delete("/routing-rules/:id", deps.deleteRoutingRule)
+
+	api.Get("/grouping-rules", deps.listGroupingRules)
+	api.Put("/grouping-rules", deps.saveGroupingRule)
+	api.Delete("/grouping-rules/:id", deps.deleteGroupingRule)
+
+	api.Get("/escalation-policies", deps.listEscalationPolicies)
+	api.Put("/escalation-policies", deps.saveEscalationPolicy)
+	api.Delete("/escalation-policies/:id", deps.deleteEscalationPolicy)
+	api.Get("/escalations", deps.listEscalations)
+	api.Post("/escalations/:id/ack", deps.acknowledgeEscalation)
+
+	api.Get("/alerts", deps.listAlerts)
+	api.Post("/alerts/:key/ack", deps.acknowledgeAlert)
+	api.Post("/alerts/:key/resolve", deps.resolveAlert)
 
 	api.Get("/logs", deps.listLogs)
 
@@ -35,38 +99,98 @@ func RegisterRoutes(app *fiber.App, deps HandlerDeps) {
 
 	// Internal event ingress (used by services like billing)
 	api.Post("/events", deps.ingestEvent)
+	api.Delete("/events/scheduled/:key", deps.cancelScheduledEvent)
 }
 
 type Notifier interface {
 	HandleEvent(ctx context.Context, evt domain.NotificationEvent) error
 }
 
+// PreferenceResolver explains which destinations an event reaches; implemented
+// by NotificationService.
+type PreferenceResolver interface {
+	EffectivePreferences(ctx context.Context, evt domain.NotificationEvent) ([]domain.EffectivePreference, error)
+}
+
 // HandlerDeps groups dependencies for handlers.
 type HandlerDeps struct {
-	Templates    domain.TemplateRepository
-	Preferences  domain.PreferenceRepository
-	Logs         domain.LogRepository
-	Inbox        domain.InboxRepository
-	ServiceToken string
-	Svc          Notifier
+	Templates          domain.TemplateRepository
+	Versions           domain.TemplateVersionRepository
+	Partials           domain.PartialRepository
+	Preferences        domain.PreferenceRepository
+	Logs               domain.LogRepository
+	Inbox              domain.InboxRepository
+	QuietHours         domain.QuietHoursRepository
+	ScheduledEvents    domain.ScheduledEventRepository
+	Escalations        domain.EscalationRepository
+	EscalationPolicies domain.EscalationPolicyRepository
+	Alerts             domain.AlertRepository
+	RateLimits         domain.RateLimitRepository
+	GroupingRules      domain.GroupingRuleRepository
+	Routes             domain.RoutingRuleRepository
+	Audiences          domain.AudienceRepository
+	Watchers           domain.WatcherRepository
+	Destinations       domain.DestinationRepository
+	Snoozes            domain.SnoozeRepository
+	Renderer           templates.Renderer
+	ServiceToken       string
+	Svc                Notifier
+	Tester             TestSender
+	Lifecycle          AlertLifecycle
+	Resolver           PreferenceResolver
+	Verifier           DestinationVerifier
+	UserSettings       UserSettingsManager
+	// Channels lists the channels with a registered provider; nil accepts
+	// every known channel.
+	Channels []string
 }
 
+// listTemplates returns the caller's org overrides, or global defaults for trusted callers.
 func (h HandlerDeps) listTemplates(c *fiber.Ctx) error {
+	orgID, err := h.templateScope(c, false)
+	if err != nil {
+		return errorJSON(c, err)
+	}
 	limit, _ := strconv.Atoi(c.Query("limit", "50"))
 	offset, _ := strconv.Atoi(c.Query("offset", "0"))
 
-	res, err := h.Templates.List(c.Context(), limit, offset)
+	res, err := h.Templates.List(c.Context(), orgID, limit, offset)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 	return c.JSON(res)
 }
 
+// upsertTemplate saves an org override for org admins, or a global default for trusted callers.
 func (h HandlerDeps) upsertTemplate(c *fiber.Ctx) error {
+	orgID, err := h.templateScope(c, true)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+
 	var body domain.NotificationTemplate
 	if err := c.BodyParser(&body); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
 	}
+	switch body.Format {
+	case "", domain.FormatText, domain.FormatMarkdown:
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "format must be text or markdown"})
+	}
+	set, err := h.partialSet(c, orgID, body.Channel)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if issues := h.validateTemplate(set, body.Subject, body.Body); len(issues) > 0 {
+		return c.Status(422).JSON(fiber.Map{"error": "invalid template", "details": issues})
+	}
+	// Org callers can only write their own overrides, regardless of the body.
+	body.OrganizationID = nil
+	if orgID != 0 {
+		body.OrganizationID = &orgID
+	}
+	// The version history's author always comes from the request identity.
+	body.Author = requestAuthor(c)
 	saved, err := h.Templates.Upsert(c.Context(), body)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
@@ -90,6 +214,33 @@ func (h HandlerDeps) listPreferences(c *fiber.Ctx) error {
 	return c.JSON(prefs)
 }
 
+// effectivePreferences explains which destinations ?event_type reaches for
+// ?organization_id and ?user_id, and which preference layer each came from.
+func (h HandlerDeps) effectivePreferences(c *fiber.Ctx) error {
+	if h.Resolver == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "effective preferences not enabled"})
+	}
+	eventType := c.Query("event_type")
+	if eventType == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "event_type is required"})
+	}
+	orgID := preferenceOrg(c)
+	evt := domain.NotificationEvent{EventType: eventType, OrganizationID: orgID, Severity: c.Query("severity")}
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid user_id"})
+		}
+		evt.UserID = &id
+	}
+
+	res, err := h.Resolver.EffectivePreferences(c.Context(), evt)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(res)
+}
+
 func (h HandlerDeps) updatePreference(c *fiber.Ctx) error {
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
 	if err != nil {
@@ -101,12 +252,120 @@ func (h HandlerDeps) updatePreference(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
 	}
 	body.ID = id
+	if err := h.validatePreference(body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	saved, err := h.Preferences.Save(c.Context(), body)
+	if errors.Is(err, domain.ErrNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "preference not found"})
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(saved)
+	return c.JSON(h.verifyTarget(c.Context(), saved))
+}
+
+// createPreference adds a preference, or updates the one with the same
+// organization, user, event type and channel. Unverified targets are sent a
+// confirmation code and stay unused until confirmed.
+func (h HandlerDeps) createPreference(c *fiber.Ctx) error {
+	var body domain.NotificationPreference
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+	}
+	body.ID = 0
+	if body.OrganizationID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization_id is required"})
+	}
+	if err := h.validatePreference(body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	saved, err := h.Preferences.Save(c.Context(), body)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(201).JSON(h.verifyTarget(c.Context(), saved))
+}
+
+// deletePreference removes a preference of ?organization_id.
+func (h HandlerDeps) deletePreference(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+	}
+	orgID := preferenceOrg(c)
+	if orgID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization required"})
+	}
+
+	if err := h.Preferences.Delete(c.Context(), orgID, id); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "preference not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(204)
+}
+
+type replacePreferencesRequest struct {
+	OrganizationID int64                           `json:"organization_id"`
+	UserID         *int64                          `json:"user_id,omitempty"`
+	Preferences    []domain.NotificationPreference `json:"preferences"`
+}
+
+// replacePreferences swaps every preference of one scope, the organization's
+// own rows or one user's, for the body's list. Nothing is written unless
+// every row is valid.
+func (h HandlerDeps) replacePreferences(c *fiber.Ctx) error {
+	var body replacePreferencesRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+	}
+	if body.OrganizationID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization_id is required"})
+	}
+	seen := map[string]bool{}
+	for i := range body.Preferences {
+		p := &body.Preferences[i]
+		p.ID, p.OrganizationID, p.UserID = 0, body.OrganizationID, body.UserID
+		if err := h.validatePreference(*p); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("preferences[%d]: %v", i, err)})
+		}
+		key := p.EventType + "/" + p.Channel
+		if seen[key] {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("preferences[%d]: duplicate %s preference for %s", i, p.Channel, p.EventType)})
+		}
+		seen[key] = true
+	}
+
+	saved, err := h.Preferences.Replace(c.Context(), body.OrganizationID, body.UserID, body.Preferences)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	out := make([]preferenceResponse, 0, len(saved))
+	for _, p := range saved {
+		out = append(out, h.verifyTarget(c.Context(), p))
+	}
+	return c.JSON(out)
+}
+
+// validatePreference checks a preference and that its channel has a
+// registered provider.
+func (h HandlerDeps) validatePreference(p domain.NotificationPreference) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if h.Channels == nil {
+		return nil
+	}
+	for _, ch := range h.Channels {
+		if ch == p.Channel {
+			return nil
+		}
+	}
+	return fmt.Errorf("channel %q has no registered provider", p.Channel)
 }
 
 func (h HandlerDeps) listLogs(c *fiber.Ctx) error {
@@ -218,12 +477,123 @@ func (h HandlerDeps) ingestEvent(c *fiber.Ctx) error {
 	if evt.OccurredAt.IsZero() {
 		evt.OccurredAt = time.Now().UTC()
 	}
+	deliverAt, err := evt.ScheduledFor(time.Now())
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
 	if err := h.Svc.HandleEvent(c.Context(), evt); err != nil {
+		if errors.Is(err, domain.ErrSchedulingUnavailable) {
+			return c.Status(501).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
+	if !deliverAt.IsZero() {
+		return c.Status(202).JSON(fiber.Map{"status": "scheduled", "deliver_at": deliverAt})
+	}
 	return c.Status(202).JSON(fiber.Map{"status": "accepted"})
 }
 
+// cancelScheduledEvent drops a pending delayed event by its schedule key.
+func (h HandlerDeps) cancelScheduledEvent(c *fiber.Ctx) error {
+	if h.ScheduledEvents == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "scheduled events not enabled"})
+	}
+	if !h.trustedCaller(c) {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+	orgID := extractOrgID(c)
+	if orgID == 0 {
+		orgID, _ = strconv.ParseInt(c.Query("organization_id"), 10, 64)
+	}
+
+	ok, err := h.ScheduledEvents.Cancel(c.Context(), orgID, c.Params("key"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if !ok {
+		return c.Status(404).JSON(fiber.Map{"error": "no pending event with that key"})
+	}
+	return c.SendStatus(204)
+}
+
+// extractOrgID reads the caller's organization as forwarded by the gateway.
+func extractOrgID(c *fiber.Ctx) int64 {
+	if v := c.Get("X-Organization-Id"); v != "" {
+		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return id
+		}
+	}
+	return 0
+}
+
+// preferenceOrg reads ?organization_id like the other preference endpoints,
+// falling back to the organization header.
+func preferenceOrg(c *fiber.Ctx) int64 {
+	if orgID, _ := strconv.ParseInt(c.Query("organization_id", "0"), 10, 64); orgID != 0 {
+		return orgID
+	}
+	return extractOrgID(c)
+}
+
+func isOrgAdmin(c *fiber.Ctx) bool {
+	role := strings.ToLower(c.Get("X-User-Role"))
+	return role == "admin" || role == "owner"
+}
+
+// templateScope resolves which templates the caller may access: their org's
+// overrides (writes need an admin role) or global defaults for trusted callers.
+func (h HandlerDeps) templateScope(c *fiber.Ctx, write bool) (int64, error) {
+	orgID := extractOrgID(c)
+	switch {
+	case orgID != 0 && write && !isOrgAdmin(c):
+		return 0, fiber.NewError(403, "admin role required")
+	case orgID == 0 && !h.trustedCaller(c):
+		return 0, fiber.NewError(401, "unauthorized")
+	}
+	return orgID, nil
+}
+
+// orgAdminScope returns the caller's organization when they may manage
+// its settings.
+func orgAdminScope(c *fiber.Ctx) (int64, error) {
+	orgID := extractOrgID(c)
+	if orgID == 0 {
+		return 0, fiber.NewError(400, "organization required")
+	}
+	if !isOrgAdmin(c) {
+		return 0, fiber.NewError(403, "admin role required")
+	}
+	return orgID, nil
+}
+
+// errorJSON renders a *fiber.Error with its status code and anything else as a 500.
+func errorJSON(c *fiber.Ctx, err error) error {
+	if fe, ok := err.(*fiber.Error); ok {
+		return c.Status(fe.Code).JSON(fiber.Map{"error": fe.Message})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+}
+
+// requestAuthor identifies who made a change for audit trails.
+func requestAuthor(c *fiber.Ctx) string {
+	if v := c.Get("X-User-Email"); v != "" {
+		return v
+	}
+	if id := extractUserID(c); id != 0 {
+		return "user:" + strconv.FormatInt(id, 10)
+	}
+	if c.Get("X-Service-Token") != "" {
+		return "service"
+	}
+	return ""
+}
+
+// trustedCaller reports whether the request comes from an internal service.
+// When no service token is configured every caller is trusted, mirroring ingestEvent.
+func (h HandlerDeps) trustedCaller(c *fiber.Ctx) bool {
+	return h.ServiceToken == "" || c.Get("X-Service-Token") == h.ServiceToken
+}
+
 func extractUserID(c *fiber.Ctx) int64 {
 	if v := c.Get("X-User-Id"); v != "" {
 		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,30 +17,39 @@ import (
 )
 
 type stubTemplates struct {
-	listErr   error
-	upsertErr error
+	listErr    error
+	upsertErr  error
+	lastOrgID  int64
+	lastUpsert domain.NotificationTemplate
 }
 
-func (s *stubTemplates) List(ctx domain.Context, limit, offset int) ([]domain.NotificationTemplate, error) {
+func (s *stubTemplates) List(ctx domain.Context, orgID int64, limit, offset int) ([]domain.NotificationTemplate, error) {
+	s.lastOrgID = orgID
 	if s.listErr != nil {
 		return nil, s.listErr
 	}
 	return []domain.NotificationTemplate{{ID: 1, Name: "t", EventType: "x", Channel: "email"}}, nil
 }
 func (s *stubTemplates) Upsert(ctx domain.Context, tpl domain.NotificationTemplate) (domain.NotificationTemplate, error) {
+	s.lastUpsert = tpl
 	if s.upsertErr != nil {
 		return domain.NotificationTemplate{}, s.upsertErr
 	}
 	tpl.ID = 99
 	return tpl, nil
 }
-func (s *stubTemplates) FindByEventAndChannel(ctx domain.Context, eventType, channel string) (*domain.NotificationTemplate, error) {
+func (s *stubTemplates) FindByEventAndChannel(ctx domain.Context, orgID int64, eventType, channel, locale string) (*domain.NotificationTemplate, error) {
+	return nil, nil
+}
+func (s *stubTemplates) Get(ctx domain.Context, orgID, id int64) (*domain.NotificationTemplate, error) {
 	return nil, nil
 }
 
 type stubPrefs struct {
-	listErr error
-	saveErr error
+	listErr  error
+	saveErr  error
+	deleted  int64
+	replaced []domain.NotificationPreference
 }
 
 func (s *stubPrefs) List(ctx domain.Context, orgID int64, userID *int64, eventType string) ([]domain.NotificationPreference, error) {
@@ -57,6 +67,24 @@ func (s *stubPrefs) Save(ctx domain.Context, pref domain.NotificationPreference)
 	}
 	return pref, nil
 }
+func (s *stubPrefs) Get(ctx domain.Context, id int64) (*domain.NotificationPreference, error) {
+	return nil, nil
+}
+func (s *stubPrefs) Delete(ctx domain.Context, orgID, id int64) error {
+	if id != 1 {
+		return domain.ErrNotFound
+	}
+	s.deleted = id
+	return nil
+}
+func (s *stubPrefs) Replace(ctx domain.Context, orgID int64, userID *int64, prefs []domain.NotificationPreference) ([]domain.NotificationPreference, error) {
+	s.replaced = prefs
+	return prefs, nil
+}
+func (s *stubPrefs) ReplaceMatrix(ctx domain.Context, orgID, userID int64, eventTypes, channels []string, prefs []domain.NotificationPreference) ([]domain.NotificationPreference, error) {
+	s.replaced = prefs
+	return prefs, nil
+}
 
 type stubLogs struct {
 	listErr error
@@ -167,6 +195,73 @@ func TestUpsertTemplate_Success(t *testing.T) {
 	}
 }
 
+func TestListTemplates_ScopedToCallerOrg(t *testing.T) {
+	tpls := &stubTemplates{}
+	app := newApp(api.HandlerDeps{Templates: tpls, ServiceToken: "secret"})
+	req, _ := http.NewRequest(http.MethodGet, "/api/notification/templates", nil)
+	req.Header.Set("X-Organization-Id", "42")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	if tpls.lastOrgID != 42 {
+		t.Fatalf("expected org 42 got %d", tpls.lastOrgID)
+	}
+}
+
+func TestListTemplates_GlobalRequiresServiceToken(t *testing.T) {
+	app := newApp(api.HandlerDeps{Templates: &stubTemplates{}, ServiceToken: "secret"})
+	req, _ := http.NewRequest(http.MethodGet, "/api/notification/templates", nil)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 401 {
+		t.Fatalf("expected 401 got %d", resp.StatusCode)
+	}
+}
+
+func TestUpsertTemplate_OrgNonAdminForbidden(t *testing.T) {
+	app := newApp(api.HandlerDeps{Templates: &stubTemplates{}})
+	req, _ := http.NewRequest(http.MethodPost, "/api/notification/templates", bytes.NewBufferString(`{"event_type":"x","channel":"email"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Organization-Id", "42")
+	req.Header.Set("X-User-Role", "developer")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 403 {
+		t.Fatalf("expected 403 got %d", resp.StatusCode)
+	}
+}
+
+func TestUpsertTemplate_OrgAdminForcesOwnOrg(t *testing.T) {
+	tpls := &stubTemplates{}
+	app := newApp(api.HandlerDeps{Templates: tpls})
+	body := bytes.NewBufferString(`{"organization_id":7,"event_type":"payment.success","channel":"email","subject":"s","body":"b"}`)
+	req, _ := http.NewRequest(http.MethodPost, "/api/notification/templates", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Organization-Id", "42")
+	req.Header.Set("X-User-Role", "admin")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	if tpls.lastUpsert.OrganizationID == nil || *tpls.lastUpsert.OrganizationID != 42 {
+		t.Fatalf("expected override scoped to org 42, got %v", tpls.lastUpsert.OrganizationID)
+	}
+}
+
+func TestUpsertTemplate_AuthorFromRequest(t *testing.T) {
+	tpls := &stubTemplates{}
+	app := newApp(api.HandlerDeps{Templates: tpls})
+	body := bytes.NewBufferString(`{"event_type":"payment.success","channel":"email","subject":"s","body":"b","author":"ceo@x.io"}`)
+	req, _ := http.NewRequest(http.MethodPost, "/api/notification/templates", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Organization-Id", "42")
+	req.Header.Set("X-User-Role", "admin")
+	req.Header.Set("X-User-Email", "admin@x.io")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 || tpls.lastUpsert.Author != "admin@x.io" {
+		t.Fatalf("expected author from the request, got %d %q", resp.StatusCode, tpls.lastUpsert.Author)
+	}
+}
+
 func TestListPreferences_Success(t *testing.T) {
 	app := newApp(api.HandlerDeps{Preferences: &stubPrefs{}})
 	req, _ := http.NewRequest(http.MethodGet, "/api/notification/preferences?organization_id=12&event_type=payment.success&user_id=9", nil)
@@ -176,6 +271,32 @@ func TestListPreferences_Success(t *testing.T) {
 	}
 }
 
+func TestEffectivePreferences_ExplainsSources(t *testing.T) {
+	svc := &domain.NotificationService{
+		Preferences: &stubPrefs{},
+		Defaults:    domain.Defaults{SlackWebhook: "https://hooks/default"},
+	}
+	app := newApp(api.HandlerDeps{Resolver: svc})
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/notification/preferences/effective?organization_id=12&event_type=payment.success&user_id=9", nil)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	var got []domain.EffectivePreference
+	_ = json.NewDecoder(resp.Body).Decode(&got)
+	// stubPrefs returns one disabled org row; slack falls through to the default.
+	if len(got) != 2 || got[0].Source != domain.SourceOrg || got[0].Reason != "opted out" ||
+		got[1].Source != domain.SourceDefault || !got[1].Active || got[1].Target != "https://hooks/default" {
+		t.Fatalf("unexpected effective preferences %#v", got)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/notification/preferences/effective?organization_id=12", nil)
+	if resp, _ := app.Test(req); resp.StatusCode != 400 {
+		t.Fatalf("expected 400 without event_type, got %d", resp.StatusCode)
+	}
+}
+
 func TestUpdatePreference_InvalidID(t *testing.T) {
 	app := newApp(api.HandlerDeps{Preferences: &stubPrefs{}})
 	req, _ := http.NewRequest(http.MethodPut, "/api/notification/preferences/abc", bytes.NewBufferString(`{}`))
@@ -196,10 +317,31 @@ func TestUpdatePreference_InvalidBody(t *testing.T) {
 	}
 }
 
+func TestUpdatePreference_InvalidDigest(t *testing.T) {
+	app := newApp(api.HandlerDeps{Preferences: &stubPrefs{}})
+	req, _ := http.NewRequest(http.MethodPut, "/api/notification/preferences/1", bytes.NewBufferString(`{"digest":"monthly"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 400 {
+		t.Fatalf("expected 400 got %d", resp.StatusCode)
+	}
+}
+
+func TestUpdatePreference_InvalidEventPattern(t *testing.T) {
+	app := newApp(api.HandlerDeps{Preferences: &stubPrefs{}})
+	req, _ := http.NewRequest(http.MethodPut, "/api/notification/preferences/1",
+		bytes.NewBufferString(`{"event_type":"vuln*.critical","channel":"email","target":"a@b.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 400 {
+		t.Fatalf("expected 400 got %d", resp.StatusCode)
+	}
+}
+
 func TestUpdatePreference_Success(t *testing.T) {
 	app := newApp(api.HandlerDeps{Preferences: &stubPrefs{}})
 	req, _ := http.NewRequest(http.MethodPut, "/api/notification/preferences/7",
-		bytes.NewBufferString(`{"organization_id":1,"event_type":"payment.success","channel":"email","target":"a@b.com","enabled":true,"severity_min":"low"}`))
+		bytes.NewBufferString(`{"organization_id":1,"event_type":"payment.*","channel":"email","target":"a@b.com","enabled":true,"severity_min":"low"}`))
 	req.Header.Set("Content-Type", "application/json")
 	resp, _ := app.Test(req)
 	if resp.StatusCode != 200 {
@@ -211,6 +353,89 @@ func TestUpdatePreference_Success(t *testing.T) {
 	}
 }
 
+func TestUpdatePreference_ValidatesChannelTargetAndSeverity(t *testing.T) {
+	app := newApp(api.HandlerDeps{Preferences: &stubPrefs{}, Channels: []string{"email", "slack"}})
+	for body, want := range map[string]string{
+		`{"event_type":"*","channel":"sms","target":"+1555"}`:                             "unknown channel",
+		`{"event_type":"*","channel":"teams","target":"https://teams/x","enabled":true}`:  "no registered provider",
+		`{"event_type":"*","channel":"email","target":"not-an-email","enabled":true}`:     "invalid email address",
+		`{"event_type":"*","channel":"slack","target":"http://hooks/x","enabled":true}`:   "https URL",
+		`{"event_type":"*","channel":"slack","target":"","enabled":true}`:                 "target is required",
+		`{"event_type":"*","channel":"email","target":"a@b.com","severity_min":"urgent"}`: "severity_min",
+	} {
+		req := putJSON(t, "/api/notification/preferences/7", body)
+		resp, _ := app.Test(req)
+		data := readJSON(t, resp)
+		if resp.StatusCode != 400 || !strings.Contains(data["error"].(string), want) {
+			t.Fatalf("%s: expected 400 %q, got %d %v", body, want, resp.StatusCode, data)
+		}
+	}
+	// A disabled row is an opt-out and needs no target.
+	resp, _ := app.Test(putJSON(t, "/api/notification/preferences/7", `{"event_type":"*","channel":"slack","enabled":false}`))
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected opt-out row to be accepted, got %d", resp.StatusCode)
+	}
+}
+
+func TestCreatePreference(t *testing.T) {
+	app := newApp(api.HandlerDeps{Preferences: &stubPrefs{}})
+	resp, _ := app.Test(postJSON(t, "/api/notification/preferences",
+		`{"id":9,"organization_id":1,"event_type":"payment.*","channel":"webhook","target":"https://hooks.example.com/x","enabled":true}`))
+	if resp.StatusCode != 201 {
+		t.Fatalf("expected 201 got %d", resp.StatusCode)
+	}
+	if data := readJSON(t, resp); data["id"].(float64) != 123 {
+		t.Fatalf("expected a new preference, got %v", data)
+	}
+	resp, _ = app.Test(postJSON(t, "/api/notification/preferences", `{"event_type":"*","channel":"email","target":"a@b.com"}`))
+	if resp.StatusCode != 400 {
+		t.Fatalf("expected 400 without organization, got %d", resp.StatusCode)
+	}
+}
+
+func TestDeletePreference(t *testing.T) {
+	prefs := &stubPrefs{}
+	app := newApp(api.HandlerDeps{Preferences: prefs})
+	for path, want := range map[string]int{
+		"/api/notification/preferences/1":                   400,
+		"/api/notification/preferences/2?organization_id=1": 404,
+		"/api/notification/preferences/1?organization_id=1": 204,
+	} {
+		req, _ := http.NewRequest(http.MethodDelete, path, nil)
+		if resp, _ := app.Test(req); resp.StatusCode != want {
+			t.Fatalf("%s: expected %d got %d", path, want, resp.StatusCode)
+		}
+	}
+	if prefs.deleted != 1 {
+		t.Fatalf("expected preference 1 deleted, got %d", prefs.deleted)
+	}
+}
+
+func TestReplacePreferences(t *testing.T) {
+	prefs := &stubPrefs{}
+	app := newApp(api.HandlerDeps{Preferences: prefs})
+	resp, _ := app.Test(putJSON(t, "/api/notification/preferences", `{"organization_id":1,"user_id":9,"preferences":[
+		{"event_type":"*","channel":"email","target":"me@x.io","enabled":true},
+		{"event_type":"vulnerability.*","channel":"slack","enabled":false,"user_id":4,"organization_id":2}]}`))
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	if len(prefs.replaced) != 2 || prefs.replaced[1].OrganizationID != 1 || *prefs.replaced[1].UserID != 9 {
+		t.Fatalf("expected rows scoped to org 1 user 9, got %#v", prefs.replaced)
+	}
+
+	prefs.replaced = nil
+	resp, _ = app.Test(putJSON(t, "/api/notification/preferences", `{"organization_id":1,"preferences":[
+		{"event_type":"*","channel":"email","target":"a@x.io","enabled":true},
+		{"event_type":"*","channel":"email","target":"b@x.io","enabled":true}]}`))
+	if data := readJSON(t, resp); resp.StatusCode != 400 || !strings.Contains(data["error"].(string), "preferences[1]: duplicate") {
+		t.Fatalf("expected duplicate rejected, got %d %v", resp.StatusCode, data)
+	}
+	if prefs.replaced != nil {
+		t.Fatalf("nothing should be written for an invalid list")
+	}
+}
+
 func TestListLogs_Success(t *testing.T) {
 	app := newApp(api.HandlerDeps{Logs: &stubLogs{}})
 	req, _ := http.NewRequest(http.MethodGet, "/api/notification/logs?organization_id=1&limit=10&offset=0", nil)
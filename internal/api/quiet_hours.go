@@ -0,0 +1,98 @@
+package api
+
+import (
+	"myesi-notification-service/internal/domain"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// getQuietHours returns the caller's org-wide window and their own, if any.
+func (h HandlerDeps) getQuietHours(c *fiber.Ctx) error {
+	if h.QuietHours == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "quiet hours not enabled"})
+	}
+	orgID := extractOrgID(c)
+	if orgID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization required"})
+	}
+
+	org, err := h.QuietHours.Find(c.Context(), orgID, nil)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	var user *domain.QuietHours
+	if uid := extractUserID(c); uid != 0 {
+		user, err = h.QuietHours.Find(c.Context(), orgID, &uid)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if user != nil && user.UserID == nil {
+			user = nil
+		}
+	}
+	return c.JSON(fiber.Map{"organization": org, "user": user})
+}
+
+// saveQuietHours sets the window for ?scope=user (default) or ?scope=organization.
+func (h HandlerDeps) saveQuietHours(c *fiber.Ctx) error {
+	if h.QuietHours == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "quiet hours not enabled"})
+	}
+	orgID, userID, err := quietHoursScope(c)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	var body domain.QuietHours
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+	}
+	body.OrganizationID = orgID
+	body.UserID = userID
+	if err := body.Validate(); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	saved, err := h.QuietHours.Save(c.Context(), body)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(saved)
+}
+
+func (h HandlerDeps) deleteQuietHours(c *fiber.Ctx) error {
+	if h.QuietHours == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "quiet hours not enabled"})
+	}
+	orgID, userID, err := quietHoursScope(c)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	if err := h.QuietHours.Delete(c.Context(), orgID, userID); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(204)
+}
+
+// quietHoursScope resolves which window a write applies to. Org-wide windows
+// need an admin role; personal ones need the caller's user ID.
+func quietHoursScope(c *fiber.Ctx) (int64, *int64, error) {
+	orgID := extractOrgID(c)
+	if orgID == 0 {
+		return 0, nil, fiber.NewError(400, "organization required")
+	}
+	switch c.Query("scope", "user") {
+	case "organization":
+		if !isOrgAdmin(c) {
+			return 0, nil, fiber.NewError(403, "admin role required")
+		}
+		return orgID, nil, nil
+	case "user":
+		uid := extractUserID(c)
+		if uid == 0 {
+			return 0, nil, fiber.NewError(400, "user required")
+		}
+		return orgID, &uid, nil
+	default:
+		return 0, nil, fiber.NewError(400, "scope must be user or organization")
+	}
+}
@@ -0,0 +1,92 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"myesi-notification-service/internal/api"
+	"myesi-notification-service/internal/domain"
+)
+
+type quietHoursMock struct {
+	rows  map[int64]*domain.QuietHours
+	saved *domain.QuietHours
+}
+
+func (m *quietHoursMock) Find(ctx domain.Context, orgID int64, userID *int64) (*domain.QuietHours, error) {
+	if userID != nil {
+		if q, ok := m.rows[*userID]; ok {
+			return q, nil
+		}
+	}
+	return m.rows[0], nil
+}
+func (m *quietHoursMock) Save(ctx domain.Context, q domain.QuietHours) (domain.QuietHours, error) {
+	m.saved = &q
+	return q, nil
+}
+func (m *quietHoursMock) Delete(ctx domain.Context, orgID int64, userID *int64) error { return nil }
+
+func putJSON(t *testing.T, path, body string) *http.Request {
+	t.Helper()
+	req := postJSON(t, path, body)
+	req.Method = http.MethodPut
+	return req
+}
+
+func TestQuietHours_NotEnabled(t *testing.T) {
+	app := newApp(api.HandlerDeps{})
+	req, _ := http.NewRequest(http.MethodGet, "/api/notification/quiet-hours", nil)
+	resp, _ := app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 501 {
+		t.Fatalf("expected 501 got %d", resp.StatusCode)
+	}
+}
+
+func TestQuietHours_GetSeparatesOrgAndUser(t *testing.T) {
+	m := &quietHoursMock{rows: map[int64]*domain.QuietHours{0: {OrganizationID: 5, TimeZone: "UTC", Start: "22:00", End: "07:00"}}}
+	app := newApp(api.HandlerDeps{QuietHours: m})
+	req, _ := http.NewRequest(http.MethodGet, "/api/notification/quiet-hours", nil)
+	req = orgAdminRequest(req)
+	req.Header.Set("X-User-Id", "9")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	out := readJSON(t, resp)
+	if out["organization"] == nil || out["user"] != nil {
+		t.Fatalf("org fallback must not be reported as the user's window: %v", out)
+	}
+}
+
+func TestQuietHours_SaveUserScope(t *testing.T) {
+	m := &quietHoursMock{}
+	app := newApp(api.HandlerDeps{QuietHours: m})
+	req := putJSON(t, "/api/notification/quiet-hours", `{"time_zone":"Asia/Tokyo","start":"23:00","end":"06:00","user_id":1}`)
+	req.Header.Set("X-Organization-Id", "5")
+	req.Header.Set("X-User-Id", "9")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	if m.saved == nil || m.saved.OrganizationID != 5 || m.saved.UserID == nil || *m.saved.UserID != 9 {
+		t.Fatalf("unexpected saved %#v", m.saved)
+	}
+}
+
+func TestQuietHours_SaveValidation(t *testing.T) {
+	app := newApp(api.HandlerDeps{QuietHours: &quietHoursMock{}})
+	req := putJSON(t, "/api/notification/quiet-hours?scope=organization", `{"time_zone":"Nowhere/Land","start":"23:00","end":"06:00"}`)
+	resp, _ := app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 400 {
+		t.Fatalf("expected 400 got %d", resp.StatusCode)
+	}
+
+	req = putJSON(t, "/api/notification/quiet-hours?scope=organization", `{"time_zone":"UTC","start":"23:00","end":"06:00"}`)
+	req.Header.Set("X-Organization-Id", "5")
+	req.Header.Set("X-User-Role", "developer")
+	resp, _ = app.Test(req)
+	if resp.StatusCode != 403 {
+		t.Fatalf("expected 403 for non-admin org write got %d", resp.StatusCode)
+	}
+}
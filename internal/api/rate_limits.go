@@ -0,0 +1,71 @@
+package api
+
+import (
+	"strconv"
+
+	"myesi-notification-service/internal/domain"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+func (h HandlerDeps) listRateLimits(c *fiber.Ctx) error {
+	if h.RateLimits == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "rate limits not enabled"})
+	}
+	orgID := extractOrgID(c)
+	if orgID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization required"})
+	}
+	res, err := h.RateLimits.List(c.Context(), orgID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(res)
+}
+
+// saveRateLimit creates or replaces the org's limit for a channel and event
+// type; either may be empty to match all. Overflow defaults to summarize.
+func (h HandlerDeps) saveRateLimit(c *fiber.Ctx) error {
+	if h.RateLimits == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "rate limits not enabled"})
+	}
+	orgID, err := orgAdminScope(c)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	var body domain.RateLimit
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+	}
+	body.OrganizationID = orgID
+	if body.Overflow == "" {
+		body.Overflow = domain.OverflowSummarize
+	}
+	if err := body.Validate(); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	saved, err := h.RateLimits.Save(c.Context(), body)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(saved)
+}
+
+func (h HandlerDeps) deleteRateLimit(c *fiber.Ctx) error {
+	if h.RateLimits == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "rate limits not enabled"})
+	}
+	orgID, err := orgAdminScope(c)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+	}
+	if err := h.RateLimits.Delete(c.Context(), orgID, id); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(204)
+}
@@ -0,0 +1,49 @@
+package api_test
+
+import (
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/api"
+	"myesi-notification-service/internal/domain"
+)
+
+type rateLimitMock struct{ saved *domain.RateLimit }
+
+func (m *rateLimitMock) List(ctx domain.Context, orgID int64) ([]domain.RateLimit, error) {
+	return nil, nil
+}
+func (m *rateLimitMock) Save(ctx domain.Context, l domain.RateLimit) (domain.RateLimit, error) {
+	m.saved = &l
+	return l, nil
+}
+func (m *rateLimitMock) Delete(ctx domain.Context, orgID, id int64) error { return nil }
+func (m *rateLimitMock) Match(ctx domain.Context, orgID int64, channel, eventType string) (*domain.RateLimit, error) {
+	return nil, nil
+}
+func (m *rateLimitMock) Take(ctx domain.Context, l domain.RateLimit, target domain.DeliveryTarget, eventType string, now time.Time) (time.Duration, bool, error) {
+	return 0, true, nil
+}
+func (m *rateLimitMock) ClaimSuppressed(ctx domain.Context, now time.Time, limit int) ([]domain.SuppressedBatch, error) {
+	return nil, nil
+}
+
+func TestRateLimits_Save(t *testing.T) {
+	m := &rateLimitMock{}
+	app := newApp(api.HandlerDeps{RateLimits: m})
+
+	req := putJSON(t, "/api/notification/rate-limits", `{"channel":"slack","burst":0,"per_minute":1}`)
+	resp, _ := app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 400 {
+		t.Fatalf("expected 400 for empty bucket, got %d", resp.StatusCode)
+	}
+
+	req = putJSON(t, "/api/notification/rate-limits", `{"channel":"slack","event_type":"code_finding.assignment","burst":20,"per_minute":1}`)
+	resp, _ = app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	if m.saved == nil || m.saved.OrganizationID != 5 || m.saved.Overflow != domain.OverflowSummarize {
+		t.Fatalf("unexpected saved limit %#v", m.saved)
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"myesi-notification-service/internal/domain"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+func (h HandlerDeps) listRoutingRules(c *fiber.Ctx) error {
+	if h.Routes == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "routing rules not enabled"})
+	}
+	orgID := extractOrgID(c)
+	if orgID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization required"})
+	}
+	res, err := h.Routes.List(c.Context(), orgID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(res)
+}
+
+// saveRoutingRule creates a rule (POST) or replaces the rule named by :id
+// (PUT). New rules are enabled unless the body says otherwise.
+func (h HandlerDeps) saveRoutingRule(c *fiber.Ctx) error {
+	if h.Routes == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "routing rules not enabled"})
+	}
+	orgID, err := orgAdminScope(c)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	body := domain.RoutingRule{Enabled: true}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+	}
+	body.ID = 0
+	if c.Params("id") != "" {
+		if body.ID, err = strconv.ParseInt(c.Params("id"), 10, 64); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+		}
+	}
+	body.OrganizationID = orgID
+	if err := body.Validate(); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	saved, err := h.Routes.Save(c.Context(), body)
+	if errors.Is(err, domain.ErrNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "routing rule not found"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(saved)
+}
+
+func (h HandlerDeps) deleteRoutingRule(c *fiber.Ctx) error {
+	if h.Routes == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "routing rules not enabled"})
+	}
+	orgID, err := orgAdminScope(c)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+	}
+	if err := h.Routes.Delete(c.Context(), orgID, id); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "routing rule not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(204)
+}
+
+// evaluateRoutingRules dry-runs the org's rules against a sample event and
+// returns the merged route without sending anything.
+func (h HandlerDeps) evaluateRoutingRules(c *fiber.Ctx) error {
+	if h.Routes == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "routing rules not enabled"})
+	}
+	orgID, err := orgAdminScope(c)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	var evt domain.NotificationEvent
+	if err := c.BodyParser(&evt); err != nil || evt.EventType == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "event with type required"})
+	}
+	evt.OrganizationID = orgID
+	list, err := h.Routes.List(c.Context(), orgID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(domain.MatchRoutes(list, evt))
+}
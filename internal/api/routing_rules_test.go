@@ -0,0 +1,69 @@
+package api_test
+
+import (
+	"testing"
+
+	"myesi-notification-service/internal/api"
+	"myesi-notification-service/internal/domain"
+)
+
+type routingMock struct {
+	rules []domain.RoutingRule
+	saved *domain.RoutingRule
+}
+
+func (m *routingMock) List(ctx domain.Context, orgID int64) ([]domain.RoutingRule, error) {
+	return m.rules, nil
+}
+func (m *routingMock) Save(ctx domain.Context, r domain.RoutingRule) (domain.RoutingRule, error) {
+	if r.ID > 100 {
+		return r, domain.ErrNotFound
+	}
+	m.saved = &r
+	return r, nil
+}
+func (m *routingMock) Delete(ctx domain.Context, orgID, id int64) error { return nil }
+
+func TestRoutingRules_Save(t *testing.T) {
+	m := &routingMock{}
+	app := newApp(api.HandlerDeps{Routes: m})
+
+	req := postJSON(t, "/api/notification/routing-rules", `{"event_pattern":"vulnerability.*","condition":"payload.count >","actions":{"all_users":true}}`)
+	resp, _ := app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 400 {
+		t.Fatalf("expected 400 for a broken condition, got %d", resp.StatusCode)
+	}
+
+	req = putJSON(t, "/api/notification/routing-rules/7", `{"id":99,"event_pattern":"vulnerability.*","actions":{"roles":["security"]}}`)
+	resp, _ = app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	if m.saved == nil || m.saved.ID != 7 || m.saved.OrganizationID != 5 || !m.saved.Enabled {
+		t.Fatalf("unexpected saved rule %#v", m.saved)
+	}
+
+	req = putJSON(t, "/api/notification/routing-rules/101", `{"actions":{"drop":true}}`)
+	resp, _ = app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 404 {
+		t.Fatalf("expected 404 for unknown rule, got %d", resp.StatusCode)
+	}
+}
+
+func TestRoutingRules_Evaluate(t *testing.T) {
+	m := &routingMock{rules: []domain.RoutingRule{
+		{ID: 3, Enabled: true, EventPattern: "vulnerability.*", Condition: `payload.critical_count > 3`,
+			Actions: domain.RouteActions{Channels: []string{"slack"}}},
+	}}
+	app := newApp(api.HandlerDeps{Routes: m})
+
+	req := postJSON(t, "/api/notification/routing-rules/evaluate", `{"type":"vulnerability.critical","payload":{"critical_count":5}}`)
+	resp, _ := app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	body := readJSON(t, resp)
+	if rules, _ := body["rules"].([]any); len(rules) != 1 || rules[0] != float64(3) {
+		t.Fatalf("unexpected route %v", body)
+	}
+}
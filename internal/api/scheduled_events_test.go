@@ -0,0 +1,76 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/api"
+	"myesi-notification-service/internal/domain"
+)
+
+type scheduledEventsMock struct {
+	cancelled map[string]bool
+	lastOrg   int64
+}
+
+func (m *scheduledEventsMock) Schedule(ctx domain.Context, e domain.ScheduledEvent) (domain.ScheduledEvent, error) {
+	return e, nil
+}
+func (m *scheduledEventsMock) Claim(ctx domain.Context, now time.Time, lease time.Duration, limit int) ([]domain.ScheduledEvent, error) {
+	return nil, nil
+}
+func (m *scheduledEventsMock) Complete(ctx domain.Context, id int64) error { return nil }
+func (m *scheduledEventsMock) Cancel(ctx domain.Context, orgID int64, key string) (bool, error) {
+	m.lastOrg = orgID
+	return m.cancelled[key], nil
+}
+
+func TestIngestEvent_ScheduledResponse(t *testing.T) {
+	n := &stubNotifier{}
+	app := newApp(api.HandlerDeps{Svc: n})
+	resp, _ := app.Test(postJSON(t, "/api/notification/events", `{"type":"trial.expiring","organization_id":1,"delay":"48h","schedule_key":"trial:1"}`))
+	if resp.StatusCode != 202 {
+		t.Fatalf("expected 202 got %d", resp.StatusCode)
+	}
+	out := readJSON(t, resp)
+	if out["status"] != "scheduled" || out["deliver_at"] == nil {
+		t.Fatalf("unexpected response %v", out)
+	}
+	if n.last.ScheduleKey != "trial:1" || n.last.Delay != "48h" {
+		t.Fatalf("schedule fields must reach the service, got %+v", n.last)
+	}
+}
+
+func TestIngestEvent_InvalidDelay(t *testing.T) {
+	app := newApp(api.HandlerDeps{Svc: &stubNotifier{}})
+	resp, _ := app.Test(postJSON(t, "/api/notification/events", `{"type":"x","delay":"tomorrow"}`))
+	if resp.StatusCode != 400 {
+		t.Fatalf("expected 400 got %d", resp.StatusCode)
+	}
+}
+
+func TestCancelScheduledEvent(t *testing.T) {
+	m := &scheduledEventsMock{cancelled: map[string]bool{"sla:42": true}}
+	app := newApp(api.HandlerDeps{ScheduledEvents: m, ServiceToken: "secret"})
+
+	req, _ := http.NewRequest(http.MethodDelete, "/api/notification/events/scheduled/sla:42?organization_id=3", nil)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 401 {
+		t.Fatalf("expected 401 without token got %d", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, "/api/notification/events/scheduled/sla:42?organization_id=3", nil)
+	req.Header.Set("X-Service-Token", "secret")
+	resp, _ = app.Test(req)
+	if resp.StatusCode != 204 || m.lastOrg != 3 {
+		t.Fatalf("expected 204 for org 3, got %d org %d", resp.StatusCode, m.lastOrg)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, "/api/notification/events/scheduled/unknown", nil)
+	req.Header.Set("X-Service-Token", "secret")
+	resp, _ = app.Test(req)
+	if resp.StatusCode != 404 {
+		t.Fatalf("expected 404 got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"myesi-notification-service/internal/domain"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// UserSettingsManager presents a user's preferences as the settings matrix;
+// implemented by NotificationService.
+type UserSettingsManager interface {
+	UserSettings(ctx context.Context, orgID, userID int64) (domain.UserSettings, error)
+	SaveUserSettings(ctx context.Context, orgID, userID int64, settings domain.UserSettings) (domain.UserSettings, error)
+}
+
+// getMySettings returns the calling user's settings matrix.
+func (h HandlerDeps) getMySettings(c *fiber.Ctx) error {
+	if h.UserSettings == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "user settings not enabled"})
+	}
+	orgID, userID := extractOrgID(c), extractUserID(c)
+	if orgID == 0 || userID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization and user required"})
+	}
+	res, err := h.UserSettings.UserSettings(c.Context(), orgID, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(res)
+}
+
+// saveMySettings replaces the calling user's settings matrix.
+func (h HandlerDeps) saveMySettings(c *fiber.Ctx) error {
+	if h.UserSettings == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "user settings not enabled"})
+	}
+	orgID, userID := extractOrgID(c), extractUserID(c)
+	if orgID == 0 || userID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization and user required"})
+	}
+	var body domain.UserSettings
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	res, err := h.UserSettings.SaveUserSettings(c.Context(), orgID, userID, body)
+	if errors.Is(err, domain.ErrInvalidSettings) {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(res)
+}
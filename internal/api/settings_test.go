@@ -0,0 +1,56 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"myesi-notification-service/internal/api"
+	"myesi-notification-service/internal/domain"
+)
+
+type settingsMock struct {
+	saved domain.UserSettings
+}
+
+func (m *settingsMock) UserSettings(ctx context.Context, orgID, userID int64) (domain.UserSettings, error) {
+	return domain.UserSettings{Channels: []domain.ChannelSettings{{Channel: domain.ChannelInbox, Available: true}}}, nil
+}
+func (m *settingsMock) SaveUserSettings(ctx context.Context, orgID, userID int64, s domain.UserSettings) (domain.UserSettings, error) {
+	if len(s.Categories) > 0 && s.Categories[0].Category == "weather" {
+		return domain.UserSettings{}, fmt.Errorf("%w: unknown category %q", domain.ErrInvalidSettings, "weather")
+	}
+	m.saved = s
+	return s, nil
+}
+
+func TestMySettings(t *testing.T) {
+	m := &settingsMock{}
+	app := newApp(api.HandlerDeps{UserSettings: m})
+	me := func(req *http.Request) *http.Request {
+		req.Header.Set("X-Organization-Id", "5")
+		req.Header.Set("X-User-Id", "7")
+		return req
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/notification/me/settings", nil)
+	if resp, _ := app.Test(req); resp.StatusCode != 400 {
+		t.Fatalf("expected 400 without a user, got %d", resp.StatusCode)
+	}
+	req, _ = http.NewRequest(http.MethodGet, "/api/notification/me/settings", nil)
+	resp, _ := app.Test(me(req))
+	if data := readJSON(t, resp); resp.StatusCode != 200 || data["channels"] == nil {
+		t.Fatalf("expected settings, got %d %v", resp.StatusCode, data)
+	}
+
+	resp, _ = app.Test(me(putJSON(t, "/api/notification/me/settings",
+		`{"categories":[{"category":"billing","cells":{"inbox":{"enabled":false}}}]}`)))
+	if resp.StatusCode != 200 || len(m.saved.Categories) != 1 || m.saved.Categories[0].Cells["inbox"].Enabled {
+		t.Fatalf("expected settings saved, got %d %#v", resp.StatusCode, m.saved)
+	}
+	resp, _ = app.Test(me(putJSON(t, "/api/notification/me/settings", `{"categories":[{"category":"weather"}]}`)))
+	if resp.StatusCode != 400 {
+		t.Fatalf("expected 400 for invalid settings, got %d", resp.StatusCode)
+	}
+}
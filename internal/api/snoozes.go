@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// listSnoozes returns the calling user's active snoozes.
+func (h HandlerDeps) listSnoozes(c *fiber.Ctx) error {
+	if h.Snoozes == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "snoozes not enabled"})
+	}
+	orgID, userID := extractOrgID(c), extractUserID(c)
+	if orgID == 0 || userID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization and user required"})
+	}
+	res, err := h.Snoozes.ListForUser(c.Context(), orgID, userID, time.Now().UTC())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(res)
+}
+
+// createSnooze mutes the calling user until a time, given as until or as a
+// duration such as "8h" from now.
+func (h HandlerDeps) createSnooze(c *fiber.Ctx) error {
+	if h.Snoozes == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "snoozes not enabled"})
+	}
+	orgID, userID := extractOrgID(c), extractUserID(c)
+	if orgID == 0 || userID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization and user required"})
+	}
+	var body struct {
+		domain.Snooze
+		Duration string `json:"duration"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+	}
+	now := time.Now().UTC()
+	z := body.Snooze
+	z.OrganizationID, z.UserID = orgID, userID
+	if body.Duration != "" {
+		if !z.Until.IsZero() {
+			return c.Status(400).JSON(fiber.Map{"error": "set until or duration, not both"})
+		}
+		d, err := time.ParseDuration(body.Duration)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid duration"})
+		}
+		z.Until = now.Add(d)
+	}
+	if err := z.Validate(now); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	saved, err := h.Snoozes.Create(c.Context(), z)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(201).JSON(saved)
+}
+
+// cancelSnooze ends one of the calling user's snoozes early.
+func (h HandlerDeps) cancelSnooze(c *fiber.Ctx) error {
+	if h.Snoozes == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "snoozes not enabled"})
+	}
+	orgID, userID := extractOrgID(c), extractUserID(c)
+	if orgID == 0 || userID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization and user required"})
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+	}
+	if err := h.Snoozes.Delete(c.Context(), orgID, userID, id); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "snooze not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(204)
+}
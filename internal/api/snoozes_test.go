@@ -0,0 +1,87 @@
+package api_test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/api"
+	"myesi-notification-service/internal/domain"
+)
+
+type snoozeMock struct {
+	created []domain.Snooze
+}
+
+func (m *snoozeMock) Active(ctx domain.Context, orgID int64, now time.Time) ([]domain.Snooze, error) {
+	return nil, nil
+}
+func (m *snoozeMock) ListForUser(ctx domain.Context, orgID, userID int64, now time.Time) ([]domain.Snooze, error) {
+	return m.created, nil
+}
+func (m *snoozeMock) Create(ctx domain.Context, z domain.Snooze) (domain.Snooze, error) {
+	z.ID = int64(len(m.created) + 1)
+	m.created = append(m.created, z)
+	return z, nil
+}
+func (m *snoozeMock) Delete(ctx domain.Context, orgID, userID, id int64) error {
+	if userID != 7 || id != 1 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+func (m *snoozeMock) DeleteExpired(ctx domain.Context, now time.Time) (int, error) {
+	return 0, nil
+}
+
+func snoozeRequest(method, path, body string) *http.Request {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Organization-Id", "5")
+	req.Header.Set("X-User-Id", "7")
+	return req
+}
+
+func TestSnoozes_CreateWithDuration(t *testing.T) {
+	m := &snoozeMock{}
+	app := newApp(api.HandlerDeps{Snoozes: m})
+
+	resp, _ := app.Test(snoozeRequest(http.MethodPost, "/api/notification/me/snoozes",
+		`{"scope":"event_type","event_type":"vulnerability.*","duration":"8h"}`))
+	if resp.StatusCode != 201 || len(m.created) != 1 {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	z := m.created[0]
+	if z.OrganizationID != 5 || z.UserID != 7 || z.Until.Before(time.Now().Add(7*time.Hour)) {
+		t.Fatalf("unexpected snooze %#v", z)
+	}
+}
+
+func TestSnoozes_CreateRejectsInvalid(t *testing.T) {
+	app := newApp(api.HandlerDeps{Snoozes: &snoozeMock{}})
+	for _, body := range []string{
+		`{"scope":"project","duration":"1h"}`,
+		`{"scope":"all","until":"2001-01-01T00:00:00Z"}`,
+		`{"scope":"all","duration":"soon"}`,
+		`{"scope":"all","duration":"1h","until":"2999-01-01T00:00:00Z"}`,
+	} {
+		resp, _ := app.Test(snoozeRequest(http.MethodPost, "/api/notification/me/snoozes", body))
+		if resp.StatusCode != 400 {
+			t.Fatalf("expected 400 for %s, got %d", body, resp.StatusCode)
+		}
+	}
+}
+
+func TestSnoozes_Cancel(t *testing.T) {
+	app := newApp(api.HandlerDeps{Snoozes: &snoozeMock{}})
+
+	resp, _ := app.Test(snoozeRequest(http.MethodDelete, "/api/notification/me/snoozes/1", ""))
+	if resp.StatusCode != 204 {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	resp, _ = app.Test(snoozeRequest(http.MethodDelete, "/api/notification/me/snoozes/2", ""))
+	if resp.StatusCode != 404 {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"myesi-notification-service/internal/domain"
+	"myesi-notification-service/internal/templates"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// ===== Template partial and layout handlers =====
+func (h HandlerDeps) listPartials(c *fiber.Ctx) error {
+	if h.Partials == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "template partials not enabled"})
+	}
+	orgID, err := h.templateScope(c, false)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	parts, err := h.Partials.List(c.Context(), orgID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(parts)
+}
+
+// upsertPartial saves a partial or layout after checking that, together with the
+// partials already visible to the caller, every channel still parses without
+// undefined references or cycles.
+func (h HandlerDeps) upsertPartial(c *fiber.Ctx) error {
+	if h.Partials == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "template partials not enabled"})
+	}
+	orgID, err := h.templateScope(c, true)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+
+	var body domain.NotificationPartial
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+	}
+	switch body.Kind {
+	case "":
+		body.Kind = domain.PartialKindPartial
+	case domain.PartialKindPartial, domain.PartialKindLayout:
+	default:
+		return c.Status(400).JSON(fiber.Map{"error": "kind must be partial or layout"})
+	}
+	if body.Kind == domain.PartialKindLayout {
+		// Layouts are selected by channel, never by name.
+		body.Name = domain.PartialKindLayout
+	} else if !templates.ValidPartialName(body.Name) {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid partial name"})
+	}
+	if body.Channel != "" && !knownPartialChannel(body.Channel) {
+		return c.Status(400).JSON(fiber.Map{"error": "unsupported channel"})
+	}
+	body.OrganizationID = nil
+	if orgID != 0 {
+		body.OrganizationID = &orgID
+	}
+
+	existing, err := h.Partials.List(c.Context(), orgID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	candidate := make([]domain.NotificationPartial, 0, len(existing)+1)
+	for _, p := range existing {
+		if !samePartialSlot(p, body) {
+			candidate = append(candidate, p)
+		}
+	}
+	candidate = append(candidate, body)
+	if issues := h.validatePartials(candidate); len(issues) > 0 {
+		return c.Status(422).JSON(fiber.Map{"error": "invalid partial", "details": issues})
+	}
+
+	saved, err := h.Partials.Upsert(c.Context(), body)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(saved)
+}
+
+// deletePartial refuses to remove a partial other partials, layouts, templates
+// or stored template versions still call.
+func (h HandlerDeps) deletePartial(c *fiber.Ctx) error {
+	if h.Partials == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "template partials not enabled"})
+	}
+	orgID, err := h.templateScope(c, true)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	existing, err := h.Partials.List(c.Context(), orgID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	remaining := make([]domain.NotificationPartial, 0, len(existing))
+	for _, p := range existing {
+		if p.ID != id {
+			remaining = append(remaining, p)
+		}
+	}
+	if issues := h.validatePartials(remaining); len(issues) > 0 {
+		return c.Status(409).JSON(fiber.Map{"error": "partial is still referenced", "details": issues})
+	}
+	issues, err := h.templatesUsingPartial(c, orgID, id, existing)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if len(issues) > 0 {
+		return c.Status(409).JSON(fiber.Map{"error": "partial is still referenced", "details": issues})
+	}
+
+	if err := h.Partials.Delete(c.Context(), orgID, id); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "partial not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(204)
+}
+
+// templatesUsingPartial re-checks the templates, and the versions a rollback
+// could restore, that mention partial id against the partials left without it.
+// Templates of other organizations are checked with their own partials.
+func (h HandlerDeps) templatesUsingPartial(c *fiber.Ctx, orgID, id int64, existing []domain.NotificationPartial) ([]templateIssue, error) {
+	var name string
+	for _, p := range existing {
+		if p.ID == id && p.Kind == domain.PartialKindPartial {
+			name = p.Name
+		}
+	}
+	if h.Versions == nil || name == "" {
+		return nil, nil
+	}
+	sources, err := h.Versions.Sources(c.Context(), orgID, strconv.Quote(name))
+	if err != nil {
+		return nil, err
+	}
+
+	partials := map[int64][]domain.NotificationPartial{orgID: existing}
+	issues := make([]templateIssue, 0)
+	for _, src := range sources {
+		scope := orgID
+		if src.OrganizationID != nil {
+			scope = *src.OrganizationID
+		}
+		parts, ok := partials[scope]
+		if !ok {
+			if parts, err = h.Partials.List(c.Context(), scope); err != nil {
+				return nil, err
+			}
+			partials[scope] = parts
+		}
+		remaining := make([]domain.NotificationPartial, 0, len(parts))
+		for _, p := range parts {
+			if p.ID != id {
+				remaining = append(remaining, p)
+			}
+		}
+		channel := src.Channel
+		if channel == "" {
+			channel = domain.ChannelInbox
+		}
+		for _, issue := range h.validateTemplate(domain.PartialSet(remaining, channel), src.Subject, src.Body) {
+			issue.Field = fmt.Sprintf("template %d version %d %s", src.TemplateID, src.Version, issue.Field)
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+// validatePartials checks the set each channel would render with.
+func (h HandlerDeps) validatePartials(parts []domain.NotificationPartial) []templateIssue {
+	issues := make([]templateIssue, 0)
+	for _, channel := range append([]string{""}, domain.PartialChannels...) {
+		if err := h.Renderer.ValidateWith(domain.PartialSet(parts, channel), ""); err != nil {
+			issues = append(issues, templateIssue{Field: partialField(channel), Stage: "parse", Error: err.Error()})
+		}
+	}
+	return issues
+}
+
+// partialSet loads the partials and layout a template for channel renders with.
+// Templates without a channel are the in-app inbox ones.
+func (h HandlerDeps) partialSet(c *fiber.Ctx, orgID int64, channel string) (templates.Set, error) {
+	if h.Partials == nil {
+		return templates.Set{}, nil
+	}
+	parts, err := h.Partials.List(c.Context(), orgID)
+	if err != nil {
+		return templates.Set{}, err
+	}
+	if channel == "" {
+		channel = domain.ChannelInbox
+	}
+	return domain.PartialSet(parts, channel), nil
+}
+
+func samePartialSlot(a, b domain.NotificationPartial) bool {
+	sameOrg := (a.OrganizationID == nil) == (b.OrganizationID == nil)
+	if sameOrg && a.OrganizationID != nil {
+		sameOrg = *a.OrganizationID == *b.OrganizationID
+	}
+	return sameOrg && a.Kind == b.Kind && a.Name == b.Name && a.Channel == b.Channel
+}
+
+func knownPartialChannel(channel string) bool {
+	for _, ch := range domain.PartialChannels {
+		if ch == channel {
+			return true
+		}
+	}
+	return false
+}
+
+func partialField(channel string) string {
+	if channel == "" {
+		return "partials"
+	}
+	return "partials." + channel
+}
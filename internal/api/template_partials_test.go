@@ -0,0 +1,162 @@
+package api_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"myesi-notification-service/internal/api"
+	"myesi-notification-service/internal/domain"
+)
+
+type partialsMock struct {
+	parts     []domain.NotificationPartial
+	saved     *domain.NotificationPartial
+	deletedID int64
+	lastOrgID int64
+}
+
+func (m *partialsMock) List(ctx domain.Context, orgID int64) ([]domain.NotificationPartial, error) {
+	m.lastOrgID = orgID
+	return m.parts, nil
+}
+func (m *partialsMock) Upsert(ctx domain.Context, p domain.NotificationPartial) (domain.NotificationPartial, error) {
+	m.saved = &p
+	p.ID = 42
+	return p, nil
+}
+func (m *partialsMock) Delete(ctx domain.Context, orgID, id int64) error {
+	m.deletedID = id
+	return nil
+}
+
+func orgAdminRequest(req *http.Request) *http.Request {
+	req.Header.Set("X-Organization-Id", "5")
+	req.Header.Set("X-User-Role", "admin")
+	return req
+}
+
+func TestPartials_NotEnabled(t *testing.T) {
+	app := newApp(api.HandlerDeps{})
+	req, _ := http.NewRequest(http.MethodGet, "/api/notification/partials", nil)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 501 {
+		t.Fatalf("expected 501 got %d", resp.StatusCode)
+	}
+}
+
+func TestPartials_UpsertScopedToOrg(t *testing.T) {
+	m := &partialsMock{}
+	app := newApp(api.HandlerDeps{Partials: m})
+	req := orgAdminRequest(postJSON(t, "/api/notification/partials", `{"name":"footer","body":"View in MyESI","organization_id":9}`))
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d: %v", resp.StatusCode, readJSON(t, resp))
+	}
+	if m.saved == nil || m.saved.OrganizationID == nil || *m.saved.OrganizationID != 5 || m.saved.Kind != domain.PartialKindPartial {
+		t.Fatalf("unexpected saved partial %#v", m.saved)
+	}
+}
+
+func TestPartials_UpsertRejectsCycle(t *testing.T) {
+	org := int64(5)
+	m := &partialsMock{parts: []domain.NotificationPartial{
+		{ID: 1, OrganizationID: &org, Name: "header", Kind: domain.PartialKindPartial, Body: `{{template "footer" .}}`},
+	}}
+	app := newApp(api.HandlerDeps{Partials: m})
+	req := orgAdminRequest(postJSON(t, "/api/notification/partials", `{"name":"footer","body":"{{template \"header\" .}}"}`))
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 422 {
+		t.Fatalf("expected 422 got %d", resp.StatusCode)
+	}
+	out := readJSON(t, resp)
+	details, _ := out["details"].([]any)
+	if len(details) == 0 || !strings.Contains(details[0].(map[string]any)["error"].(string), "partial cycle") {
+		t.Fatalf("expected cycle error, got %v", out)
+	}
+	if m.saved != nil {
+		t.Fatalf("cyclic partial must not be saved")
+	}
+}
+
+func TestPartials_LayoutRequiresContent(t *testing.T) {
+	m := &partialsMock{}
+	app := newApp(api.HandlerDeps{Partials: m})
+	req := orgAdminRequest(postJSON(t, "/api/notification/partials", `{"kind":"layout","channel":"email","body":"header only"}`))
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 422 {
+		t.Fatalf("expected 422 got %d", resp.StatusCode)
+	}
+}
+
+func TestPartials_DeleteReferencedConflicts(t *testing.T) {
+	m := &partialsMock{parts: []domain.NotificationPartial{
+		{ID: 1, Name: "link", Kind: domain.PartialKindPartial, Body: "https://myesi"},
+		{ID: 2, Name: "footer", Kind: domain.PartialKindPartial, Body: `{{template "link" .}}`},
+	}}
+	app := newApp(api.HandlerDeps{Partials: m})
+
+	req, _ := http.NewRequest(http.MethodDelete, "/api/notification/partials/1", nil)
+	resp, _ := app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 409 || m.deletedID != 0 {
+		t.Fatalf("expected 409 without delete, got %d (deleted %d)", resp.StatusCode, m.deletedID)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, "/api/notification/partials/2", nil)
+	resp, _ = app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 204 || m.deletedID != 2 {
+		t.Fatalf("expected 204 and delete, got %d (deleted %d)", resp.StatusCode, m.deletedID)
+	}
+}
+
+func TestPartials_DeleteUsedByTemplateVersionConflicts(t *testing.T) {
+	m := &partialsMock{parts: []domain.NotificationPartial{
+		{ID: 1, Name: "footer", Kind: domain.PartialKindPartial, Body: "MyESI"},
+	}}
+	versions := newVersionsMock()
+	versions.sources = []domain.TemplateSource{{TemplateID: 7, Version: 1, Channel: "email", Body: `Hi {{template "footer" .}}`}}
+	app := newApp(api.HandlerDeps{Partials: m, Versions: versions})
+
+	req, _ := http.NewRequest(http.MethodDelete, "/api/notification/partials/1", nil)
+	resp, _ := app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 409 || m.deletedID != 0 {
+		t.Fatalf("expected 409 without delete, got %d (deleted %d)", resp.StatusCode, m.deletedID)
+	}
+	if versions.lastOrgID != 5 {
+		t.Fatalf("expected templates of org 5 checked, got %d", versions.lastOrgID)
+	}
+
+	versions.sources = nil
+	req, _ = http.NewRequest(http.MethodDelete, "/api/notification/partials/1", nil)
+	resp, _ = app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 204 || m.deletedID != 1 {
+		t.Fatalf("expected 204 and delete, got %d (deleted %d)", resp.StatusCode, m.deletedID)
+	}
+}
+
+func TestUpsertTemplate_RejectsUndefinedPartial(t *testing.T) {
+	app := newApp(api.HandlerDeps{Templates: &stubTemplates{}, Partials: &partialsMock{}})
+	req := orgAdminRequest(postJSON(t, "/api/notification/templates",
+		`{"event_type":"x","channel":"email","subject":"s","body":"{{template \"footer\" .}}"}`))
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 422 {
+		t.Fatalf("expected 422 got %d", resp.StatusCode)
+	}
+}
+
+func TestPreviewTemplate_AppliesLayout(t *testing.T) {
+	m := &partialsMock{parts: []domain.NotificationPartial{
+		{Name: "footer", Kind: domain.PartialKindPartial, Body: "-- MyESI"},
+		{Name: "layout", Kind: domain.PartialKindLayout, Channel: "email", Body: "{{template \"content\" .}}\n{{template \"footer\" .}}"},
+	}}
+	app := newApp(api.HandlerDeps{Partials: m})
+	req := postJSON(t, "/api/notification/templates/preview", `{"event_type":"x","channel":"email","subject":"Hi","body":"Body"}`)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	rendered, _ := readJSON(t, resp)["rendered"].(map[string]any)
+	if rendered["subject"] != "Hi" || rendered["body"] != "Body\n-- MyESI" {
+		t.Fatalf("unexpected rendered %v", rendered)
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"myesi-notification-service/internal/domain"
+	"myesi-notification-service/internal/templates"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// templateIssue describes why a template field failed to parse or execute.
+type templateIssue struct {
+	Field string `json:"field"`
+	Stage string `json:"stage"`
+	Error string `json:"error"`
+}
+
+type previewRequest struct {
+	EventType string                    `json:"event_type"`
+	Channel   string                    `json:"channel"`
+	Format    string                    `json:"format"`
+	Subject   string                    `json:"subject"`
+	Body      string                    `json:"body"`
+	Event     *domain.NotificationEvent `json:"event,omitempty"`
+}
+
+// previewTemplate renders a subject/body against the supplied event, or a sample
+// event for event_type, and reports parse/execution errors per field. The
+// result uses the caller's partials and layout for channel and is formatted
+// for channel as delivery would send it.
+func (h HandlerDeps) previewTemplate(c *fiber.Ctx) error {
+	orgID, err := h.templateScope(c, false)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	var body previewRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+	}
+	if body.Subject == "" && body.Body == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "subject or body is required"})
+	}
+
+	if body.Channel == "" {
+		body.Channel = domain.ChannelInbox
+	}
+
+	evt := domain.SampleEvent(body.EventType, orgID)
+	if body.Event != nil {
+		evt = *body.Event
+		if evt.EventType == "" {
+			evt.EventType = body.EventType
+		}
+		if evt.Payload == nil {
+			evt.Payload = map[string]interface{}{}
+		}
+	}
+	evt.OrganizationID = orgID
+	data := domain.BuildTemplateData(evt)
+
+	set, err := h.partialSet(c, orgID, body.Channel)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	issues := h.validateTemplate(set, body.Subject, body.Body)
+	rendered := fiber.Map{}
+	if len(issues) == 0 {
+		fields := []struct {
+			name, src string
+			set       templates.Set
+		}{{"subject", body.Subject, templates.Set{Partials: set.Partials}}, {"body", body.Body, set}}
+		for _, f := range fields {
+			out, err := h.Renderer.RenderWith(f.set, f.src, data)
+			if err != nil {
+				issues = append(issues, templateIssue{Field: f.name, Stage: "execute", Error: err.Error()})
+				continue
+			}
+			rendered[f.name] = out
+		}
+	}
+
+	// Markdown bodies are shown as every channel would receive them.
+	if out, ok := rendered["body"].(string); ok && body.Format == domain.FormatMarkdown {
+		formatted := fiber.Map{}
+		for _, output := range []string{templates.OutputHTML, templates.OutputSafeHTML, templates.OutputSlack, templates.OutputTeams, templates.OutputText} {
+			formatted[output] = templates.Markdown(out, output)
+		}
+		rendered["formatted"] = formatted
+	}
+	if len(issues) == 0 {
+		subject, _ := rendered["subject"].(string)
+		out, _ := rendered["body"].(string)
+		rendered["channel"] = domain.FormatMessage(body.Format, body.Channel, subject, out)
+	}
+
+	status := 200
+	if len(issues) > 0 {
+		status = 422
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"event_type": evt.EventType,
+		"channel":    body.Channel,
+		"rendered":   rendered,
+		"errors":     issues,
+	})
+}
+
+// validateTemplate parses subject and body without executing them. Both may call
+// the partials in set; only the body is wrapped in the layout.
+func (h HandlerDeps) validateTemplate(set templates.Set, subject, body string) []templateIssue {
+	issues := make([]templateIssue, 0)
+	if err := h.Renderer.ValidateWith(templates.Set{Partials: set.Partials}, subject); err != nil {
+		issues = append(issues, templateIssue{Field: "subject", Stage: "parse", Error: err.Error()})
+	}
+	if err := h.Renderer.ValidateWith(set, body); err != nil {
+		issues = append(issues, templateIssue{Field: "body", Stage: "parse", Error: err.Error()})
+	}
+	return issues
+}
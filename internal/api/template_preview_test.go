@@ -0,0 +1,143 @@
+package api_test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"myesi-notification-service/internal/api"
+	"myesi-notification-service/internal/domain"
+)
+
+func postJSON(t *testing.T, path, body string) *http.Request {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestPreviewTemplate_SampleEvent(t *testing.T) {
+	app := newApp(api.HandlerDeps{})
+	req := postJSON(t, "/api/notification/templates/preview",
+		`{"event_type":"project.scan.completed","channel":"email","subject":"Scan {{.payload.project}}","body":"{{.payload.vulns}} vulns"}`)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	data := readJSON(t, resp)
+	rendered := data["rendered"].(map[string]any)
+	if rendered["subject"] != "Scan sample-project" || rendered["body"] != "12 vulns" {
+		t.Fatalf("unexpected render: %v", rendered)
+	}
+}
+
+func TestPreviewTemplate_SuppliedEvent(t *testing.T) {
+	app := newApp(api.HandlerDeps{})
+	req := postJSON(t, "/api/notification/templates/preview",
+		`{"channel":"slack","body":"{{.event.type}} for {{.payload.project}}","event":{"type":"x.y","payload":{"project":"core-api"}}}`)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	data := readJSON(t, resp)
+	if data["rendered"].(map[string]any)["body"] != "x.y for core-api" {
+		t.Fatalf("unexpected render: %v", data["rendered"])
+	}
+}
+
+func TestPreviewTemplate_ParseError(t *testing.T) {
+	app := newApp(api.HandlerDeps{})
+	req := postJSON(t, "/api/notification/templates/preview",
+		`{"event_type":"x","subject":"ok","body":"{{.payload.project"}`)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 422 {
+		t.Fatalf("expected 422 got %d", resp.StatusCode)
+	}
+	data := readJSON(t, resp)
+	errs := data["errors"].([]any)
+	if len(errs) != 1 || errs[0].(map[string]any)["field"] != "body" || errs[0].(map[string]any)["stage"] != "parse" {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
+
+func TestPreviewTemplate_ExecuteError(t *testing.T) {
+	app := newApp(api.HandlerDeps{})
+	req := postJSON(t, "/api/notification/templates/preview",
+		`{"event_type":"x","body":"{{index .payload.project 99}}"}`)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 422 {
+		t.Fatalf("expected 422 got %d", resp.StatusCode)
+	}
+	errs := readJSON(t, resp)["errors"].([]any)
+	if len(errs) != 1 || errs[0].(map[string]any)["stage"] != "execute" {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
+
+func TestUpsertTemplate_RejectsUndefinedFunction(t *testing.T) {
+	tpls := &stubTemplates{}
+	app := newApp(api.HandlerDeps{Templates: tpls})
+	req := postJSON(t, "/api/notification/templates",
+		`{"event_type":"x","channel":"email","subject":"s","body":"{{shout .payload.project}}"}`)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 422 {
+		t.Fatalf("expected 422 got %d", resp.StatusCode)
+	}
+	if tpls.lastUpsert.EventType != "" {
+		t.Fatalf("invalid template must not be saved")
+	}
+}
+
+func TestPreviewTemplate_MarkdownFormats(t *testing.T) {
+	app := newApp(api.HandlerDeps{})
+	req := postJSON(t, "/api/notification/templates/preview",
+		`{"event_type":"x","channel":"slack","format":"markdown","subject":"s","body":"**{{.event.type}}**"}`)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	rendered, _ := readJSON(t, resp)["rendered"].(map[string]any)
+	formatted, _ := rendered["formatted"].(map[string]any)
+	if formatted["slack"] != "*x*" || formatted["html"] != "<p><strong>x</strong></p>" || formatted["text"] != "x" {
+		t.Fatalf("unexpected formatted outputs %v", formatted)
+	}
+}
+
+func TestUpsertTemplate_RejectsUnknownFormat(t *testing.T) {
+	app := newApp(api.HandlerDeps{Templates: &stubTemplates{}})
+	req := postJSON(t, "/api/notification/templates", `{"event_type":"x","channel":"email","format":"rtf","subject":"s","body":"b"}`)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 400 {
+		t.Fatalf("expected 400 got %d", resp.StatusCode)
+	}
+}
+
+func TestPreviewTemplate_GlobalRequiresServiceToken(t *testing.T) {
+	app := newApp(api.HandlerDeps{ServiceToken: "secret"})
+	resp, _ := app.Test(postJSON(t, "/api/notification/templates/preview", `{"event_type":"x","body":"b"}`))
+	if resp.StatusCode != 401 {
+		t.Fatalf("expected 401 got %d", resp.StatusCode)
+	}
+}
+
+func TestPreviewTemplate_UsesCallerPartialsAndChannelFormat(t *testing.T) {
+	parts := &partialsMock{parts: []domain.NotificationPartial{
+		{Kind: domain.PartialKindPartial, Name: "footer", Channel: "slack", Body: "_from slack_"},
+	}}
+	app := newApp(api.HandlerDeps{Partials: parts, ServiceToken: "secret"})
+	req := postJSON(t, "/api/notification/templates/preview",
+		`{"event_type":"x","channel":"slack","format":"markdown","subject":"s","body":"**hi** {{template \"footer\" .}}"}`)
+	req.Header.Set("X-Organization-Id", "5")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d: %v", resp.StatusCode, readJSON(t, resp))
+	}
+	if parts.lastOrgID != 5 {
+		t.Fatalf("expected org 5 partials, got org %d", parts.lastOrgID)
+	}
+	rendered, _ := readJSON(t, resp)["rendered"].(map[string]any)
+	channel, _ := rendered["channel"].(map[string]any)
+	if channel["body"] != "*hi* _from slack_" {
+		t.Fatalf("unexpected channel output %v", rendered)
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"myesi-notification-service/internal/domain"
+	"myesi-notification-service/internal/templates"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// ===== Template version handlers =====
+func (h HandlerDeps) listTemplateVersions(c *fiber.Ctx) error {
+	if h.Versions == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "template versioning not enabled"})
+	}
+	orgID, err := h.templateScope(c, false)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+	}
+
+	versions, err := h.Versions.ListVersions(c.Context(), orgID, id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(versions)
+}
+
+// diffTemplateVersions compares ?from=<version>&to=<version> of a template line by line.
+func (h HandlerDeps) diffTemplateVersions(c *fiber.Ctx) error {
+	if h.Versions == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "template versioning not enabled"})
+	}
+	orgID, err := h.templateScope(c, false)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+	}
+	from, errFrom := strconv.Atoi(c.Query("from"))
+	to, errTo := strconv.Atoi(c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "from and to versions are required"})
+	}
+
+	fromVer, err := h.Versions.GetVersion(c.Context(), orgID, id, from)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	toVer, err := h.Versions.GetVersion(c.Context(), orgID, id, to)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if fromVer == nil || toVer == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "version not found"})
+	}
+
+	return c.JSON(fiber.Map{
+		"template_id": id,
+		"from":        fromVer,
+		"to":          toVer,
+		"subject":     templates.Diff(fromVer.Subject, toVer.Subject),
+		"body":        templates.Diff(fromVer.Body, toVer.Body),
+	})
+}
+
+// rollbackTemplate restores an earlier version after checking that it still
+// parses against the partials and layout it would render with today.
+func (h HandlerDeps) rollbackTemplate(c *fiber.Ctx) error {
+	if h.Versions == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "template versioning not enabled"})
+	}
+	orgID, err := h.templateScope(c, true)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+	}
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid version"})
+	}
+
+	target, err := h.Versions.GetVersion(c.Context(), orgID, id, version)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if target == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "version not found"})
+	}
+	var channel string
+	if h.Templates != nil {
+		tpl, err := h.Templates.Get(c.Context(), orgID, id)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if tpl != nil {
+			channel = tpl.Channel
+		}
+	}
+	set, err := h.partialSet(c, orgID, channel)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if issues := h.validateTemplate(set, target.Subject, target.Body); len(issues) > 0 {
+		return c.Status(422).JSON(fiber.Map{"error": "invalid template", "details": issues})
+	}
+
+	saved, err := h.Versions.Rollback(c.Context(), orgID, id, version, requestAuthor(c))
+	if errors.Is(err, domain.ErrNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "version not found"})
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(saved)
+}
@@ -0,0 +1,160 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"myesi-notification-service/internal/api"
+	"myesi-notification-service/internal/domain"
+)
+
+type versionsMock struct {
+	versions     map[int]domain.TemplateVersion
+	lastOrgID    int64
+	rollbackTo   int
+	rollbackBy   string
+	rollbackErr  error
+	rollbackSeen int
+	sources      []domain.TemplateSource
+}
+
+func (m *versionsMock) ListVersions(ctx domain.Context, orgID, templateID int64) ([]domain.TemplateVersion, error) {
+	m.lastOrgID = orgID
+	out := make([]domain.TemplateVersion, 0, len(m.versions))
+	for _, v := range m.versions {
+		out = append(out, v)
+	}
+	return out, nil
+}
+func (m *versionsMock) GetVersion(ctx domain.Context, orgID, templateID int64, version int) (*domain.TemplateVersion, error) {
+	m.lastOrgID = orgID
+	v, ok := m.versions[version]
+	if !ok {
+		return nil, nil
+	}
+	return &v, nil
+}
+func (m *versionsMock) Rollback(ctx domain.Context, orgID, templateID int64, version int, author string) (domain.NotificationTemplate, error) {
+	m.rollbackSeen++
+	m.lastOrgID = orgID
+	m.rollbackTo = version
+	m.rollbackBy = author
+	if m.rollbackErr != nil {
+		return domain.NotificationTemplate{}, m.rollbackErr
+	}
+	return domain.NotificationTemplate{ID: templateID, Version: 3}, nil
+}
+
+func (m *versionsMock) Sources(ctx domain.Context, orgID int64, text string) ([]domain.TemplateSource, error) {
+	m.lastOrgID = orgID
+	return m.sources, nil
+}
+
+func newVersionsMock() *versionsMock {
+	return &versionsMock{versions: map[int]domain.TemplateVersion{
+		1: {TemplateID: 7, Version: 1, Subject: "Hi", Body: "line a\nline b"},
+		2: {TemplateID: 7, Version: 2, Subject: "Hi", Body: "line a\nline c"},
+	}}
+}
+
+func TestTemplateVersions_NotEnabled(t *testing.T) {
+	app := newApp(api.HandlerDeps{})
+	req, _ := http.NewRequest(http.MethodGet, "/api/notification/templates/7/versions", nil)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 501 {
+		t.Fatalf("expected 501 got %d", resp.StatusCode)
+	}
+}
+
+func TestTemplateVersions_ListScopedToOrg(t *testing.T) {
+	m := newVersionsMock()
+	app := newApp(api.HandlerDeps{Versions: m})
+	req, _ := http.NewRequest(http.MethodGet, "/api/notification/templates/7/versions", nil)
+	req.Header.Set("X-Organization-Id", "5")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	if m.lastOrgID != 5 {
+		t.Fatalf("expected org 5 got %d", m.lastOrgID)
+	}
+}
+
+func TestTemplateVersions_Diff(t *testing.T) {
+	app := newApp(api.HandlerDeps{Versions: newVersionsMock()})
+	req, _ := http.NewRequest(http.MethodGet, "/api/notification/templates/7/versions/diff?from=1&to=2", nil)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	data := readJSON(t, resp)
+	body := data["body"].([]any)
+	if len(body) != 3 {
+		t.Fatalf("expected 3 diff lines got %v", body)
+	}
+}
+
+func TestTemplateVersions_DiffMissingVersion(t *testing.T) {
+	app := newApp(api.HandlerDeps{Versions: newVersionsMock()})
+	req, _ := http.NewRequest(http.MethodGet, "/api/notification/templates/7/versions/diff?from=1&to=9", nil)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 404 {
+		t.Fatalf("expected 404 got %d", resp.StatusCode)
+	}
+}
+
+func TestTemplateVersions_RollbackRequiresAdmin(t *testing.T) {
+	m := newVersionsMock()
+	app := newApp(api.HandlerDeps{Versions: m})
+	req, _ := http.NewRequest(http.MethodPost, "/api/notification/templates/7/versions/1/rollback", nil)
+	req.Header.Set("X-Organization-Id", "5")
+	req.Header.Set("X-User-Role", "developer")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 403 {
+		t.Fatalf("expected 403 got %d", resp.StatusCode)
+	}
+	if m.rollbackSeen != 0 {
+		t.Fatalf("rollback should not be attempted")
+	}
+}
+
+func TestTemplateVersions_Rollback(t *testing.T) {
+	m := newVersionsMock()
+	app := newApp(api.HandlerDeps{Versions: m})
+	req, _ := http.NewRequest(http.MethodPost, "/api/notification/templates/7/versions/1/rollback", nil)
+	req.Header.Set("X-Organization-Id", "5")
+	req.Header.Set("X-User-Role", "admin")
+	req.Header.Set("X-User-Email", "admin@x.com")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	if m.rollbackTo != 1 || m.rollbackBy != "admin@x.com" || m.lastOrgID != 5 {
+		t.Fatalf("unexpected rollback call: to=%d by=%s org=%d", m.rollbackTo, m.rollbackBy, m.lastOrgID)
+	}
+}
+
+func TestTemplateVersions_RollbackNotFound(t *testing.T) {
+	m := newVersionsMock()
+	m.rollbackErr = domain.ErrNotFound
+	app := newApp(api.HandlerDeps{Versions: m})
+	req, _ := http.NewRequest(http.MethodPost, "/api/notification/templates/7/versions/9/rollback", nil)
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 404 {
+		t.Fatalf("expected 404 got %d", resp.StatusCode)
+	}
+}
+
+func TestTemplateVersions_RollbackRejectsBrokenVersion(t *testing.T) {
+	m := newVersionsMock()
+	m.versions[1] = domain.TemplateVersion{TemplateID: 7, Version: 1, Subject: "Hi", Body: `{{template "gone" .}}`}
+	app := newApp(api.HandlerDeps{Versions: m, Partials: &partialsMock{}})
+	req, _ := http.NewRequest(http.MethodPost, "/api/notification/templates/7/versions/1/rollback", nil)
+	resp, _ := app.Test(orgAdminRequest(req))
+	if resp.StatusCode != 422 {
+		t.Fatalf("expected 422 got %d", resp.StatusCode)
+	}
+	if m.rollbackSeen != 0 {
+		t.Fatalf("a version that no longer parses must not be restored")
+	}
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"myesi-notification-service/internal/domain"
+	"myesi-notification-service/internal/providers"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// TestSender delivers sample notifications through the real providers.
+type TestSender interface {
+	SendTest(ctx context.Context, orgID int64, eventType string, tpl *domain.NotificationTemplate, target domain.DeliveryTarget) (domain.TestDelivery, error)
+}
+
+type testSendRequest struct {
+	EventType     string `json:"event_type"`
+	Channel       string `json:"channel"`
+	Target        string `json:"target"`
+	DestinationID int64  `json:"destination_id"`
+}
+
+// testPreference sends a sample notification to a preference's destination
+// using the template a real event would resolve to.
+func (h HandlerDeps) testPreference(c *fiber.Ctx) error {
+	if h.Tester == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "test send not enabled"})
+	}
+	orgID, err := h.templateScope(c, true)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+	}
+	var body testSendRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+		}
+	}
+
+	pref, err := h.Preferences.Get(c.Context(), id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if pref == nil || (orgID != 0 && pref.OrganizationID != orgID) {
+		return c.Status(404).JSON(fiber.Map{"error": "preference not found"})
+	}
+	eventType := body.EventType
+	if eventType == "" {
+		eventType = pref.EventType
+	}
+
+	target := domain.DeliveryTarget{Channel: pref.Channel, Target: pref.Target}
+	return h.sendTest(c, pref.OrganizationID, eventType, nil, target)
+}
+
+// testTemplate renders a stored template with sample data and sends it to the
+// verified destination given in the body, by destination_id or by target.
+func (h HandlerDeps) testTemplate(c *fiber.Ctx) error {
+	if h.Tester == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "test send not enabled"})
+	}
+	orgID, err := h.templateScope(c, true)
+	if err != nil {
+		return errorJSON(c, err)
+	}
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+	}
+	var body testSendRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	tpl, err := h.Templates.Get(c.Context(), orgID, id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if tpl == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "template not found"})
+	}
+	channel := body.Channel
+	if channel == "" {
+		channel = tpl.Channel
+	}
+	if channel == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "channel is required"})
+	}
+	eventType := body.EventType
+	if eventType == "" {
+		eventType = tpl.EventType
+	}
+
+	target := domain.DeliveryTarget{Channel: channel, Target: body.Target}
+	if body.DestinationID != 0 {
+		if h.Destinations == nil {
+			return c.Status(501).JSON(fiber.Map{"error": "destination verification not enabled"})
+		}
+		d, err := h.Destinations.Get(c.Context(), orgID, body.DestinationID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		if d == nil {
+			return c.Status(404).JSON(fiber.Map{"error": "destination not found"})
+		}
+		target = domain.DeliveryTarget{Channel: d.Channel, Target: d.Target}
+	}
+	return h.sendTest(c, orgID, eventType, tpl, target)
+}
+
+// sendTest delivers and reports the provider's status code and headers. A
+// failed delivery is a 502 so clients can tell it apart from a rejected request.
+func (h HandlerDeps) sendTest(c *fiber.Ctx, orgID int64, eventType string, tpl *domain.NotificationTemplate, target domain.DeliveryTarget) error {
+	var resp providers.Response
+	ctx := providers.WithResponse(c.Context(), &resp)
+
+	result, err := h.Tester.SendTest(ctx, orgID, eventType, tpl, target)
+	switch {
+	case errors.Is(err, domain.ErrUnsupportedChannel):
+		return c.Status(400).JSON(fiber.Map{"error": "channel does not support test sends"})
+	case errors.Is(err, domain.ErrMissingTarget):
+		return c.Status(400).JSON(fiber.Map{"error": "target is required"})
+	case errors.Is(err, domain.ErrUnverifiedDestination):
+		return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	status := 200
+	if !result.Delivered {
+		status = 502
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"delivery": result,
+		"provider": resp,
+	})
+}
@@ -0,0 +1,189 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/api"
+	"myesi-notification-service/internal/domain"
+	"myesi-notification-service/internal/providers"
+)
+
+type testerMock struct {
+	orgID     int64
+	eventType string
+	tpl       *domain.NotificationTemplate
+	target    domain.DeliveryTarget
+	fail      bool
+	err       error
+}
+
+func (m *testerMock) SendTest(ctx context.Context, orgID int64, eventType string, tpl *domain.NotificationTemplate, target domain.DeliveryTarget) (domain.TestDelivery, error) {
+	m.orgID, m.eventType, m.tpl, m.target = orgID, eventType, tpl, target
+	if m.err != nil {
+		return domain.TestDelivery{}, m.err
+	}
+	if resp := providers.ResponseFrom(ctx); resp != nil {
+		resp.StatusCode, resp.Headers = 200, map[string]string{"X-Request-Id": "r1"}
+	}
+	out := domain.TestDelivery{Channel: target.Channel, Target: target.Target, EventType: eventType, Delivered: !m.fail}
+	if m.fail {
+		out.Error = "boom"
+	}
+	return out, nil
+}
+
+type prefsWithGet struct {
+	stubPrefs
+	pref *domain.NotificationPreference
+}
+
+func (s *prefsWithGet) Get(ctx domain.Context, id int64) (*domain.NotificationPreference, error) {
+	return s.pref, nil
+}
+
+type templatesWithGet struct {
+	stubTemplates
+	tpl       *domain.NotificationTemplate
+	lastGetID int64
+}
+
+func (s *templatesWithGet) Get(ctx domain.Context, orgID, id int64) (*domain.NotificationTemplate, error) {
+	s.lastOrgID, s.lastGetID = orgID, id
+	return s.tpl, nil
+}
+
+type destinationsMock struct {
+	byID map[int64]domain.Destination
+}
+
+func (m *destinationsMock) List(ctx domain.Context, orgID int64) ([]domain.Destination, error) {
+	return nil, nil
+}
+func (m *destinationsMock) Get(ctx domain.Context, orgID, id int64) (*domain.Destination, error) {
+	if d, ok := m.byID[id]; ok && d.OrganizationID == orgID {
+		return &d, nil
+	}
+	return nil, nil
+}
+func (m *destinationsMock) Find(ctx domain.Context, orgID int64, channel, target string) (*domain.Destination, error) {
+	return nil, nil
+}
+func (m *destinationsMock) Save(ctx domain.Context, d domain.Destination) (domain.Destination, error) {
+	return d, nil
+}
+func (m *destinationsMock) UseAttempt(ctx domain.Context, orgID, id int64, max int) (string, bool, error) {
+	return "", false, nil
+}
+func (m *destinationsMock) Reissue(ctx domain.Context, orgID, id int64, codeHash string, sentAt time.Time) (*domain.Destination, error) {
+	return nil, nil
+}
+
+func TestTestSend_NotEnabled(t *testing.T) {
+	app := newApp(api.HandlerDeps{Preferences: &stubPrefs{}})
+	resp, _ := app.Test(orgAdminRequest(postJSON(t, "/api/notification/preferences/1/test", `{}`)))
+	if resp.StatusCode != 501 {
+		t.Fatalf("expected 501 got %d", resp.StatusCode)
+	}
+}
+
+func TestTestPreference_OtherOrgNotFound(t *testing.T) {
+	prefs := &prefsWithGet{pref: &domain.NotificationPreference{ID: 1, OrganizationID: 9, EventType: "scan.done", Channel: "slack", Target: "https://hooks"}}
+	tester := &testerMock{}
+	app := newApp(api.HandlerDeps{Preferences: prefs, Tester: tester})
+	resp, _ := app.Test(orgAdminRequest(postJSON(t, "/api/notification/preferences/1/test", `{}`)))
+	if resp.StatusCode != 404 {
+		t.Fatalf("expected 404 got %d", resp.StatusCode)
+	}
+	if tester.eventType != "" {
+		t.Fatalf("tester should not be called")
+	}
+}
+
+func TestTestPreference_ReturnsProviderResponse(t *testing.T) {
+	prefs := &prefsWithGet{pref: &domain.NotificationPreference{ID: 1, OrganizationID: 5, EventType: "scan.done", Channel: "slack", Target: "https://hooks"}}
+	tester := &testerMock{}
+	app := newApp(api.HandlerDeps{Preferences: prefs, Tester: tester})
+	resp, _ := app.Test(orgAdminRequest(postJSON(t, "/api/notification/preferences/1/test", `{}`)))
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	out := readJSON(t, resp)
+	provider := out["provider"].(map[string]any)
+	headers, _ := provider["headers"].(map[string]any)
+	if provider["status_code"].(float64) != 200 || headers["X-Request-Id"] != "r1" {
+		t.Fatalf("unexpected provider response %v", provider)
+	}
+	if tester.orgID != 5 || tester.eventType != "scan.done" || tester.tpl != nil || tester.target.Target != "https://hooks" {
+		t.Fatalf("unexpected call %#v", tester)
+	}
+}
+
+func TestTestPreference_FailedDeliveryIsBadGateway(t *testing.T) {
+	prefs := &prefsWithGet{pref: &domain.NotificationPreference{ID: 1, OrganizationID: 5, EventType: "scan.done", Channel: "email", Target: "a@b.c"}}
+	app := newApp(api.HandlerDeps{Preferences: prefs, Tester: &testerMock{fail: true}})
+	resp, _ := app.Test(orgAdminRequest(postJSON(t, "/api/notification/preferences/1/test", `{}`)))
+	if resp.StatusCode != 502 {
+		t.Fatalf("expected 502 got %d", resp.StatusCode)
+	}
+	if out := readJSON(t, resp); out["delivery"].(map[string]any)["error"] != "boom" {
+		t.Fatalf("expected delivery error, got %v", out)
+	}
+}
+
+func TestTestTemplate_DefaultsFromTemplate(t *testing.T) {
+	tpls := &templatesWithGet{tpl: &domain.NotificationTemplate{ID: 3, EventType: "scan.done", Channel: "email"}}
+	tester := &testerMock{}
+	app := newApp(api.HandlerDeps{Templates: tpls, Tester: tester})
+	resp, _ := app.Test(orgAdminRequest(postJSON(t, "/api/notification/templates/3/test", `{"target":"a@b.c"}`)))
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 got %d", resp.StatusCode)
+	}
+	if tpls.lastOrgID != 5 || tpls.lastGetID != 3 {
+		t.Fatalf("expected lookup scoped to org 5, got org %d id %d", tpls.lastOrgID, tpls.lastGetID)
+	}
+	if tester.tpl == nil || tester.target.Channel != "email" || tester.eventType != "scan.done" {
+		t.Fatalf("unexpected call %#v", tester)
+	}
+}
+
+func TestTestTemplate_MissingTarget(t *testing.T) {
+	tpls := &templatesWithGet{tpl: &domain.NotificationTemplate{ID: 3, EventType: "scan.done", Channel: "email"}}
+	app := newApp(api.HandlerDeps{Templates: tpls, Tester: &testerMock{err: domain.ErrMissingTarget}})
+	resp, _ := app.Test(orgAdminRequest(postJSON(t, "/api/notification/templates/3/test", `{}`)))
+	if resp.StatusCode != 400 {
+		t.Fatalf("expected 400 got %d", resp.StatusCode)
+	}
+}
+
+func TestTestTemplate_NotFound(t *testing.T) {
+	app := newApp(api.HandlerDeps{Templates: &templatesWithGet{}, Tester: &testerMock{err: errors.New("unreachable")}})
+	resp, _ := app.Test(orgAdminRequest(postJSON(t, "/api/notification/templates/3/test", `{"target":"x"}`)))
+	if resp.StatusCode != 404 {
+		t.Fatalf("expected 404 got %d", resp.StatusCode)
+	}
+}
+
+func TestTestTemplate_UnverifiedTargetForbidden(t *testing.T) {
+	tpls := &templatesWithGet{tpl: &domain.NotificationTemplate{ID: 3, EventType: "scan.done", Channel: "webhook"}}
+	app := newApp(api.HandlerDeps{Templates: tpls, Tester: &testerMock{err: domain.ErrUnverifiedDestination}})
+	resp, _ := app.Test(orgAdminRequest(postJSON(t, "/api/notification/templates/3/test", `{"target":"http://169.254.169.254/"}`)))
+	if resp.StatusCode != 403 {
+		t.Fatalf("expected 403 got %d", resp.StatusCode)
+	}
+}
+
+func TestTestTemplate_ByDestinationID(t *testing.T) {
+	tpls := &templatesWithGet{tpl: &domain.NotificationTemplate{ID: 3, EventType: "scan.done", Channel: "email"}}
+	dests := &destinationsMock{byID: map[int64]domain.Destination{
+		4: {ID: 4, OrganizationID: 5, Channel: "slack", Target: "https://hooks/ok", Status: domain.DestinationVerified},
+	}}
+	tester := &testerMock{}
+	app := newApp(api.HandlerDeps{Templates: tpls, Tester: tester, Destinations: dests})
+	resp, _ := app.Test(orgAdminRequest(postJSON(t, "/api/notification/templates/3/test", `{"destination_id":4}`)))
+	if resp.StatusCode != 200 || tester.target.Channel != "slack" || tester.target.Target != "https://hooks/ok" {
+		t.Fatalf("expected send to destination 4, got %d %#v", resp.StatusCode, tester.target)
+	}
+}
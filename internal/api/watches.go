@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"myesi-notification-service/internal/domain"
+
+	fiber "github.com/gofiber/fiber/v2"
+)
+
+// listWatches returns the projects the calling user watches, owned ones
+// included.
+func (h HandlerDeps) listWatches(c *fiber.Ctx) error {
+	if h.Watchers == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "watchers not enabled"})
+	}
+	orgID, userID := extractOrgID(c), extractUserID(c)
+	if orgID == 0 || userID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization and user required"})
+	}
+	res, err := h.Watchers.ListForUser(c.Context(), orgID, userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(res)
+}
+
+func (h HandlerDeps) watchProject(c *fiber.Ctx) error {
+	return h.setWatch(c, true)
+}
+
+// unwatchProject also opts an owner out of their project's notifications.
+func (h HandlerDeps) unwatchProject(c *fiber.Ctx) error {
+	return h.setWatch(c, false)
+}
+
+func (h HandlerDeps) setWatch(c *fiber.Ctx, watching bool) error {
+	if h.Watchers == nil {
+		return c.Status(501).JSON(fiber.Map{"error": "watchers not enabled"})
+	}
+	orgID, userID := extractOrgID(c), extractUserID(c)
+	if orgID == 0 || userID == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "organization and user required"})
+	}
+	projectID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid project id"})
+	}
+	if err := h.Watchers.Watch(c.Context(), orgID, projectID, userID, watching); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "project not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"project_id": projectID, "watching": watching})
+}
@@ -0,0 +1,56 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"myesi-notification-service/internal/api"
+	"myesi-notification-service/internal/domain"
+)
+
+type watcherMock struct {
+	watched map[int64]bool
+}
+
+func (m *watcherMock) Watch(ctx domain.Context, orgID, projectID, userID int64, watching bool) error {
+	if projectID == 404 {
+		return domain.ErrNotFound
+	}
+	m.watched[projectID] = watching
+	return nil
+}
+func (m *watcherMock) ListForUser(ctx domain.Context, orgID, userID int64) ([]domain.ProjectWatch, error) {
+	return nil, nil
+}
+func (m *watcherMock) Watchers(ctx domain.Context, orgID, projectID int64, project string) ([]int64, error) {
+	return nil, nil
+}
+
+func TestWatches_WatchAndUnwatch(t *testing.T) {
+	m := &watcherMock{watched: map[int64]bool{}}
+	app := newApp(api.HandlerDeps{Watchers: m})
+
+	req, _ := http.NewRequest(http.MethodPut, "/api/notification/projects/4/watch", nil)
+	req.Header.Set("X-Organization-Id", "5")
+	req.Header.Set("X-User-Id", "7")
+	resp, _ := app.Test(req)
+	if resp.StatusCode != 200 || !m.watched[4] {
+		t.Fatalf("expected watch, got %d %v", resp.StatusCode, m.watched)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, "/api/notification/projects/4/watch", nil)
+	req.Header.Set("X-Organization-Id", "5")
+	req.Header.Set("X-User-Id", "7")
+	resp, _ = app.Test(req)
+	if resp.StatusCode != 200 || m.watched[4] {
+		t.Fatalf("expected unwatch, got %d %v", resp.StatusCode, m.watched)
+	}
+
+	req, _ = http.NewRequest(http.MethodPut, "/api/notification/projects/404/watch", nil)
+	req.Header.Set("X-Organization-Id", "5")
+	req.Header.Set("X-User-Id", "7")
+	resp, _ = app.Test(req)
+	if resp.StatusCode != 404 {
+		t.Fatalf("expected 404 for a foreign project, got %d", resp.StatusCode)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -25,6 +26,13 @@ type Config struct {
 	SlackDefaultWebhook  string
 	WebhookDefaultTarget string
 	ServiceToken         string
+	TemplateCacheSize    int
+	TemplateCacheTTL     time.Duration
+	DigestFlushInterval  time.Duration
+	ScheduledInterval    time.Duration
+	// DestinationVerifyURL is the page linked from destination confirmation
+	// messages; without it they only carry the code.
+	DestinationVerifyURL string
 }
 
 // LoadConfig reads configuration from environment variables with sane defaults.
@@ -47,6 +55,11 @@ func LoadConfig() *Config {
 		SlackDefaultWebhook:  getEnv("SLACK_DEFAULT_WEBHOOK", ""),
 		WebhookDefaultTarget: getEnv("WEBHOOK_DEFAULT_TARGET", ""),
 		ServiceToken:         getEnv("NOTIFICATION_SERVICE_TOKEN", ""),
+		TemplateCacheSize:    getEnvInt("TEMPLATE_CACHE_SIZE", 512),
+		TemplateCacheTTL:     time.Duration(getEnvInt("TEMPLATE_CACHE_TTL_SECONDS", 300)) * time.Second,
+		DigestFlushInterval:  time.Duration(getEnvInt("DIGEST_FLUSH_INTERVAL_SECONDS", 60)) * time.Second,
+		ScheduledInterval:    time.Duration(getEnvInt("SCHEDULED_DELIVERY_INTERVAL_SECONDS", 30)) * time.Second,
+		DestinationVerifyURL: getEnv("DESTINATION_VERIFY_URL", ""),
 	}
 
 	if cfg.DatabaseURL == "" {
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func withEnv(t *testing.T, key, val string, fn func()) {
@@ -37,6 +38,7 @@ func TestLoadConfig_Defaults(t *testing.T) {
 		"POSTGRES_DSN", "DATABASE_URL", "DEFAULT_ALERT_EMAILS",
 		"SMTP_HOST", "SMTP_PORT", "SMTP_USER", "SMTP_PASS", "FROM_ADDRESS",
 		"SLACK_DEFAULT_WEBHOOK", "WEBHOOK_DEFAULT_TARGET", "NOTIFICATION_SERVICE_TOKEN",
+		"TEMPLATE_CACHE_SIZE", "TEMPLATE_CACHE_TTL_SECONDS",
 	}
 	for _, k := range keys {
 		k := k
@@ -59,6 +61,12 @@ func TestLoadConfig_Defaults(t *testing.T) {
 	if cfg.SMTPPort != 587 {
 		t.Fatalf("expected default smtp port 587 got %d", cfg.SMTPPort)
 	}
+	if cfg.TemplateCacheSize != 512 || cfg.TemplateCacheTTL != 5*time.Minute {
+		t.Fatalf("unexpected template cache defaults: %d %v", cfg.TemplateCacheSize, cfg.TemplateCacheTTL)
+	}
+	if cfg.DigestFlushInterval != time.Minute || cfg.ScheduledInterval != 30*time.Second {
+		t.Fatalf("unexpected scheduler defaults: %v %v", cfg.DigestFlushInterval, cfg.ScheduledInterval)
+	}
 }
 
 func TestLoadConfig_PrefersKAFKA_BROKERSOverKAFKA_BROKER(t *testing.T) {
@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+// EventAlertResolved is the event type resolution notices are resolved and logged under.
+const EventAlertResolved = "notification.alert_resolved"
+
+var (
+	// ErrAlertsUnavailable is returned when no alert store is configured.
+	ErrAlertsUnavailable = errors.New("alerts not enabled")
+	// ErrAlertNotFound is returned for unknown correlation keys.
+	ErrAlertNotFound = errors.New("alert not found")
+	// ErrInvalidTransition is returned when the alert's state does not allow the change.
+	ErrInvalidTransition = errors.New("invalid alert transition")
+)
+
+// alertTransitions lists the states each state may be entered from.
+var alertTransitions = map[string][]string{
+	AlertAcknowledged: {AlertOpen},
+	AlertResolved:     {AlertOpen, AlertAcknowledged},
+}
+
+// resolvesAlert reports whether the event closes the alert with its key.
+func (e NotificationEvent) resolvesAlert() bool {
+	return e.CorrelationKey != "" && strings.HasSuffix(e.EventType, ".resolved")
+}
+
+// openAlert records the event's alert and returns its current state, or ""
+// when the event is not correlated or alerts are not stored.
+func (s *NotificationService) openAlert(ctx context.Context, evt NotificationEvent, summary string) string {
+	if s.Alerts == nil || evt.CorrelationKey == "" || evt.OrganizationID == 0 {
+		return ""
+	}
+	a, err := s.Alerts.Open(ctx, Alert{
+		OrganizationID: evt.OrganizationID,
+		CorrelationKey: evt.CorrelationKey,
+		EventType:      evt.EventType,
+		Severity:       evt.Severity,
+		Summary:        summary,
+	})
+	if err != nil {
+		log.Printf("[NOTIFY] opening alert %s failed: %v", evt.CorrelationKey, err)
+		return ""
+	}
+	return a.State
+}
+
+// TransitionAlert acknowledges or resolves the alert with key. Resolving
+// announces the resolution on the channels that carried the original alert.
+// When the state does not allow the change the current alert is returned
+// with ErrInvalidTransition.
+func (s *NotificationService) TransitionAlert(ctx context.Context, orgID int64, key, state, by string) (*Alert, error) {
+	if s.Alerts == nil {
+		return nil, ErrAlertsUnavailable
+	}
+	from, ok := alertTransitions[state]
+	if !ok {
+		return nil, ErrInvalidTransition
+	}
+	a, err := s.Alerts.Transition(ctx, orgID, key, state, by, from)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		cur, err := s.Alerts.Get(ctx, orgID, key)
+		if err != nil {
+			return nil, err
+		}
+		if cur == nil {
+			return nil, ErrAlertNotFound
+		}
+		return cur, ErrInvalidTransition
+	}
+	if state == AlertResolved {
+		s.announceResolution(ctx, *a)
+	}
+	return a, nil
+}
+
+// announceResolution sends a resolution notice to the routed outbound targets
+// of the alert's original event type. Inbox items are updated in place instead.
+func (s *NotificationService) announceResolution(ctx context.Context, a Alert) {
+	evt := NotificationEvent{
+		EventType:      a.EventType,
+		OrganizationID: a.OrganizationID,
+		Severity:       a.Severity,
+		CorrelationKey: a.CorrelationKey,
+		OccurredAt:     time.Now().UTC(),
+		Payload: map[string]interface{}{
+			"alert": map[string]interface{}{
+				"correlation_key": a.CorrelationKey,
+				"event_type":      a.EventType,
+				"summary":         a.Summary,
+				"state":           a.State,
+				"resolved_by":     a.ResolvedBy,
+				"resolved_at":     a.ResolvedAt,
+			},
+		},
+	}
+
+	var settings *OrgSettings
+	if s.OrgSettings != nil {
+		if st, err := s.OrgSettings.Get(ctx, a.OrganizationID); err == nil {
+			settings = st
+		}
+	}
+	targets := s.outboundTargets(ctx, evt, settings)
+	evt.EventType = EventAlertResolved
+
+	locale := localeFor(evt, "", settings)
+	data := withLocale(BuildTemplateData(evt), locale)
+	partials := s.loadPartials(ctx, a.OrganizationID)
+	quiet := quietHoursCache{}
+	for _, target := range targets {
+		tpl := s.resolveTemplate(ctx, a.OrganizationID, EventAlertResolved, target.Channel, locale)
+		subject, body := s.renderTemplate(tpl, PartialSet(partials, target.Channel), data)
+		if until, ok := s.quietUntil(ctx, a.OrganizationID, target.UserID, "", quiet); ok &&
+			s.deferDelivery(ctx, evt, target, tpl.Format, subject, body, until) {
+			continue
+		}
+		_ = s.deliver(ctx, evt, target, tpl.Format, subject, body)
+	}
+}
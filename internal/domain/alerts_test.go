@@ -0,0 +1,146 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"myesi-notification-service/internal/templates"
+)
+
+type stubAlerts struct{ byKey map[string]*Alert }
+
+func (s *stubAlerts) Open(ctx Context, a Alert) (Alert, error) {
+	if cur, ok := s.byKey[a.CorrelationKey]; ok && cur.State != AlertResolved {
+		return *cur, nil
+	}
+	a.State = AlertOpen
+	s.byKey[a.CorrelationKey] = &a
+	return a, nil
+}
+func (s *stubAlerts) Get(ctx Context, orgID int64, key string) (*Alert, error) {
+	return s.byKey[key], nil
+}
+func (s *stubAlerts) List(ctx Context, orgID int64, state string, limit, offset int) ([]Alert, error) {
+	return nil, nil
+}
+func (s *stubAlerts) Transition(ctx Context, orgID int64, key, state, by string, from []string) (*Alert, error) {
+	a, ok := s.byKey[key]
+	if !ok {
+		return nil, nil
+	}
+	for _, f := range from {
+		if a.State == f {
+			a.State = state
+			if state == AlertResolved {
+				a.ResolvedBy = by
+			}
+			return a, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestHandleEvent_OpensCorrelatedAlert(t *testing.T) {
+	alerts := &stubAlerts{byKey: map[string]*Alert{}}
+	inbox := &stubInboxRepo{}
+	escalations := &stubEscalations{}
+	uid := int64(7)
+	svc := &NotificationService{
+		Templates:   &stubTemplateRepoAlways{tpl: NotificationTemplate{Subject: "CVE in {{.payload.project}}", Body: "b"}},
+		Preferences: &stubPrefRepoStatic{},
+		Logs:        &stubLogRepo{},
+		Inbox:       inbox,
+		Alerts:      alerts,
+		Escalations: escalations,
+		EscalationPolicies: &stubPolicies{policies: []EscalationPolicy{{
+			ID: 1, EventType: "alert.raised", Enabled: true, Tiers: []EscalationTier{{AfterMinutes: 5, Role: "admin"}},
+		}}},
+		Renderer: templates.Renderer{},
+	}
+
+	evt := NotificationEvent{EventType: "alert.raised", OrganizationID: 1, UserID: &uid, CorrelationKey: "vuln:42",
+		Payload: map[string]interface{}{"project": "api"}}
+	_ = svc.HandleEvent(context.Background(), evt)
+
+	a := alerts.byKey["vuln:42"]
+	if a == nil || a.State != AlertOpen || a.Summary != "CVE in api" || a.EventType != "alert.raised" {
+		t.Fatalf("unexpected alert %#v", a)
+	}
+	if len(inbox.saved) != 1 || inbox.saved[0].CorrelationKey != "vuln:42" || inbox.saved[0].AlertState != AlertOpen {
+		t.Fatalf("inbox item should carry the alert, got %#v", inbox.saved)
+	}
+	if len(escalations.items) != 1 {
+		t.Fatalf("open alert should escalate, got %d", len(escalations.items))
+	}
+
+	a.State = AlertAcknowledged
+	_ = svc.HandleEvent(context.Background(), evt)
+	if inbox.saved[1].AlertState != AlertAcknowledged || len(escalations.items) != 1 {
+		t.Fatalf("repeat of an acknowledged alert must not escalate again: %#v %d", inbox.saved[1], len(escalations.items))
+	}
+}
+
+func TestHandleEvent_ResolveEventClosesAlertOnOriginalChannels(t *testing.T) {
+	alerts := &stubAlerts{byKey: map[string]*Alert{
+		"vuln:42": {OrganizationID: 1, CorrelationKey: "vuln:42", EventType: "vulnerability.critical", Summary: "CVE in api", State: AlertAcknowledged},
+	}}
+	email := &stubEmail{}
+	logs := &stubLogRepo{}
+	svc := &NotificationService{
+		Templates:   &stubTemplateRepo{},
+		Preferences: &stubPrefRepo{},
+		Logs:        logs,
+		Alerts:      alerts,
+		Email:       email,
+		Renderer:    templates.Renderer{},
+		Defaults:    Defaults{Emails: []string{"ops@x.io"}},
+	}
+
+	evt := NotificationEvent{EventType: "vulnerability.resolved", OrganizationID: 1, CorrelationKey: "vuln:42"}
+	if err := svc.HandleEvent(context.Background(), evt); err != nil {
+		t.Fatalf("handle event: %v", err)
+	}
+	if a := alerts.byKey["vuln:42"]; a.State != AlertResolved || a.ResolvedBy != "event:vulnerability.resolved" {
+		t.Fatalf("alert should be resolved by the event, got %#v", a)
+	}
+	if len(logs.entries) != 1 || logs.entries[0].EventType != EventAlertResolved || email.subject != "Resolved: CVE in api" {
+		t.Fatalf("expected one resolution notice, got %#v %q", logs.entries, email.subject)
+	}
+
+	// A duplicate resolve event is swallowed rather than sent as a new notification.
+	_ = svc.HandleEvent(context.Background(), evt)
+	if len(logs.entries) != 1 {
+		t.Fatalf("duplicate resolve should not notify again, got %d", len(logs.entries))
+	}
+
+	// Resolve events without a known alert are regular events.
+	evt.CorrelationKey = "vuln:99"
+	_ = svc.HandleEvent(context.Background(), evt)
+	if len(logs.entries) != 2 || logs.entries[1].EventType != "vulnerability.resolved" {
+		t.Fatalf("expected regular dispatch, got %#v", logs.entries)
+	}
+}
+
+func TestTransitionAlert(t *testing.T) {
+	alerts := &stubAlerts{byKey: map[string]*Alert{"k": {CorrelationKey: "k", State: AlertOpen}}}
+	svc := &NotificationService{Alerts: alerts}
+
+	if _, err := (&NotificationService{}).TransitionAlert(context.Background(), 1, "k", AlertAcknowledged, "me"); !errors.Is(err, ErrAlertsUnavailable) {
+		t.Fatalf("expected unavailable, got %v", err)
+	}
+	if _, err := svc.TransitionAlert(context.Background(), 1, "missing", AlertAcknowledged, "me"); !errors.Is(err, ErrAlertNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if _, err := svc.TransitionAlert(context.Background(), 1, "k", AlertOpen, "me"); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("alerts cannot be reopened by hand, got %v", err)
+	}
+	a, err := svc.TransitionAlert(context.Background(), 1, "k", AlertAcknowledged, "me")
+	if err != nil || a.State != AlertAcknowledged {
+		t.Fatalf("expected acknowledged, got %#v %v", a, err)
+	}
+	a, err = svc.TransitionAlert(context.Background(), 1, "k", AlertAcknowledged, "me")
+	if !errors.Is(err, ErrInvalidTransition) || a == nil || a.State != AlertAcknowledged {
+		t.Fatalf("double ack should report the current alert, got %#v %v", a, err)
+	}
+}
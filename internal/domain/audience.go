@@ -0,0 +1,220 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// builtinAudiences keep the historical broadcast behaviour for event types
+// the organization has not configured. Scan audiences honour
+// payload.target_role.
+var builtinAudiences = []Audience{
+	{EventType: "payment.*", AllUsers: true},
+	{EventType: "project.scan.*", Roles: []string{"developer"}},
+	{EventType: "sbom.scan.*", Roles: []string{"developer"}},
+	{EventType: "*", AllUsers: true},
+}
+
+// Validate checks the event type glob and that the audience selects someone.
+func (a Audience) Validate() error {
+	if strings.TrimSpace(a.EventType) == "" {
+		return errors.New("event_type is required")
+	}
+	if _, err := path.Match(a.EventType, ""); err != nil {
+		return fmt.Errorf("invalid event_type pattern %q", a.EventType)
+	}
+	if !a.AllUsers && len(a.Roles) == 0 && len(a.UserIDs) == 0 && !a.ProjectMembers {
+		return errors.New("audience selects no recipients")
+	}
+	for _, role := range a.Roles {
+		if strings.TrimSpace(role) == "" {
+			return errors.New("roles must not be empty")
+		}
+	}
+	return nil
+}
+
+// MatchAudience returns the audience for eventType: an exact event type wins,
+// then the longest matching glob. It returns nil when none matches.
+func MatchAudience(list []Audience, eventType string) *Audience {
+	var best *Audience
+	for i := range list {
+		a := &list[i]
+		if ok, _ := path.Match(a.EventType, eventType); !ok {
+			continue
+		}
+		if a.EventType == eventType {
+			return a
+		}
+		if best == nil || len(a.EventType) > len(best.EventType) {
+			best = a
+		}
+	}
+	return best
+}
+
+// audienceFor returns the organization's audience for evt, or the built-in
+// one. The flag reports whether the organization configured it.
+func (s *NotificationService) audienceFor(ctx context.Context, evt NotificationEvent) (Audience, bool) {
+	if s.Audiences != nil && evt.OrganizationID != 0 {
+		list, err := s.Audiences.List(ctx, evt.OrganizationID)
+		if err != nil {
+			log.Printf("[NOTIFY] audience lookup failed: %v", err)
+		}
+		if a := MatchAudience(list, evt.EventType); a != nil {
+			return *a, true
+		}
+	}
+	a := *MatchAudience(builtinAudiences, evt.EventType)
+	if role, ok := evt.Payload["target_role"].(string); ok && role != "" && len(a.Roles) > 0 {
+		a.Roles = []string{role}
+	}
+	return a, false
+}
+
+// recipients returns the org members to notify besides the event's own user.
+// Routing rules that select recipients take precedence over audiences, and
+// audiences only apply to events not addressed to a user. Project-scoped
+// events go to the project's watchers unless the organization configured an
+// audience for the event type. The flag reports whether the members were
+// configured and should also scope user-level outbound preferences.
+func (s *NotificationService) recipients(ctx context.Context, evt NotificationEvent, route Route) ([]int64, bool) {
+	targeted := evt.UserID != nil && *evt.UserID != 0
+	var a Audience
+	configured := true
+	switch {
+	case route.SelectsRecipients():
+		a = Audience{AllUsers: route.AllUsers, Roles: route.Roles, UserIDs: route.UserIDs}
+	case targeted || evt.OrganizationID == 0:
+		return nil, false
+	default:
+		a, configured = s.audienceFor(ctx, evt)
+		if !configured {
+			if watchers, ok := s.projectWatchers(ctx, evt); ok {
+				a, configured = Audience{UserIDs: watchers}, true
+			}
+		}
+	}
+	return s.resolveAudience(ctx, evt, a), configured
+}
+
+// projectWatchers returns the watchers of the project named by
+// payload.project_id or payload.project. It reports false for events without
+// a project, projects nobody watches and lookup failures, which keep the
+// event type's audience.
+func (s *NotificationService) projectWatchers(ctx context.Context, evt NotificationEvent) ([]int64, bool) {
+	if s.Watchers == nil {
+		return nil, false
+	}
+	projectID, _ := payloadInt64(evt.Payload, "project_id")
+	name, _ := evt.Payload["project"].(string)
+	if projectID == 0 && name == "" {
+		return nil, false
+	}
+	watchers, err := s.Watchers.Watchers(ctx, evt.OrganizationID, projectID, name)
+	if err != nil {
+		log.Printf("[NOTIFY] project watcher lookup failed: %v", err)
+		return nil, false
+	}
+	return watchers, len(watchers) > 0
+}
+
+// resolveAudience lists the members of a, excluding the event's own user and,
+// when asked, its actor.
+func (s *NotificationService) resolveAudience(ctx context.Context, evt NotificationEvent, a Audience) []int64 {
+	userIDs := append([]int64(nil), a.UserIDs...)
+	if s.OrgUsers != nil && a.AllUsers {
+		all, err := s.OrgUsers.ListUserIDsByOrg(ctx, evt.OrganizationID)
+		if err != nil {
+			log.Printf("[NOTIFY] cannot load org users for event %s: %v", evt.EventType, err)
+		}
+		userIDs = append(userIDs, all...)
+	} else if s.OrgUsers != nil {
+		for _, role := range a.Roles {
+			ids, err := s.OrgUsers.ListUserIDsByOrgWithRole(ctx, evt.OrganizationID, role)
+			if err != nil {
+				log.Printf("[NOTIFY] cannot load %s users for event %s: %v", role, evt.EventType, err)
+			}
+			userIDs = append(userIDs, ids...)
+		}
+	}
+	if a.ProjectMembers && !a.AllUsers && s.ProjectMembers != nil {
+		if projectID, ok := payloadInt64(evt.Payload, "project_id"); ok {
+			ids, err := s.ProjectMembers.ListUserIDsByProject(ctx, evt.OrganizationID, projectID)
+			if err != nil {
+				log.Printf("[NOTIFY] cannot load members of project %d: %v", projectID, err)
+			}
+			userIDs = append(userIDs, ids...)
+		}
+	}
+
+	skip := map[int64]bool{0: true}
+	if evt.UserID != nil {
+		skip[*evt.UserID] = true
+	}
+	if a.ExcludeActor {
+		if actor, ok := evt.actor(); ok {
+			skip[actor] = true
+		}
+	}
+	out := make([]int64, 0, len(userIDs))
+	for _, uid := range userIDs {
+		if !skip[uid] {
+			skip[uid] = true
+			out = append(out, uid)
+		}
+	}
+	return out
+}
+
+// audienceTargets drops user-scoped targets of users outside members; the
+// event's own user and org-level targets are kept.
+func audienceTargets(targets []DeliveryTarget, evt NotificationEvent, members []int64) []DeliveryTarget {
+	keep := make(map[int64]bool, len(members)+1)
+	for _, uid := range members {
+		keep[uid] = true
+	}
+	if evt.UserID != nil {
+		keep[*evt.UserID] = true
+	}
+	out := make([]DeliveryTarget, 0, len(targets))
+	for _, t := range targets {
+		if t.UserID == nil || keep[*t.UserID] {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// actor returns the user who triggered the event, falling back to
+// payload.actor_id for producers that do not set the field.
+func (e NotificationEvent) actor() (int64, bool) {
+	if e.ActorID != nil {
+		return *e.ActorID, true
+	}
+	return payloadInt64(e.Payload, "actor_id")
+}
+
+func payloadInt64(payload map[string]interface{}, key string) (int64, bool) {
+	switch v := payload[key].(type) {
+	case float64:
+		return int64(v), true
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
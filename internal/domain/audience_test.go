@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"myesi-notification-service/internal/templates"
+)
+
+type stubAudiences struct{ list []Audience }
+
+func (s *stubAudiences) List(ctx Context, orgID int64) ([]Audience, error) { return s.list, nil }
+func (s *stubAudiences) Save(ctx Context, a Audience) (Audience, error)    { return a, nil }
+func (s *stubAudiences) Delete(ctx Context, orgID, id int64) error         { return nil }
+
+type stubProjectMembers map[int64][]int64
+
+func (s stubProjectMembers) ListUserIDsByProject(ctx Context, orgID, projectID int64) ([]int64, error) {
+	return s[projectID], nil
+}
+
+func TestMatchAudience(t *testing.T) {
+	list := []Audience{{ID: 1, EventType: "*"}, {ID: 2, EventType: "payment.*"}, {ID: 3, EventType: "payment.failed"}}
+	for eventType, want := range map[string]int64{"payment.failed": 3, "payment.succeeded": 2, "project.scan.done": 1} {
+		if a := MatchAudience(list, eventType); a == nil || a.ID != want {
+			t.Fatalf("%s: expected audience %d, got %#v", eventType, want, a)
+		}
+	}
+	if MatchAudience(list[1:], "sbom.scan.done") != nil {
+		t.Fatalf("expected no audience")
+	}
+}
+
+func TestHandleEvent_AudienceScopesInboxAndOutbound(t *testing.T) {
+	u2, u4 := int64(2), int64(4)
+	inbox := &stubInboxRepo{}
+	logs := &stubLogRepo{}
+	svc := &NotificationService{
+		Templates: &stubTemplateRepo{},
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{OrganizationID: 1, EventType: "project.scan.completed", Channel: ChannelEmail, Target: "two@example.com", UserID: &u2, Enabled: true},
+			{OrganizationID: 1, EventType: "project.scan.completed", Channel: ChannelEmail, Target: "four@example.com", UserID: &u4, Enabled: true},
+			{OrganizationID: 1, EventType: "project.scan.completed", Channel: ChannelSlack, Target: "https://hooks/org", Enabled: true},
+		}},
+		Logs:     logs,
+		Inbox:    inbox,
+		Email:    &stubEmail{},
+		Slack:    &stubSlack{},
+		OrgUsers: &stubOrgUsers{all: []int64{1, 2, 3, 4, 5}, byRole: map[string][]int64{"security": {2, 3}}},
+		Audiences: &stubAudiences{list: []Audience{
+			{EventType: "project.scan.*", Roles: []string{"security"}, UserIDs: []int64{6}, ProjectMembers: true, ExcludeActor: true},
+		}},
+		ProjectMembers: stubProjectMembers{9: {3, 5}},
+		Renderer:       templates.Renderer{},
+	}
+
+	evt := NotificationEvent{EventType: "project.scan.completed", OrganizationID: 1,
+		Payload: map[string]interface{}{"project_id": float64(9), "actor_id": float64(3)}}
+	if err := svc.HandleEvent(context.Background(), evt); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	var got []int64
+	for _, n := range inbox.saved {
+		got = append(got, n.UserID)
+	}
+	if len(got) != 3 || got[0] != 6 || got[1] != 2 || got[2] != 5 {
+		t.Fatalf("expected users 6, 2 and 5 without the actor, got %v", got)
+	}
+	var targets []string
+	for _, e := range logs.entries {
+		targets = append(targets, e.Target)
+	}
+	if len(targets) != 2 || targets[0] != "two@example.com" || targets[1] != "https://hooks/org" {
+		t.Fatalf("expected member and org targets only, got %v", targets)
+	}
+}
+
+func TestHandleEvent_BuiltinAudienceHonoursTargetRole(t *testing.T) {
+	inbox := &stubInboxRepo{}
+	svc := &NotificationService{
+		Templates:   &stubTemplateRepo{},
+		Preferences: &stubPrefRepoStatic{},
+		Logs:        &stubLogRepo{},
+		Inbox:       inbox,
+		OrgUsers:    &stubOrgUsers{all: []int64{1, 2}, byRole: map[string][]int64{"developer": {1}, "admin": {2}}},
+		Audiences:   &stubAudiences{},
+		Renderer:    templates.Renderer{},
+	}
+	_ = svc.HandleEvent(context.Background(), NotificationEvent{EventType: "sbom.scan.completed", OrganizationID: 1,
+		Payload: map[string]interface{}{"target_role": "admin"}})
+	if len(inbox.saved) != 1 || inbox.saved[0].UserID != 2 {
+		t.Fatalf("expected the target role, got %#v", inbox.saved)
+	}
+}
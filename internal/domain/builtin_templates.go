@@ -0,0 +1,255 @@
+package domain
+
+type builtinTemplate struct{ Subject, Body string }
+
+// genericEvent keys the catch-all message used when nothing else matches.
+const genericEvent = ""
+
+// builtinTemplates are opinionated defaults for common events to keep messages
+// user-friendly, keyed by base language and then event type.
+var builtinTemplates = map[string]map[string]builtinTemplate{
+	DefaultLocale: {
+		genericEvent: {
+			Subject: "MyESI update",
+			Body:    "You have a new update: {{.event.type}}.",
+		},
+		"vulnerability.assignment": {
+			Subject: "New vulnerability assigned to you",
+			Body:    "A vulnerability task for project {{.payload.project}} has been assigned to you. Priority: {{.event.severity}}.",
+		},
+		"code_finding.assignment": {
+			Subject: "New code finding assigned to you",
+			Body:    "A code finding in project {{.payload.project}} has been assigned to you. Priority: {{.event.severity}}.",
+		},
+		"project.scan.completed": {
+			Subject: "Project scan completed",
+			Body:    "Scan finished for {{.payload.project}}. Findings: {{.payload.vulns}} vulns, {{.payload.code_findings}} code findings.",
+		},
+		"project.scan.failed": {
+			Subject: "Project scan failed",
+			Body:    "Scan failed for {{.payload.project}}. Error: {{.payload.error}}",
+		},
+		"sbom.scan.completed": {
+			Subject: "SBOM scan completed",
+			Body:    "Manual SBOM scan finished for {{.payload.project}}. Findings: {{.payload.vulns}} vulns, {{.payload.code_findings}} code findings.",
+		},
+		"sbom.scan.failed": {
+			Subject: "SBOM scan failed",
+			Body:    "Manual SBOM scan failed for {{.payload.project}}. Error: {{.payload.error}}",
+		},
+		"project.scan.summary": {
+			Subject: "Project scan summary",
+			Body:    "{{.payload.project}} scan complete: {{.payload.vulns}} vulns, {{.payload.code_findings}} code findings.",
+		},
+		"vulnerability.critical": {
+			Subject: "Critical vulnerability detected",
+			Body:    "{{.payload.project}} reported {{.payload.critical_count}} critical vulnerabilities. Please review immediately.",
+		},
+		"weekly.report.generated": {
+			Subject: "Weekly security summary",
+			Body:    "Summary for {{.payload.organization_name}} ({{.payload.report_week}}): {{.payload.metrics.active_vulnerabilities}} active vulns, {{.payload.metrics.critical_vulnerabilities}} critical, avg risk {{printf \"%.2f\" .payload.metrics.average_risk_score}}.",
+		},
+		"user.activity.suspicious-login": {
+			Subject: "Suspicious login detected",
+			Body:    "User {{.payload.user.email}} logged in from {{.payload.current_ip}} (previous {{.payload.previous_ip}}). Verify this activity.",
+		},
+		"sbom.scan.summary": {
+			Subject: "SBOM scan summary",
+			Body:    "SBOM uploaded for {{.payload.project}}: {{.payload.components}} components, {{.payload.vulns}} vulns found.",
+		},
+		"payment.success": {
+			Subject: "Payment received",
+			Body:    "Your payment for {{.payload.plan_name}} succeeded. Amount: {{.payload.amount}}. Thank you!",
+		},
+		"payment.failed": {
+			Subject: "Payment failed",
+			Body:    "A payment attempt for {{.payload.plan_name}} failed. Please update billing details.",
+		},
+		EventDigest: {
+			Subject: "Your MyESI {{.payload.digest.cadence}} digest: {{.payload.digest.count}} updates",
+			Body:    "{{range .payload.digest.groups}}{{.event_type}} ({{.count}}):\n{{range .items}}- {{.subject}}\n{{end}}{{end}}",
+		},
+		EventEscalation: {
+			Subject: "Escalation: {{.payload.escalation.summary}}",
+			Body:    "\"{{.payload.escalation.summary}}\" ({{.payload.escalation.event_type}}) has not been acknowledged for {{.payload.escalation.minutes}} minutes. Escalation level {{.payload.escalation.tier}}.",
+		},
+		EventAlertResolved: {
+			Subject: "Resolved: {{.payload.alert.summary}}",
+			Body:    "\"{{.payload.alert.summary}}\" ({{.payload.alert.event_type}}) has been resolved.",
+		},
+		EventSuppressed: {
+			Subject: "{{.payload.suppressed.count}} more notifications suppressed",
+			Body:    "{{.payload.suppressed.count}} more {{.payload.suppressed.event_type}} notifications were suppressed by rate limiting.",
+		},
+		EventGrouped: {
+			Subject: "{{.payload.group.count}} more {{.payload.group.event_type}} notifications",
+			Body:    "{{.payload.group.count}} more {{.payload.group.event_type}} notifications were grouped with the first one ({{.payload.group.total}} in total).",
+		},
+	},
+	"vi": {
+		genericEvent: {
+			Subject: "Cập nhật từ MyESI",
+			Body:    "Bạn có cập nhật mới: {{.event.type}}.",
+		},
+		"vulnerability.assignment": {
+			Subject: "Bạn được giao một lỗ hổng mới",
+			Body:    "Một nhiệm vụ xử lý lỗ hổng trong dự án {{.payload.project}} đã được giao cho bạn. Mức ưu tiên: {{.event.severity}}.",
+		},
+		"code_finding.assignment": {
+			Subject: "Bạn được giao một phát hiện mã nguồn mới",
+			Body:    "Một phát hiện mã nguồn trong dự án {{.payload.project}} đã được giao cho bạn. Mức ưu tiên: {{.event.severity}}.",
+		},
+		"project.scan.completed": {
+			Subject: "Quét dự án hoàn tất",
+			Body:    "Đã quét xong {{.payload.project}}. Kết quả: {{.payload.vulns}} lỗ hổng, {{.payload.code_findings}} phát hiện mã nguồn.",
+		},
+		"project.scan.failed": {
+			Subject: "Quét dự án thất bại",
+			Body:    "Quét {{.payload.project}} thất bại. Lỗi: {{.payload.error}}",
+		},
+		"sbom.scan.completed": {
+			Subject: "Quét SBOM hoàn tất",
+			Body:    "Quét SBOM thủ công cho {{.payload.project}} đã hoàn tất. Kết quả: {{.payload.vulns}} lỗ hổng, {{.payload.code_findings}} phát hiện mã nguồn.",
+		},
+		"sbom.scan.failed": {
+			Subject: "Quét SBOM thất bại",
+			Body:    "Quét SBOM thủ công cho {{.payload.project}} thất bại. Lỗi: {{.payload.error}}",
+		},
+		"project.scan.summary": {
+			Subject: "Tóm tắt quét dự án",
+			Body:    "{{.payload.project}} đã quét xong: {{.payload.vulns}} lỗ hổng, {{.payload.code_findings}} phát hiện mã nguồn.",
+		},
+		"vulnerability.critical": {
+			Subject: "Phát hiện lỗ hổng nghiêm trọng",
+			Body:    "{{.payload.project}} ghi nhận {{.payload.critical_count}} lỗ hổng nghiêm trọng. Vui lòng xem xét ngay.",
+		},
+		"weekly.report.generated": {
+			Subject: "Tóm tắt bảo mật hàng tuần",
+			Body:    "Tóm tắt cho {{.payload.organization_name}} ({{.payload.report_week}}): {{.payload.metrics.active_vulnerabilities}} lỗ hổng đang mở, {{.payload.metrics.critical_vulnerabilities}} nghiêm trọng, điểm rủi ro trung bình {{localeNumber .locale .payload.metrics.average_risk_score}}.",
+		},
+		"user.activity.suspicious-login": {
+			Subject: "Phát hiện đăng nhập đáng ngờ",
+			Body:    "Người dùng {{.payload.user.email}} đã đăng nhập từ {{.payload.current_ip}} (trước đó {{.payload.previous_ip}}). Vui lòng xác minh hoạt động này.",
+		},
+		"sbom.scan.summary": {
+			Subject: "Tóm tắt quét SBOM",
+			Body:    "Đã tải lên SBOM cho {{.payload.project}}: {{.payload.components}} thành phần, phát hiện {{.payload.vulns}} lỗ hổng.",
+		},
+		"payment.success": {
+			Subject: "Đã nhận thanh toán",
+			Body:    "Thanh toán cho gói {{.payload.plan_name}} đã thành công. Số tiền: {{.payload.amount}}. Xin cảm ơn!",
+		},
+		"payment.failed": {
+			Subject: "Thanh toán thất bại",
+			Body:    "Một lần thanh toán cho gói {{.payload.plan_name}} đã thất bại. Vui lòng cập nhật thông tin thanh toán.",
+		},
+		EventDigest: {
+			Subject: "Bản tin MyESI: {{.payload.digest.count}} cập nhật",
+			Body:    "{{range .payload.digest.groups}}{{.event_type}} ({{.count}}):\n{{range .items}}- {{.subject}}\n{{end}}{{end}}",
+		},
+		EventEscalation: {
+			Subject: "Cảnh báo leo thang: {{.payload.escalation.summary}}",
+			Body:    "\"{{.payload.escalation.summary}}\" ({{.payload.escalation.event_type}}) chưa được xác nhận sau {{.payload.escalation.minutes}} phút. Cấp leo thang {{.payload.escalation.tier}}.",
+		},
+		EventAlertResolved: {
+			Subject: "Đã xử lý: {{.payload.alert.summary}}",
+			Body:    "\"{{.payload.alert.summary}}\" ({{.payload.alert.event_type}}) đã được xử lý.",
+		},
+		EventSuppressed: {
+			Subject: "{{.payload.suppressed.count}} thông báo khác đã bị ẩn",
+			Body:    "{{.payload.suppressed.count}} thông báo {{.payload.suppressed.event_type}} khác đã bị ẩn do giới hạn tần suất.",
+		},
+		EventGrouped: {
+			Subject: "{{.payload.group.count}} thông báo {{.payload.group.event_type}} khác",
+			Body:    "{{.payload.group.count}} thông báo {{.payload.group.event_type}} khác đã được gộp với thông báo đầu tiên (tổng cộng {{.payload.group.total}}).",
+		},
+	},
+	"ja": {
+		genericEvent: {
+			Subject: "MyESI からのお知らせ",
+			Body:    "新しい更新があります: {{.event.type}}。",
+		},
+		"vulnerability.assignment": {
+			Subject: "新しい脆弱性が割り当てられました",
+			Body:    "プロジェクト {{.payload.project}} の脆弱性対応タスクがあなたに割り当てられました。優先度: {{.event.severity}}。",
+		},
+		"code_finding.assignment": {
+			Subject: "新しいコード検出結果が割り当てられました",
+			Body:    "プロジェクト {{.payload.project}} のコード検出結果があなたに割り当てられました。優先度: {{.event.severity}}。",
+		},
+		"project.scan.completed": {
+			Subject: "プロジェクトのスキャンが完了しました",
+			Body:    "{{.payload.project}} のスキャンが完了しました。検出: 脆弱性 {{.payload.vulns}} 件、コード検出 {{.payload.code_findings}} 件。",
+		},
+		"project.scan.failed": {
+			Subject: "プロジェクトのスキャンに失敗しました",
+			Body:    "{{.payload.project}} のスキャンに失敗しました。エラー: {{.payload.error}}",
+		},
+		"sbom.scan.completed": {
+			Subject: "SBOM スキャンが完了しました",
+			Body:    "{{.payload.project}} の手動 SBOM スキャンが完了しました。検出: 脆弱性 {{.payload.vulns}} 件、コード検出 {{.payload.code_findings}} 件。",
+		},
+		"sbom.scan.failed": {
+			Subject: "SBOM スキャンに失敗しました",
+			Body:    "{{.payload.project}} の手動 SBOM スキャンに失敗しました。エラー: {{.payload.error}}",
+		},
+		"project.scan.summary": {
+			Subject: "プロジェクトスキャンの概要",
+			Body:    "{{.payload.project}} のスキャン完了: 脆弱性 {{.payload.vulns}} 件、コード検出 {{.payload.code_findings}} 件。",
+		},
+		"vulnerability.critical": {
+			Subject: "重大な脆弱性が検出されました",
+			Body:    "{{.payload.project}} で重大な脆弱性が {{.payload.critical_count}} 件報告されました。直ちに確認してください。",
+		},
+		"weekly.report.generated": {
+			Subject: "週次セキュリティサマリー",
+			Body:    "{{.payload.organization_name}} のサマリー ({{.payload.report_week}}): 未解決の脆弱性 {{.payload.metrics.active_vulnerabilities}} 件、重大 {{.payload.metrics.critical_vulnerabilities}} 件、平均リスクスコア {{localeNumber .locale .payload.metrics.average_risk_score}}。",
+		},
+		"user.activity.suspicious-login": {
+			Subject: "不審なログインが検出されました",
+			Body:    "ユーザー {{.payload.user.email}} が {{.payload.current_ip}} からログインしました (前回 {{.payload.previous_ip}})。このアクティビティを確認してください。",
+		},
+		"sbom.scan.summary": {
+			Subject: "SBOM スキャンの概要",
+			Body:    "{{.payload.project}} の SBOM をアップロードしました: コンポーネント {{.payload.components}} 件、脆弱性 {{.payload.vulns}} 件。",
+		},
+		"payment.success": {
+			Subject: "お支払いを受け付けました",
+			Body:    "{{.payload.plan_name}} のお支払いが完了しました。金額: {{.payload.amount}}。ありがとうございます。",
+		},
+		"payment.failed": {
+			Subject: "お支払いに失敗しました",
+			Body:    "{{.payload.plan_name}} のお支払いに失敗しました。請求情報を更新してください。",
+		},
+		EventDigest: {
+			Subject: "MyESI ダイジェスト: {{.payload.digest.count}} 件の更新",
+			Body:    "{{range .payload.digest.groups}}{{.event_type}} ({{.count}}件):\n{{range .items}}- {{.subject}}\n{{end}}{{end}}",
+		},
+		EventEscalation: {
+			Subject: "エスカレーション: {{.payload.escalation.summary}}",
+			Body:    "「{{.payload.escalation.summary}}」({{.payload.escalation.event_type}}) が {{.payload.escalation.minutes}} 分間確認されていません。エスカレーション段階 {{.payload.escalation.tier}}。",
+		},
+		EventAlertResolved: {
+			Subject: "解決済み: {{.payload.alert.summary}}",
+			Body:    "「{{.payload.alert.summary}}」({{.payload.alert.event_type}}) は解決されました。",
+		},
+		EventSuppressed: {
+			Subject: "他 {{.payload.suppressed.count}} 件の通知を抑制しました",
+			Body:    "レート制限により {{.payload.suppressed.event_type}} の通知 {{.payload.suppressed.count}} 件が抑制されました。",
+		},
+		EventGrouped: {
+			Subject: "{{.payload.group.event_type}} の通知が他に {{.payload.group.count}} 件あります",
+			Body:    "{{.payload.group.event_type}} の通知 {{.payload.group.count}} 件を最初の通知とまとめました (合計 {{.payload.group.total}} 件)。",
+		},
+	},
+}
+
+// builtinFor returns the built-in template for an exact locale tag, if any.
+func builtinFor(locale, eventType string) (builtinTemplate, bool) {
+	byEvent, ok := builtinTemplates[locale]
+	if !ok {
+		return builtinTemplate{}, false
+	}
+	tpl, ok := byEvent[eventType]
+	return tpl, ok
+}
@@ -0,0 +1,338 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Destination states.
+const (
+	DestinationPending  = "pending"
+	DestinationVerified = "verified"
+)
+
+// EventDestinationVerification is the event type of confirmation messages.
+const EventDestinationVerification = "destination.verification"
+
+const (
+	verificationTTL         = 24 * time.Hour
+	verificationResendAfter = time.Minute
+	verificationMaxAttempts = 5
+)
+
+var (
+	// ErrInvalidCode is returned when a confirmation code does not match.
+	ErrInvalidCode = errors.New("invalid verification code")
+	// ErrVerificationExpired is returned for expired or exhausted codes; a new
+	// one must be requested.
+	ErrVerificationExpired = errors.New("verification code expired, request a new one")
+	// ErrResendTooSoon is returned when a code was sent less than a minute ago.
+	ErrResendTooSoon = errors.New("verification code sent recently, try again later")
+	// ErrTooManyAttempts is returned when resending a code whose attempts are
+	// used up; a new one can be requested once it expires.
+	ErrTooManyAttempts = errors.New("too many invalid codes, try again after the code expires")
+)
+
+// NeedsVerification reports whether targets on channel must be confirmed
+// before preferences deliver to them.
+func NeedsVerification(channel string) bool {
+	switch channel {
+	case ChannelEmail, ChannelSlack, ChannelTeams, ChannelWebhook:
+		return true
+	}
+	return false
+}
+
+// VerificationTargets splits a target into the destinations verified one by
+// one: every address of an email list, or the target itself.
+func VerificationTargets(channel, target string) []string {
+	if channel != ChannelEmail {
+		return []string{target}
+	}
+	var out []string
+	for _, addr := range strings.Split(target, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			out = append(out, addr)
+		}
+	}
+	return out
+}
+
+// RequestVerification makes sure target is verified or has a live code
+// pending, sending a new code when there is none. It is called for each of
+// VerificationTargets whenever a preference points at a target.
+func (s *NotificationService) RequestVerification(ctx context.Context, orgID int64, channel, target string) (Destination, error) {
+	if s.Destinations == nil || !NeedsVerification(channel) {
+		return Destination{}, nil
+	}
+	d, err := s.Destinations.Find(ctx, orgID, channel, target)
+	if err != nil {
+		return Destination{}, err
+	}
+	now := time.Now().UTC()
+	if d != nil && (d.Status == DestinationVerified || (d.ExpiresAt != nil && now.Before(*d.ExpiresAt))) {
+		return *d, nil
+	}
+	if d == nil {
+		d = &Destination{OrganizationID: orgID, Channel: channel, Target: target}
+	}
+	return s.sendVerification(ctx, *d, now)
+}
+
+// ResendVerification sends a fresh code for a pending destination, at most
+// once a minute. A resent code keeps the attempts and expiry of the one it
+// replaces; once that has expired, verification starts over.
+func (s *NotificationService) ResendVerification(ctx context.Context, orgID, id int64) (Destination, error) {
+	d, err := s.destination(ctx, orgID, id)
+	if err != nil || d.Status == DestinationVerified {
+		return d, err
+	}
+	now := time.Now().UTC()
+	if d.SentAt != nil && now.Sub(*d.SentAt) < verificationResendAfter {
+		return d, ErrResendTooSoon
+	}
+	if d.ExpiresAt == nil || !now.Before(*d.ExpiresAt) {
+		return s.sendVerification(ctx, d, now)
+	}
+	if d.Attempts >= verificationMaxAttempts {
+		return d, ErrTooManyAttempts
+	}
+	if !s.hasChannel(d.Channel) {
+		return d, ErrUnsupportedChannel
+	}
+	code, err := newVerificationCode()
+	if err != nil {
+		return d, err
+	}
+	saved, err := s.Destinations.Reissue(ctx, orgID, id, hashCode(code), now)
+	if err != nil {
+		return d, err
+	}
+	if saved == nil {
+		return d, ErrNotFound
+	}
+	return *saved, s.sendCode(ctx, *saved, code)
+}
+
+// ConfirmDestination verifies a destination with the code sent to it. The
+// attempt is counted before the code is compared, so concurrent guesses
+// share the attempt limit.
+func (s *NotificationService) ConfirmDestination(ctx context.Context, orgID, id int64, code string) (Destination, error) {
+	d, err := s.destination(ctx, orgID, id)
+	if err != nil || d.Status == DestinationVerified {
+		return d, err
+	}
+	now := time.Now().UTC()
+	if d.ExpiresAt == nil || !now.Before(*d.ExpiresAt) {
+		return d, ErrVerificationExpired
+	}
+	hash, ok, err := s.Destinations.UseAttempt(ctx, orgID, id, verificationMaxAttempts)
+	if err != nil {
+		return d, err
+	}
+	if !ok {
+		return d, ErrVerificationExpired
+	}
+	if subtle.ConstantTimeCompare([]byte(hashCode(code)), []byte(hash)) != 1 {
+		return d, ErrInvalidCode
+	}
+	d.Status, d.CodeHash, d.ExpiresAt, d.VerifiedAt = DestinationVerified, "", nil, &now
+	return s.Destinations.Save(ctx, d)
+}
+
+func (s *NotificationService) destination(ctx context.Context, orgID, id int64) (Destination, error) {
+	if s.Destinations == nil {
+		return Destination{}, ErrNotFound
+	}
+	d, err := s.Destinations.Get(ctx, orgID, id)
+	if err != nil {
+		return Destination{}, err
+	}
+	if d == nil {
+		return Destination{}, ErrNotFound
+	}
+	return *d, nil
+}
+
+// sendVerification stores a new code for d with a fresh expiry and attempt
+// count and sends it to the target. The destination stays pending when
+// delivery fails so it can be resent.
+func (s *NotificationService) sendVerification(ctx context.Context, d Destination, now time.Time) (Destination, error) {
+	if !s.hasChannel(d.Channel) {
+		return d, ErrUnsupportedChannel
+	}
+	code, err := newVerificationCode()
+	if err != nil {
+		return d, err
+	}
+	expires := now.Add(verificationTTL)
+	d.Status, d.CodeHash, d.Attempts, d.ExpiresAt, d.SentAt = DestinationPending, hashCode(code), 0, &expires, &now
+	saved, err := s.Destinations.Save(ctx, d)
+	if err != nil {
+		return d, err
+	}
+	return saved, s.sendCode(ctx, saved, code)
+}
+
+// sendCode delivers a confirmation code to the destination's target.
+func (s *NotificationService) sendCode(ctx context.Context, d Destination, code string) error {
+	now := time.Now().UTC()
+	if d.SentAt != nil {
+		now = *d.SentAt
+	}
+	var expires time.Time
+	if d.ExpiresAt != nil {
+		expires = *d.ExpiresAt
+	}
+	evt := NotificationEvent{
+		EventType:      EventDestinationVerification,
+		OrganizationID: d.OrganizationID,
+		OccurredAt:     now,
+		Payload:        map[string]interface{}{"destination_id": d.ID, "code": code, "expires_at": expires},
+	}
+	body := fmt.Sprintf("Your confirmation code for MyESI notifications is %s. It expires at %s.",
+		code, expires.Format(time.RFC1123))
+	if s.VerifyURL != "" {
+		link := s.VerifyURL + "?" + url.Values{
+			"organization_id": {strconv.FormatInt(d.OrganizationID, 10)},
+			"destination_id":  {strconv.FormatInt(d.ID, 10)},
+			"code":            {code},
+		}.Encode()
+		evt.Payload["verify_url"] = link
+		body += "\nConfirm: " + link
+	}
+	target := DeliveryTarget{Channel: d.Channel, Target: d.Target}
+	if err := s.send(ctx, evt, target, FormatText, "Confirm your notification destination", body); err != nil {
+		log.Printf("[NOTIFY][%s] verification send failed: %v", d.Channel, err)
+		return err
+	}
+	log.Printf("[NOTIFY][%s] verification code sent to %s", d.Channel, d.Target)
+	return nil
+}
+
+func newVerificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// BackfillDestinations marks every target that preferences already point at
+// as verified, one destination per address like the delivery gate checks,
+// and returns how many it added. It is run once when destination
+// verification is deployed, so existing preferences keep delivering; run
+// later, it would verify targets nobody confirmed.
+func (s *NotificationService) BackfillDestinations(ctx context.Context) (int, error) {
+	if s.Destinations == nil {
+		return 0, nil
+	}
+	prefs, err := s.Preferences.List(ctx, 0, nil, "")
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().UTC()
+	added := 0
+	for _, p := range prefs {
+		if p.Target == "" || !NeedsVerification(p.Channel) {
+			continue
+		}
+		for _, addr := range VerificationTargets(p.Channel, p.Target) {
+			d, err := s.Destinations.Find(ctx, p.OrganizationID, p.Channel, addr)
+			if err != nil {
+				return added, err
+			}
+			if d != nil {
+				continue
+			}
+			if _, err := s.Destinations.Save(ctx, Destination{OrganizationID: p.OrganizationID, Channel: p.Channel,
+				Target: addr, Status: DestinationVerified, VerifiedAt: &now}); err != nil {
+				return added, err
+			}
+			added++
+		}
+	}
+	return added, nil
+}
+
+// verifiedDestinations returns the organization's verified channel/target
+// pairs, or nil when verification is not enabled. Lookup failures count as
+// nothing verified.
+func (s *NotificationService) verifiedDestinations(ctx context.Context, orgID int64) map[string]bool {
+	if s.Destinations == nil {
+		return nil
+	}
+	verified := map[string]bool{}
+	list, err := s.Destinations.List(ctx, orgID)
+	if err != nil {
+		log.Printf("[NOTIFY] destination lookup failed: %v", err)
+	}
+	for _, d := range list {
+		if d.Status == DestinationVerified {
+			verified[destinationKey(d.Channel, d.Target)] = true
+		}
+	}
+	return verified
+}
+
+// destinationVerified reports whether every address of target is a verified
+// destination of the organization. Without destination verification nothing
+// is.
+func (s *NotificationService) destinationVerified(ctx context.Context, orgID int64, channel, target string) bool {
+	if s.Destinations == nil {
+		return false
+	}
+	addrs := VerificationTargets(channel, target)
+	for _, addr := range addrs {
+		d, err := s.Destinations.Find(ctx, orgID, channel, addr)
+		if err != nil {
+			log.Printf("[NOTIFY] destination lookup failed: %v", err)
+			return false
+		}
+		if d == nil || d.Status != DestinationVerified {
+			return false
+		}
+	}
+	return len(addrs) > 0
+}
+
+// splitVerified splits target into its verified and pending addresses.
+func splitVerified(verified map[string]bool, channel, target string) (ok, pending []string) {
+	for _, addr := range VerificationTargets(channel, target) {
+		if verified[destinationKey(channel, addr)] {
+			ok = append(ok, addr)
+		} else {
+			pending = append(pending, addr)
+		}
+	}
+	return ok, pending
+}
+
+func (s *NotificationService) hasChannel(channel string) bool {
+	for _, c := range s.Channels() {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+func destinationKey(channel, target string) string {
+	return channel + " " + target
+}
+
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
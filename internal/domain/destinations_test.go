@@ -0,0 +1,243 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stubDestinations struct{ byKey map[string]*Destination }
+
+func (s *stubDestinations) List(ctx Context, orgID int64) ([]Destination, error) {
+	var out []Destination
+	for _, d := range s.byKey {
+		out = append(out, *d)
+	}
+	return out, nil
+}
+func (s *stubDestinations) Get(ctx Context, orgID, id int64) (*Destination, error) {
+	for _, d := range s.byKey {
+		if d.ID == id && d.OrganizationID == orgID {
+			cp := *d
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+func (s *stubDestinations) Find(ctx Context, orgID int64, channel, target string) (*Destination, error) {
+	if d, ok := s.byKey[destinationKey(channel, target)]; ok {
+		cp := *d
+		return &cp, nil
+	}
+	return nil, nil
+}
+func (s *stubDestinations) Save(ctx Context, d Destination) (Destination, error) {
+	if cur, ok := s.byKey[destinationKey(d.Channel, d.Target)]; ok {
+		d.ID = cur.ID
+	} else {
+		d.ID = int64(len(s.byKey) + 1)
+	}
+	s.byKey[destinationKey(d.Channel, d.Target)] = &d
+	return d, nil
+}
+
+func (s *stubDestinations) UseAttempt(ctx Context, orgID, id int64, max int) (string, bool, error) {
+	for _, d := range s.byKey {
+		if d.ID == id && d.OrganizationID == orgID && d.Status == DestinationPending && d.Attempts < max {
+			d.Attempts++
+			return d.CodeHash, true, nil
+		}
+	}
+	return "", false, nil
+}
+func (s *stubDestinations) Reissue(ctx Context, orgID, id int64, codeHash string, sentAt time.Time) (*Destination, error) {
+	for _, d := range s.byKey {
+		if d.ID == id && d.OrganizationID == orgID && d.Status == DestinationPending {
+			d.CodeHash, d.SentAt = codeHash, &sentAt
+			cp := *d
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+var sentCode = regexp.MustCompile(`code for MyESI notifications is (\d{6})`)
+
+func TestDestinationVerification_GatesPreferenceTargets(t *testing.T) {
+	email := &stubEmail{}
+	dests := &stubDestinations{byKey: map[string]*Destination{}}
+	svc := &NotificationService{
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{ID: 1, OrganizationID: 1, EventType: "*", Channel: ChannelEmail, Target: "sec@x.io", Enabled: true},
+		}},
+		Email:        email,
+		Destinations: dests,
+		VerifyURL:    "https://app.example.com/verify",
+	}
+	ctx := context.Background()
+	evt := NotificationEvent{EventType: "payment.success", OrganizationID: 1}
+
+	d, err := svc.RequestVerification(ctx, 1, ChannelEmail, "sec@x.io")
+	if err != nil || d.Status != DestinationPending {
+		t.Fatalf("expected pending destination, got %#v %v", d, err)
+	}
+	m := sentCode.FindStringSubmatch(email.body)
+	if m == nil || !strings.Contains(email.body, "https://app.example.com/verify?code="+m[1]+"&destination_id=1&organization_id=1") {
+		t.Fatalf("expected code and link in %q", email.body)
+	}
+	if len(svc.resolveTargets(ctx, evt, nil)) != 0 {
+		t.Fatalf("pending destinations must not receive notifications")
+	}
+	// A live code is not resent when another preference points at the target.
+	email.body = ""
+	if _, err := svc.RequestVerification(ctx, 1, ChannelEmail, "sec@x.io"); err != nil || email.body != "" {
+		t.Fatalf("expected no second code, got %q %v", email.body, err)
+	}
+
+	if _, err := svc.ConfirmDestination(ctx, 1, d.ID, "000000x"); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("expected ErrInvalidCode, got %v", err)
+	}
+	if _, err := svc.ConfirmDestination(ctx, 2, d.ID, m[1]); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected another org's destination to be hidden, got %v", err)
+	}
+	d, err = svc.ConfirmDestination(ctx, 1, d.ID, m[1])
+	if err != nil || d.Status != DestinationVerified || d.VerifiedAt == nil {
+		t.Fatalf("expected verified destination, got %#v %v", d, err)
+	}
+	targets := svc.resolveTargets(ctx, evt, nil)
+	if len(targets) != 1 || targets[0].Target != "sec@x.io" {
+		t.Fatalf("expected verified target to be used, got %v", targets)
+	}
+}
+
+func TestDestinationVerification_ExpiryAndResend(t *testing.T) {
+	email := &stubEmail{}
+	dests := &stubDestinations{byKey: map[string]*Destination{}}
+	svc := &NotificationService{Email: email, Destinations: dests}
+	ctx := context.Background()
+
+	d, _ := svc.RequestVerification(ctx, 1, ChannelEmail, "a@x.io")
+	if _, err := svc.ResendVerification(ctx, 1, d.ID); !errors.Is(err, ErrResendTooSoon) {
+		t.Fatalf("expected ErrResendTooSoon, got %v", err)
+	}
+
+	stored := dests.byKey[destinationKey(ChannelEmail, "a@x.io")]
+	past := time.Now().Add(-25 * time.Hour)
+	stored.SentAt, stored.ExpiresAt = &past, &past
+	code := sentCode.FindStringSubmatch(email.body)[1]
+	if _, err := svc.ConfirmDestination(ctx, 1, d.ID, code); !errors.Is(err, ErrVerificationExpired) {
+		t.Fatalf("expected ErrVerificationExpired, got %v", err)
+	}
+
+	if _, err := svc.ResendVerification(ctx, 1, d.ID); err != nil {
+		t.Fatalf("unexpected resend err: %v", err)
+	}
+	fresh := sentCode.FindStringSubmatch(email.body)[1]
+	for i := 0; i < verificationMaxAttempts; i++ {
+		_, _ = svc.ConfirmDestination(ctx, 1, d.ID, "wrong")
+	}
+	if _, err := svc.ConfirmDestination(ctx, 1, d.ID, fresh); !errors.Is(err, ErrVerificationExpired) {
+		t.Fatalf("expected the code to be exhausted, got %v", err)
+	}
+}
+
+func TestDestinationVerification_ResendKeepsAttempts(t *testing.T) {
+	email := &stubEmail{}
+	dests := &stubDestinations{byKey: map[string]*Destination{}}
+	svc := &NotificationService{Email: email, Destinations: dests}
+	ctx := context.Background()
+
+	d, _ := svc.RequestVerification(ctx, 1, ChannelEmail, "a@x.io")
+	stored := dests.byKey[destinationKey(ChannelEmail, "a@x.io")]
+	expires := *stored.ExpiresAt
+	for i := 0; i < verificationMaxAttempts-1; i++ {
+		_, _ = svc.ConfirmDestination(ctx, 1, d.ID, "wrong")
+	}
+	past := time.Now().Add(-2 * time.Minute)
+	stored.SentAt = &past
+	if _, err := svc.ResendVerification(ctx, 1, d.ID); err != nil {
+		t.Fatalf("unexpected resend err: %v", err)
+	}
+	if stored.Attempts != verificationMaxAttempts-1 || !stored.ExpiresAt.Equal(expires) {
+		t.Fatalf("resend must keep attempts and expiry, got %d %v", stored.Attempts, stored.ExpiresAt)
+	}
+	fresh := sentCode.FindStringSubmatch(email.body)[1]
+	_, _ = svc.ConfirmDestination(ctx, 1, d.ID, "wrong")
+	if _, err := svc.ConfirmDestination(ctx, 1, d.ID, fresh); !errors.Is(err, ErrVerificationExpired) {
+		t.Fatalf("expected the attempts to be used up, got %v", err)
+	}
+	stored.SentAt = &past
+	if _, err := svc.ResendVerification(ctx, 1, d.ID); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("expected ErrTooManyAttempts, got %v", err)
+	}
+}
+
+func TestDestinationVerification_EmailListGatedPerAddress(t *testing.T) {
+	email := &stubEmail{}
+	dests := &stubDestinations{byKey: map[string]*Destination{}}
+	svc := &NotificationService{
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{ID: 1, OrganizationID: 1, EventType: "*", Channel: ChannelEmail, Target: "a@x.io, b@x.io", Enabled: true},
+		}},
+		Email:        email,
+		Destinations: dests,
+	}
+	ctx := context.Background()
+	for _, addr := range VerificationTargets(ChannelEmail, "a@x.io, b@x.io") {
+		if _, err := svc.RequestVerification(ctx, 1, ChannelEmail, addr); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	}
+	if len(dests.byKey) != 2 || strings.Join(email.to, ",") != "a@x.io,b@x.io" {
+		t.Fatalf("expected one code per address, got %v to %v", dests.byKey, email.to)
+	}
+	code := sentCode.FindStringSubmatch(email.body)[1]
+	b := dests.byKey[destinationKey(ChannelEmail, "b@x.io")]
+	if _, err := svc.ConfirmDestination(ctx, 1, b.ID, code); err != nil {
+		t.Fatalf("unexpected confirm err: %v", err)
+	}
+
+	evt := NotificationEvent{EventType: "payment.success", OrganizationID: 1}
+	targets := svc.resolveTargets(ctx, evt, nil)
+	if len(targets) != 1 || targets[0].Target != "b@x.io" {
+		t.Fatalf("expected only the confirmed address, got %v", targets)
+	}
+	got, _ := svc.EffectivePreferences(ctx, evt)
+	if len(got) != 2 || got[1].Target != "a@x.io" || got[1].Active || got[1].Reason != "destination pending verification" {
+		t.Fatalf("expected the pending address to be explained, got %#v", got)
+	}
+	if svc.destinationVerified(ctx, 1, ChannelEmail, "a@x.io,b@x.io") {
+		t.Fatalf("a list with a pending address must not count as verified")
+	}
+}
+
+func TestBackfillDestinations_OneRowPerEmailAddress(t *testing.T) {
+	dests := &stubDestinations{byKey: map[string]*Destination{}}
+	svc := &NotificationService{
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{OrganizationID: 1, EventType: "*", Channel: ChannelEmail, Target: "a@x.com, b@y.com", Enabled: true},
+			{OrganizationID: 1, EventType: "payment.*", Channel: ChannelEmail, Target: "b@y.com", Enabled: true},
+			{OrganizationID: 1, EventType: "*", Channel: ChannelSlack, Target: "https://hooks/a,b", Enabled: true},
+			{OrganizationID: 1, EventType: "*", Channel: ChannelInbox, Enabled: true},
+		}},
+		Destinations: dests,
+	}
+	ctx := context.Background()
+	n, err := svc.BackfillDestinations(ctx)
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 destinations, got %d %v", n, err)
+	}
+	for _, key := range []string{destinationKey(ChannelEmail, "a@x.com"), destinationKey(ChannelEmail, "b@y.com"),
+		destinationKey(ChannelSlack, "https://hooks/a,b")} {
+		if d := dests.byKey[key]; d == nil || d.Status != DestinationVerified {
+			t.Fatalf("expected %q to be verified, got %#v", key, d)
+		}
+	}
+	targets := svc.resolveTargets(ctx, NotificationEvent{EventType: "vulnerability.critical", OrganizationID: 1}, nil)
+	if len(targets) != 2 || targets[0].Target != "a@x.com, b@y.com" {
+		t.Fatalf("expected the backfilled list to keep delivering, got %#v", targets)
+	}
+}
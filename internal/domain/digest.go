@@ -0,0 +1,297 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+// EventDigest is the event type digest templates are resolved and logged under.
+const EventDigest = "notification.digest"
+
+const (
+	// digestBatchSize bounds how many recipients' digests a flush claims at once.
+	digestBatchSize = 100
+	// digestLease is how long claimed items are hidden from other flushes.
+	digestLease = 5 * time.Minute
+	// maxDigestAttempts bounds how often a failing digest is retried.
+	maxDigestAttempts = 5
+)
+
+// ValidDigest reports whether cadence is a known digest cadence. Empty means
+// immediate delivery.
+func ValidDigest(cadence string) bool {
+	switch cadence {
+	case "", DigestImmediate, DigestHourly, DigestDaily, DigestWeekly:
+		return true
+	}
+	return false
+}
+
+func periodicDigest(cadence string) bool {
+	return cadence != "" && cadence != DigestImmediate && ValidDigest(cadence)
+}
+
+// NextDigestAt returns when a digest collecting events at t is due: the next
+// full hour, the next midnight or the next Monday midnight, in UTC.
+func NextDigestAt(cadence string, t time.Time) time.Time {
+	t = t.UTC()
+	switch cadence {
+	case DigestHourly:
+		return t.Truncate(time.Hour).Add(time.Hour)
+	case DigestDaily:
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+	case DigestWeekly:
+		days := (8 - int(t.Weekday())) % 7
+		if days == 0 {
+			days = 7
+		}
+		return time.Date(t.Year(), t.Month(), t.Day()+days, 0, 0, 0, 0, time.UTC)
+	}
+	return t
+}
+
+// bufferDigest stores a rendered event for the target's digest. It reports false
+// when the event must be delivered immediately instead.
+func (s *NotificationService) bufferDigest(ctx context.Context, evt NotificationEvent, target DeliveryTarget, subject, body string) bool {
+	if s.Digests == nil || !periodicDigest(target.Digest) {
+		return false
+	}
+	err := s.Digests.Add(ctx, DigestItem{
+		OrganizationID: evt.OrganizationID,
+		UserID:         target.UserID,
+		Channel:        target.Channel,
+		Target:         target.Target,
+		Cadence:        target.Digest,
+		EventType:      evt.EventType,
+		Severity:       evt.Severity,
+		Subject:        subject,
+		Body:           body,
+		Payload:        evt.Payload,
+		OccurredAt:     evt.OccurredAt,
+		DueAt:          NextDigestAt(target.Digest, time.Now()),
+	})
+	if err != nil {
+		log.Printf("[NOTIFY] digest buffer failed, delivering now: %v", err)
+		return false
+	}
+	return true
+}
+
+// inboxPreferences returns the most specific inbox preference per user for
+// an event; the 0 key holds the org-wide one that applies to users without
+// their own.
+func (s *NotificationService) inboxPreferences(ctx context.Context, evt NotificationEvent) map[int64]NotificationPreference {
+	prefs, err := s.Preferences.List(ctx, evt.OrganizationID, nil, evt.EventType)
+	if err != nil {
+		log.Printf("[NOTIFY] preference lookup failed: %v", err)
+		return nil
+	}
+	out := map[int64]NotificationPreference{}
+	for _, pref := range mostSpecific(prefs, evt.EventType) {
+		if pref.Channel != ChannelInbox {
+			continue
+		}
+		var uid int64
+		if pref.UserID != nil {
+			uid = *pref.UserID
+		}
+		if _, ok := out[uid]; !ok {
+			out[uid] = pref
+		}
+	}
+	return out
+}
+
+// inboxPreferenceFor picks a user's own inbox preference over the org-wide
+// one. It reports false when neither exists.
+func inboxPreferenceFor(prefs map[int64]NotificationPreference, userID int64) (NotificationPreference, bool) {
+	if p, ok := prefs[userID]; ok {
+		return p, true
+	}
+	p, ok := prefs[0]
+	return p, ok
+}
+
+// FlushDigests delivers every digest due at now and returns how many were sent.
+// Items stay buffered until their digest is sent; a digest that fails is
+// retried when the lease expires, up to maxDigestAttempts.
+func (s *NotificationService) FlushDigests(ctx context.Context, now time.Time) (int, error) {
+	if s.Digests == nil {
+		return 0, nil
+	}
+	sent := 0
+	for {
+		items, err := s.Digests.ClaimDue(ctx, now, digestLease, digestBatchSize)
+		if err != nil {
+			return sent, err
+		}
+		batches := groupDigestItems(items)
+		for _, batch := range batches {
+			if attempts := digestAttempts(batch); attempts > maxDigestAttempts {
+				log.Printf("[NOTIFY] giving up on digest of %d items after %d attempts", len(batch), attempts-1)
+			} else if err := s.deliverDigest(ctx, now, batch); err != nil {
+				log.Printf("[NOTIFY] digest delivery failed, will retry: %v", err)
+				continue
+			} else {
+				sent++
+			}
+			if err := s.Digests.Complete(ctx, digestItemIDs(batch)); err != nil {
+				log.Printf("[NOTIFY] completing digest items: %v", err)
+			}
+		}
+		if len(batches) < digestBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+func digestAttempts(items []DigestItem) int {
+	n := 0
+	for _, item := range items {
+		if item.Attempts > n {
+			n = item.Attempts
+		}
+	}
+	return n
+}
+
+func digestItemIDs(items []DigestItem) []int64 {
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+// groupDigestItems splits claimed items into one batch per recipient, keeping
+// the order in which recipients first appear.
+func groupDigestItems(items []DigestItem) [][]DigestItem {
+	index := map[string]int{}
+	var batches [][]DigestItem
+	for _, item := range items {
+		var uid int64
+		if item.UserID != nil {
+			uid = *item.UserID
+		}
+		key := strconv.FormatInt(item.OrganizationID, 10) + "|" + strconv.FormatInt(uid, 10) + "|" +
+			item.Channel + "|" + item.Target + "|" + item.Cadence
+		i, ok := index[key]
+		if !ok {
+			i = len(batches)
+			index[key] = i
+			batches = append(batches, nil)
+		}
+		batches[i] = append(batches[i], item)
+	}
+	return batches
+}
+
+// digestPayload summarizes items for {{.payload.digest}}, grouped by event type.
+func digestPayload(items []DigestItem, now time.Time) map[string]interface{} {
+	var groups []map[string]interface{}
+	byType := map[string]int{}
+	since := now
+	for _, item := range items {
+		if item.OccurredAt.Before(since) {
+			since = item.OccurredAt
+		}
+		i, ok := byType[item.EventType]
+		if !ok {
+			i = len(groups)
+			byType[item.EventType] = i
+			groups = append(groups, map[string]interface{}{
+				"event_type": item.EventType,
+				"count":      0,
+				"items":      []map[string]interface{}{},
+			})
+		}
+		groups[i]["count"] = groups[i]["count"].(int) + 1
+		groups[i]["items"] = append(groups[i]["items"].([]map[string]interface{}), map[string]interface{}{
+			"subject":     item.Subject,
+			"body":        item.Body,
+			"severity":    item.Severity,
+			"occurred_at": item.OccurredAt,
+			"payload":     item.Payload,
+		})
+	}
+	return map[string]interface{}{
+		"cadence": items[0].Cadence,
+		"count":   len(items),
+		"since":   since,
+		"until":   now,
+		"groups":  groups,
+	}
+}
+
+// deliverDigest renders the digest template for one recipient's items and
+// delivers it like a regular notification. It returns an error only when
+// trying again later may succeed.
+func (s *NotificationService) deliverDigest(ctx context.Context, now time.Time, items []DigestItem) error {
+	first := items[0]
+	digest := digestPayload(items, now)
+	evt := NotificationEvent{
+		EventType:      EventDigest,
+		OrganizationID: first.OrganizationID,
+		UserID:         first.UserID,
+		OccurredAt:     now,
+		Payload:        map[string]interface{}{"digest": digest},
+	}
+
+	var settings *OrgSettings
+	if s.OrgSettings != nil && evt.OrganizationID != 0 {
+		if st, err := s.OrgSettings.Get(ctx, evt.OrganizationID); err == nil {
+			settings = st
+		}
+	}
+	var userLocale string
+	if first.UserID != nil {
+		userLocale = s.userLocales(ctx, []int64{*first.UserID})[*first.UserID]
+	}
+	locale := localeFor(evt, userLocale, settings)
+
+	channel := first.Channel
+	if channel == ChannelInbox {
+		channel = ""
+	}
+	tpl := s.resolveTemplate(ctx, evt.OrganizationID, EventDigest, channel, locale)
+	set := PartialSet(s.loadPartials(ctx, evt.OrganizationID), first.Channel)
+	subject, body := s.renderTemplate(tpl, set, withLocale(BuildTemplateData(evt), locale))
+
+	// Logs and inbox rows keep the summary, not every buffered item.
+	evt.Payload = map[string]interface{}{"cadence": first.Cadence, "count": len(items)}
+
+	if first.Channel == ChannelInbox {
+		if s.Inbox == nil || first.UserID == nil {
+			log.Printf("[NOTIFY] dropping inbox digest of %d items: inbox not available", len(items))
+			return nil
+		}
+		msg := FormatMessage(tpl.Format, ChannelInbox, subject, body)
+		if _, err := s.Inbox.Save(ctx, UserNotification{
+			UserID:         *first.UserID,
+			OrganizationID: evt.OrganizationID,
+			Title:          msg.Subject,
+			Message:        msg.Body,
+			Format:         msg.Format,
+			Type:           EventDigest,
+			Payload:        evt.Payload,
+			CreatedAt:      time.Now().UTC(),
+		}); err != nil {
+			return fmt.Errorf("inbox digest save: %w", err)
+		}
+		return nil
+	}
+
+	target := DeliveryTarget{Channel: first.Channel, Target: first.Target, UserID: first.UserID}
+	if until, ok := s.quietUntil(ctx, evt.OrganizationID, first.UserID, "", quietHoursCache{}); ok &&
+		s.deferDelivery(ctx, evt, target, tpl.Format, subject, body, until) {
+		return nil
+	}
+	if err := s.deliver(ctx, evt, target, tpl.Format, subject, body); err != nil && !errors.Is(err, ErrUnsupportedChannel) {
+		return err
+	}
+	return nil
+}
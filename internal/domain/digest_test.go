@@ -0,0 +1,171 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/templates"
+)
+
+type stubDigests struct {
+	items    []DigestItem
+	claimed  []DigestItem
+	complete []int64
+}
+
+func (s *stubDigests) Add(ctx Context, item DigestItem) error {
+	s.items = append(s.items, item)
+	return nil
+}
+func (s *stubDigests) ClaimDue(ctx Context, now time.Time, lease time.Duration, limit int) ([]DigestItem, error) {
+	var due, rest []DigestItem
+	for _, item := range s.items {
+		if !item.DueAt.After(now) && len(due) < limit {
+			item.Attempts++
+			due = append(due, item)
+		} else {
+			rest = append(rest, item)
+		}
+	}
+	s.items = rest
+	s.claimed = append(s.claimed, due...)
+	return due, nil
+}
+func (s *stubDigests) Complete(ctx Context, ids []int64) error {
+	s.complete = append(s.complete, ids...)
+	return nil
+}
+
+func TestNextDigestAt(t *testing.T) {
+	// 2024-01-03 is a Wednesday.
+	at := time.Date(2024, 1, 3, 10, 15, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		DigestHourly:    time.Date(2024, 1, 3, 11, 0, 0, 0, time.UTC),
+		DigestDaily:     time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC),
+		DigestWeekly:    time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC),
+		DigestImmediate: at,
+	}
+	for cadence, want := range cases {
+		if got := NextDigestAt(cadence, at); !got.Equal(want) {
+			t.Fatalf("%s: got %v want %v", cadence, got, want)
+		}
+	}
+	monday := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	if got := NextDigestAt(DigestWeekly, monday); !got.Equal(monday.AddDate(0, 0, 7)) {
+		t.Fatalf("weekly digest at Monday midnight should be due a week later, got %v", got)
+	}
+}
+
+func TestHandleEvent_BuffersDigestPreferences(t *testing.T) {
+	uid := int64(3)
+	digests := &stubDigests{}
+	email := &stubEmail{}
+	inbox := &stubInboxRepo{}
+	svc := &NotificationService{
+		Templates: &stubTemplateRepo{},
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{OrganizationID: 1, EventType: "sbom.scan.summary", Channel: ChannelEmail, Target: "a@b.c", Enabled: true, Digest: DigestDaily},
+			{OrganizationID: 1, UserID: &uid, EventType: "sbom.scan.summary", Channel: ChannelInbox, Enabled: true, Digest: DigestHourly},
+		}},
+		Logs:     &stubLogRepo{},
+		Inbox:    inbox,
+		OrgUsers: &stubOrgUsers{byRole: map[string][]int64{"developer": {3, 4}}},
+		Digests:  digests,
+		Email:    email,
+		Renderer: templates.Renderer{},
+	}
+
+	evt := NotificationEvent{EventType: "sbom.scan.summary", OrganizationID: 1, Payload: map[string]interface{}{"project": "api"}}
+	if err := svc.HandleEvent(context.Background(), evt); err != nil {
+		t.Fatalf("handle event: %v", err)
+	}
+
+	if len(email.to) != 0 {
+		t.Fatalf("digest email should not be sent immediately")
+	}
+	if len(inbox.saved) != 1 || inbox.saved[0].UserID != 4 {
+		t.Fatalf("expected only user 4 to get an immediate inbox item, got %#v", inbox.saved)
+	}
+	if len(digests.items) != 2 {
+		t.Fatalf("expected email and inbox digest items, got %#v", digests.items)
+	}
+	for _, item := range digests.items {
+		if item.Channel == ChannelInbox && (item.UserID == nil || *item.UserID != 3 || item.Cadence != DigestHourly) {
+			t.Fatalf("unexpected inbox item %#v", item)
+		}
+		if item.Channel == ChannelEmail && (item.Target != "a@b.c" || !item.DueAt.After(time.Now())) {
+			t.Fatalf("unexpected email item %#v", item)
+		}
+	}
+}
+
+func TestFlushDigests_SendsOneDigestPerRecipient(t *testing.T) {
+	now := time.Now().UTC()
+	uid := int64(3)
+	digests := &stubDigests{items: []DigestItem{
+		{ID: 1, OrganizationID: 1, Channel: ChannelEmail, Target: "a@b.c", Cadence: DigestDaily, EventType: "sbom.scan.summary", Subject: "SBOM scan summary", DueAt: now},
+		{ID: 2, OrganizationID: 1, UserID: &uid, Channel: ChannelInbox, Cadence: DigestDaily, EventType: "project.scan.completed", Subject: "Project scan completed", DueAt: now},
+		{ID: 3, OrganizationID: 1, Channel: ChannelEmail, Target: "a@b.c", Cadence: DigestDaily, EventType: "project.scan.completed", Subject: "Project scan completed", DueAt: now},
+		{ID: 4, OrganizationID: 1, Channel: ChannelEmail, Target: "a@b.c", Cadence: DigestDaily, EventType: "sbom.scan.summary", Subject: "Later", DueAt: now.Add(time.Hour)},
+	}}
+	email := &stubEmail{}
+	inbox := &stubInboxRepo{}
+	logs := &stubLogRepo{}
+	svc := &NotificationService{
+		Templates: &stubTemplateRepo{},
+		Logs:      logs,
+		Inbox:     inbox,
+		Digests:   digests,
+		Email:     email,
+		Renderer:  templates.Renderer{},
+	}
+
+	sent, err := svc.FlushDigests(context.Background(), now)
+	if err != nil || sent != 2 {
+		t.Fatalf("expected 2 digests, got %d %v", sent, err)
+	}
+	if email.subject != "Your MyESI daily digest: 2 updates" {
+		t.Fatalf("unexpected subject %q", email.subject)
+	}
+	if !strings.Contains(email.body, "sbom.scan.summary (1):\n- SBOM scan summary") || !strings.Contains(email.body, "project.scan.completed (1):") {
+		t.Fatalf("unexpected body %q", email.body)
+	}
+	if len(inbox.saved) != 1 || inbox.saved[0].Type != EventDigest || inbox.saved[0].UserID != 3 {
+		t.Fatalf("expected inbox digest for user 3, got %#v", inbox.saved)
+	}
+	if len(logs.entries) != 1 || logs.entries[0].EventType != EventDigest || logs.entries[0].Status != "success" {
+		t.Fatalf("expected digest log entry, got %#v", logs.entries)
+	}
+	if len(digests.items) != 1 {
+		t.Fatalf("items not yet due must stay buffered, got %#v", digests.items)
+	}
+	if len(digests.complete) != 3 {
+		t.Fatalf("expected the delivered items to be completed, got %v", digests.complete)
+	}
+}
+
+func TestFlushDigests_FailedDeliveryKeepsItems(t *testing.T) {
+	now := time.Now().UTC()
+	digests := &stubDigests{items: []DigestItem{
+		{ID: 1, OrganizationID: 1, Channel: ChannelEmail, Target: "a@b.c", Cadence: DigestDaily, EventType: "sbom.scan.summary", Subject: "s", DueAt: now},
+		{ID: 2, OrganizationID: 1, Channel: ChannelEmail, Target: "c@d.e", Cadence: DigestDaily, EventType: "sbom.scan.summary", Subject: "s", DueAt: now, Attempts: maxDigestAttempts},
+	}}
+	svc := &NotificationService{
+		Templates: &stubTemplateRepo{},
+		Logs:      &stubLogRepo{},
+		Digests:   digests,
+		Email:     &stubEmail{err: errors.New("smtp down")},
+		Renderer:  templates.Renderer{},
+	}
+
+	sent, err := svc.FlushDigests(context.Background(), now)
+	if err != nil || sent != 0 {
+		t.Fatalf("expected no digest sent, got %d %v", sent, err)
+	}
+	if len(digests.complete) != 1 || digests.complete[0] != 2 {
+		t.Fatalf("only the digest out of attempts may be dropped, got %v", digests.complete)
+	}
+}
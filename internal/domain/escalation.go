@@ -0,0 +1,217 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// EventEscalation is the event type escalation notices are resolved and logged under.
+const EventEscalation = "notification.escalation"
+
+const (
+	// escalationLease is how long a claimed escalation stays hidden from other
+	// workers before it is retried.
+	escalationLease = 5 * time.Minute
+	// escalationBatchSize bounds how many escalations a run claims at once.
+	escalationBatchSize = 100
+)
+
+// Validate checks the event type and that every tier has a delay and exactly
+// one kind of recipient.
+func (p EscalationPolicy) Validate() error {
+	if strings.TrimSpace(p.EventType) == "" {
+		return errors.New("event_type is required")
+	}
+	if len(p.Tiers) == 0 {
+		return errors.New("at least one tier is required")
+	}
+	for i, t := range p.Tiers {
+		if t.AfterMinutes <= 0 {
+			return fmt.Errorf("tiers[%d]: after_minutes must be positive", i)
+		}
+		switch {
+		case t.Role != "" && t.Channel != "":
+			return fmt.Errorf("tiers[%d]: set either role or channel, not both", i)
+		case t.Role != "", t.Channel == ChannelEmail:
+		case t.Channel == ChannelSlack, t.Channel == ChannelTeams, t.Channel == ChannelWebhook:
+			if strings.TrimSpace(t.Target) == "" {
+				return fmt.Errorf("tiers[%d]: target is required for %s", i, t.Channel)
+			}
+		default:
+			return fmt.Errorf("tiers[%d]: role or channel (email, slack, teams, webhook) is required", i)
+		}
+	}
+	return nil
+}
+
+// startEscalation opens an escalation when the organization has an enabled
+// policy for the event. summary is the rendered inbox subject, and inboxIDs
+// are the items whose reading acknowledges the alert.
+func (s *NotificationService) startEscalation(ctx context.Context, evt NotificationEvent, summary string, inboxIDs []int64) {
+	if s.Escalations == nil || s.EscalationPolicies == nil || evt.OrganizationID == 0 || evt.EventType == EventEscalation {
+		return
+	}
+	policy, err := s.EscalationPolicies.FindForEvent(ctx, evt.OrganizationID, evt.EventType)
+	if err != nil {
+		log.Printf("[NOTIFY] escalation policy lookup failed: %v", err)
+		return
+	}
+	if policy == nil || len(policy.Tiers) == 0 || !shouldSendForSeverity(policy.SeverityMin, evt.Severity) {
+		return
+	}
+	next := time.Now().UTC().Add(time.Duration(policy.Tiers[0].AfterMinutes) * time.Minute)
+	if _, err := s.Escalations.Start(ctx, Escalation{
+		OrganizationID: evt.OrganizationID,
+		PolicyID:       policy.ID,
+		Event:          evt,
+		Summary:        summary,
+		InboxIDs:       inboxIDs,
+		NextAt:         &next,
+	}); err != nil {
+		log.Printf("[NOTIFY] starting escalation for %s failed: %v", evt.EventType, err)
+	}
+}
+
+// EscalateDue notifies the next tier of every unacknowledged escalation due at
+// now and returns how many tiers were notified.
+func (s *NotificationService) EscalateDue(ctx context.Context, now time.Time) (int, error) {
+	if s.Escalations == nil || s.EscalationPolicies == nil {
+		return 0, nil
+	}
+	fired := 0
+	for {
+		due, err := s.Escalations.ClaimDue(ctx, now, escalationLease, escalationBatchSize)
+		if err != nil {
+			return fired, err
+		}
+		for _, e := range due {
+			if s.escalate(ctx, e, now) {
+				fired++
+			}
+		}
+		if len(due) < escalationBatchSize {
+			return fired, nil
+		}
+	}
+}
+
+// escalate notifies the escalation's current tier and schedules the next one.
+// Chains whose policy was removed, disabled or exhausted are ended.
+func (s *NotificationService) escalate(ctx context.Context, e Escalation, now time.Time) bool {
+	policy, err := s.EscalationPolicies.Get(ctx, e.OrganizationID, e.PolicyID)
+	if err != nil {
+		log.Printf("[NOTIFY] escalation %d: policy lookup failed: %v", e.ID, err)
+		return false
+	}
+	if policy == nil || !policy.Enabled || e.NextTier >= len(policy.Tiers) {
+		if err := s.Escalations.Advance(ctx, e.ID, e.NextTier, nil, nil); err != nil {
+			log.Printf("[NOTIFY] escalation %d: closing failed: %v", e.ID, err)
+		}
+		return false
+	}
+
+	inboxIDs := s.notifyTier(ctx, e, policy.Tiers[e.NextTier], now)
+
+	tier := e.NextTier + 1
+	var next *time.Time
+	if tier < len(policy.Tiers) {
+		at := now.Add(time.Duration(policy.Tiers[tier].AfterMinutes) * time.Minute)
+		next = &at
+	}
+	if err := s.Escalations.Advance(ctx, e.ID, tier, next, inboxIDs); err != nil {
+		log.Printf("[NOTIFY] escalation %d: advancing failed: %v", e.ID, err)
+	}
+	return true
+}
+
+// notifyTier sends the escalation notice to one tier and returns the inbox
+// items it created.
+func (s *NotificationService) notifyTier(ctx context.Context, e Escalation, tier EscalationTier, now time.Time) []int64 {
+	payload := map[string]interface{}{
+		"escalation_id": e.ID,
+		"escalation": map[string]interface{}{
+			"id":         e.ID,
+			"tier":       e.NextTier + 1,
+			"summary":    e.Summary,
+			"event_type": e.Event.EventType,
+			"started_at": e.CreatedAt,
+			"minutes":    int(now.Sub(e.CreatedAt).Minutes()),
+		},
+	}
+	actionURL, _ := e.Event.Payload["action_url"].(string)
+	if actionURL != "" {
+		payload["action_url"] = actionURL
+	}
+	evt := NotificationEvent{
+		EventType:      EventEscalation,
+		OrganizationID: e.OrganizationID,
+		Severity:       e.Event.Severity,
+		OccurredAt:     now,
+		Payload:        payload,
+	}
+
+	var settings *OrgSettings
+	if s.OrgSettings != nil {
+		if st, err := s.OrgSettings.Get(ctx, e.OrganizationID); err == nil {
+			settings = st
+		}
+	}
+	partials := s.loadPartials(ctx, e.OrganizationID)
+	data := BuildTemplateData(evt)
+
+	if tier.Role != "" {
+		if s.Inbox == nil || s.OrgUsers == nil {
+			log.Printf("[NOTIFY] escalation %d: inbox not available for role %s", e.ID, tier.Role)
+			return nil
+		}
+		userIDs, err := s.OrgUsers.ListUserIDsByOrgWithRole(ctx, e.OrganizationID, tier.Role)
+		if err != nil {
+			log.Printf("[NOTIFY] escalation %d: cannot load %s users: %v", e.ID, tier.Role, err)
+			return nil
+		}
+		locales := s.userLocales(ctx, userIDs)
+		var ids []int64
+		for _, uid := range userIDs {
+			locale := localeFor(evt, locales[uid], settings)
+			tpl := s.resolveTemplate(ctx, e.OrganizationID, EventEscalation, "", locale)
+			subject, body := s.renderTemplate(tpl, PartialSet(partials, ChannelInbox), withLocale(data, locale))
+			msg := FormatMessage(tpl.Format, ChannelInbox, subject, body)
+			saved, err := s.Inbox.Save(ctx, UserNotification{
+				UserID:         uid,
+				OrganizationID: e.OrganizationID,
+				Title:          msg.Subject,
+				Message:        msg.Body,
+				Format:         msg.Format,
+				Type:           EventEscalation,
+				Severity:       evt.Severity,
+				ActionURL:      actionURL,
+				Payload:        payload,
+				CreatedAt:      time.Now().UTC(),
+			})
+			if err != nil {
+				log.Printf("[NOTIFY] escalation %d: inbox save failed: %v", e.ID, err)
+				continue
+			}
+			ids = append(ids, saved.ID)
+		}
+		return ids
+	}
+
+	target := tier.Target
+	if tier.Channel == ChannelEmail && target == "" && settings != nil {
+		target = settings.AdminEmail
+	}
+	if strings.TrimSpace(target) == "" {
+		log.Printf("[NOTIFY] escalation %d: no %s target for tier %d", e.ID, tier.Channel, e.NextTier+1)
+		return nil
+	}
+	locale := localeFor(evt, "", settings)
+	tpl := s.resolveTemplate(ctx, e.OrganizationID, EventEscalation, tier.Channel, locale)
+	subject, body := s.renderTemplate(tpl, PartialSet(partials, tier.Channel), withLocale(data, locale))
+	_ = s.deliver(ctx, evt, DeliveryTarget{Channel: tier.Channel, Target: target}, tpl.Format, subject, body)
+	return nil
+}
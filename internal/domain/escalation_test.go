@@ -0,0 +1,207 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/templates"
+)
+
+type stubPolicies struct{ policies []EscalationPolicy }
+
+func (s *stubPolicies) List(ctx Context, orgID int64) ([]EscalationPolicy, error) {
+	return s.policies, nil
+}
+func (s *stubPolicies) Get(ctx Context, orgID, id int64) (*EscalationPolicy, error) {
+	for i := range s.policies {
+		if s.policies[i].ID == id {
+			return &s.policies[i], nil
+		}
+	}
+	return nil, nil
+}
+func (s *stubPolicies) FindForEvent(ctx Context, orgID int64, eventType string) (*EscalationPolicy, error) {
+	for i := range s.policies {
+		if s.policies[i].EventType == eventType && s.policies[i].Enabled {
+			return &s.policies[i], nil
+		}
+	}
+	return nil, nil
+}
+func (s *stubPolicies) Save(ctx Context, p EscalationPolicy) (EscalationPolicy, error) {
+	return p, nil
+}
+func (s *stubPolicies) Delete(ctx Context, orgID, id int64) error { return nil }
+
+type stubEscalations struct{ items []Escalation }
+
+func (s *stubEscalations) Start(ctx Context, e Escalation) (Escalation, error) {
+	e.ID = int64(len(s.items) + 1)
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	s.items = append(s.items, e)
+	return e, nil
+}
+func (s *stubEscalations) ClaimDue(ctx Context, now time.Time, lease time.Duration, limit int) ([]Escalation, error) {
+	var due []Escalation
+	for i := range s.items {
+		e := &s.items[i]
+		if e.AcknowledgedAt == nil && e.NextAt != nil && !e.NextAt.After(now) {
+			leased := now.Add(lease)
+			e.NextAt = &leased
+			due = append(due, *e)
+		}
+	}
+	return due, nil
+}
+func (s *stubEscalations) Advance(ctx Context, id int64, tier int, nextAt *time.Time, inboxIDs []int64) error {
+	e := &s.items[id-1]
+	e.NextTier = tier
+	e.NextAt = nextAt
+	e.InboxIDs = append(e.InboxIDs, inboxIDs...)
+	return nil
+}
+func (s *stubEscalations) Acknowledge(ctx Context, orgID, id int64, by string) (bool, error) {
+	return false, nil
+}
+func (s *stubEscalations) ListOpen(ctx Context, orgID int64) ([]Escalation, error) {
+	return s.items, nil
+}
+
+func TestEscalationPolicyValidate(t *testing.T) {
+	valid := EscalationPolicy{EventType: "vulnerability.critical", Tiers: []EscalationTier{
+		{AfterMinutes: 15, Role: "team_lead"},
+		{AfterMinutes: 30, Channel: ChannelEmail},
+		{AfterMinutes: 30, Channel: ChannelWebhook, Target: "https://events.pagerduty.example/hook"},
+	}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid policy, got %v", err)
+	}
+
+	cases := map[string]EscalationPolicy{
+		"event_type":    {Tiers: valid.Tiers},
+		"tier":          {EventType: "x"},
+		"after_minutes": {EventType: "x", Tiers: []EscalationTier{{Role: "admin"}}},
+		"not both":      {EventType: "x", Tiers: []EscalationTier{{AfterMinutes: 5, Role: "admin", Channel: ChannelEmail}}},
+		"target":        {EventType: "x", Tiers: []EscalationTier{{AfterMinutes: 5, Channel: ChannelSlack}}},
+		"role or":       {EventType: "x", Tiers: []EscalationTier{{AfterMinutes: 5, Channel: "sms"}}},
+	}
+	for want, p := range cases {
+		if err := p.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: unexpected error %v", want, err)
+		}
+	}
+}
+
+func TestHandleEvent_StartsEscalationForCriticalAlert(t *testing.T) {
+	escalations := &stubEscalations{}
+	inbox := &stubInboxRepo{}
+	uid := int64(7)
+	svc := &NotificationService{
+		Templates:   &stubTemplateRepoAlways{tpl: NotificationTemplate{Subject: "Critical CVE in {{.payload.project}}", Body: "b"}},
+		Preferences: &stubPrefRepoStatic{},
+		Logs:        &stubLogRepo{},
+		Inbox:       inbox,
+		Escalations: escalations,
+		EscalationPolicies: &stubPolicies{policies: []EscalationPolicy{{
+			ID: 3, OrganizationID: 1, EventType: "alert.raised", SeverityMin: "critical", Enabled: true,
+			Tiers: []EscalationTier{{AfterMinutes: 15, Role: "team_lead"}},
+		}}},
+		Renderer: templates.Renderer{},
+	}
+
+	evt := NotificationEvent{EventType: "alert.raised", OrganizationID: 1, UserID: &uid, Severity: "high",
+		Payload: map[string]interface{}{"project": "api"}}
+	_ = svc.HandleEvent(context.Background(), evt)
+	if len(escalations.items) != 0 {
+		t.Fatalf("high severity should not escalate: %#v", escalations.items)
+	}
+
+	evt.Severity = "critical"
+	start := time.Now()
+	_ = svc.HandleEvent(context.Background(), evt)
+	if len(escalations.items) != 1 {
+		t.Fatalf("expected one escalation, got %#v", escalations.items)
+	}
+	e := escalations.items[0]
+	if e.PolicyID != 3 || e.Summary != "Critical CVE in api" || len(e.InboxIDs) != 1 || e.NextTier != 0 {
+		t.Fatalf("unexpected escalation %#v", e)
+	}
+	if e.NextAt == nil || e.NextAt.Before(start.Add(15*time.Minute)) {
+		t.Fatalf("first tier should be due after 15 minutes, got %v", e.NextAt)
+	}
+}
+
+func TestEscalateDue_WalksTiersUntilExhausted(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	due := created.Add(15 * time.Minute)
+	escalations := &stubEscalations{items: []Escalation{{
+		ID: 1, OrganizationID: 1, PolicyID: 3, Summary: "Critical CVE in api", NextAt: &due, CreatedAt: created,
+		Event: NotificationEvent{EventType: "vulnerability.critical", OrganizationID: 1, Severity: "critical"},
+	}}}
+	inbox := &stubInboxRepo{}
+	email := &stubEmail{}
+	logs := &stubLogRepo{}
+	svc := &NotificationService{
+		Templates:   &stubTemplateRepo{},
+		Preferences: &stubPrefRepo{},
+		Logs:        logs,
+		Inbox:       inbox,
+		OrgUsers:    &stubOrgUsers{byRole: map[string][]int64{"team_lead": {4, 5}}},
+		OrgSettings: &stubOrgSettings{st: &OrgSettings{AdminEmail: "admin@x.io"}},
+		Email:       email,
+		Escalations: escalations,
+		EscalationPolicies: &stubPolicies{policies: []EscalationPolicy{{
+			ID: 3, OrganizationID: 1, EventType: "vulnerability.critical", Enabled: true,
+			Tiers: []EscalationTier{{AfterMinutes: 15, Role: "team_lead"}, {AfterMinutes: 30, Channel: ChannelEmail}},
+		}}},
+		Renderer: templates.Renderer{},
+	}
+
+	if n, err := svc.EscalateDue(context.Background(), due.Add(-time.Minute)); err != nil || n != 0 {
+		t.Fatalf("nothing should be due yet, got %d %v", n, err)
+	}
+
+	if n, err := svc.EscalateDue(context.Background(), due); err != nil || n != 1 {
+		t.Fatalf("expected first tier, got %d %v", n, err)
+	}
+	if len(inbox.saved) != 2 || inbox.saved[0].Type != EventEscalation || inbox.saved[1].UserID != 5 {
+		t.Fatalf("team leads should get inbox items, got %#v", inbox.saved)
+	}
+	if !strings.Contains(inbox.saved[0].Message, "15 minutes") || inbox.saved[0].Payload["escalation_id"] != int64(1) {
+		t.Fatalf("unexpected escalation notice %#v", inbox.saved[0])
+	}
+	e := escalations.items[0]
+	if e.NextTier != 1 || e.NextAt == nil || !e.NextAt.Equal(due.Add(30*time.Minute)) || len(e.InboxIDs) != 2 {
+		t.Fatalf("unexpected escalation after first tier %#v", e)
+	}
+
+	if n, _ := svc.EscalateDue(context.Background(), *e.NextAt); n != 1 {
+		t.Fatalf("expected second tier, got %d", n)
+	}
+	if len(email.to) != 1 || email.to[0] != "admin@x.io" || !strings.HasPrefix(email.subject, "Escalation: ") {
+		t.Fatalf("admin should be emailed, got %v %q", email.to, email.subject)
+	}
+	if len(logs.entries) != 1 || logs.entries[0].EventType != EventEscalation {
+		t.Fatalf("expected logged escalation email, got %#v", logs.entries)
+	}
+	if e := escalations.items[0]; e.NextAt != nil || e.NextTier != 2 {
+		t.Fatalf("chain should end after last tier, got %#v", e)
+	}
+}
+
+func TestEscalateDue_EndsChainWhenPolicyRemoved(t *testing.T) {
+	due := time.Now().UTC()
+	escalations := &stubEscalations{items: []Escalation{{ID: 1, OrganizationID: 1, PolicyID: 9, NextAt: &due}}}
+	svc := &NotificationService{Escalations: escalations, EscalationPolicies: &stubPolicies{}}
+
+	if n, err := svc.EscalateDue(context.Background(), due); err != nil || n != 0 {
+		t.Fatalf("expected no notification, got %d %v", n, err)
+	}
+	if escalations.items[0].NextAt != nil {
+		t.Fatalf("chain should be closed, got %#v", escalations.items[0])
+	}
+}
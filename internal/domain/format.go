@@ -0,0 +1,37 @@
+package domain
+
+import "myesi-notification-service/internal/templates"
+
+// FormattedMessage is a rendered template converted for one channel.
+type FormattedMessage struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`           // channel-native body; plain text for email and webhooks
+	HTML    string `json:"html,omitempty"` // email HTML part, only for Markdown templates
+	Format  string `json:"format"`         // inbox message format
+}
+
+// FormatMessage converts rendered output for channel. Text templates pass
+// through unchanged; Markdown ones become Slack mrkdwn, Teams markdown, HTML
+// with a plain-text alternative for email, plain text for webhooks, and
+// sanitized HTML for the inbox. Subjects are always plain text.
+func FormatMessage(format, channel, subject, body string) FormattedMessage {
+	if format != FormatMarkdown {
+		return FormattedMessage{Subject: subject, Body: body, Format: FormatText}
+	}
+	msg := FormattedMessage{Subject: templates.Markdown(subject, templates.OutputText), Format: FormatText}
+	switch channel {
+	case ChannelEmail:
+		msg.Body = templates.Markdown(body, templates.OutputText)
+		msg.HTML = templates.Markdown(body, templates.OutputHTML)
+	case ChannelSlack:
+		msg.Body = templates.Markdown(body, templates.OutputSlack)
+	case ChannelTeams:
+		msg.Body = templates.Markdown(body, templates.OutputTeams)
+	case ChannelInbox:
+		msg.Body = templates.Markdown(body, templates.OutputSafeHTML)
+		msg.Format = InboxFormatHTML
+	default:
+		msg.Body = templates.Markdown(body, templates.OutputText)
+	}
+	return msg
+}
@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"myesi-notification-service/internal/templates"
+)
+
+type stubHTMLEmail struct {
+	stubEmail
+	text, html string
+}
+
+func (s *stubHTMLEmail) SendHTMLEmail(ctx Context, to []string, subject, text, html string) error {
+	s.to = append(s.to, to...)
+	s.subject, s.text, s.html = subject, text, html
+	return nil
+}
+
+type stubTeams struct{ msg string }
+
+func (s *stubTeams) SendTeamsMessage(ctx Context, webhookURL, text string) error {
+	s.msg = text
+	return nil
+}
+
+func TestFormatMessage_TextPassesThrough(t *testing.T) {
+	msg := FormatMessage(FormatText, ChannelSlack, "**s**", "**b**")
+	if msg.Subject != "**s**" || msg.Body != "**b**" || msg.HTML != "" {
+		t.Fatalf("expected text template unchanged, got %#v", msg)
+	}
+}
+
+func TestHandleEvent_MarkdownPerChannel(t *testing.T) {
+	email := &stubHTMLEmail{}
+	slack := &stubSlack{}
+	teams := &stubTeams{}
+	inbox := &stubInboxRepo{}
+	uid := int64(4)
+	svc := &NotificationService{
+		Templates: &stubTemplateRepoAlways{tpl: NotificationTemplate{
+			Format:  FormatMarkdown,
+			Subject: "Scan of **{{.payload.project}}**",
+			Body:    "Project **{{.payload.project}}** has [findings](https://myesi.io/f).",
+		}},
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{EventType: "custom.event", Channel: ChannelEmail, Target: "a@x.com", Enabled: true},
+			{EventType: "custom.event", Channel: ChannelSlack, Target: "https://slack", Enabled: true},
+			{EventType: "custom.event", Channel: ChannelTeams, Target: "https://teams", Enabled: true},
+		}},
+		Logs:     &stubLogRepo{},
+		Inbox:    inbox,
+		Email:    email,
+		Slack:    slack,
+		Teams:    teams,
+		Webhook:  &stubWebhook{},
+		Renderer: templates.Renderer{},
+	}
+
+	evt := NotificationEvent{EventType: "custom.event", OrganizationID: 1, UserID: &uid, Payload: map[string]interface{}{"project": "api"}}
+	if err := svc.HandleEvent(context.Background(), evt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if email.subject != "Scan of api" {
+		t.Fatalf("expected plain subject, got %q", email.subject)
+	}
+	if email.text != "Project api has findings (https://myesi.io/f)." {
+		t.Fatalf("unexpected text part %q", email.text)
+	}
+	if !strings.Contains(email.html, "<strong>api</strong>") {
+		t.Fatalf("unexpected html part %q", email.html)
+	}
+	if slack.msg != "Project *api* has <https://myesi.io/f|findings>." {
+		t.Fatalf("unexpected slack message %q", slack.msg)
+	}
+	if teams.msg != "Project **api** has [findings](https://myesi.io/f)." {
+		t.Fatalf("unexpected teams message %q", teams.msg)
+	}
+	if len(inbox.saved) != 1 || inbox.saved[0].Format != InboxFormatHTML || !strings.Contains(inbox.saved[0].Message, `rel="noopener`) {
+		t.Fatalf("expected sanitized html inbox message, got %#v", inbox.saved)
+	}
+}
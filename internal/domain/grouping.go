@@ -0,0 +1,243 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// EventGrouped is the event type "N more notifications" group summaries are
+// resolved and logged under.
+const EventGrouped = "notification.grouped"
+
+// maxGroupWindow bounds how long a group may stay open.
+const maxGroupWindow = 24 * 60 * 60
+
+// groupBatchSize bounds how many closed groups a flush run claims at once.
+const groupBatchSize = 200
+
+// MaxGroupEvents bounds how many counted events a group keeps for its summary.
+const MaxGroupEvents = 100
+
+// ErrGroupClosed reports that an event's group has reached its window end
+// but was not flushed yet.
+var ErrGroupClosed = errors.New("group window has ended")
+
+// Validate checks the window and group-by fields.
+func (r GroupingRule) Validate() error {
+	if r.Window < 1 || r.Window > maxGroupWindow {
+		return fmt.Errorf("window_seconds must be between 1 and %d", maxGroupWindow)
+	}
+	for _, f := range r.GroupBy {
+		if strings.TrimSpace(f) == "" || strings.ContainsAny(f, "=,") {
+			return fmt.Errorf("invalid group_by field %q", f)
+		}
+	}
+	return nil
+}
+
+// GroupKey identifies the group evt belongs to under r. Without group-by
+// fields events are grouped by organization, event type and user, so one
+// user's burst is never summarized to another.
+func (r GroupingRule) GroupKey(evt NotificationEvent) string {
+	fields := r.GroupBy
+	if len(fields) == 0 {
+		fields = []string{"organization_id", "event_type", "user_id"}
+	}
+	parts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		parts = append(parts, f+"="+groupField(evt, f))
+	}
+	return strings.Join(parts, ",")
+}
+
+// groupField reads a group-by field from the event or, for other names, from
+// the payload; dotted names walk nested payload objects.
+func groupField(evt NotificationEvent, field string) string {
+	switch field {
+	case "organization_id":
+		return fmt.Sprint(evt.OrganizationID)
+	case "event_type":
+		return evt.EventType
+	case "severity":
+		return strings.ToLower(evt.Severity)
+	case "user_id":
+		if evt.UserID == nil {
+			return ""
+		}
+		return fmt.Sprint(*evt.UserID)
+	}
+	var cur interface{} = evt.Payload
+	for _, part := range strings.Split(field, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		cur = m[part]
+	}
+	if cur == nil {
+		return ""
+	}
+	return fmt.Sprint(cur)
+}
+
+// groupEvent counts evt into its open group. It reports true when the event
+// was absorbed by a group that already notified; otherwise the returned
+// group, if any, was just opened by evt and should collect its inbox items.
+// Lookup failures leave the event ungrouped.
+func (s *NotificationService) groupEvent(ctx context.Context, evt NotificationEvent) (*NotificationGroup, bool) {
+	if s.GroupingRules == nil || s.Groups == nil || evt.OrganizationID == 0 {
+		return nil, false
+	}
+	rule, err := s.GroupingRules.Match(ctx, evt.OrganizationID, evt.EventType)
+	if err != nil {
+		log.Printf("[NOTIFY] grouping rule lookup failed: %v", err)
+		return nil, false
+	}
+	if rule == nil {
+		return nil, false
+	}
+
+	now := time.Now().UTC()
+	g, opened, err := s.Groups.Add(ctx, NotificationGroup{
+		OrganizationID: evt.OrganizationID,
+		RuleID:         rule.ID,
+		Key:            rule.GroupKey(evt),
+		Event:          evt,
+		Count:          1,
+		FirstAt:        now,
+		LastAt:         now,
+		WindowEnd:      now.Add(time.Duration(rule.Window) * time.Second),
+	})
+	if errors.Is(err, ErrGroupClosed) {
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("[NOTIFY] grouping %s failed, sending: %v", evt.EventType, err)
+		return nil, false
+	}
+	if opened {
+		return &g, false
+	}
+	log.Printf("[NOTIFY] grouped %s into %s (%d)", evt.EventType, g.Key, g.Count)
+	return nil, true
+}
+
+// attachGroupInbox records the inbox items created for the event that opened g
+// so later events in the group can update them.
+func (s *NotificationService) attachGroupInbox(ctx context.Context, g *NotificationGroup, inboxIDs []int64) {
+	if g == nil || len(inboxIDs) == 0 {
+		return
+	}
+	if err := s.Groups.AttachInbox(ctx, g.ID, inboxIDs); err != nil {
+		log.Printf("[NOTIFY] attaching inbox items to group %d failed: %v", g.ID, err)
+	}
+}
+
+// FlushGroups closes groups whose window has ended and sends one summary per
+// group that absorbed further events to the original outbound channels. It
+// returns how many groups were closed.
+func (s *NotificationService) FlushGroups(ctx context.Context, now time.Time) (int, error) {
+	if s.Groups == nil {
+		return 0, nil
+	}
+	closed := 0
+	for {
+		groups, err := s.Groups.ClaimDue(ctx, now, groupBatchSize)
+		if err != nil {
+			return closed, err
+		}
+		for _, g := range groups {
+			if g.Count > 1 {
+				s.sendGroupSummary(ctx, g)
+			}
+			closed++
+		}
+		if len(groups) < groupBatchSize {
+			return closed, nil
+		}
+	}
+}
+
+// sendGroupSummary sends the summary to everyone the counted events would have
+// reached after routing, each target in its owner's locale. The payload is the latest
+// event's, with the counted events listed under group.items.
+func (s *NotificationService) sendGroupSummary(ctx context.Context, g NotificationGroup) {
+	events := g.Events
+	if len(events) == 0 {
+		events = []NotificationEvent{g.Event}
+	}
+	evt := g.Event
+	evt.OccurredAt = g.LastAt
+	items := make([]map[string]interface{}, 0, len(events))
+	for _, e := range events {
+		item := map[string]interface{}{
+			"event_type":  e.EventType,
+			"severity":    e.Severity,
+			"occurred_at": e.OccurredAt,
+			"payload":     e.Payload,
+		}
+		if e.UserID != nil {
+			item["user_id"] = *e.UserID
+		}
+		items = append(items, item)
+	}
+	payload := make(map[string]interface{}, len(g.Event.Payload)+1)
+	for k, v := range g.Event.Payload {
+		payload[k] = v
+	}
+	payload["group"] = map[string]interface{}{
+		"count":      g.Count - 1,
+		"total":      g.Count,
+		"event_type": g.Event.EventType,
+		"key":        g.Key,
+		"first_at":   g.FirstAt,
+		"last_at":    g.LastAt,
+		"items":      items,
+	}
+	evt.Payload = payload
+
+	var settings *OrgSettings
+	if s.OrgSettings != nil {
+		if st, err := s.OrgSettings.Get(ctx, g.OrganizationID); err == nil {
+			settings = st
+		}
+	}
+	var targets []DeliveryTarget
+	seen := map[string]bool{}
+	for _, e := range events {
+		for _, target := range s.outboundTargets(ctx, e, settings) {
+			key := destinationKey(target.Channel, target.Target)
+			if target.UserID != nil {
+				key += "|" + fmt.Sprint(*target.UserID)
+			}
+			if !seen[key] {
+				seen[key] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+	evt.EventType = EventGrouped
+
+	locale := localeFor(evt, "", settings)
+	targetLocales := s.userLocales(ctx, targetUsers(targets, nil))
+	data := BuildTemplateData(evt)
+	partials := s.loadPartials(ctx, g.OrganizationID)
+	quiet := quietHoursCache{}
+	for _, target := range targets {
+		targetLocale := locale
+		if target.UserID != nil {
+			targetLocale = localeFor(evt, targetLocales[*target.UserID], settings)
+		}
+		tpl := s.resolveTemplate(ctx, g.OrganizationID, EventGrouped, target.Channel, targetLocale)
+		subject, body := s.renderTemplate(tpl, PartialSet(partials, target.Channel), withLocale(data, targetLocale))
+		if until, ok := s.quietUntil(ctx, g.OrganizationID, target.UserID, evt.Severity, quiet); ok &&
+			s.deferDelivery(ctx, evt, target, tpl.Format, subject, body, until) {
+			continue
+		}
+		_ = s.deliver(ctx, evt, target, tpl.Format, subject, body)
+	}
+}
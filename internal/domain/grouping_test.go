@@ -0,0 +1,233 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/templates"
+)
+
+type stubGroupingRules struct{ rule *GroupingRule }
+
+func (s *stubGroupingRules) List(ctx Context, orgID int64) ([]GroupingRule, error) { return nil, nil }
+func (s *stubGroupingRules) Save(ctx Context, r GroupingRule) (GroupingRule, error) {
+	return r, nil
+}
+func (s *stubGroupingRules) Delete(ctx Context, orgID, id int64) error { return nil }
+func (s *stubGroupingRules) Match(ctx Context, orgID int64, eventType string) (*GroupingRule, error) {
+	return s.rule, nil
+}
+
+type stubGroups struct{ open map[string]*NotificationGroup }
+
+func (s *stubGroups) Add(ctx Context, g NotificationGroup) (NotificationGroup, bool, error) {
+	if cur, ok := s.open[g.Key]; ok {
+		if !cur.WindowEnd.After(g.LastAt) {
+			return NotificationGroup{}, false, ErrGroupClosed
+		}
+		cur.Count++
+		cur.Event, cur.LastAt = g.Event, g.LastAt
+		cur.Events = append(cur.Events, g.Event)
+		return *cur, false, nil
+	}
+	g.ID = int64(len(s.open) + 1)
+	s.open[g.Key] = &g
+	return g, true, nil
+}
+func (s *stubGroups) AttachInbox(ctx Context, id int64, inboxIDs []int64) error {
+	for _, g := range s.open {
+		if g.ID == id {
+			g.InboxIDs = append(g.InboxIDs, inboxIDs...)
+		}
+	}
+	return nil
+}
+func (s *stubGroups) ClaimDue(ctx Context, now time.Time, limit int) ([]NotificationGroup, error) {
+	var out []NotificationGroup
+	for key, g := range s.open {
+		if !g.WindowEnd.After(now) {
+			out = append(out, *g)
+			delete(s.open, key)
+		}
+	}
+	return out, nil
+}
+
+// stubPrefRepoByUser filters user rows like the PostgreSQL repository does.
+type stubPrefRepoByUser struct{ stubPrefRepoStatic }
+
+func (r *stubPrefRepoByUser) List(ctx Context, orgID int64, userID *int64, eventType string) ([]NotificationPreference, error) {
+	var out []NotificationPreference
+	for _, p := range r.prefs {
+		if userID == nil || p.UserID == nil || *p.UserID == *userID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func TestGroupingRuleGroupKey(t *testing.T) {
+	uid := int64(7)
+	evt := NotificationEvent{EventType: "vulnerability.critical", OrganizationID: 1, UserID: &uid,
+		Payload: map[string]interface{}{"project": "payments-api", "sbom": map[string]interface{}{"id": 42}}}
+
+	if got := (GroupingRule{}).GroupKey(evt); got != "organization_id=1,event_type=vulnerability.critical,user_id=7" {
+		t.Fatalf("unexpected default key %q", got)
+	}
+	r := GroupingRule{GroupBy: []string{"project", "sbom.id", "user_id", "missing"}}
+	if got := r.GroupKey(evt); got != "project=payments-api,sbom.id=42,user_id=7,missing=" {
+		t.Fatalf("unexpected key %q", got)
+	}
+}
+
+func TestGroupingRuleValidate(t *testing.T) {
+	if err := (GroupingRule{GroupBy: []string{"project"}, Window: 60}).Validate(); err != nil {
+		t.Fatalf("expected valid, got %v", err)
+	}
+	for want, r := range map[string]GroupingRule{
+		"window_seconds": {Window: 0},
+		"group_by":       {Window: 60, GroupBy: []string{"a=b"}},
+	} {
+		if err := r.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: unexpected error %v", want, err)
+		}
+	}
+}
+
+func TestHandleEvent_GroupsBurstIntoOneNotification(t *testing.T) {
+	uid := int64(3)
+	groups := &stubGroups{open: map[string]*NotificationGroup{}}
+	inbox := &stubInboxRepo{}
+	email := &stubEmail{}
+	logs := &stubLogRepo{}
+	svc := &NotificationService{
+		Templates: &stubTemplateRepo{},
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{OrganizationID: 1, EventType: "vulnerability.critical", Channel: ChannelEmail, Target: "sec@example.com", Enabled: true},
+		}},
+		Logs:          logs,
+		Inbox:         inbox,
+		Email:         email,
+		GroupingRules: &stubGroupingRules{rule: &GroupingRule{ID: 1, OrganizationID: 1, GroupBy: []string{"project"}, Window: 60, Enabled: true}},
+		Groups:        groups,
+		Renderer:      templates.Renderer{},
+	}
+
+	for _, project := range []string{"payments-api", "payments-api", "payments-api", "web"} {
+		evt := NotificationEvent{EventType: "vulnerability.critical", OrganizationID: 1, UserID: &uid,
+			Payload: map[string]interface{}{"project": project}}
+		if err := svc.HandleEvent(context.Background(), evt); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	}
+	if len(inbox.saved) != 2 || len(logs.entries) != 2 {
+		t.Fatalf("expected one notification per project, got %d inbox / %d emails", len(inbox.saved), len(logs.entries))
+	}
+	g := groups.open["project=payments-api"]
+	if g == nil || g.Count != 3 || len(g.InboxIDs) != 1 {
+		t.Fatalf("unexpected group %#v", g)
+	}
+
+	n, err := svc.FlushGroups(context.Background(), time.Now().Add(2*time.Minute))
+	if err != nil || n != 2 {
+		t.Fatalf("expected two groups closed, got %d %v", n, err)
+	}
+	if len(logs.entries) != 3 || logs.entries[2].EventType != EventGrouped {
+		t.Fatalf("expected one summary for the burst, got %#v", logs.entries)
+	}
+	if !strings.Contains(email.subject, "2 more vulnerability.critical notifications") {
+		t.Fatalf("unexpected summary subject %q", email.subject)
+	}
+}
+
+func TestFlushGroups_SummaryReachesEveryCountedEventsUser(t *testing.T) {
+	first, second, third := int64(3), int64(4), int64(5)
+	groups := &stubGroups{open: map[string]*NotificationGroup{}}
+	email := &recordingEmail{subjects: map[string]string{}}
+	svc := &NotificationService{
+		Templates: &stubTemplateRepo{},
+		Preferences: &stubPrefRepoByUser{stubPrefRepoStatic{prefs: []NotificationPreference{
+			{OrganizationID: 1, UserID: &first, EventType: "*", Channel: ChannelEmail, Target: "first@x.io", Enabled: true},
+			{OrganizationID: 1, UserID: &second, EventType: "*", Channel: ChannelEmail, Target: "second@x.io", Enabled: true},
+			{OrganizationID: 1, UserID: &third, EventType: "*", Channel: ChannelEmail, Target: "third@x.io", Enabled: true},
+		}}},
+		Logs:          &stubLogRepo{},
+		Email:         email,
+		GroupingRules: &stubGroupingRules{rule: &GroupingRule{ID: 1, OrganizationID: 1, GroupBy: []string{"project"}, Window: 60, Enabled: true}},
+		Groups:        groups,
+		Renderer:      templates.Renderer{},
+	}
+
+	for _, uid := range []*int64{&first, &second, &third} {
+		evt := NotificationEvent{EventType: "vulnerability.critical", OrganizationID: 1, UserID: uid,
+			Payload: map[string]interface{}{"project": "payments-api"}}
+		if err := svc.HandleEvent(context.Background(), evt); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	}
+	email.subjects = map[string]string{}
+	if _, err := svc.FlushGroups(context.Background(), time.Now().Add(2*time.Minute)); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(email.subjects) != 2 || email.subjects["second@x.io"] == "" || email.subjects["third@x.io"] == "" {
+		t.Fatalf("expected the summary for both counted events' users, got %v", email.subjects)
+	}
+}
+
+func TestFlushGroups_SummaryFollowsRoutingRules(t *testing.T) {
+	uid := int64(3)
+	groups := &stubGroups{open: map[string]*NotificationGroup{}}
+	email := &recordingEmail{subjects: map[string]string{}}
+	slack := &stubSlack{}
+	svc := &NotificationService{
+		Templates: &stubTemplateRepo{},
+		Preferences: &stubPrefRepoByUser{stubPrefRepoStatic{prefs: []NotificationPreference{
+			{OrganizationID: 1, UserID: &uid, EventType: "*", Channel: ChannelEmail, Target: "dev@x.io", Enabled: true},
+			{OrganizationID: 1, EventType: "*", Channel: ChannelSlack, Target: "https://hooks", Enabled: true},
+		}}},
+		Logs:          &stubLogRepo{},
+		Email:         email,
+		Slack:         slack,
+		Routes:        &stubRoutes{rules: []RoutingRule{{ID: 1, Enabled: true, EventPattern: "vulnerability.*", Actions: RouteActions{Channels: []string{ChannelSlack}}}}},
+		GroupingRules: &stubGroupingRules{rule: &GroupingRule{ID: 1, OrganizationID: 1, GroupBy: []string{"project"}, Window: 60, Enabled: true}},
+		Groups:        groups,
+		Renderer:      templates.Renderer{},
+	}
+
+	for i := 0; i < 2; i++ {
+		evt := NotificationEvent{EventType: "vulnerability.critical", OrganizationID: 1, UserID: &uid,
+			Payload: map[string]interface{}{"project": "payments-api"}}
+		if err := svc.HandleEvent(context.Background(), evt); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	}
+	slack.msg = ""
+	if _, err := svc.FlushGroups(context.Background(), time.Now().Add(2*time.Minute)); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(email.subjects) != 0 {
+		t.Fatalf("a slack-only rule must suppress the email summary, got %v", email.subjects)
+	}
+	if !strings.Contains(slack.msg, "1 more vulnerability.critical notification") {
+		t.Fatalf("expected the summary on slack, got %q", slack.msg)
+	}
+}
+
+func TestGroupEvent_EndedWindowSendsUngrouped(t *testing.T) {
+	groups := &stubGroups{open: map[string]*NotificationGroup{
+		"project=api": {ID: 1, Key: "project=api", Count: 1, WindowEnd: time.Now().Add(-time.Second)},
+	}}
+	svc := &NotificationService{
+		GroupingRules: &stubGroupingRules{rule: &GroupingRule{ID: 1, OrganizationID: 1, GroupBy: []string{"project"}, Window: 60, Enabled: true}},
+		Groups:        groups,
+	}
+	evt := NotificationEvent{EventType: "vulnerability.critical", OrganizationID: 1, Payload: map[string]interface{}{"project": "api"}}
+	if g, absorbed := svc.groupEvent(context.Background(), evt); g != nil || absorbed {
+		t.Fatalf("expected the event to be sent ungrouped, got %#v %v", g, absorbed)
+	}
+	if groups.open["project=api"].Count != 1 {
+		t.Fatalf("the ended group must not count the event")
+	}
+}
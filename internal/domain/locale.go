@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// DefaultLocale is the language every lookup eventually falls back to.
+const DefaultLocale = "en"
+
+// NormalizeLocale canonicalizes a BCP 47-ish tag: "vi_vn" -> "vi-VN".
+func NormalizeLocale(tag string) string {
+	tag = strings.TrimSpace(strings.ReplaceAll(tag, "_", "-"))
+	if tag == "" {
+		return ""
+	}
+	parts := strings.Split(tag, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// LocaleChain lists the tags to try for a locale, most specific first, ending
+// with DefaultLocale: "vi-VN" -> ["vi-VN", "vi", "en"].
+func LocaleChain(locale string) []string {
+	locale = NormalizeLocale(locale)
+	chain := make([]string, 0, 3)
+	for locale != "" {
+		chain = append(chain, locale)
+		idx := strings.LastIndex(locale, "-")
+		if idx < 0 {
+			break
+		}
+		locale = locale[:idx]
+	}
+	if len(chain) == 0 || chain[len(chain)-1] != DefaultLocale {
+		chain = append(chain, DefaultLocale)
+	}
+	return chain
+}
+
+// localeFor picks a recipient's locale: an explicit payload "locale", then the
+// user's profile, then the organization setting, then DefaultLocale.
+func localeFor(evt NotificationEvent, userLocale string, settings *OrgSettings) string {
+	if v, ok := evt.Payload["locale"].(string); ok && v != "" {
+		return NormalizeLocale(v)
+	}
+	if userLocale != "" {
+		return NormalizeLocale(userLocale)
+	}
+	if settings != nil && settings.Locale != "" {
+		return NormalizeLocale(settings.Locale)
+	}
+	return DefaultLocale
+}
+
+// userLocales loads profile locales for recipients; missing entries mean "not set".
+func (s *NotificationService) userLocales(ctx context.Context, userIDs []int64) map[int64]string {
+	if s.Locales == nil || len(userIDs) == 0 {
+		return nil
+	}
+	locales, err := s.Locales.UserLocales(ctx, userIDs)
+	if err != nil {
+		log.Printf("[NOTIFY] user locale lookup failed: %v", err)
+		return nil
+	}
+	return locales
+}
+
+// withLocale returns a shallow copy of template data with {{.locale}} set.
+func withLocale(data map[string]interface{}, locale string) map[string]interface{} {
+	out := make(map[string]interface{}, len(data)+1)
+	for k, v := range data {
+		out[k] = v
+	}
+	out["locale"] = locale
+	return out
+}
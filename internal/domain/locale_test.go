@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"myesi-notification-service/internal/templates"
+)
+
+type stubLocales struct{ byUser map[int64]string }
+
+func (s *stubLocales) UserLocales(ctx Context, userIDs []int64) (map[int64]string, error) {
+	return s.byUser, nil
+}
+
+func TestLocaleChain(t *testing.T) {
+	cases := map[string][]string{
+		"":      {"en"},
+		"en":    {"en"},
+		"vi_vn": {"vi-VN", "vi", "en"},
+		"ja":    {"ja", "en"},
+	}
+	for in, want := range cases {
+		if got := LocaleChain(in); !reflect.DeepEqual(got, want) {
+			t.Fatalf("LocaleChain(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestLocaleFor_Precedence(t *testing.T) {
+	settings := &OrgSettings{Locale: "ja"}
+	evt := NotificationEvent{Payload: map[string]interface{}{}}
+
+	if got := localeFor(evt, "", nil); got != DefaultLocale {
+		t.Fatalf("expected default locale, got %q", got)
+	}
+	if got := localeFor(evt, "", settings); got != "ja" {
+		t.Fatalf("expected org locale, got %q", got)
+	}
+	if got := localeFor(evt, "vi", settings); got != "vi" {
+		t.Fatalf("expected user locale, got %q", got)
+	}
+	evt.Payload["locale"] = "de"
+	if got := localeFor(evt, "vi", settings); got != "de" {
+		t.Fatalf("expected payload locale, got %q", got)
+	}
+}
+
+func TestResolveTemplate_RegionalFallsBackToLanguage(t *testing.T) {
+	svc := &NotificationService{Templates: &stubTemplateRepoScoped{}}
+
+	tpl := svc.resolveTemplate(context.Background(), 3, "vulnerability.assignment", ChannelEmail, "vi-VN")
+	if tpl.Subject != "Bạn được giao một lỗ hổng mới" {
+		t.Fatalf("expected vi builtin, got %q", tpl.Subject)
+	}
+
+	tpl = svc.resolveTemplate(context.Background(), 3, "vulnerability.assignment", ChannelEmail, "pt-BR")
+	if tpl.Subject != "New vulnerability assigned to you" {
+		t.Fatalf("expected en fallback, got %q", tpl.Subject)
+	}
+}
+
+func TestInbox_RendersPerRecipientLocale(t *testing.T) {
+	inbox := &stubInboxRepo{}
+	svc := &NotificationService{
+		Templates:   &stubTemplateRepoScoped{},
+		Preferences: &stubPrefRepoStatic{},
+		Logs:        &stubLogRepo{},
+		Inbox:       inbox,
+		OrgUsers:    &stubOrgUsers{all: []int64{1, 2}},
+		OrgSettings: &stubOrgSettings{st: &OrgSettings{OrganizationID: 1, EmailNotifications: true, VulnerabilityAlerts: true, WeeklyReports: true, UserActivityAlerts: true, Locale: "ja"}},
+		Locales:     &stubLocales{byUser: map[int64]string{1: "vi"}},
+		Email:       &stubEmail{},
+		Slack:       &stubSlack{},
+		Webhook:     &stubWebhook{},
+		Renderer:    templates.Renderer{},
+	}
+
+	evt := NotificationEvent{EventType: "custom.event", OrganizationID: 1, Payload: map[string]interface{}{}}
+	if err := svc.HandleEvent(context.Background(), evt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(inbox.saved) != 2 {
+		t.Fatalf("expected 2 inbox saves, got %d", len(inbox.saved))
+	}
+	titles := map[int64]string{}
+	for _, n := range inbox.saved {
+		titles[n.UserID] = n.Title
+	}
+	if titles[1] != "Cập nhật từ MyESI" {
+		t.Fatalf("expected vi title for user 1, got %q", titles[1])
+	}
+	if titles[2] != builtinTemplates["ja"][genericEvent].Subject {
+		t.Fatalf("expected org ja title for user 2, got %q", titles[2])
+	}
+}
+
+type recordingEmail struct{ subjects map[string]string }
+
+func (r *recordingEmail) SendEmail(ctx Context, to []string, subject, body string) error {
+	for _, addr := range to {
+		r.subjects[addr] = subject
+	}
+	return nil
+}
+
+func TestOutbound_RendersPerTargetUserLocale(t *testing.T) {
+	vi, en := int64(1), int64(2)
+	email := &recordingEmail{subjects: map[string]string{}}
+	svc := &NotificationService{
+		Templates: &stubTemplateRepoScoped{},
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{OrganizationID: 3, UserID: &vi, EventType: "*", Channel: ChannelEmail, Target: "vi@x.io", Enabled: true},
+			{OrganizationID: 3, UserID: &en, EventType: "*", Channel: ChannelEmail, Target: "en@x.io", Enabled: true},
+		}},
+		Logs:     &stubLogRepo{},
+		Locales:  &stubLocales{byUser: map[int64]string{vi: "vi"}},
+		Email:    email,
+		Renderer: templates.Renderer{},
+	}
+
+	evt := NotificationEvent{EventType: "vulnerability.assignment", OrganizationID: 3, Payload: map[string]interface{}{}}
+	if err := svc.HandleEvent(context.Background(), evt); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if email.subjects["vi@x.io"] != "Bạn được giao một lỗ hổng mới" || email.subjects["en@x.io"] != "New vulnerability assigned to you" {
+		t.Fatalf("expected per-user subjects, got %v", email.subjects)
+	}
+}
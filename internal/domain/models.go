@@ -2,13 +2,45 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrNotFound is returned by repositories when a scoped record does not exist.
+var ErrNotFound = errors.New("not found")
+
 const (
 	ChannelEmail   = "email"
 	ChannelSlack   = "slack"
 	ChannelWebhook = "webhook"
+	ChannelTeams   = "teams"
+	// ChannelInbox selects layouts and partials for in-app notifications. Inbox
+	// items are stored for every recipient unless an inbox preference opts out;
+	// an inbox preference can also set a digest cadence.
+	ChannelInbox = "inbox"
+)
+
+// Digest cadences. Events matching a preference with a periodic cadence are
+// buffered and delivered as one summary per recipient.
+const (
+	DigestImmediate = "immediate"
+	DigestHourly    = "hourly"
+	DigestDaily     = "daily"
+	DigestWeekly    = "weekly"
+)
+
+// Template body formats. Markdown bodies are converted for each channel.
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+)
+
+// InboxFormatHTML marks inbox messages stored as sanitized HTML.
+const InboxFormatHTML = "html"
+
+const (
+	PartialKindPartial = "partial"
+	PartialKindLayout  = "layout"
 )
 
 // NotificationEvent represents an inbound domain event (usually from Kafka).
@@ -22,19 +54,51 @@ type NotificationEvent struct {
 	WebhookURL     string                 `json:"webhook_url,omitempty"`
 	Payload        map[string]interface{} `json:"payload,omitempty"`
 	OccurredAt     time.Time              `json:"occurred_at"`
+	// DeliverAt or Delay (a Go duration such as "48h") hold the event back;
+	// ScheduleKey lets the producer replace or cancel it while pending.
+	DeliverAt   *time.Time `json:"deliver_at,omitempty"`
+	Delay       string     `json:"delay,omitempty"`
+	ScheduleKey string     `json:"schedule_key,omitempty"`
+	// CorrelationKey ties an event to an alert lifecycle; a "*.resolved"
+	// event with the same key resolves the alert.
+	CorrelationKey string `json:"correlation_key,omitempty"`
+	// ActorID is the user who triggered the event, if any.
+	ActorID *int64 `json:"actor_id,omitempty"`
 }
 
 // NotificationTemplate is the rendering blueprint for outbound messages.
+// OrganizationID is nil for global defaults and set for org-specific overrides.
 type NotificationTemplate struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	EventType string    `json:"event_type"`
-	Channel   string    `json:"channel"`
-	Subject   string    `json:"subject"`
-	Body      string    `json:"body"`
-	IsDefault bool      `json:"is_default"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             int64     `json:"id"`
+	OrganizationID *int64    `json:"organization_id,omitempty"`
+	Name           string    `json:"name"`
+	EventType      string    `json:"event_type"`
+	Channel        string    `json:"channel"`
+	Locale         string    `json:"locale"`
+	Format         string    `json:"format"`
+	Subject        string    `json:"subject"`
+	Body           string    `json:"body"`
+	IsDefault      bool      `json:"is_default"`
+	Version        int       `json:"version"`
+	Author         string    `json:"author,omitempty"`
+	ChangeNote     string    `json:"change_note,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TemplateVersion is an immutable snapshot written on every template change.
+type TemplateVersion struct {
+	ID         int64     `json:"id"`
+	TemplateID int64     `json:"template_id"`
+	Version    int       `json:"version"`
+	Name       string    `json:"name"`
+	Format     string    `json:"format"`
+	Subject    string    `json:"subject"`
+	Body       string    `json:"body"`
+	IsDefault  bool      `json:"is_default"`
+	Author     string    `json:"author"`
+	ChangeNote string    `json:"change_note"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 // NotificationPreference allows orgs/users to customize routing.
@@ -47,6 +111,7 @@ type NotificationPreference struct {
 	Target         string    `json:"target"`
 	Enabled        bool      `json:"enabled"`
 	SeverityMin    string    `json:"severity_min"`
+	Digest         string    `json:"digest"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 }
@@ -66,18 +131,58 @@ type NotificationLog struct {
 }
 
 // DeliveryTarget is a resolved destination for an event.
+// UserID is set when the destination belongs to a single user.
 type DeliveryTarget struct {
 	Channel string
 	Target  string
+	Digest  string
+	UserID  *int64
+}
+
+// EffectivePreference explains one outbound destination considered for an
+// event: the layer it came from and, when it will not be used, why not.
+type EffectivePreference struct {
+	Channel      string `json:"channel"`
+	Target       string `json:"target,omitempty"`
+	Source       string `json:"source"`
+	PreferenceID int64  `json:"preference_id,omitempty"`
+	EventType    string `json:"event_type,omitempty"`
+	UserID       *int64 `json:"user_id,omitempty"`
+	Digest       string `json:"digest,omitempty"`
+	Active       bool   `json:"active"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// DigestItem is a rendered event waiting to be summarized in a digest. Items
+// with the same organization, user, channel, target and cadence are delivered
+// together once DueAt has passed.
+type DigestItem struct {
+	ID             int64                  `json:"id"`
+	OrganizationID int64                  `json:"organization_id"`
+	UserID         *int64                 `json:"user_id,omitempty"`
+	Channel        string                 `json:"channel"`
+	Target         string                 `json:"target"`
+	Cadence        string                 `json:"cadence"`
+	EventType      string                 `json:"event_type"`
+	Severity       string                 `json:"severity"`
+	Subject        string                 `json:"subject"`
+	Body           string                 `json:"body"`
+	Payload        map[string]interface{} `json:"payload,omitempty"`
+	OccurredAt     time.Time              `json:"occurred_at"`
+	DueAt          time.Time              `json:"due_at"`
+	Attempts       int                    `json:"attempts"`
 }
 
 // UserNotification represents a notification stored for in-app bell.
+// Message is plain text unless Format is InboxFormatHTML, in which case it is
+// sanitized HTML converted from a Markdown template.
 type UserNotification struct {
 	ID             int64                  `json:"id"`
 	UserID         int64                  `json:"user_id"`
 	OrganizationID int64                  `json:"organization_id"`
 	Title          string                 `json:"title"`
 	Message        string                 `json:"message"`
+	Format         string                 `json:"format"`
 	Type           string                 `json:"type"`
 	Severity       string                 `json:"severity"`
 	ActionURL      string                 `json:"action_url,omitempty"`
@@ -85,19 +190,497 @@ type UserNotification struct {
 	Payload        map[string]interface{} `json:"payload,omitempty"`
 	CreatedAt      time.Time              `json:"created_at"`
 	ReadAt         *time.Time             `json:"read_at,omitempty"`
+	CorrelationKey string                 `json:"correlation_key,omitempty"`
+	AlertState     string                 `json:"alert_state,omitempty"`
+	GroupCount     int                    `json:"group_count,omitempty"`
 }
 
 // TemplateRepository abstracts persistence for templates.
+// An orgID of 0 addresses global defaults only; FindByEventAndChannel matches an
+// exact locale tag and prefers the org override over the global default. Get
+// returns nil when the template is not visible to orgID.
 type TemplateRepository interface {
-	List(ctx Context, limit, offset int) ([]NotificationTemplate, error)
+	List(ctx Context, orgID int64, limit, offset int) ([]NotificationTemplate, error)
 	Upsert(ctx Context, tpl NotificationTemplate) (NotificationTemplate, error)
-	FindByEventAndChannel(ctx Context, eventType, channel string) (*NotificationTemplate, error)
+	FindByEventAndChannel(ctx Context, orgID int64, eventType, channel, locale string) (*NotificationTemplate, error)
+	Get(ctx Context, orgID, id int64) (*NotificationTemplate, error)
 }
 
-// PreferenceRepository abstracts persistence for preferences.
+// TemplateVersionRepository exposes template history. orgID scopes access the
+// same way as TemplateRepository.List (0 = global defaults).
+type TemplateVersionRepository interface {
+	ListVersions(ctx Context, orgID, templateID int64) ([]TemplateVersion, error)
+	GetVersion(ctx Context, orgID, templateID int64, version int) (*TemplateVersion, error)
+	Rollback(ctx Context, orgID, templateID int64, version int, author string) (NotificationTemplate, error)
+	// Sources returns the current content and stored versions containing
+	// text of orgID's templates and the global ones, or of every template
+	// when orgID is 0.
+	Sources(ctx Context, orgID int64, text string) ([]TemplateSource, error)
+}
+
+// TemplateSource is the content of a template or one of its stored versions.
+type TemplateSource struct {
+	TemplateID     int64  `json:"template_id"`
+	OrganizationID *int64 `json:"organization_id,omitempty"`
+	Channel        string `json:"channel"`
+	Version        int    `json:"version"`
+	Subject        string `json:"subject"`
+	Body           string `json:"body"`
+}
+
+// NotificationPartial is a reusable template fragment. Partials are invoked by
+// name ({{template "footer" .}}); a layout wraps a channel's rendered body via
+// {{template "content" .}}. An empty Channel applies to every channel, and org
+// rows override global ones of the same kind, name and channel.
+type NotificationPartial struct {
+	ID             int64     `json:"id"`
+	OrganizationID *int64    `json:"organization_id,omitempty"`
+	Name           string    `json:"name"`
+	Kind           string    `json:"kind"`
+	Channel        string    `json:"channel,omitempty"`
+	Body           string    `json:"body"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// PartialRepository persists partials and layouts. List returns the global rows
+// plus those of orgID (globals only when orgID is 0).
+type PartialRepository interface {
+	List(ctx Context, orgID int64) ([]NotificationPartial, error)
+	Upsert(ctx Context, p NotificationPartial) (NotificationPartial, error)
+	Delete(ctx Context, orgID, id int64) error
+}
+
+// PreferenceRepository abstracts persistence for preferences. Delete reports
+// ErrNotFound when the preference is not in the organization; Replace swaps
+// every row of one scope, org-wide when userID is nil, in one transaction.
+// ReplaceMatrix swaps only the user's rows for the given event types and
+// channels, also in one transaction, and leaves their other rows untouched.
 type PreferenceRepository interface {
 	List(ctx Context, orgID int64, userID *int64, eventType string) ([]NotificationPreference, error)
 	Save(ctx Context, pref NotificationPreference) (NotificationPreference, error)
+	Get(ctx Context, id int64) (*NotificationPreference, error)
+	Delete(ctx Context, orgID, id int64) error
+	Replace(ctx Context, orgID int64, userID *int64, prefs []NotificationPreference) ([]NotificationPreference, error)
+	ReplaceMatrix(ctx Context, orgID, userID int64, eventTypes, channels []string, prefs []NotificationPreference) ([]NotificationPreference, error)
+}
+
+// QuietHours is a daily window, in an IANA time zone, during which non-critical
+// outbound notifications are deferred. Start and End are "HH:MM"; a window that
+// ends before it starts crosses midnight. UserID is nil for the org-wide window.
+type QuietHours struct {
+	ID             int64     `json:"id"`
+	OrganizationID int64     `json:"organization_id"`
+	UserID         *int64    `json:"user_id,omitempty"`
+	TimeZone       string    `json:"time_zone"`
+	Start          string    `json:"start"`
+	End            string    `json:"end"`
+	DeferCritical  bool      `json:"defer_critical"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// QuietHoursRepository persists quiet hours. Find returns the user's own window
+// and falls back to the organization's; a nil userID looks up the org only.
+type QuietHoursRepository interface {
+	Find(ctx Context, orgID int64, userID *int64) (*QuietHours, error)
+	Save(ctx Context, q QuietHours) (QuietHours, error)
+	Delete(ctx Context, orgID int64, userID *int64) error
+}
+
+// ScheduledDelivery is a rendered notification held back until DeliverAt.
+type ScheduledDelivery struct {
+	ID        int64             `json:"id"`
+	Event     NotificationEvent `json:"event"`
+	Channel   string            `json:"channel"`
+	Target    string            `json:"target"`
+	Format    string            `json:"format"`
+	Subject   string            `json:"subject"`
+	Body      string            `json:"body"`
+	Reason    string            `json:"reason"`
+	DeliverAt time.Time         `json:"deliver_at"`
+	Attempts  int               `json:"attempts"`
+}
+
+// ScheduledDeliveryRepository stores deferred deliveries. ClaimDue leases up
+// to limit deliveries due at or before now until now+lease, so concurrent
+// callers never receive the same delivery and deliveries held by a worker
+// that dies are claimed again; Complete removes a handled delivery.
+type ScheduledDeliveryRepository interface {
+	Add(ctx Context, d ScheduledDelivery) error
+	ClaimDue(ctx Context, now time.Time, lease time.Duration, limit int) ([]ScheduledDelivery, error)
+	Complete(ctx Context, id int64) error
+}
+
+// ScheduledEvent is an event held until DeliverAt and then processed as if it
+// had just arrived.
+type ScheduledEvent struct {
+	ID             int64             `json:"id"`
+	OrganizationID int64             `json:"organization_id"`
+	Key            string            `json:"schedule_key,omitempty"`
+	Event          NotificationEvent `json:"event"`
+	DeliverAt      time.Time         `json:"deliver_at"`
+	Attempts       int               `json:"attempts"`
+}
+
+// ScheduledEventRepository stores delayed events. Schedule replaces a pending
+// event with the same organization and key. Claim leases due events until
+// now+lease, so events held by a worker that dies are claimed again; Complete
+// removes a processed event unless it was rescheduled in the meantime.
+type ScheduledEventRepository interface {
+	Schedule(ctx Context, e ScheduledEvent) (ScheduledEvent, error)
+	Claim(ctx Context, now time.Time, lease time.Duration, limit int) ([]ScheduledEvent, error)
+	Complete(ctx Context, id int64) error
+	Cancel(ctx Context, orgID int64, key string) (bool, error)
+}
+
+// Audience defines who an organization-wide event reaches when no user is
+// addressed. EventType is a glob ("payment.*"); the most specific matching
+// audience applies. Members are everyone (AllUsers) or the union of Roles,
+// UserIDs and, with ProjectMembers, the members of payload.project_id.
+// ExcludeActor leaves out the user who triggered the event. Audiences apply
+// to the inbox and to user-scoped outbound preferences.
+type Audience struct {
+	ID             int64     `json:"id"`
+	OrganizationID int64     `json:"organization_id"`
+	EventType      string    `json:"event_type"`
+	AllUsers       bool      `json:"all_users,omitempty"`
+	Roles          []string  `json:"roles,omitempty"`
+	UserIDs        []int64   `json:"user_ids,omitempty"`
+	ProjectMembers bool      `json:"project_members,omitempty"`
+	ExcludeActor   bool      `json:"exclude_actor,omitempty"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// AudienceRepository persists audiences, one per (organization, event type).
+type AudienceRepository interface {
+	List(ctx Context, orgID int64) ([]Audience, error)
+	Save(ctx Context, a Audience) (Audience, error)
+	Delete(ctx Context, orgID, id int64) error
+}
+
+// ProjectWatch is a user's subscription to a project. Owners watch their
+// projects automatically (Owner) until they unwatch them.
+type ProjectWatch struct {
+	OrganizationID int64     `json:"organization_id"`
+	ProjectID      int64     `json:"project_id"`
+	ProjectName    string    `json:"project_name,omitempty"`
+	UserID         int64     `json:"user_id"`
+	Owner          bool      `json:"owner"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// WatcherRepository stores project watches. Watch reports ErrNotFound when
+// the project does not belong to the organization. Watchers resolves a
+// project by id or, when projectID is 0, by name and lists its active
+// watchers, owners included.
+type WatcherRepository interface {
+	Watch(ctx Context, orgID, projectID, userID int64, watching bool) error
+	ListForUser(ctx Context, orgID, userID int64) ([]ProjectWatch, error)
+	Watchers(ctx Context, orgID, projectID int64, project string) ([]int64, error)
+}
+
+// Destination is an outbound target an organization's preferences point at.
+// Preferences only deliver to it once someone who controls it has confirmed
+// the code sent there.
+type Destination struct {
+	ID             int64      `json:"id"`
+	OrganizationID int64      `json:"organization_id"`
+	Channel        string     `json:"channel"`
+	Target         string     `json:"target"`
+	Status         string     `json:"status"`
+	CodeHash       string     `json:"-"`
+	Attempts       int        `json:"-"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	SentAt         *time.Time `json:"sent_at,omitempty"`
+	VerifiedAt     *time.Time `json:"verified_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// DestinationRepository stores destinations, one per organization, channel
+// and target. Get and Find return nil when there is none; Save upserts on
+// that key. UseAttempt counts a confirmation attempt against a pending
+// destination and returns its code hash, or reports false once max attempts
+// were used; Reissue replaces a pending destination's code without touching
+// its attempts or expiry and returns nil when there is none.
+type DestinationRepository interface {
+	List(ctx Context, orgID int64) ([]Destination, error)
+	Get(ctx Context, orgID, id int64) (*Destination, error)
+	Find(ctx Context, orgID int64, channel, target string) (*Destination, error)
+	Save(ctx Context, d Destination) (Destination, error)
+	UseAttempt(ctx Context, orgID, id int64, max int) (string, bool, error)
+	Reissue(ctx Context, orgID, id int64, codeHash string, sentAt time.Time) (*Destination, error)
+}
+
+// Snooze mutes a user's notifications until a time: everything, the event
+// types matching EventType, or the events of one project, named by id or,
+// for events that only carry it, by name.
+type Snooze struct {
+	ID             int64     `json:"id"`
+	OrganizationID int64     `json:"organization_id"`
+	UserID         int64     `json:"user_id"`
+	Scope          string    `json:"scope"`
+	EventType      string    `json:"event_type,omitempty"`
+	ProjectID      int64     `json:"project_id,omitempty"`
+	Project        string    `json:"project,omitempty"`
+	Until          time.Time `json:"until"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// SnoozeRepository stores snoozes. Active and ListForUser only return
+// snoozes that have not expired at now; Delete reports ErrNotFound for
+// snoozes the user does not own and DeleteExpired purges the rest.
+type SnoozeRepository interface {
+	Active(ctx Context, orgID int64, now time.Time) ([]Snooze, error)
+	ListForUser(ctx Context, orgID, userID int64, now time.Time) ([]Snooze, error)
+	Create(ctx Context, z Snooze) (Snooze, error)
+	Delete(ctx Context, orgID, userID, id int64) error
+	DeleteExpired(ctx Context, now time.Time) (int, error)
+}
+
+// RoutingRule is one entry of an organization's ordered routing table. A rule
+// matches events whose type matches the EventPattern glob (empty for any), at
+// or above SeverityMin and satisfying Condition, an expression over
+// event.* and payload.* such as `payload.critical_count > 3`. Matching stops
+// at the first matching rule unless it sets Continue.
+type RoutingRule struct {
+	ID             int64        `json:"id"`
+	OrganizationID int64        `json:"organization_id"`
+	Position       int          `json:"position"`
+	Name           string       `json:"name"`
+	EventPattern   string       `json:"event_pattern,omitempty"`
+	SeverityMin    string       `json:"severity_min,omitempty"`
+	Condition      string       `json:"condition,omitempty"`
+	Actions        RouteActions `json:"actions"`
+	Continue       bool         `json:"continue"`
+	Enabled        bool         `json:"enabled"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// RouteActions says what a matching rule does. Recipients replace the
+// event type's audience; Channels restricts delivery to the listed channels
+// (inbox included); Targets adds outbound destinations; Template renders the
+// event with another event type's templates; Drop discards the event.
+type RouteActions struct {
+	Drop     bool          `json:"drop,omitempty"`
+	UserIDs  []int64       `json:"user_ids,omitempty"`
+	Roles    []string      `json:"roles,omitempty"`
+	AllUsers bool          `json:"all_users,omitempty"`
+	Channels []string      `json:"channels,omitempty"`
+	Targets  []RouteTarget `json:"targets,omitempty"`
+	Template string        `json:"template,omitempty"`
+}
+
+// RouteTarget is an outbound destination added by a routing rule.
+type RouteTarget struct {
+	Channel string `json:"channel"`
+	Target  string `json:"target"`
+}
+
+// RoutingRuleRepository persists routing rules. List returns them in
+// evaluation order (position, then id).
+type RoutingRuleRepository interface {
+	List(ctx Context, orgID int64) ([]RoutingRule, error)
+	Save(ctx Context, r RoutingRule) (RoutingRule, error)
+	Delete(ctx Context, orgID, id int64) error
+}
+
+// GroupingRule collapses events of EventType (empty for any) that share the
+// GroupBy fields into one notification per Window seconds. Fields are
+// organization_id, event_type, severity, user_id or payload keys.
+type GroupingRule struct {
+	ID             int64     `json:"id"`
+	OrganizationID int64     `json:"organization_id"`
+	EventType      string    `json:"event_type,omitempty"`
+	GroupBy        []string  `json:"group_by"`
+	Window         int       `json:"window_seconds"`
+	Enabled        bool      `json:"enabled"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// NotificationGroup is an open group: the first event was delivered, later
+// ones are counted until WindowEnd. Event holds the latest event and Events
+// the ones counted after the first, up to MaxGroupEvents.
+type NotificationGroup struct {
+	ID             int64               `json:"id"`
+	OrganizationID int64               `json:"organization_id"`
+	RuleID         int64               `json:"rule_id"`
+	Key            string              `json:"group_key"`
+	Event          NotificationEvent   `json:"event"`
+	Events         []NotificationEvent `json:"events,omitempty"`
+	Count          int                 `json:"count"`
+	InboxIDs       []int64             `json:"inbox_ids,omitempty"`
+	FirstAt        time.Time           `json:"first_at"`
+	LastAt         time.Time           `json:"last_at"`
+	WindowEnd      time.Time           `json:"window_end"`
+}
+
+// GroupingRuleRepository persists grouping rules. Match prefers a rule for the
+// exact event type over a catch-all one and ignores disabled rules.
+type GroupingRuleRepository interface {
+	List(ctx Context, orgID int64) ([]GroupingRule, error)
+	Save(ctx Context, r GroupingRule) (GroupingRule, error)
+	Delete(ctx Context, orgID, id int64) error
+	Match(ctx Context, orgID int64, eventType string) (*GroupingRule, error)
+}
+
+// GroupRepository tracks open groups. Add opens a group for g.Key or counts
+// g.Event into the open one, reporting true when it opened a new group; the
+// group's inbox items get the new count and become unread again. Add reports
+// ErrGroupClosed when the group's window ended before it was claimed. ClaimDue
+// removes and returns groups whose window has ended.
+type GroupRepository interface {
+	Add(ctx Context, g NotificationGroup) (NotificationGroup, bool, error)
+	AttachInbox(ctx Context, id int64, inboxIDs []int64) error
+	ClaimDue(ctx Context, now time.Time, limit int) ([]NotificationGroup, error)
+}
+
+// RateLimit caps deliveries for an organization, optionally narrowed to one
+// channel and/or event type. Every destination gets its own token bucket that
+// holds up to Burst tokens and refills at PerMinute. Overflow decides what
+// happens to deliveries that find the bucket empty.
+type RateLimit struct {
+	ID             int64     `json:"id"`
+	OrganizationID int64     `json:"organization_id"`
+	Channel        string    `json:"channel,omitempty"`
+	EventType      string    `json:"event_type,omitempty"`
+	Burst          int       `json:"burst"`
+	PerMinute      float64   `json:"per_minute"`
+	Overflow       string    `json:"overflow"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// SuppressedBatch counts deliveries a summarizing rate limit dropped for one
+// destination since Since.
+type SuppressedBatch struct {
+	OrganizationID int64     `json:"organization_id"`
+	Channel        string    `json:"channel"`
+	Target         string    `json:"target,omitempty"`
+	UserID         *int64    `json:"user_id,omitempty"`
+	EventType      string    `json:"event_type"`
+	Count          int       `json:"count"`
+	Since          time.Time `json:"since"`
+}
+
+// RateLimitRepository stores rate limits and their buckets. Match returns the
+// most specific limit for a delivery: channel and event type, then channel,
+// then event type, then org-wide. Take spends a token from the target's
+// bucket as RateLimit.Take describes and counts summarized overflow;
+// ClaimSuppressed resets and returns the counts of buckets that have refilled.
+type RateLimitRepository interface {
+	List(ctx Context, orgID int64) ([]RateLimit, error)
+	Save(ctx Context, l RateLimit) (RateLimit, error)
+	Delete(ctx Context, orgID, id int64) error
+	Match(ctx Context, orgID int64, channel, eventType string) (*RateLimit, error)
+	Take(ctx Context, l RateLimit, target DeliveryTarget, eventType string, now time.Time) (time.Duration, bool, error)
+	ClaimSuppressed(ctx Context, now time.Time, limit int) ([]SuppressedBatch, error)
+}
+
+// Alert lifecycle states.
+const (
+	AlertOpen         = "open"
+	AlertAcknowledged = "acknowledged"
+	AlertResolved     = "resolved"
+)
+
+// Alert follows events sharing a correlation key through open, acknowledged
+// and resolved.
+type Alert struct {
+	ID             int64      `json:"id"`
+	OrganizationID int64      `json:"organization_id"`
+	CorrelationKey string     `json:"correlation_key"`
+	EventType      string     `json:"event_type"`
+	Severity       string     `json:"severity,omitempty"`
+	Summary        string     `json:"summary"`
+	State          string     `json:"state"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy     string     `json:"resolved_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// AlertRepository persists alerts, one per organization and correlation key.
+// Open creates the alert or reopens a resolved one and leaves open and
+// acknowledged alerts as they are. Transition moves an alert to state when it
+// is currently in one of from, copies the state to the alert's inbox items,
+// acknowledges its escalations and returns nil when nothing matched.
+type AlertRepository interface {
+	Open(ctx Context, a Alert) (Alert, error)
+	Get(ctx Context, orgID int64, key string) (*Alert, error)
+	List(ctx Context, orgID int64, state string, limit, offset int) ([]Alert, error)
+	Transition(ctx Context, orgID int64, key, state, by string, from []string) (*Alert, error)
+}
+
+// EscalationTier is one step of an escalation policy. It fires AfterMinutes
+// after the previous step (or the original alert) and either notifies every
+// user with Role in the inbox or sends to Channel and Target. An email tier
+// without a target goes to OrgSettings.AdminEmail.
+type EscalationTier struct {
+	AfterMinutes int    `json:"after_minutes"`
+	Role         string `json:"role,omitempty"`
+	Channel      string `json:"channel,omitempty"`
+	Target       string `json:"target,omitempty"`
+}
+
+// EscalationPolicy escalates unacknowledged alerts of EventType at or above
+// SeverityMin through Tiers.
+type EscalationPolicy struct {
+	ID             int64            `json:"id"`
+	OrganizationID int64            `json:"organization_id"`
+	EventType      string           `json:"event_type"`
+	SeverityMin    string           `json:"severity_min"`
+	Tiers          []EscalationTier `json:"tiers"`
+	Enabled        bool             `json:"enabled"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
+
+// Escalation tracks one alert through its policy. NextTier indexes the tier
+// due at NextAt; a nil NextAt means the chain has ended. Reading any of the
+// InboxIDs acknowledges the alert.
+type Escalation struct {
+	ID             int64             `json:"id"`
+	OrganizationID int64             `json:"organization_id"`
+	PolicyID       int64             `json:"policy_id"`
+	Event          NotificationEvent `json:"event"`
+	Summary        string            `json:"summary"`
+	InboxIDs       []int64           `json:"inbox_ids,omitempty"`
+	NextTier       int               `json:"next_tier"`
+	NextAt         *time.Time        `json:"next_at,omitempty"`
+	AcknowledgedAt *time.Time        `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy string            `json:"acknowledged_by,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+// EscalationPolicyRepository persists escalation policies, one per
+// organization and event type. FindForEvent only returns enabled policies.
+type EscalationPolicyRepository interface {
+	List(ctx Context, orgID int64) ([]EscalationPolicy, error)
+	Get(ctx Context, orgID, id int64) (*EscalationPolicy, error)
+	FindForEvent(ctx Context, orgID int64, eventType string) (*EscalationPolicy, error)
+	Save(ctx Context, p EscalationPolicy) (EscalationPolicy, error)
+	Delete(ctx Context, orgID, id int64) error
+}
+
+// EscalationRepository tracks running escalations. ClaimDue first closes
+// escalations whose inbox items were read, then leases due ones until
+// now+lease; Advance records the next step (nil nextAt ends the chain) and
+// appends inbox items created by the step.
+type EscalationRepository interface {
+	Start(ctx Context, e Escalation) (Escalation, error)
+	ClaimDue(ctx Context, now time.Time, lease time.Duration, limit int) ([]Escalation, error)
+	Advance(ctx Context, id int64, tier int, nextAt *time.Time, inboxIDs []int64) error
+	Acknowledge(ctx Context, orgID, id int64, by string) (bool, error)
+	ListOpen(ctx Context, orgID int64) ([]Escalation, error)
+}
+
+// DigestRepository buffers events for digests. ClaimDue leases until
+// now+lease every item due at or before now for up to limit recipients, so
+// concurrent callers never receive the same item and items held by a worker
+// that dies are claimed again; Complete removes delivered items.
+type DigestRepository interface {
+	Add(ctx Context, item DigestItem) error
+	ClaimDue(ctx Context, now time.Time, lease time.Duration, limit int) ([]DigestItem, error)
+	Complete(ctx Context, ids []int64) error
 }
 
 // LogRepository abstracts auditing persistence.
@@ -121,6 +704,16 @@ type OrgUserRepository interface {
 	ListUserIDsByOrgWithRole(ctx Context, orgID int64, role string) ([]int64, error)
 }
 
+// ProjectMemberRepository resolves the active members of a project.
+type ProjectMemberRepository interface {
+	ListUserIDsByProject(ctx Context, orgID, projectID int64) ([]int64, error)
+}
+
+// LocaleRepository resolves the preferred locale stored on user profiles.
+type LocaleRepository interface {
+	UserLocales(ctx Context, userIDs []int64) (map[int64]string, error)
+}
+
 // OrgSettingsRepository exposes organization-level notification toggles.
 type OrgSettingsRepository interface {
 	Get(ctx Context, orgID int64) (*OrgSettings, error)
@@ -134,6 +727,7 @@ type OrgSettings struct {
 	WeeklyReports       bool   `json:"weekly_reports"`
 	UserActivityAlerts  bool   `json:"user_activity_alerts"`
 	AdminEmail          string `json:"admin_email"`
+	Locale              string `json:"locale"`
 }
 
 // EmailProvider dispatches email notifications.
@@ -141,11 +735,22 @@ type EmailProvider interface {
 	SendEmail(ctx Context, to []string, subject string, body string) error
 }
 
+// HTMLEmailProvider is implemented by email providers that can send a
+// multipart message with an HTML part and a plain-text alternative.
+type HTMLEmailProvider interface {
+	SendHTMLEmail(ctx Context, to []string, subject, text, html string) error
+}
+
 // SlackProvider dispatches Slack messages via webhook.
 type SlackProvider interface {
 	SendSlackMessage(ctx Context, webhookURL string, text string) error
 }
 
+// TeamsProvider dispatches Microsoft Teams messages via incoming webhook.
+type TeamsProvider interface {
+	SendTeamsMessage(ctx Context, webhookURL string, text string) error
+}
+
 // WebhookProvider dispatches generic webhooks.
 type WebhookProvider interface {
 	SendWebhook(ctx Context, url string, payload any) error
@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"context"
+	"log"
+
+	"myesi-notification-service/internal/templates"
+)
+
+// PartialChannels are the channels a layout or channel-specific partial can target.
+var PartialChannels = []string{ChannelEmail, ChannelSlack, ChannelTeams, ChannelWebhook, ChannelInbox}
+
+// PartialSet assembles the partials and layout used to render for channel.
+// For each name the most specific row wins: an org row beats a global one, and
+// within the same owner a channel-specific row beats a shared one.
+func PartialSet(parts []NotificationPartial, channel string) templates.Set {
+	type pick struct {
+		body string
+		rank int
+	}
+	partials := map[string]pick{}
+	var layout pick
+	hasLayout := false
+
+	for _, p := range parts {
+		if p.Channel != "" && p.Channel != channel {
+			continue
+		}
+		rank := 0
+		if p.OrganizationID != nil {
+			rank += 2
+		}
+		if p.Channel != "" {
+			rank++
+		}
+		switch p.Kind {
+		case PartialKindLayout:
+			if !hasLayout || rank > layout.rank {
+				layout, hasLayout = pick{body: p.Body, rank: rank}, true
+			}
+		default:
+			if cur, ok := partials[p.Name]; !ok || rank > cur.rank {
+				partials[p.Name] = pick{body: p.Body, rank: rank}
+			}
+		}
+	}
+
+	set := templates.Set{Layout: layout.body}
+	if len(partials) > 0 {
+		set.Partials = make(map[string]string, len(partials))
+		for name, p := range partials {
+			set.Partials[name] = p.body
+		}
+	}
+	return set
+}
+
+// loadPartials fetches the partials visible to an organization once per event.
+func (s *NotificationService) loadPartials(ctx context.Context, orgID int64) []NotificationPartial {
+	if s.Partials == nil {
+		return nil
+	}
+	parts, err := s.Partials.List(ctx, orgID)
+	if err != nil {
+		log.Printf("[NOTIFY] partial lookup failed: %v", err)
+		return nil
+	}
+	return parts
+}
@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"myesi-notification-service/internal/templates"
+)
+
+type stubPartials struct{ parts []NotificationPartial }
+
+func (s *stubPartials) List(ctx Context, orgID int64) ([]NotificationPartial, error) {
+	return s.parts, nil
+}
+func (s *stubPartials) Upsert(ctx Context, p NotificationPartial) (NotificationPartial, error) {
+	return p, nil
+}
+func (s *stubPartials) Delete(ctx Context, orgID, id int64) error { return nil }
+
+func TestPartialSet_MostSpecificWins(t *testing.T) {
+	org := int64(1)
+	parts := []NotificationPartial{
+		{Name: "footer", Kind: PartialKindPartial, Body: "global"},
+		{Name: "footer", Kind: PartialKindPartial, Channel: ChannelSlack, Body: "global slack"},
+		{OrganizationID: &org, Name: "footer", Kind: PartialKindPartial, Body: "org"},
+		{Name: "layout", Kind: PartialKindLayout, Channel: ChannelEmail, Body: "email {{template \"content\" .}}"},
+	}
+
+	email := PartialSet(parts, ChannelEmail)
+	if email.Partials["footer"] != "org" {
+		t.Fatalf("expected org footer for email, got %q", email.Partials["footer"])
+	}
+	if email.Layout == "" {
+		t.Fatalf("expected email layout")
+	}
+
+	slack := PartialSet(parts, ChannelSlack)
+	if slack.Partials["footer"] != "org" || slack.Layout != "" {
+		t.Fatalf("unexpected slack set %#v", slack)
+	}
+	if got := PartialSet(parts[:2], ChannelSlack).Partials["footer"]; got != "global slack" {
+		t.Fatalf("expected channel-specific footer, got %q", got)
+	}
+}
+
+func TestHandleEvent_AppliesChannelLayout(t *testing.T) {
+	email := &stubEmail{}
+	uid := int64(9)
+	svc := &NotificationService{
+		Templates: &stubTemplateRepoAlways{tpl: NotificationTemplate{Subject: "S {{template \"brand\" .}}", Body: "Body"}},
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{OrganizationID: 1, EventType: "custom.event", Channel: ChannelEmail, Target: "a@x.com", Enabled: true},
+		}},
+		Logs:    &stubLogRepo{},
+		Email:   email,
+		Slack:   &stubSlack{},
+		Webhook: &stubWebhook{},
+		Partials: &stubPartials{parts: []NotificationPartial{
+			{Name: "brand", Kind: PartialKindPartial, Body: "MyESI"},
+			{Name: "layout", Kind: PartialKindLayout, Channel: ChannelEmail, Body: "<{{template \"content\" .}}> {{template \"brand\" .}}"},
+		}},
+		Renderer: templates.Renderer{},
+	}
+
+	evt := NotificationEvent{EventType: "custom.event", OrganizationID: 1, UserID: &uid, Payload: map[string]interface{}{}}
+	if err := svc.HandleEvent(context.Background(), evt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if email.subject != "S MyESI" {
+		t.Fatalf("expected subject rendered with partial and no layout, got %q", email.subject)
+	}
+	if email.body != "<Body> MyESI" {
+		t.Fatalf("expected body wrapped in layout, got %q", email.body)
+	}
+}
+
+func TestRenderTemplate_BrokenLayoutFallsBackToTemplate(t *testing.T) {
+	svc := &NotificationService{Renderer: templates.Renderer{}}
+	set := templates.Set{Layout: "no content placeholder"}
+
+	_, body := svc.renderTemplate(NotificationTemplate{Body: "Hello {{.name}}"}, set, map[string]interface{}{"name": "x"})
+	if body != "Hello x" {
+		t.Fatalf("expected template rendered without layout, got %q", body)
+	}
+}
+
+func TestRenderTemplate_MissingPartialFallsBackToBuiltin(t *testing.T) {
+	svc := &NotificationService{Renderer: templates.Renderer{}}
+	data := BuildTemplateData(NotificationEvent{EventType: "custom.event"})
+
+	subject, body := svc.renderTemplate(NotificationTemplate{EventType: "custom.event", Subject: "Hi",
+		Body: `Hello {{template "footer" .}}`}, templates.Set{}, data)
+	if subject != "Hi" || body != "You have a new update: custom.event." {
+		t.Fatalf("expected the built-in body instead of raw markup, got %q %q", subject, body)
+	}
+}
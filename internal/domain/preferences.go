@@ -0,0 +1,317 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Preference event types are exact ("vulnerability.critical") or patterns:
+// "*" for everything, "vulnerability.*" for everything below a prefix and
+// "*.failed" for everything ending in a suffix. The most specific pattern
+// wins: exact, then the longer literal part, prefixes before suffixes.
+
+// ValidEventPattern reports whether p is an exact event type or a supported
+// pattern.
+func ValidEventPattern(p string) bool {
+	if p == "*" {
+		return true
+	}
+	literal := p
+	switch {
+	case strings.HasSuffix(p, ".*"):
+		literal = strings.TrimSuffix(p, ".*")
+	case strings.HasPrefix(p, "*."):
+		literal = strings.TrimPrefix(p, "*.")
+	}
+	if literal == "" || strings.Contains(literal, "*") {
+		return false
+	}
+	for _, seg := range strings.Split(literal, ".") {
+		if seg == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks the event type, channel, target, severity floor and digest
+// cadence. Disabled rows are opt-outs and need no target.
+func (p NotificationPreference) Validate() error {
+	if !ValidEventPattern(p.EventType) {
+		return errors.New("event_type must be an event type or a pattern such as vulnerability.*, *.failed or *")
+	}
+	if !validChannel(p.Channel) {
+		return fmt.Errorf("unknown channel %q", p.Channel)
+	}
+	if !ValidSeverity(p.SeverityMin) {
+		return errors.New("severity_min must be low, medium, high or critical")
+	}
+	if !ValidDigest(p.Digest) {
+		return errors.New("digest must be immediate, hourly, daily or weekly")
+	}
+	if strings.TrimSpace(p.Target) == "" {
+		if p.Enabled && p.Channel != ChannelInbox {
+			return errors.New("target is required")
+		}
+		return nil
+	}
+	return ValidateTarget(p.Channel, p.Target)
+}
+
+// ValidateTarget checks a destination for channel: a comma-separated list of
+// addresses for email and an https URL for slack, teams and webhooks. Inbox
+// preferences carry no target.
+func ValidateTarget(channel, target string) error {
+	switch channel {
+	case ChannelEmail:
+		addrs := filterNonEmpty(strings.Split(target, ","))
+		if len(addrs) == 0 {
+			return errors.New("target is required")
+		}
+		for _, a := range addrs {
+			parsed, err := mail.ParseAddress(a)
+			if err != nil || parsed.Address != a {
+				return fmt.Errorf("invalid email address %q", a)
+			}
+		}
+	case ChannelSlack, ChannelTeams, ChannelWebhook:
+		u, err := url.Parse(strings.TrimSpace(target))
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("%s target must be an https URL", channel)
+		}
+	case ChannelInbox:
+		return errors.New("inbox preferences take no target")
+	}
+	return nil
+}
+
+// EventPatternMatches reports whether a preference event type applies to
+// eventType.
+func EventPatternMatches(pattern, eventType string) bool {
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasSuffix(pattern, ".*"):
+		return strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*"))
+	case strings.HasPrefix(pattern, "*."):
+		return strings.HasSuffix(eventType, strings.TrimPrefix(pattern, "*"))
+	}
+	return pattern == eventType
+}
+
+// EventPatternCandidates lists every pattern that can match eventType so a
+// lookup can use an equality index instead of scanning patterns.
+func EventPatternCandidates(eventType string) []string {
+	segs := strings.Split(eventType, ".")
+	out := make([]string, 0, 2*len(segs))
+	out = append(out, eventType, "*")
+	for k := 1; k < len(segs); k++ {
+		out = append(out, strings.Join(segs[:k], ".")+".*", "*."+strings.Join(segs[k:], "."))
+	}
+	return out
+}
+
+// eventPatternSpecificity ranks patterns: exact types above any pattern,
+// then by the number of literal segments, prefixes above suffixes.
+func eventPatternSpecificity(pattern string) int {
+	switch {
+	case pattern == "*":
+		return 0
+	case strings.HasSuffix(pattern, ".*"):
+		return 2*strings.Count(pattern, ".") + 1
+	case strings.HasPrefix(pattern, "*."):
+		return 2 * strings.Count(pattern, ".")
+	}
+	return 1 << 20
+}
+
+// mostSpecific keeps, for each scope (org-wide or a user) and channel, the
+// rows whose event type is the most specific match for eventType. Rows of
+// equal specificity, such as two targets for one channel, are all kept.
+func mostSpecific(prefs []NotificationPreference, eventType string) []NotificationPreference {
+	type scope struct {
+		user    int64
+		channel string
+	}
+	best := map[scope]int{}
+	for _, p := range prefs {
+		if !EventPatternMatches(p.EventType, eventType) {
+			continue
+		}
+		k := scope{channel: p.Channel}
+		if p.UserID != nil {
+			k.user = *p.UserID
+		}
+		if rank, ok := best[k]; !ok || eventPatternSpecificity(p.EventType) > rank {
+			best[k] = eventPatternSpecificity(p.EventType)
+		}
+	}
+	out := make([]NotificationPreference, 0, len(prefs))
+	for _, p := range prefs {
+		if !EventPatternMatches(p.EventType, eventType) {
+			continue
+		}
+		k := scope{channel: p.Channel}
+		if p.UserID != nil {
+			k.user = *p.UserID
+		}
+		if eventPatternSpecificity(p.EventType) == best[k] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Preference sources in precedence order. For each channel the event user's
+// own rows override the organization's rows, which override the targets the
+// event carries, which override the service defaults.
+const (
+	SourceUser    = "user"
+	SourceOrg     = "org"
+	SourceEvent   = "event"
+	SourceDefault = "default"
+)
+
+// EffectivePreferences explains which outbound destinations evt reaches
+// before routing rules and audiences apply, and where each one comes from.
+func (s *NotificationService) EffectivePreferences(ctx context.Context, evt NotificationEvent) ([]EffectivePreference, error) {
+	var settings *OrgSettings
+	if s.OrgSettings != nil && evt.OrganizationID != 0 {
+		if st, err := s.OrgSettings.Get(ctx, evt.OrganizationID); err == nil {
+			settings = st
+		}
+	}
+	prefs, err := s.Preferences.List(ctx, evt.OrganizationID, evt.UserID, evt.EventType)
+	if err != nil {
+		return nil, err
+	}
+	out := s.effectivePreferences(prefs, evt, settings, s.verifiedDestinations(ctx, evt.OrganizationID),
+		s.activeSnoozes(ctx, evt.OrganizationID))
+	if !eventEnabled(evt.EventType, settings) {
+		for i := range out {
+			out[i].Active, out[i].Reason = false, "event type disabled for organization"
+		}
+	}
+	return out, nil
+}
+
+// effectivePreferences applies the precedence model to the preference rows
+// for evt. The highest layer with a row for a channel decides that channel
+// even when its rows are disabled, which is how a row opts out of the layers
+// below. Rows of users other than the event's are their own subscriptions
+// and are kept alongside. When verified is not nil, rows only deliver to
+// targets in it, address by address for email lists, and each pending
+// address is listed on its own; event and default targets are trusted. User rows, and the
+// event's own targets when it is for a user, are held back while that user
+// snoozed the event.
+func (s *NotificationService) effectivePreferences(prefs []NotificationPreference, evt NotificationEvent, settings *OrgSettings, verified map[string]bool, snoozes snoozeSet) []EffectivePreference {
+	var eventUser int64
+	if evt.UserID != nil {
+		eventUser = *evt.UserID
+	}
+	rows := mostSpecific(prefs, evt.EventType)
+	userChannels, orgChannels := map[string]bool{}, map[string]bool{}
+	for _, p := range rows {
+		switch {
+		case p.UserID == nil:
+			orgChannels[p.Channel] = true
+		case eventUser != 0 && *p.UserID == eventUser:
+			userChannels[p.Channel] = true
+		}
+	}
+	emailOff := settings != nil && !settings.EmailNotifications
+
+	out := make([]EffectivePreference, 0, len(rows)+3)
+	for _, p := range rows {
+		// Inbox preferences are applied when the inbox item is saved; see
+		// inboxPreferences.
+		if p.Channel == ChannelInbox {
+			continue
+		}
+		e := EffectivePreference{Channel: p.Channel, Target: p.Target, Source: SourceOrg, PreferenceID: p.ID,
+			EventType: p.EventType, UserID: p.UserID, Digest: p.Digest}
+		var snoozedUntil time.Time
+		if p.UserID != nil {
+			e.Source = SourceUser
+			snoozedUntil = snoozes.until(*p.UserID, evt)
+		}
+		var confirmed, pending []string
+		if verified != nil && NeedsVerification(p.Channel) {
+			confirmed, pending = splitVerified(verified, p.Channel, p.Target)
+		}
+		switch {
+		case p.UserID == nil && userChannels[p.Channel]:
+			e.Reason = "overridden by user preference"
+		case !p.Enabled:
+			e.Reason = "opted out"
+		case !shouldSendForSeverity(p.SeverityMin, evt.Severity):
+			e.Reason = "below severity_min " + p.SeverityMin
+		case p.Channel == ChannelEmail && emailOff:
+			e.Reason = "email notifications disabled for organization"
+		case verified != nil && NeedsVerification(p.Channel) && len(confirmed) == 0:
+			e.Reason = "destination pending verification"
+		case !snoozedUntil.IsZero():
+			e.Reason = snoozeReason(snoozedUntil)
+		default:
+			e.Active = true
+		}
+		if !e.Active || len(pending) == 0 {
+			out = append(out, e)
+			continue
+		}
+		e.Target = strings.Join(confirmed, ",")
+		out = append(out, e)
+		for _, addr := range pending {
+			held := e
+			held.Target, held.Active, held.Reason = addr, false, "destination pending verification"
+			out = append(out, held)
+		}
+	}
+	var eventUserSnoozed time.Time
+	if eventUser != 0 {
+		eventUserSnoozed = snoozes.until(eventUser, evt)
+	}
+	for _, e := range s.fallbackTargets(evt) {
+		switch {
+		case userChannels[e.Channel]:
+			e.Reason = "overridden by user preference"
+		case orgChannels[e.Channel]:
+			e.Reason = "overridden by organization preference"
+		case e.Channel == ChannelEmail && emailOff:
+			e.Reason = "email notifications disabled for organization"
+		case e.Source == SourceEvent && !eventUserSnoozed.IsZero():
+			e.Reason = snoozeReason(eventUserSnoozed)
+		default:
+			e.Active = true
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func snoozeReason(until time.Time) string {
+	return "snoozed until " + until.UTC().Format(time.RFC3339)
+}
+
+// fallbackTargets returns the event's own targets per channel, or the service
+// defaults where the event carries none.
+func (s *NotificationService) fallbackTargets(evt NotificationEvent) []EffectivePreference {
+	var out []EffectivePreference
+	add := func(channel, fromEvent, fromDefaults string) {
+		switch {
+		case fromEvent != "":
+			out = append(out, EffectivePreference{Channel: channel, Target: fromEvent, Source: SourceEvent})
+		case fromDefaults != "":
+			out = append(out, EffectivePreference{Channel: channel, Target: fromDefaults, Source: SourceDefault})
+		}
+	}
+	add(ChannelEmail, strings.Join(evt.TargetEmails, ","), strings.Join(s.Defaults.Emails, ","))
+	add(ChannelSlack, evt.SlackWebhook, s.Defaults.SlackWebhook)
+	add(ChannelWebhook, evt.WebhookURL, s.Defaults.WebhookURL)
+	return out
+}
@@ -0,0 +1,221 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"myesi-notification-service/internal/templates"
+)
+
+func TestEventPatterns(t *testing.T) {
+	for p, want := range map[string]bool{
+		"*": true, "vulnerability.*": true, "*.failed": true, "project.scan.*": true, "payment.success": true,
+		"": false, "vuln*": false, "*.*": false, "a.*.b": false, "a..b": false, ".*": false,
+	} {
+		if got := ValidEventPattern(p); got != want {
+			t.Fatalf("ValidEventPattern(%q) = %v", p, got)
+		}
+	}
+	for _, c := range []struct {
+		pattern, eventType string
+		want               bool
+	}{
+		{"vulnerability.*", "vulnerability.critical", true},
+		{"vulnerability.*", "vulnerability", false},
+		{"vulnerability.*", "vulnerabilityx.critical", false},
+		{"*.failed", "project.scan.failed", true},
+		{"*.failed", "failed", false},
+		{"*", "anything", true},
+	} {
+		if got := EventPatternMatches(c.pattern, c.eventType); got != c.want {
+			t.Fatalf("EventPatternMatches(%q, %q) = %v", c.pattern, c.eventType, got)
+		}
+		if c.want {
+			found := false
+			for _, cand := range EventPatternCandidates(c.eventType) {
+				found = found || cand == c.pattern
+			}
+			if !found {
+				t.Fatalf("candidates for %q miss %q", c.eventType, c.pattern)
+			}
+		}
+	}
+}
+
+func TestMostSpecificPerScopeAndChannel(t *testing.T) {
+	uid := int64(4)
+	prefs := []NotificationPreference{
+		{ID: 1, EventType: "*", Channel: ChannelSlack},
+		{ID: 2, EventType: "project.*", Channel: ChannelSlack},
+		{ID: 3, EventType: "*.scan.failed", Channel: ChannelSlack},
+		{ID: 4, EventType: "project.scan.*", Channel: ChannelSlack, UserID: &uid},
+		{ID: 5, EventType: "project.scan.failed", Channel: ChannelSlack, UserID: &uid},
+		{ID: 6, EventType: "*", Channel: ChannelEmail},
+		{ID: 7, EventType: "*", Channel: ChannelEmail},
+		{ID: 8, EventType: "payment.*", Channel: ChannelWebhook},
+	}
+	var ids []int64
+	for _, p := range mostSpecific(prefs, "project.scan.failed") {
+		ids = append(ids, p.ID)
+	}
+	want := []int64{3, 5, 6, 7}
+	if len(ids) != len(want) {
+		t.Fatalf("expected %v got %v", want, ids)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("expected %v got %v", want, ids)
+		}
+	}
+}
+
+func TestHandleEvent_SpecificPreferenceOverridesWildcard(t *testing.T) {
+	slack := &stubSlack{}
+	svc := &NotificationService{
+		Templates: &stubTemplateRepo{},
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{OrganizationID: 1, EventType: "vulnerability.*", Channel: ChannelSlack, Target: "https://hooks/all", Enabled: true},
+			{OrganizationID: 1, EventType: "vulnerability.low", Channel: ChannelSlack, Target: "https://hooks/all", Enabled: false},
+		}},
+		Logs:     &stubLogRepo{},
+		Slack:    slack,
+		Renderer: templates.Renderer{},
+	}
+
+	_ = svc.HandleEvent(context.Background(), NotificationEvent{EventType: "vulnerability.low", OrganizationID: 1})
+	if slack.url != "" {
+		t.Fatalf("disabled exact preference should silence the wildcard, sent to %q", slack.url)
+	}
+	_ = svc.HandleEvent(context.Background(), NotificationEvent{EventType: "vulnerability.critical", OrganizationID: 1})
+	if slack.url != "https://hooks/all" {
+		t.Fatalf("wildcard preference should apply, got %q", slack.url)
+	}
+}
+
+func TestEffectivePreferences_PrecedenceMatrix(t *testing.T) {
+	user := int64(9)
+	other := int64(10)
+	orgEmail := NotificationPreference{ID: 1, EventType: "*", Channel: ChannelEmail, Target: "org@x.io", Enabled: true}
+	orgSlack := NotificationPreference{ID: 2, EventType: "payment.*", Channel: ChannelSlack, Target: "https://hooks/org", Enabled: true}
+	userEmail := NotificationPreference{ID: 3, EventType: "payment.success", Channel: ChannelEmail, Target: "me@x.io", Enabled: true, UserID: &user}
+	userSlackOff := NotificationPreference{ID: 4, EventType: "*", Channel: ChannelSlack, Enabled: false, UserID: &user}
+	orgWebhookOff := NotificationPreference{ID: 5, EventType: "*", Channel: ChannelWebhook, Enabled: false}
+	otherEmail := NotificationPreference{ID: 6, EventType: "*", Channel: ChannelEmail, Target: "other@x.io", Enabled: true, UserID: &other}
+	highOnly := NotificationPreference{ID: 7, EventType: "*", Channel: ChannelEmail, Target: "me@x.io", Enabled: true, SeverityMin: "high", UserID: &user}
+
+	cases := []struct {
+		name     string
+		prefs    []NotificationPreference
+		userID   *int64
+		settings *OrgSettings
+		want     []string
+	}{
+		{"defaults only, org event", nil, nil, nil,
+			[]string{"email:ops@x.io", "slack:https://hooks/default", "webhook:https://wh/default"}},
+		{"defaults only, user event", nil, &user, nil,
+			[]string{"email:ops@x.io", "slack:https://hooks/default", "webhook:https://wh/default"}},
+		{"org rows override defaults per channel", []NotificationPreference{orgEmail}, nil, nil,
+			[]string{"email:org@x.io", "slack:https://hooks/default", "webhook:https://wh/default"}},
+		{"org opt-out silences the default", []NotificationPreference{orgWebhookOff}, nil, nil,
+			[]string{"email:ops@x.io", "slack:https://hooks/default"}},
+		{"user row overrides org row", []NotificationPreference{orgEmail, userEmail}, &user, nil,
+			[]string{"email:me@x.io", "slack:https://hooks/default", "webhook:https://wh/default"}},
+		{"user opt-out overrides org row", []NotificationPreference{orgSlack, userSlackOff}, &user, nil,
+			[]string{"email:ops@x.io", "webhook:https://wh/default"}},
+		{"user severity floor does not fall through", []NotificationPreference{orgEmail, highOnly}, &user, nil,
+			[]string{"slack:https://hooks/default", "webhook:https://wh/default"}},
+		{"other users' rows do not override the org", []NotificationPreference{orgEmail, otherEmail}, nil, nil,
+			[]string{"email:org@x.io", "email:other@x.io", "slack:https://hooks/default", "webhook:https://wh/default"}},
+		{"email switched off for the organization", []NotificationPreference{orgEmail, orgSlack}, nil, &OrgSettings{EmailNotifications: false},
+			[]string{"slack:https://hooks/org", "webhook:https://wh/default"}},
+	}
+	for _, c := range cases {
+		svc := &NotificationService{
+			Preferences: &stubPrefRepoStatic{prefs: c.prefs},
+			Defaults:    Defaults{Emails: []string{"ops@x.io"}, SlackWebhook: "https://hooks/default", WebhookURL: "https://wh/default"},
+		}
+		evt := NotificationEvent{EventType: "payment.success", OrganizationID: 1, UserID: c.userID, Severity: "medium"}
+		var got []string
+		for _, tgt := range svc.resolveTargets(context.Background(), evt, c.settings) {
+			got = append(got, tgt.Channel+":"+tgt.Target)
+		}
+		if strings.Join(got, " ") != strings.Join(c.want, " ") {
+			t.Fatalf("%s: expected %v got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestEffectivePreferences_ExplainsSources(t *testing.T) {
+	user := int64(9)
+	svc := &NotificationService{
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{ID: 1, EventType: "payment.*", Channel: ChannelEmail, Target: "org@x.io", Enabled: true},
+			{ID: 2, EventType: "payment.success", Channel: ChannelEmail, Enabled: false, UserID: &user},
+		}},
+		Defaults: Defaults{Emails: []string{"ops@x.io"}, SlackWebhook: "https://hooks/default"},
+	}
+	evt := NotificationEvent{EventType: "payment.success", OrganizationID: 1, UserID: &user, SlackWebhook: "https://hooks/event"}
+	got, err := svc.EffectivePreferences(context.Background(), evt)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	want := []EffectivePreference{
+		{Channel: ChannelEmail, Target: "org@x.io", Source: SourceOrg, PreferenceID: 1, EventType: "payment.*", Reason: "overridden by user preference"},
+		{Channel: ChannelEmail, Source: SourceUser, PreferenceID: 2, EventType: "payment.success", UserID: &user, Reason: "opted out"},
+		{Channel: ChannelEmail, Target: "ops@x.io", Source: SourceDefault, Reason: "overridden by user preference"},
+		{Channel: ChannelSlack, Target: "https://hooks/event", Source: SourceEvent, Active: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d entries, got %#v", len(want), got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Channel != w.Channel || g.Target != w.Target || g.Source != w.Source || g.PreferenceID != w.PreferenceID ||
+			g.EventType != w.EventType || g.Active != w.Active || g.Reason != w.Reason {
+			t.Fatalf("entry %d: expected %#v got %#v", i, w, g)
+		}
+	}
+}
+
+type stubPrefRepoFailing struct{ stubPrefRepoStatic }
+
+func (r *stubPrefRepoFailing) List(ctx Context, orgID int64, userID *int64, eventType string) ([]NotificationPreference, error) {
+	return nil, errors.New("db down")
+}
+
+func TestResolveTargets_FailedLookupKeepsOnlyEventTargets(t *testing.T) {
+	svc := &NotificationService{
+		Preferences: &stubPrefRepoFailing{},
+		Defaults:    Defaults{Emails: []string{"ops@x.io"}, SlackWebhook: "https://hooks/default"},
+	}
+	evt := NotificationEvent{EventType: "payment.success", OrganizationID: 1, WebhookURL: "https://hooks/event"}
+	got := svc.resolveTargets(context.Background(), evt, nil)
+	if len(got) != 1 || got[0].Channel != ChannelWebhook || got[0].Target != "https://hooks/event" {
+		t.Fatalf("expected only the event's webhook, got %#v", got)
+	}
+}
+
+func TestValidateTarget(t *testing.T) {
+	for _, c := range []struct {
+		channel, target string
+		ok              bool
+	}{
+		{ChannelEmail, "a@x.io, b@x.io", true},
+		{ChannelEmail, "a@x.io,,", true},
+		{ChannelEmail, "Ops <ops@x.io>", false},
+		{ChannelEmail, "a@x.io, nope", false},
+		{ChannelSlack, "https://hooks.slack.com/services/T/B/X", true},
+		{ChannelTeams, "http://outlook.office.com/webhook", false},
+		{ChannelWebhook, "https:///path", false},
+		{ChannelInbox, "x", false},
+	} {
+		if err := ValidateTarget(c.channel, c.target); (err == nil) != c.ok {
+			t.Fatalf("ValidateTarget(%s, %q) = %v", c.channel, c.target, err)
+		}
+	}
+	if err := (NotificationPreference{EventType: "*", Channel: ChannelInbox, Digest: DigestDaily, Enabled: true}).Validate(); err != nil {
+		t.Fatalf("inbox cadence rows need no target: %v", err)
+	}
+}
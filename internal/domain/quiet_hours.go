@@ -0,0 +1,196 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// StatusDeferred marks log rows for deliveries held back until a later time.
+const StatusDeferred = "deferred"
+
+// ReasonQuietHours is the ScheduledDelivery reason for quiet-hours deferrals.
+const ReasonQuietHours = "quiet_hours"
+
+const (
+	// scheduledBatchSize bounds how many deferred deliveries a run claims at once.
+	scheduledBatchSize = 200
+	// scheduledDeliveryLease is how long a claimed delivery is hidden from
+	// other runs.
+	scheduledDeliveryLease = 5 * time.Minute
+)
+
+// Validate checks the time zone and the HH:MM bounds of the window.
+func (q QuietHours) Validate() error {
+	if strings.TrimSpace(q.TimeZone) == "" {
+		return errors.New("time_zone is required")
+	}
+	if _, err := time.LoadLocation(q.TimeZone); err != nil {
+		return fmt.Errorf("unknown time_zone %q", q.TimeZone)
+	}
+	start, err := parseClock(q.Start)
+	if err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	end, err := parseClock(q.End)
+	if err != nil {
+		return fmt.Errorf("end: %w", err)
+	}
+	if start == end {
+		return errors.New("start and end must differ")
+	}
+	return nil
+}
+
+// Until reports whether t falls inside the window and, if so, when it ends.
+func (q QuietHours) Until(t time.Time) (time.Time, bool) {
+	loc, err := time.LoadLocation(q.TimeZone)
+	if err != nil {
+		return time.Time{}, false
+	}
+	start, err1 := parseClock(q.Start)
+	end, err2 := parseClock(q.End)
+	if err1 != nil || err2 != nil || start == end {
+		return time.Time{}, false
+	}
+
+	local := t.In(loc)
+	now := local.Hour()*60 + local.Minute()
+	inside := start <= now && now < end
+	if start > end {
+		inside = now >= start || now < end
+	}
+	if !inside {
+		return time.Time{}, false
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), end/60, end%60, 0, 0, loc)
+	if !until.After(local) {
+		until = time.Date(local.Year(), local.Month(), local.Day()+1, end/60, end%60, 0, 0, loc)
+	}
+	return until.UTC(), true
+}
+
+// parseClock converts "HH:MM" to minutes after midnight.
+func parseClock(v string) (int, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", v)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// quietHoursCache memoizes lookups per user (0 for the org) within one event.
+type quietHoursCache map[int64]*QuietHours
+
+// quietUntil reports whether a delivery to userID (nil for org-level targets)
+// falls in quiet hours right now and when the window ends. Critical events
+// bypass the window unless it was configured to defer them too.
+func (s *NotificationService) quietUntil(ctx context.Context, orgID int64, userID *int64, severity string, cache quietHoursCache) (time.Time, bool) {
+	if s.QuietHours == nil || s.Scheduled == nil || orgID == 0 {
+		return time.Time{}, false
+	}
+	var key int64
+	if userID != nil {
+		key = *userID
+	}
+	q, ok := cache[key]
+	if !ok {
+		var err error
+		q, err = s.QuietHours.Find(ctx, orgID, userID)
+		if err != nil {
+			log.Printf("[NOTIFY] quiet hours lookup failed: %v", err)
+		}
+		cache[key] = q
+	}
+	if q == nil {
+		return time.Time{}, false
+	}
+	if strings.EqualFold(severity, "critical") && !q.DeferCritical {
+		return time.Time{}, false
+	}
+	return q.Until(time.Now())
+}
+
+// deferDelivery stores a rendered notification for delivery at until. It
+// reports false when the notification must be sent immediately instead.
+func (s *NotificationService) deferDelivery(ctx context.Context, evt NotificationEvent, target DeliveryTarget, format, subject, body string, until time.Time) bool {
+	err := s.Scheduled.Add(ctx, ScheduledDelivery{
+		Event:     evt,
+		Channel:   target.Channel,
+		Target:    target.Target,
+		Format:    format,
+		Subject:   subject,
+		Body:      body,
+		Reason:    ReasonQuietHours,
+		DeliverAt: until,
+	})
+	if err != nil {
+		log.Printf("[NOTIFY] deferring delivery failed, sending now: %v", err)
+		return false
+	}
+	log.Printf("[NOTIFY][%s] deferred to %s for quiet hours", target.Channel, until.Format(time.RFC3339))
+	_ = s.logAttempt(ctx, evt, target, StatusDeferred, nil)
+	return true
+}
+
+// DeliverScheduled sends every deferred delivery due at now and returns how
+// many were sent. A delivery that fails stays leased and is retried when the
+// lease expires, up to maxScheduledAttempts.
+func (s *NotificationService) DeliverScheduled(ctx context.Context, now time.Time) (int, error) {
+	if s.Scheduled == nil {
+		return 0, nil
+	}
+	sent := 0
+	for {
+		due, err := s.Scheduled.ClaimDue(ctx, now, scheduledDeliveryLease, scheduledBatchSize)
+		if err != nil {
+			return sent, err
+		}
+		for _, d := range due {
+			if d.Attempts > maxScheduledAttempts {
+				log.Printf("[NOTIFY] giving up on deferred delivery %d after %d attempts", d.ID, d.Attempts-1)
+			} else if err := s.sendScheduled(ctx, d); err != nil {
+				log.Printf("[NOTIFY] deferred delivery %d failed, will retry: %v", d.ID, err)
+				continue
+			} else {
+				sent++
+			}
+			if err := s.Scheduled.Complete(ctx, d.ID); err != nil {
+				log.Printf("[NOTIFY] completing deferred delivery %d: %v", d.ID, err)
+			}
+		}
+		if len(due) < scheduledBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+// sendScheduled delivers one deferred delivery. It returns an error only when
+// trying again later may succeed.
+func (s *NotificationService) sendScheduled(ctx context.Context, d ScheduledDelivery) error {
+	if d.Channel == ChannelInbox {
+		return s.saveScheduledInbox(ctx, d)
+	}
+	target := DeliveryTarget{Channel: d.Channel, Target: d.Target}
+	if err := s.deliver(ctx, d.Event, target, d.Format, d.Subject, d.Body); err != nil && !errors.Is(err, ErrUnsupportedChannel) {
+		return err
+	}
+	return nil
+}
+
+// saveScheduledInbox stores a delayed inbox item for the event's user.
+func (s *NotificationService) saveScheduledInbox(ctx context.Context, d ScheduledDelivery) error {
+	if s.Inbox == nil || d.Event.UserID == nil {
+		log.Printf("[NOTIFY] dropping delayed inbox item %d: inbox not available", d.ID)
+		return nil
+	}
+	msg := FormattedMessage{Subject: d.Subject, Body: d.Body, Format: d.Format}
+	if _, err := s.Inbox.Save(ctx, inboxNotification(d.Event, *d.Event.UserID, msg)); err != nil {
+		return fmt.Errorf("delayed inbox save: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/templates"
+)
+
+type stubQuietHours struct {
+	byUser map[int64]*QuietHours
+	calls  int
+}
+
+func (s *stubQuietHours) Find(ctx Context, orgID int64, userID *int64) (*QuietHours, error) {
+	s.calls++
+	if userID != nil {
+		if q, ok := s.byUser[*userID]; ok {
+			return q, nil
+		}
+	}
+	return s.byUser[0], nil
+}
+func (s *stubQuietHours) Save(ctx Context, q QuietHours) (QuietHours, error) { return q, nil }
+func (s *stubQuietHours) Delete(ctx Context, orgID int64, userID *int64) error {
+	return nil
+}
+
+type stubScheduled struct {
+	items    []ScheduledDelivery
+	complete []int64
+}
+
+func (s *stubScheduled) Add(ctx Context, d ScheduledDelivery) error {
+	s.items = append(s.items, d)
+	return nil
+}
+func (s *stubScheduled) ClaimDue(ctx Context, now time.Time, lease time.Duration, limit int) ([]ScheduledDelivery, error) {
+	var due, rest []ScheduledDelivery
+	for _, d := range s.items {
+		if !d.DeliverAt.After(now) {
+			d.Attempts++
+			due = append(due, d)
+		} else {
+			rest = append(rest, d)
+		}
+	}
+	s.items = rest
+	return due, nil
+}
+func (s *stubScheduled) Complete(ctx Context, id int64) error {
+	s.complete = append(s.complete, id)
+	return nil
+}
+
+func TestQuietHours_Until(t *testing.T) {
+	q := QuietHours{TimeZone: "Asia/Ho_Chi_Minh", Start: "22:00", End: "07:00"}
+	cases := []struct {
+		at     time.Time
+		inside bool
+		until  time.Time
+	}{
+		// 16:30 UTC is 23:30 in Ho Chi Minh City; the window ends at 07:00 local the next day.
+		{time.Date(2024, 3, 1, 16, 30, 0, 0, time.UTC), true, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+		// 20:00 UTC is 03:00 local, still inside; the window ends the same local morning.
+		{time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC), true, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+		// 05:00 UTC is noon local.
+		{time.Date(2024, 3, 1, 5, 0, 0, 0, time.UTC), false, time.Time{}},
+		// 00:00 UTC is exactly 07:00 local: the window has just ended.
+		{time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), false, time.Time{}},
+	}
+	for _, tc := range cases {
+		until, inside := q.Until(tc.at)
+		if inside != tc.inside || !until.Equal(tc.until) {
+			t.Fatalf("at %v: got %v %v want %v %v", tc.at, until, inside, tc.until, tc.inside)
+		}
+	}
+
+	day := QuietHours{TimeZone: "UTC", Start: "12:00", End: "13:00"}
+	if until, ok := day.Until(time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)); !ok || until.Hour() != 13 {
+		t.Fatalf("same-day window: got %v %v", until, ok)
+	}
+}
+
+func TestQuietHours_Validate(t *testing.T) {
+	valid := QuietHours{TimeZone: "Europe/Berlin", Start: "22:00", End: "06:30"}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid, got %v", err)
+	}
+	for _, q := range []QuietHours{
+		{TimeZone: "Mars/Olympus", Start: "22:00", End: "06:00"},
+		{TimeZone: "UTC", Start: "25:00", End: "06:00"},
+		{TimeZone: "UTC", Start: "06:00", End: "06:00"},
+		{Start: "22:00", End: "06:00"},
+	} {
+		if err := q.Validate(); err == nil {
+			t.Fatalf("expected error for %#v", q)
+		}
+	}
+}
+
+// windowAroundNow returns quiet hours that contain the current time.
+func windowAroundNow() *QuietHours {
+	now := time.Now().UTC()
+	return &QuietHours{
+		TimeZone: "UTC",
+		Start:    now.Add(-time.Hour).Format("15:04"),
+		End:      now.Add(time.Hour).Format("15:04"),
+	}
+}
+
+func TestHandleEvent_DefersDuringQuietHours(t *testing.T) {
+	uid := int64(5)
+	slack := &stubSlack{}
+	email := &stubEmail{}
+	scheduled := &stubScheduled{}
+	logs := &stubLogRepo{}
+	quiet := &stubQuietHours{byUser: map[int64]*QuietHours{5: windowAroundNow()}}
+	svc := &NotificationService{
+		Templates: &stubTemplateRepo{tpl: NotificationTemplate{Subject: "s", Body: "b"}},
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{OrganizationID: 1, UserID: &uid, EventType: "scan.done", Channel: ChannelEmail, Target: "me@x.io", Enabled: true},
+			{OrganizationID: 1, EventType: "scan.done", Channel: ChannelSlack, Target: "https://hooks", Enabled: true},
+		}},
+		Logs:       logs,
+		Email:      email,
+		Slack:      slack,
+		QuietHours: quiet,
+		Scheduled:  scheduled,
+		Renderer:   templates.Renderer{},
+	}
+
+	evt := NotificationEvent{EventType: "scan.done", OrganizationID: 1, Severity: "low"}
+	if err := svc.HandleEvent(context.Background(), evt); err != nil {
+		t.Fatalf("handle event: %v", err)
+	}
+	if len(email.to) != 0 || slack.url != "https://hooks" {
+		t.Fatalf("expected user email deferred and org slack sent, got email %v slack %q", email.to, slack.url)
+	}
+	if len(scheduled.items) != 1 || scheduled.items[0].Channel != ChannelEmail || scheduled.items[0].Reason != ReasonQuietHours {
+		t.Fatalf("unexpected scheduled %#v", scheduled.items)
+	}
+	if logs.entries[0].Status != StatusDeferred {
+		t.Fatalf("expected deferred log first, got %#v", logs.entries)
+	}
+
+	evt.Severity = "critical"
+	if err := svc.HandleEvent(context.Background(), evt); err != nil {
+		t.Fatalf("handle event: %v", err)
+	}
+	if len(email.to) != 1 || len(scheduled.items) != 1 {
+		t.Fatalf("critical events should bypass quiet hours, email %v scheduled %d", email.to, len(scheduled.items))
+	}
+}
+
+func TestDeliverScheduled_SendsDueDeliveries(t *testing.T) {
+	now := time.Now().UTC()
+	slack := &stubSlack{}
+	logs := &stubLogRepo{}
+	scheduled := &stubScheduled{items: []ScheduledDelivery{
+		{Event: NotificationEvent{EventType: "scan.done", OrganizationID: 1}, Channel: ChannelSlack, Target: "https://hooks", Body: "due", DeliverAt: now},
+		{Event: NotificationEvent{EventType: "scan.done", OrganizationID: 1}, Channel: ChannelSlack, Target: "https://hooks", Body: "later", DeliverAt: now.Add(time.Hour)},
+	}}
+	svc := &NotificationService{Logs: logs, Slack: slack, Scheduled: scheduled}
+
+	sent, err := svc.DeliverScheduled(context.Background(), now)
+	if err != nil || sent != 1 {
+		t.Fatalf("expected 1 delivery, got %d %v", sent, err)
+	}
+	if slack.msg != "due" || len(scheduled.items) != 1 {
+		t.Fatalf("unexpected state: slack %q remaining %d", slack.msg, len(scheduled.items))
+	}
+	if len(logs.entries) != 1 || logs.entries[0].Status != "success" {
+		t.Fatalf("expected success log, got %#v", logs.entries)
+	}
+	if len(scheduled.complete) != 1 {
+		t.Fatalf("expected the sent delivery to be completed, got %v", scheduled.complete)
+	}
+}
+
+func TestDeliverScheduled_FailedSendIsRetried(t *testing.T) {
+	now := time.Now().UTC()
+	scheduled := &stubScheduled{items: []ScheduledDelivery{
+		{ID: 1, Event: NotificationEvent{EventType: "scan.done", OrganizationID: 1}, Channel: ChannelSlack, Target: "https://hooks", DeliverAt: now},
+		{ID: 2, Event: NotificationEvent{EventType: "scan.done", OrganizationID: 1}, Channel: ChannelSlack, Target: "https://hooks", DeliverAt: now,
+			Attempts: maxScheduledAttempts},
+	}}
+	svc := &NotificationService{Logs: &stubLogRepo{}, Slack: &stubSlack{err: errors.New("slack down")}, Scheduled: scheduled}
+
+	sent, err := svc.DeliverScheduled(context.Background(), now)
+	if err != nil || sent != 0 {
+		t.Fatalf("expected nothing sent, got %d %v", sent, err)
+	}
+	if len(scheduled.complete) != 1 || scheduled.complete[0] != 2 {
+		t.Fatalf("only the delivery out of attempts may be dropped, got %v", scheduled.complete)
+	}
+}
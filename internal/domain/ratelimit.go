@@ -0,0 +1,202 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Rate limit overflow modes.
+const (
+	OverflowDelay     = "delay"
+	OverflowSummarize = "summarize"
+)
+
+// StatusThrottled marks log rows for deliveries dropped by a rate limit.
+const StatusThrottled = "throttled"
+
+// ReasonRateLimited is the ScheduledDelivery reason for rate-limit delays.
+const ReasonRateLimited = "rate_limited"
+
+// EventSuppressed is the event type "N more notifications suppressed" notices
+// are resolved and logged under.
+const EventSuppressed = "notification.suppressed"
+
+// suppressedBatchSize bounds how many buckets a summary run claims at once.
+const suppressedBatchSize = 200
+
+// Validate checks the channel, bucket size, refill rate and overflow mode.
+func (l RateLimit) Validate() error {
+	switch l.Channel {
+	case "", ChannelEmail, ChannelSlack, ChannelTeams, ChannelWebhook, ChannelInbox:
+	default:
+		return fmt.Errorf("unknown channel %q", l.Channel)
+	}
+	if l.Burst < 1 {
+		return errors.New("burst must be at least 1")
+	}
+	if l.PerMinute <= 0 {
+		return errors.New("per_minute must be positive")
+	}
+	if l.Overflow != OverflowDelay && l.Overflow != OverflowSummarize {
+		return errors.New("overflow must be delay or summarize")
+	}
+	return nil
+}
+
+// Take refills a bucket that held tokens at last (zero for a new bucket) and
+// spends one token for a delivery at now. It returns the new balance and
+// whether the delivery may go out. Delaying limits go into debt instead and
+// report how long the delivery must wait for its token.
+func (l RateLimit) Take(tokens float64, last, now time.Time) (float64, time.Duration, bool) {
+	if last.IsZero() {
+		tokens = float64(l.Burst)
+	} else if now.After(last) {
+		tokens += now.Sub(last).Minutes() * l.PerMinute
+	}
+	if tokens > float64(l.Burst) {
+		tokens = float64(l.Burst)
+	}
+	if tokens >= 1 {
+		return tokens - 1, 0, true
+	}
+	if l.Overflow != OverflowDelay {
+		return tokens, 0, false
+	}
+	wait := time.Duration((1 - tokens) / l.PerMinute * float64(time.Minute))
+	return tokens - 1, wait, false
+}
+
+// rateLimitCache memoizes limit lookups per channel within one event.
+type rateLimitCache map[string]*RateLimit
+
+// throttle applies the matching rate limit to a rendered delivery. It reports
+// true when the delivery was delayed or suppressed and must not be sent now.
+// Lookup failures let the delivery through.
+func (s *NotificationService) throttle(ctx context.Context, evt NotificationEvent, target DeliveryTarget, format, subject, body string, cache rateLimitCache) bool {
+	if s.RateLimits == nil || evt.OrganizationID == 0 {
+		return false
+	}
+	l, ok := cache[target.Channel]
+	if !ok {
+		var err error
+		l, err = s.RateLimits.Match(ctx, evt.OrganizationID, target.Channel, evt.EventType)
+		if err != nil {
+			log.Printf("[NOTIFY] rate limit lookup failed: %v", err)
+		}
+		cache[target.Channel] = l
+	}
+	if l == nil {
+		return false
+	}
+
+	now := time.Now().UTC()
+	wait, allowed, err := s.RateLimits.Take(ctx, *l, target, evt.EventType, now)
+	if err != nil {
+		log.Printf("[NOTIFY] rate limit check failed, sending: %v", err)
+		return false
+	}
+	if allowed {
+		return false
+	}
+
+	if l.Overflow == OverflowDelay && s.Scheduled != nil {
+		deferred := evt
+		deferred.UserID = target.UserID
+		err := s.Scheduled.Add(ctx, ScheduledDelivery{
+			Event:     deferred,
+			Channel:   target.Channel,
+			Target:    target.Target,
+			Format:    format,
+			Subject:   subject,
+			Body:      body,
+			Reason:    ReasonRateLimited,
+			DeliverAt: now.Add(wait),
+		})
+		if err != nil {
+			log.Printf("[NOTIFY] delaying rate-limited delivery failed, sending now: %v", err)
+			return false
+		}
+		log.Printf("[NOTIFY][%s] rate limited %s, delayed by %s", target.Channel, evt.EventType, wait.Round(time.Second))
+		s.Metrics.ObserveThrottled(ctx, target.Channel, evt.EventType, OverflowDelay)
+		_ = s.logAttempt(ctx, evt, target, StatusDeferred, nil)
+		return true
+	}
+
+	log.Printf("[NOTIFY][%s] rate limited %s, suppressed", target.Channel, evt.EventType)
+	s.Metrics.ObserveThrottled(ctx, target.Channel, evt.EventType, OverflowSummarize)
+	_ = s.logAttempt(ctx, evt, target, StatusThrottled, nil)
+	return true
+}
+
+// SendSuppressedSummaries tells every destination whose bucket has refilled
+// how many notifications were suppressed, and returns how many notices went out.
+func (s *NotificationService) SendSuppressedSummaries(ctx context.Context, now time.Time) (int, error) {
+	if s.RateLimits == nil {
+		return 0, nil
+	}
+	sent := 0
+	for {
+		batches, err := s.RateLimits.ClaimSuppressed(ctx, now, suppressedBatchSize)
+		if err != nil {
+			return sent, err
+		}
+		for _, b := range batches {
+			s.sendSuppressedSummary(ctx, now, b)
+			sent++
+		}
+		if len(batches) < suppressedBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+func (s *NotificationService) sendSuppressedSummary(ctx context.Context, now time.Time, b SuppressedBatch) {
+	evt := NotificationEvent{
+		EventType:      EventSuppressed,
+		OrganizationID: b.OrganizationID,
+		UserID:         b.UserID,
+		OccurredAt:     now,
+		Payload: map[string]interface{}{
+			"suppressed": map[string]interface{}{
+				"count":      b.Count,
+				"event_type": b.EventType,
+				"since":      b.Since,
+			},
+		},
+	}
+
+	var settings *OrgSettings
+	if s.OrgSettings != nil {
+		if st, err := s.OrgSettings.Get(ctx, b.OrganizationID); err == nil {
+			settings = st
+		}
+	}
+	var userLocale string
+	if b.UserID != nil {
+		userLocale = s.userLocales(ctx, []int64{*b.UserID})[*b.UserID]
+	}
+	locale := localeFor(evt, userLocale, settings)
+
+	channel := b.Channel
+	if channel == ChannelInbox {
+		channel = ""
+	}
+	tpl := s.resolveTemplate(ctx, b.OrganizationID, EventSuppressed, channel, locale)
+	set := PartialSet(s.loadPartials(ctx, b.OrganizationID), b.Channel)
+	subject, body := s.renderTemplate(tpl, set, withLocale(BuildTemplateData(evt), locale))
+
+	if b.Channel == ChannelInbox {
+		if s.Inbox == nil || b.UserID == nil {
+			return
+		}
+		msg := FormatMessage(tpl.Format, ChannelInbox, subject, body)
+		if _, err := s.Inbox.Save(ctx, inboxNotification(evt, *b.UserID, msg)); err != nil {
+			log.Printf("[NOTIFY] inbox suppression summary failed: %v", err)
+		}
+		return
+	}
+	_ = s.deliver(ctx, evt, DeliveryTarget{Channel: b.Channel, Target: b.Target, UserID: b.UserID}, tpl.Format, subject, body)
+}
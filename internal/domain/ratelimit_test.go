@@ -0,0 +1,189 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/templates"
+)
+
+type stubBucket struct {
+	tokens     float64
+	last       time.Time
+	suppressed int
+	target     DeliveryTarget
+	eventType  string
+}
+
+type stubRateLimits struct {
+	limits  []RateLimit
+	buckets map[string]*stubBucket
+}
+
+func (s *stubRateLimits) List(ctx Context, orgID int64) ([]RateLimit, error) { return s.limits, nil }
+func (s *stubRateLimits) Save(ctx Context, l RateLimit) (RateLimit, error)   { return l, nil }
+func (s *stubRateLimits) Delete(ctx Context, orgID, id int64) error          { return nil }
+func (s *stubRateLimits) Match(ctx Context, orgID int64, channel, eventType string) (*RateLimit, error) {
+	for i := range s.limits {
+		if s.limits[i].Channel == channel {
+			return &s.limits[i], nil
+		}
+	}
+	return nil, nil
+}
+func (s *stubRateLimits) Take(ctx Context, l RateLimit, target DeliveryTarget, eventType string, now time.Time) (time.Duration, bool, error) {
+	key := target.Channel + "|" + target.Target
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &stubBucket{target: target}
+		s.buckets[key] = b
+	}
+	left, wait, allowed := l.Take(b.tokens, b.last, now)
+	b.tokens, b.last, b.eventType = left, now, eventType
+	if !allowed && l.Overflow == OverflowSummarize {
+		b.suppressed++
+	}
+	return wait, allowed, nil
+}
+func (s *stubRateLimits) ClaimSuppressed(ctx Context, now time.Time, limit int) ([]SuppressedBatch, error) {
+	var out []SuppressedBatch
+	for _, b := range s.buckets {
+		if b.suppressed > 0 {
+			out = append(out, SuppressedBatch{OrganizationID: 1, Channel: b.target.Channel, Target: b.target.Target,
+				UserID: b.target.UserID, EventType: b.eventType, Count: b.suppressed})
+			b.suppressed = 0
+		}
+	}
+	return out, nil
+}
+
+func TestRateLimitTake(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := RateLimit{Burst: 2, PerMinute: 6, Overflow: OverflowSummarize}
+
+	tokens, _, ok := l.Take(0, time.Time{}, now)
+	if !ok || tokens != 1 {
+		t.Fatalf("new bucket starts full, got %v %v", tokens, ok)
+	}
+	tokens, _, ok = l.Take(tokens, now, now)
+	if !ok || tokens != 0 {
+		t.Fatalf("second token, got %v %v", tokens, ok)
+	}
+	if left, _, ok := l.Take(tokens, now, now); ok || left != 0 {
+		t.Fatalf("summarize must not spend on an empty bucket, got %v %v", left, ok)
+	}
+
+	l.Overflow = OverflowDelay
+	left, wait, ok := l.Take(tokens, now, now)
+	if ok || left != -1 || wait != 10*time.Second {
+		t.Fatalf("delay should borrow a token 10s ahead, got %v %v %v", left, wait, ok)
+	}
+	if _, wait, ok := l.Take(left, now, now); ok || wait != 20*time.Second {
+		t.Fatalf("next delayed delivery queues behind, got %v %v", wait, ok)
+	}
+	if _, _, ok := l.Take(0, now, now.Add(10*time.Second)); !ok {
+		t.Fatalf("bucket should refill after 10s")
+	}
+	if left, _, _ := l.Take(0, now, now.Add(time.Hour)); left != 1 {
+		t.Fatalf("refill is capped at burst, got %v", left)
+	}
+}
+
+func TestRateLimitValidate(t *testing.T) {
+	if err := (RateLimit{Channel: ChannelSlack, Burst: 5, PerMinute: 1, Overflow: OverflowDelay}).Validate(); err != nil {
+		t.Fatalf("expected valid, got %v", err)
+	}
+	for want, l := range map[string]RateLimit{
+		"channel":    {Channel: "sms", Burst: 1, PerMinute: 1, Overflow: OverflowDelay},
+		"burst":      {PerMinute: 1, Overflow: OverflowDelay},
+		"per_minute": {Burst: 1, Overflow: OverflowDelay},
+		"overflow":   {Burst: 1, PerMinute: 1, Overflow: "drop"},
+	} {
+		if err := l.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: unexpected error %v", want, err)
+		}
+	}
+}
+
+func TestHandleEvent_RateLimitSummarizesSlackFlood(t *testing.T) {
+	limits := &stubRateLimits{
+		limits:  []RateLimit{{ID: 1, OrganizationID: 1, Channel: ChannelSlack, Burst: 1, PerMinute: 1, Overflow: OverflowSummarize}},
+		buckets: map[string]*stubBucket{},
+	}
+	slack := &stubSlack{}
+	logs := &stubLogRepo{}
+	svc := &NotificationService{
+		Templates: &stubTemplateRepo{},
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{OrganizationID: 1, EventType: "code_finding.assignment", Channel: ChannelSlack, Target: "https://hooks/1", Enabled: true},
+		}},
+		Logs:       logs,
+		Slack:      slack,
+		RateLimits: limits,
+		Renderer:   templates.Renderer{},
+	}
+
+	evt := NotificationEvent{EventType: "code_finding.assignment", OrganizationID: 1}
+	for i := 0; i < 3; i++ {
+		_ = svc.HandleEvent(context.Background(), evt)
+	}
+	var sent, throttled int
+	for _, e := range logs.entries {
+		switch e.Status {
+		case "success":
+			sent++
+		case StatusThrottled:
+			throttled++
+		}
+	}
+	if sent != 1 || throttled != 2 {
+		t.Fatalf("expected 1 sent and 2 throttled, got %d/%d", sent, throttled)
+	}
+
+	n, err := svc.SendSuppressedSummaries(context.Background(), time.Now())
+	if err != nil || n != 1 {
+		t.Fatalf("expected one summary, got %d %v", n, err)
+	}
+	if slack.url != "https://hooks/1" || !strings.Contains(slack.msg, "2 more code_finding.assignment notifications") {
+		t.Fatalf("unexpected summary %q to %q", slack.msg, slack.url)
+	}
+}
+
+func TestHandleEvent_RateLimitDelaysInbox(t *testing.T) {
+	uid := int64(3)
+	limits := &stubRateLimits{
+		limits:  []RateLimit{{ID: 1, OrganizationID: 1, Channel: ChannelInbox, Burst: 1, PerMinute: 2, Overflow: OverflowDelay}},
+		buckets: map[string]*stubBucket{},
+	}
+	inbox := &stubInboxRepo{}
+	scheduled := &stubScheduled{}
+	svc := &NotificationService{
+		Templates:   &stubTemplateRepo{},
+		Preferences: &stubPrefRepoStatic{},
+		Logs:        &stubLogRepo{},
+		Inbox:       inbox,
+		Scheduled:   scheduled,
+		RateLimits:  limits,
+		Renderer:    templates.Renderer{},
+	}
+
+	evt := NotificationEvent{EventType: "code_finding.assignment", OrganizationID: 1, UserID: &uid}
+	_ = svc.HandleEvent(context.Background(), evt)
+	_ = svc.HandleEvent(context.Background(), evt)
+	if len(inbox.saved) != 1 || len(scheduled.items) != 1 {
+		t.Fatalf("expected one inbox item and one delayed, got %d/%d", len(inbox.saved), len(scheduled.items))
+	}
+	d := scheduled.items[0]
+	if d.Reason != ReasonRateLimited || d.Channel != ChannelInbox || d.DeliverAt.Before(time.Now().Add(25*time.Second)) {
+		t.Fatalf("unexpected delayed delivery %#v", d)
+	}
+
+	if n, _ := svc.DeliverScheduled(context.Background(), d.DeliverAt); n != 1 {
+		t.Fatalf("expected delayed item delivered, got %d", n)
+	}
+	if len(inbox.saved) != 2 || inbox.saved[1].UserID != 3 || inbox.saved[1].Type != "code_finding.assignment" {
+		t.Fatalf("delayed inbox item should be saved, got %#v", inbox.saved)
+	}
+}
@@ -0,0 +1,183 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"myesi-notification-service/internal/rules"
+)
+
+// Route is the combined outcome of the routing rules matching an event.
+type Route struct {
+	Rules    []int64       `json:"rules"`
+	Drop     bool          `json:"drop"`
+	UserIDs  []int64       `json:"user_ids,omitempty"`
+	Roles    []string      `json:"roles,omitempty"`
+	AllUsers bool          `json:"all_users,omitempty"`
+	Channels []string      `json:"channels,omitempty"`
+	Targets  []RouteTarget `json:"targets,omitempty"`
+	Template string        `json:"template,omitempty"`
+}
+
+// SelectsRecipients reports whether the route replaces the event type's
+// audience.
+func (r Route) SelectsRecipients() bool {
+	return r.AllUsers || len(r.UserIDs) > 0 || len(r.Roles) > 0
+}
+
+// Allows reports whether the route lets the event out on channel.
+func (r Route) Allows(channel string) bool {
+	if len(r.Channels) == 0 {
+		return true
+	}
+	for _, c := range r.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+func validChannel(channel string) bool {
+	switch channel {
+	case ChannelEmail, ChannelSlack, ChannelTeams, ChannelWebhook, ChannelInbox:
+		return true
+	}
+	return false
+}
+
+// Validate checks the pattern, severity, condition and actions.
+func (r RoutingRule) Validate() error {
+	if _, err := path.Match(r.EventPattern, ""); err != nil {
+		return fmt.Errorf("invalid event_pattern %q", r.EventPattern)
+	}
+	if !ValidSeverity(r.SeverityMin) {
+		return fmt.Errorf("unknown severity_min %q", r.SeverityMin)
+	}
+	if _, err := rules.Compile(r.Condition); err != nil {
+		return fmt.Errorf("invalid condition: %v", err)
+	}
+	a := r.Actions
+	if !a.Drop && !a.AllUsers && len(a.UserIDs) == 0 && len(a.Roles) == 0 &&
+		len(a.Channels) == 0 && len(a.Targets) == 0 && a.Template == "" {
+		return errors.New("at least one action is required")
+	}
+	for _, role := range a.Roles {
+		if strings.TrimSpace(role) == "" {
+			return errors.New("roles must not be empty")
+		}
+	}
+	for _, c := range a.Channels {
+		if !validChannel(c) {
+			return fmt.Errorf("unknown channel %q", c)
+		}
+	}
+	for _, t := range a.Targets {
+		if !validChannel(t.Channel) || t.Channel == ChannelInbox {
+			return fmt.Errorf("unknown target channel %q", t.Channel)
+		}
+		if strings.TrimSpace(t.Target) == "" {
+			return errors.New("target is required")
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the rule applies to evt. Rules whose condition no
+// longer compiles never match.
+func (r RoutingRule) Matches(evt NotificationEvent) bool {
+	if !r.Enabled {
+		return false
+	}
+	if r.EventPattern != "" {
+		if ok, _ := path.Match(r.EventPattern, evt.EventType); !ok {
+			return false
+		}
+	}
+	if r.SeverityMin != "" && (evt.Severity == "" || !shouldSendForSeverity(r.SeverityMin, evt.Severity)) {
+		return false
+	}
+	cond, err := rules.Compile(r.Condition)
+	if err != nil {
+		log.Printf("[NOTIFY] routing rule %d has an invalid condition: %v", r.ID, err)
+		return false
+	}
+	return cond.Match(BuildTemplateData(evt))
+}
+
+// MatchRoutes evaluates ordered rules against evt and merges the actions of
+// every matching rule up to the first one that does not continue.
+func MatchRoutes(list []RoutingRule, evt NotificationEvent) Route {
+	var route Route
+	for _, r := range list {
+		if !r.Matches(evt) {
+			continue
+		}
+		a := r.Actions
+		route.Rules = append(route.Rules, r.ID)
+		route.Drop = route.Drop || a.Drop
+		route.UserIDs = append(route.UserIDs, a.UserIDs...)
+		route.Roles = append(route.Roles, a.Roles...)
+		route.AllUsers = route.AllUsers || a.AllUsers
+		route.Channels = append(route.Channels, a.Channels...)
+		route.Targets = append(route.Targets, a.Targets...)
+		if route.Template == "" {
+			route.Template = a.Template
+		}
+		if !r.Continue {
+			break
+		}
+	}
+	return route
+}
+
+// routeEvent applies the organization's routing rules. Lookup failures fall
+// back to built-in routing.
+func (s *NotificationService) routeEvent(ctx context.Context, evt NotificationEvent) Route {
+	if s.Routes == nil || evt.OrganizationID == 0 {
+		return Route{}
+	}
+	list, err := s.Routes.List(ctx, evt.OrganizationID)
+	if err != nil {
+		log.Printf("[NOTIFY] routing rule lookup failed: %v", err)
+		return Route{}
+	}
+	return MatchRoutes(list, evt)
+}
+
+// outboundTargets resolves evt's outbound targets the way HandleEvent does:
+// routing rules pick channels and add destinations, and a scoped audience
+// drops other users' targets. It returns nil when a rule drops the event.
+func (s *NotificationService) outboundTargets(ctx context.Context, evt NotificationEvent, settings *OrgSettings) []DeliveryTarget {
+	route := s.routeEvent(ctx, evt)
+	if route.Drop {
+		log.Printf("[NOTIFY] %s dropped by routing rules %v", evt.EventType, route.Rules)
+		return nil
+	}
+	targets := routeTargets(s.resolveTargets(ctx, evt, settings), route)
+	if members, scoped := s.recipients(ctx, evt, route); scoped {
+		targets = audienceTargets(targets, evt, members)
+	}
+	return targets
+}
+
+// routeTargets restricts outbound targets to the route's channels and adds
+// the route's own destinations.
+func routeTargets(targets []DeliveryTarget, route Route) []DeliveryTarget {
+	out := make([]DeliveryTarget, 0, len(targets)+len(route.Targets))
+	for _, t := range targets {
+		if route.Allows(t.Channel) {
+			out = append(out, t)
+		}
+	}
+	for _, t := range route.Targets {
+		if route.Allows(t.Channel) {
+			out = append(out, DeliveryTarget{Channel: t.Channel, Target: t.Target})
+		}
+	}
+	return out
+}
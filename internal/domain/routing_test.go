@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"myesi-notification-service/internal/templates"
+)
+
+type stubRoutes struct{ rules []RoutingRule }
+
+func (s *stubRoutes) List(ctx Context, orgID int64) ([]RoutingRule, error) { return s.rules, nil }
+func (s *stubRoutes) Save(ctx Context, r RoutingRule) (RoutingRule, error) { return r, nil }
+func (s *stubRoutes) Delete(ctx Context, orgID, id int64) error            { return nil }
+
+func TestMatchRoutes(t *testing.T) {
+	list := []RoutingRule{
+		{ID: 1, Enabled: true, EventPattern: "vulnerability.*", Condition: `payload.project == "core-api" && payload.critical_count > 3`,
+			Actions: RouteActions{Roles: []string{"security"}}, Continue: true},
+		{ID: 2, Enabled: false, EventPattern: "*", Actions: RouteActions{Drop: true}},
+		{ID: 3, Enabled: true, EventPattern: "vulnerability.*", SeverityMin: "high", Actions: RouteActions{Channels: []string{ChannelSlack}}},
+		{ID: 4, Enabled: true, Actions: RouteActions{AllUsers: true}},
+	}
+	evt := NotificationEvent{EventType: "vulnerability.critical", Severity: "critical",
+		Payload: map[string]interface{}{"project": "core-api", "critical_count": 5}}
+
+	route := MatchRoutes(list, evt)
+	if len(route.Rules) != 2 || route.Rules[1] != 3 || route.Drop || route.AllUsers {
+		t.Fatalf("expected rules 1 and 3 to match, got %#v", route)
+	}
+	if !route.SelectsRecipients() || !route.Allows(ChannelSlack) || route.Allows(ChannelEmail) {
+		t.Fatalf("unexpected route actions %#v", route)
+	}
+
+	evt.Payload["critical_count"] = 1
+	evt.Severity = "low"
+	if route := MatchRoutes(list, evt); len(route.Rules) != 1 || route.Rules[0] != 4 {
+		t.Fatalf("expected catch-all only, got %#v", route)
+	}
+}
+
+func TestRoutingRuleValidate(t *testing.T) {
+	ok := RoutingRule{EventPattern: "payment.*", Condition: `payload.amount > 100`, Actions: RouteActions{AllUsers: true}}
+	if err := ok.Validate(); err != nil {
+		t.Fatalf("expected valid, got %v", err)
+	}
+	for want, r := range map[string]RoutingRule{
+		"event_pattern": {EventPattern: "[", Actions: RouteActions{Drop: true}},
+		"condition":     {Condition: `payload.a ==`, Actions: RouteActions{Drop: true}},
+		"action":        {EventPattern: "payment.*"},
+		"channel":       {Actions: RouteActions{Channels: []string{"sms"}}},
+		"target":        {Actions: RouteActions{Targets: []RouteTarget{{Channel: ChannelSlack}}}},
+	} {
+		if err := r.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: unexpected error %v", want, err)
+		}
+	}
+}
+
+func TestHandleEvent_RoutingRulesSelectRecipientsAndChannels(t *testing.T) {
+	inbox := &stubInboxRepo{}
+	slack := &stubSlack{}
+	email := &stubEmail{}
+	svc := &NotificationService{
+		Templates: &stubTemplateRepo{},
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{OrganizationID: 1, EventType: "vulnerability.critical", Channel: ChannelEmail, Target: "all@example.com", Enabled: true},
+		}},
+		Logs:     &stubLogRepo{},
+		Inbox:    inbox,
+		OrgUsers: &stubOrgUsers{all: []int64{1, 2, 3, 4}, byRole: map[string][]int64{"security": {2, 3}}},
+		Email:    email,
+		Slack:    slack,
+		Routes: &stubRoutes{rules: []RoutingRule{{
+			ID: 1, Enabled: true, EventPattern: "vulnerability.*", Condition: `payload.project == "core-api"`,
+			Actions: RouteActions{
+				Roles:    []string{"security"},
+				Channels: []string{ChannelInbox, ChannelSlack},
+				Targets:  []RouteTarget{{Channel: ChannelSlack, Target: "https://hooks/core"}},
+			},
+		}}},
+		Renderer: templates.Renderer{},
+	}
+
+	evt := NotificationEvent{EventType: "vulnerability.critical", OrganizationID: 1,
+		Payload: map[string]interface{}{"project": "core-api"}}
+	if err := svc.HandleEvent(context.Background(), evt); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(inbox.saved) != 2 || inbox.saved[0].UserID != 2 || inbox.saved[1].UserID != 3 {
+		t.Fatalf("expected the security role only, got %#v", inbox.saved)
+	}
+	if slack.url != "https://hooks/core" || email.to != nil {
+		t.Fatalf("expected slack only, got slack=%q email=%v", slack.url, email.to)
+	}
+
+	// Events the rule does not match keep the built-in broadcast.
+	inbox.saved = nil
+	evt.Payload["project"] = "web"
+	_ = svc.HandleEvent(context.Background(), evt)
+	if len(inbox.saved) != 4 || email.to == nil {
+		t.Fatalf("expected built-in routing, got %d inbox items, email=%v", len(inbox.saved), email.to)
+	}
+}
+
+func TestHandleEvent_RoutingRuleDrops(t *testing.T) {
+	uid := int64(7)
+	inbox := &stubInboxRepo{}
+	svc := &NotificationService{
+		Templates:   &stubTemplateRepo{},
+		Preferences: &stubPrefRepoStatic{},
+		Logs:        &stubLogRepo{},
+		Inbox:       inbox,
+		Routes:      &stubRoutes{rules: []RoutingRule{{ID: 1, Enabled: true, EventPattern: "user.activity.*", Actions: RouteActions{Drop: true}}}},
+		Renderer:    templates.Renderer{},
+	}
+	_ = svc.HandleEvent(context.Background(), NotificationEvent{EventType: "user.activity.login", OrganizationID: 1, UserID: &uid})
+	if len(inbox.saved) != 0 {
+		t.Fatalf("expected dropped event, got %#v", inbox.saved)
+	}
+}
@@ -0,0 +1,74 @@
+package domain
+
+import "time"
+
+// SampleEvent builds a representative event for previews and test sends.
+// The payload covers every field referenced by the built-in templates.
+func SampleEvent(eventType string, orgID int64) NotificationEvent {
+	return NotificationEvent{
+		EventType:      eventType,
+		OrganizationID: orgID,
+		Severity:       "high",
+		OccurredAt:     time.Now().UTC(),
+		Payload: map[string]interface{}{
+			"project":           "sample-project",
+			"project_id":        1,
+			"vulns":             12,
+			"code_findings":     4,
+			"critical_count":    2,
+			"components":        248,
+			"error":             "dependency resolution timed out",
+			"plan_name":         "Pro",
+			"amount":            "49.00 USD",
+			"organization_name": "Sample Org",
+			"report_week":       "2024-W01",
+			"metrics": map[string]interface{}{
+				"active_vulnerabilities":   12,
+				"critical_vulnerabilities": 2,
+				"average_risk_score":       6.4,
+			},
+			"user": map[string]interface{}{
+				"email": "user@example.com",
+			},
+			"current_ip":  "203.0.113.10",
+			"previous_ip": "198.51.100.7",
+			"action_url":  "https://app.myesi.local/notifications",
+			"digest": map[string]interface{}{
+				"cadence": DigestDaily,
+				"count":   2,
+				"groups": []map[string]interface{}{{
+					"event_type": "project.scan.completed",
+					"count":      2,
+					"items": []map[string]interface{}{
+						{"subject": "Project scan completed", "body": "Scan finished for sample-project.", "severity": "low"},
+						{"subject": "Project scan completed", "body": "Scan finished for sample-api.", "severity": "low"},
+					},
+				}},
+			},
+			"escalation": map[string]interface{}{
+				"id":         1,
+				"tier":       1,
+				"summary":    "Critical vulnerability found in sample-project",
+				"event_type": "vulnerability.critical",
+				"minutes":    15,
+			},
+			"alert": map[string]interface{}{
+				"correlation_key": "vuln:42",
+				"event_type":      "vulnerability.critical",
+				"summary":         "Critical vulnerability found in sample-project",
+				"state":           AlertResolved,
+				"resolved_by":     "user@example.com",
+			},
+			"suppressed": map[string]interface{}{
+				"count":      37,
+				"event_type": "code_finding.assignment",
+			},
+			"group": map[string]interface{}{
+				"count":      9,
+				"total":      10,
+				"event_type": "vulnerability.critical",
+				"key":        "project=payments-api",
+			},
+		},
+	}
+}
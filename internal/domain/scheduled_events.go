@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrSchedulingUnavailable is returned for delayed events when no scheduled
+// event store is configured.
+var ErrSchedulingUnavailable = errors.New("scheduled delivery not configured")
+
+const (
+	// scheduledEventLease is how long a claimed event is hidden from other
+	// workers; it must outlast processing a single event.
+	scheduledEventLease = 5 * time.Minute
+	// maxScheduledAttempts caps retries of an event that keeps failing.
+	maxScheduledAttempts = 5
+	scheduledEventBatch  = 100
+)
+
+// ScheduledFor returns when the event should be processed, or the zero time
+// when it is due now. DeliverAt wins over Delay.
+func (evt NotificationEvent) ScheduledFor(now time.Time) (time.Time, error) {
+	var at time.Time
+	switch {
+	case evt.DeliverAt != nil:
+		at = *evt.DeliverAt
+	case evt.Delay != "":
+		d, err := time.ParseDuration(evt.Delay)
+		if err != nil || d < 0 {
+			return time.Time{}, fmt.Errorf("invalid delay %q", evt.Delay)
+		}
+		at = now.Add(d)
+	}
+	if !at.After(now) {
+		return time.Time{}, nil
+	}
+	return at.UTC(), nil
+}
+
+// scheduleEvent stores evt for processing at at.
+func (s *NotificationService) scheduleEvent(ctx context.Context, evt NotificationEvent, at time.Time) error {
+	if s.ScheduledEvents == nil {
+		return ErrSchedulingUnavailable
+	}
+	key := evt.ScheduleKey
+	evt.DeliverAt, evt.Delay, evt.ScheduleKey = nil, "", ""
+	_, err := s.ScheduledEvents.Schedule(ctx, ScheduledEvent{
+		OrganizationID: evt.OrganizationID,
+		Key:            key,
+		Event:          evt,
+		DeliverAt:      at,
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[NOTIFY] event %s scheduled for %s", evt.EventType, at.Format(time.RFC3339))
+	return nil
+}
+
+// FireScheduledEvents processes events due at now and returns how many were
+// handled. An event whose processing fails stays leased and is retried when the
+// lease expires, up to maxScheduledAttempts.
+func (s *NotificationService) FireScheduledEvents(ctx context.Context, now time.Time) (int, error) {
+	if s.ScheduledEvents == nil {
+		return 0, nil
+	}
+	fired := 0
+	for {
+		due, err := s.ScheduledEvents.Claim(ctx, now, scheduledEventLease, scheduledEventBatch)
+		if err != nil {
+			return fired, err
+		}
+		for _, e := range due {
+			if e.Attempts > maxScheduledAttempts {
+				log.Printf("[NOTIFY] giving up on scheduled event %d after %d attempts", e.ID, e.Attempts-1)
+			} else {
+				evt := e.Event
+				evt.DeliverAt, evt.Delay = nil, ""
+				evt.OccurredAt = e.DeliverAt
+				if err := s.HandleEvent(ctx, evt); err != nil {
+					log.Printf("[NOTIFY] scheduled event %d failed, will retry: %v", e.ID, err)
+					continue
+				}
+				fired++
+			}
+			if err := s.ScheduledEvents.Complete(ctx, e.ID); err != nil {
+				log.Printf("[NOTIFY] completing scheduled event %d: %v", e.ID, err)
+			}
+		}
+		if len(due) < scheduledEventBatch {
+			return fired, nil
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/templates"
+)
+
+type stubScheduledEvents struct {
+	scheduled []ScheduledEvent
+	completed []int64
+}
+
+func (s *stubScheduledEvents) Schedule(ctx Context, e ScheduledEvent) (ScheduledEvent, error) {
+	e.ID = int64(len(s.scheduled) + 1)
+	s.scheduled = append(s.scheduled, e)
+	return e, nil
+}
+func (s *stubScheduledEvents) Claim(ctx Context, now time.Time, lease time.Duration, limit int) ([]ScheduledEvent, error) {
+	var due []ScheduledEvent
+	for i := range s.scheduled {
+		if !s.scheduled[i].DeliverAt.After(now) {
+			s.scheduled[i].Attempts++
+			due = append(due, s.scheduled[i])
+		}
+	}
+	return due, nil
+}
+func (s *stubScheduledEvents) Complete(ctx Context, id int64) error {
+	s.completed = append(s.completed, id)
+	return nil
+}
+func (s *stubScheduledEvents) Cancel(ctx Context, orgID int64, key string) (bool, error) {
+	return false, nil
+}
+
+func TestScheduledFor(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	if at, err := (NotificationEvent{Delay: "48h"}).ScheduledFor(now); err != nil || !at.Equal(now.Add(48*time.Hour)) {
+		t.Fatalf("delay: got %v %v", at, err)
+	}
+	if at, _ := (NotificationEvent{DeliverAt: &later, Delay: "1m"}).ScheduledFor(now); !at.Equal(later) {
+		t.Fatalf("deliver_at should win over delay, got %v", at)
+	}
+	if at, _ := (NotificationEvent{DeliverAt: &past}).ScheduledFor(now); !at.IsZero() {
+		t.Fatalf("past deliver_at is due now, got %v", at)
+	}
+	if _, err := (NotificationEvent{Delay: "soon"}).ScheduledFor(now); err == nil {
+		t.Fatalf("expected invalid delay error")
+	}
+}
+
+func TestHandleEvent_StoresDelayedEvent(t *testing.T) {
+	events := &stubScheduledEvents{}
+	email := &stubEmail{}
+	svc := &NotificationService{
+		Templates:       &stubTemplateRepo{},
+		Preferences:     &stubPrefRepo{},
+		Logs:            &stubLogRepo{},
+		Email:           email,
+		ScheduledEvents: events,
+		Renderer:        templates.Renderer{},
+		Defaults:        Defaults{Emails: []string{"ops@x.io"}},
+	}
+
+	evt := NotificationEvent{EventType: "trial.expiring", OrganizationID: 1, Delay: "24h", ScheduleKey: "trial:1"}
+	if err := svc.HandleEvent(context.Background(), evt); err != nil {
+		t.Fatalf("handle event: %v", err)
+	}
+	if len(email.to) != 0 || len(events.scheduled) != 1 {
+		t.Fatalf("expected event stored, not sent: email %v scheduled %#v", email.to, events.scheduled)
+	}
+	stored := events.scheduled[0]
+	if stored.Key != "trial:1" || stored.Event.Delay != "" || stored.Event.ScheduleKey != "" || stored.DeliverAt.Before(time.Now().Add(23*time.Hour)) {
+		t.Fatalf("unexpected stored event %#v", stored)
+	}
+
+	if _, err := svc.FireScheduledEvents(context.Background(), time.Now()); err != nil {
+		t.Fatalf("fire: %v", err)
+	}
+	if len(email.to) != 0 {
+		t.Fatalf("event fired early")
+	}
+	fired, err := svc.FireScheduledEvents(context.Background(), stored.DeliverAt)
+	if err != nil || fired != 1 || len(email.to) != 1 || len(events.completed) != 1 {
+		t.Fatalf("expected event fired once: fired %d err %v email %v completed %v", fired, err, email.to, events.completed)
+	}
+}
+
+func TestHandleEvent_DelayedWithoutStore(t *testing.T) {
+	svc := &NotificationService{}
+	err := svc.HandleEvent(context.Background(), NotificationEvent{EventType: "x", Delay: "1h"})
+	if !errors.Is(err, ErrSchedulingUnavailable) {
+		t.Fatalf("expected ErrSchedulingUnavailable, got %v", err)
+	}
+}
+
+func TestFireScheduledEvents_GivesUpAfterMaxAttempts(t *testing.T) {
+	events := &stubScheduledEvents{scheduled: []ScheduledEvent{{ID: 7, Attempts: maxScheduledAttempts, Event: NotificationEvent{EventType: "x"}}}}
+	svc := &NotificationService{ScheduledEvents: events}
+	fired, err := svc.FireScheduledEvents(context.Background(), time.Now())
+	if err != nil || fired != 0 || len(events.completed) != 1 {
+		t.Fatalf("expected exhausted event dropped, fired %d completed %v err %v", fired, events.completed, err)
+	}
+}
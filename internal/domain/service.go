@@ -3,6 +3,7 @@ package domain
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"strings"
 	"time"
@@ -11,7 +12,7 @@ import (
 	"myesi-notification-service/internal/templates"
 )
 
-// Defaults holds fallback destinations when preferences are absent.
+// Defaults holds fallback destinations for channels no preference configures.
 type Defaults struct {
 	Emails       []string
 	SlackWebhook string
@@ -20,18 +21,40 @@ type Defaults struct {
 
 // NotificationService orchestrates routing, rendering, and delivery.
 type NotificationService struct {
-	Templates   TemplateRepository
-	Preferences PreferenceRepository
-	Logs        LogRepository
-	Inbox       InboxRepository
-	OrgUsers    OrgUserRepository
-	OrgSettings OrgSettingsRepository
-	Email       EmailProvider
-	Slack       SlackProvider
-	Webhook     WebhookProvider
-	Renderer    templates.Renderer
-	Metrics     *metrics.Collector
-	Defaults    Defaults
+	Templates          TemplateRepository
+	Preferences        PreferenceRepository
+	Logs               LogRepository
+	Inbox              InboxRepository
+	OrgUsers           OrgUserRepository
+	OrgSettings        OrgSettingsRepository
+	Email              EmailProvider
+	Slack              SlackProvider
+	Webhook            WebhookProvider
+	Teams              TeamsProvider
+	Locales            LocaleRepository
+	Partials           PartialRepository
+	Digests            DigestRepository
+	QuietHours         QuietHoursRepository
+	Scheduled          ScheduledDeliveryRepository
+	ScheduledEvents    ScheduledEventRepository
+	Escalations        EscalationRepository
+	EscalationPolicies EscalationPolicyRepository
+	Alerts             AlertRepository
+	RateLimits         RateLimitRepository
+	GroupingRules      GroupingRuleRepository
+	Routes             RoutingRuleRepository
+	Audiences          AudienceRepository
+	ProjectMembers     ProjectMemberRepository
+	Watchers           WatcherRepository
+	Groups             GroupRepository
+	Destinations       DestinationRepository
+	Snoozes            SnoozeRepository
+	Renderer           templates.Renderer
+	Metrics            *metrics.Collector
+	Defaults           Defaults
+	// VerifyURL is the page that confirms a destination; when set,
+	// confirmation messages link to it with the code.
+	VerifyURL string
 }
 
 // HandleEvent processes a single domain event and dispatches notifications.
@@ -42,6 +65,23 @@ func (s *NotificationService) HandleEvent(ctx context.Context, evt NotificationE
 	if evt.OccurredAt.IsZero() {
 		evt.OccurredAt = time.Now().UTC()
 	}
+	at, err := evt.ScheduledFor(time.Now())
+	if err != nil {
+		return err
+	}
+	if !at.IsZero() {
+		return s.scheduleEvent(ctx, evt, at)
+	}
+	if evt.resolvesAlert() && s.Alerts != nil {
+		_, err := s.TransitionAlert(ctx, evt.OrganizationID, evt.CorrelationKey, AlertResolved, "event:"+evt.EventType)
+		switch {
+		case err == nil, errors.Is(err, ErrInvalidTransition):
+			// Resolved now or already: the original channels were told once.
+			return nil
+		case !errors.Is(err, ErrAlertNotFound):
+			log.Printf("[NOTIFY] resolving alert %s failed: %v", evt.CorrelationKey, err)
+		}
+	}
 
 	var settings *OrgSettings
 	if s.OrgSettings != nil && evt.OrganizationID != 0 {
@@ -52,294 +92,349 @@ func (s *NotificationService) HandleEvent(ctx context.Context, evt NotificationE
 	if !eventEnabled(evt.EventType, settings) {
 		return nil
 	}
+	route := s.routeEvent(ctx, evt)
+	if route.Drop {
+		log.Printf("[NOTIFY] %s dropped by routing rules %v", evt.EventType, route.Rules)
+		return nil
+	}
+	templateType := evt.EventType
+	if route.Template != "" {
+		templateType = route.Template
+	}
+	group, absorbed := s.groupEvent(ctx, evt)
+	if absorbed {
+		return nil
+	}
+
+	data := BuildTemplateData(evt)
+	partials := s.loadPartials(ctx, evt.OrganizationID)
 
-	data := buildTemplateData(evt)
+	// Inbox content is rendered once per locale and shared by recipients.
+	inboxContent := map[string]FormattedMessage{}
+	renderInbox := func(locale string) FormattedMessage {
+		if msg, ok := inboxContent[locale]; ok {
+			return msg
+		}
+		tpl := s.resolveTemplate(ctx, evt.OrganizationID, templateType, "", locale)
+		subject, body := s.renderTemplate(tpl, PartialSet(partials, ChannelInbox), withLocale(data, locale))
+		msg := FormatMessage(tpl.Format, ChannelInbox, subject, body)
+		inboxContent[locale] = msg
+		return msg
+	}
 
-	baseTpl := s.resolveTemplate(ctx, evt.EventType, "")
-	baseSubject, baseBody := s.renderTemplate(baseTpl, data)
+	var eventUserLocale string
+	if evt.UserID != nil && *evt.UserID != 0 {
+		eventUserLocale = s.userLocales(ctx, []int64{*evt.UserID})[*evt.UserID]
+	}
+	locale := localeFor(evt, eventUserLocale, settings)
+	alertState := s.openAlert(ctx, evt, renderInbox(locale).Subject)
 
 	// Store in-app inbox for targeted user, independent of outbound channels.
-	if s.Inbox != nil && evt.UserID != nil && *evt.UserID != 0 {
-		actionURL, _ := evt.Payload["action_url"].(string)
-		_, _ = s.Inbox.Save(ctx, UserNotification{
-			UserID:         *evt.UserID,
-			OrganizationID: evt.OrganizationID,
-			Title:          baseSubject,
-			Message:        baseBody,
-			Type:           evt.EventType,
-			Severity:       evt.Severity,
-			ActionURL:      actionURL,
-			Read:           false,
-			Payload:        evt.Payload,
-			CreatedAt:      time.Now().UTC(),
-		})
-	} else if s.Inbox != nil && s.OrgUsers != nil && evt.OrganizationID != 0 {
-		// Org-wide notifications for payment/scan/vuln summaries when no explicit user.
-		targetRole := ""
-		if r, ok := evt.Payload["target_role"].(string); ok {
-			targetRole = r
+	// An inbox preference can set a digest cadence, or opt out of the inbox
+	// entirely or below a severity; a snooze mutes the user for a while.
+	inboxPrefs := map[int64]NotificationPreference(nil)
+	var snoozes snoozeSet
+	if s.Inbox != nil {
+		inboxPrefs = s.inboxPreferences(ctx, evt)
+		snoozes = s.activeSnoozes(ctx, evt.OrganizationID)
+	}
+	limits := rateLimitCache{}
+	var inboxIDs []int64
+	saveInbox := func(uid int64, locale string) {
+		pref, ok := inboxPreferenceFor(inboxPrefs, uid)
+		if ok && (!pref.Enabled || !shouldSendForSeverity(pref.SeverityMin, evt.Severity)) {
+			return
 		}
-		var userIDs []int64
-		var err error
-		switch {
-		case strings.HasPrefix(evt.EventType, "payment."):
-			userIDs, err = s.OrgUsers.ListUserIDsByOrg(ctx, evt.OrganizationID)
-		case strings.HasPrefix(evt.EventType, "project.scan.") || strings.HasPrefix(evt.EventType, "sbom.scan."):
-			// Default to developers unless target_role overrides.
-			role := targetRole
-			if role == "" {
-				role = "developer"
-			}
-			userIDs, err = s.OrgUsers.ListUserIDsByOrgWithRole(ctx, evt.OrganizationID, role)
-		default:
-			// Fallback broadcast to all if no specific handler.
-			userIDs, err = s.OrgUsers.ListUserIDsByOrg(ctx, evt.OrganizationID)
+		if !snoozes.until(uid, evt).IsZero() {
+			return
 		}
-		if err != nil {
-			log.Printf("[NOTIFY] cannot load org users for event %s: %v", evt.EventType, err)
+		msg := renderInbox(locale)
+		target := DeliveryTarget{Channel: ChannelInbox, Digest: pref.Digest, UserID: &uid}
+		if s.bufferDigest(ctx, evt, target, msg.Subject, msg.Body) {
+			return
+		}
+		if s.throttle(ctx, evt, target, msg.Format, msg.Subject, msg.Body, limits) {
+			return
 		}
-		actionURL, _ := evt.Payload["action_url"].(string)
-		for _, uid := range userIDs {
-			_, _ = s.Inbox.Save(ctx, UserNotification{
-				UserID:         uid,
-				OrganizationID: evt.OrganizationID,
-				Title:          baseSubject,
-				Message:        baseBody,
-				Type:           evt.EventType,
-				Severity:       evt.Severity,
-				ActionURL:      actionURL,
-				Read:           false,
-				Payload:        evt.Payload,
-				CreatedAt:      time.Now().UTC(),
-			})
+		n := inboxNotification(evt, uid, msg)
+		n.AlertState = alertState
+		saved, err := s.Inbox.Save(ctx, n)
+		if err == nil {
+			inboxIDs = append(inboxIDs, saved.ID)
 		}
 	}
+	members, scoped := s.recipients(ctx, evt, route)
+	if s.Inbox != nil && route.Allows(ChannelInbox) {
+		if evt.UserID != nil && *evt.UserID != 0 {
+			saveInbox(*evt.UserID, localeFor(evt, eventUserLocale, settings))
+		}
+		locales := s.userLocales(ctx, members)
+		for _, uid := range members {
+			saveInbox(uid, localeFor(evt, locales[uid], settings))
+		}
+	}
+
+	s.attachGroupInbox(ctx, group, inboxIDs)
+
+	if alertState != AlertAcknowledged {
+		s.startEscalation(ctx, evt, renderInbox(locale).Subject, inboxIDs)
+	}
 
-	targets := s.resolveTargets(ctx, evt, settings)
+	targets := routeTargets(s.resolveTargets(ctx, evt, settings), route)
+	if scoped {
+		targets = audienceTargets(targets, evt, members)
+	}
 	if len(targets) == 0 {
 		log.Printf("[NOTIFY] No targets resolved for event %s", evt.EventType)
 		return nil
 	}
 
+	// Targets that belong to a user are rendered in that user's locale, like
+	// their inbox items; the rest use the event's.
+	targetLocales := s.userLocales(ctx, targetUsers(targets, evt.UserID))
+	quiet := quietHoursCache{}
 	for _, target := range targets {
-		tpl := s.resolveTemplate(ctx, evt.EventType, target.Channel)
-		subject, body := s.renderTemplate(tpl, data)
-
-		start := time.Now()
-		status := "success"
-		var sendErr error
-
-		switch target.Channel {
-		case ChannelEmail:
-			recipients := strings.Split(target.Target, ",")
-			sendErr = s.Email.SendEmail(ctx, filterNonEmpty(recipients), subject, body)
-		case ChannelSlack:
-			sendErr = s.Slack.SendSlackMessage(ctx, target.Target, body)
-		case ChannelWebhook:
-			payload := map[string]interface{}{
-				"event":            evt,
-				"rendered_subject": subject,
-				"rendered_body":    body,
+		targetLocale := locale
+		if target.UserID != nil {
+			userLocale, ok := targetLocales[*target.UserID]
+			if !ok && evt.UserID != nil && *target.UserID == *evt.UserID {
+				userLocale = eventUserLocale
 			}
-			sendErr = s.Webhook.SendWebhook(ctx, target.Target, payload)
-		default:
+			targetLocale = localeFor(evt, userLocale, settings)
+		}
+		tpl := s.resolveTemplate(ctx, evt.OrganizationID, templateType, target.Channel, targetLocale)
+		subject, body := s.renderTemplate(tpl, PartialSet(partials, target.Channel), withLocale(data, targetLocale))
+		if s.bufferDigest(ctx, evt, target, subject, body) {
 			continue
 		}
-
-		if sendErr != nil {
-			status = "failed"
-			log.Printf("[NOTIFY][%s] send failed: %v", target.Channel, sendErr)
-		} else {
-			log.Printf("[NOTIFY][%s] dispatched to %s", target.Channel, target.Target)
+		if until, ok := s.quietUntil(ctx, evt.OrganizationID, target.UserID, evt.Severity, quiet); ok &&
+			s.deferDelivery(ctx, evt, target, tpl.Format, subject, body, until) {
+			continue
 		}
-
-		if s.Metrics != nil {
-			s.Metrics.ObserveSend(ctx, target.Channel, status, time.Since(start))
+		if s.throttle(ctx, evt, target, tpl.Format, subject, body, limits) {
+			continue
 		}
 
-		_ = s.logAttempt(ctx, evt, target, status, sendErr)
+		_ = s.deliver(ctx, evt, target, tpl.Format, subject, body)
 	}
 
 	return nil
 }
 
-func (s *NotificationService) resolveTargets(ctx context.Context, evt NotificationEvent, settings *OrgSettings) []DeliveryTarget {
-	prefs, err := s.Preferences.List(ctx, evt.OrganizationID, evt.UserID, evt.EventType)
-	if err != nil {
-		log.Printf("[NOTIFY] preference lookup failed: %v", err)
-	}
-
-	resolved := make([]DeliveryTarget, 0)
-	for _, pref := range prefs {
-		if !pref.Enabled {
+// targetUsers lists the users owning targets, other than the event's own
+// user whose locale is already known.
+func targetUsers(targets []DeliveryTarget, eventUser *int64) []int64 {
+	seen := map[int64]bool{}
+	var out []int64
+	for _, t := range targets {
+		if t.UserID == nil || seen[*t.UserID] || (eventUser != nil && *t.UserID == *eventUser) {
 			continue
 		}
-		if !shouldSendForSeverity(pref.SeverityMin, evt.Severity) {
-			continue
-		}
-		if pref.Channel == ChannelEmail && settings != nil && !settings.EmailNotifications {
-			continue
-		}
-		resolved = append(resolved, DeliveryTarget{Channel: pref.Channel, Target: pref.Target})
+		seen[*t.UserID] = true
+		out = append(out, *t.UserID)
 	}
+	return out
+}
 
-	// Fallback: use event-provided targets or defaults if no preferences.
-	// If a specific user is provided, avoid broadcasting to org defaults.
-	if len(resolved) == 0 {
-		if evt.UserID != nil && *evt.UserID != 0 {
-			return resolved
-		}
+// inboxNotification builds the inbox item for uid from a formatted message.
+func inboxNotification(evt NotificationEvent, uid int64, msg FormattedMessage) UserNotification {
+	actionURL, _ := evt.Payload["action_url"].(string)
+	return UserNotification{
+		UserID:         uid,
+		OrganizationID: evt.OrganizationID,
+		Title:          msg.Subject,
+		Message:        msg.Body,
+		Format:         msg.Format,
+		Type:           evt.EventType,
+		Severity:       evt.Severity,
+		ActionURL:      actionURL,
+		Read:           false,
+		Payload:        evt.Payload,
+		CreatedAt:      time.Now().UTC(),
+		CorrelationKey: evt.CorrelationKey,
+	}
+}
 
-		if len(evt.TargetEmails) > 0 && (settings == nil || settings.EmailNotifications) {
-			resolved = append(resolved, DeliveryTarget{Channel: ChannelEmail, Target: strings.Join(evt.TargetEmails, ",")})
-		} else if len(s.Defaults.Emails) > 0 && (settings == nil || settings.EmailNotifications) {
-			resolved = append(resolved, DeliveryTarget{Channel: ChannelEmail, Target: strings.Join(s.Defaults.Emails, ",")})
-		}
+// ErrUnsupportedChannel is returned for channels that have no outbound provider.
+var ErrUnsupportedChannel = errors.New("unsupported channel")
+
+// deliver sends rendered output and records the attempt in logs and metrics.
+// Channels without an outbound provider are skipped silently.
+func (s *NotificationService) deliver(ctx context.Context, evt NotificationEvent, target DeliveryTarget, format, subject, body string) error {
+	start := time.Now()
+	status := "success"
+	sendErr := s.send(ctx, evt, target, format, subject, body)
+	if errors.Is(sendErr, ErrUnsupportedChannel) {
+		return sendErr
+	}
 
-		slackURL := evt.SlackWebhook
-		if slackURL == "" {
-			slackURL = s.Defaults.SlackWebhook
-		}
-		if slackURL != "" {
-			resolved = append(resolved, DeliveryTarget{Channel: ChannelSlack, Target: slackURL})
-		}
+	if sendErr != nil {
+		status = "failed"
+		log.Printf("[NOTIFY][%s] send failed: %v", target.Channel, sendErr)
+	} else {
+		log.Printf("[NOTIFY][%s] dispatched to %s", target.Channel, target.Target)
+	}
 
-		webhookURL := evt.WebhookURL
-		if webhookURL == "" {
-			webhookURL = s.Defaults.WebhookURL
-		}
-		if webhookURL != "" {
-			resolved = append(resolved, DeliveryTarget{Channel: ChannelWebhook, Target: webhookURL})
-		}
+	if s.Metrics != nil {
+		s.Metrics.ObserveSend(ctx, target.Channel, status, time.Since(start))
 	}
 
-	return resolved
+	_ = s.logAttempt(ctx, evt, target, status, sendErr)
+	return sendErr
 }
 
-func (s *NotificationService) resolveTemplate(ctx context.Context, eventType, channel string) NotificationTemplate {
-	// Opinionated defaults for common events to keep messages user-friendly without org IDs.
-	switch eventType {
-	case "vulnerability.assignment":
-		return NotificationTemplate{
-			EventType: eventType,
-			Channel:   channel,
-			Subject:   "New vulnerability assigned to you",
-			Body:      "A vulnerability task for project {{.payload.project}} has been assigned to you. Priority: {{.event.severity}}.",
-		}
-	case "code_finding.assignment":
-		return NotificationTemplate{
-			EventType: eventType,
-			Channel:   channel,
-			Subject:   "New code finding assigned to you",
-			Body:      "A code finding in project {{.payload.project}} has been assigned to you. Priority: {{.event.severity}}.",
-		}
-	case "project.scan.completed":
-		return NotificationTemplate{
-			EventType: eventType,
-			Channel:   channel,
-			Subject:   "Project scan completed",
-			Body:      "Scan finished for {{.payload.project}}. Findings: {{.payload.vulns}} vulns, {{.payload.code_findings}} code findings.",
-		}
-	case "project.scan.failed":
-		return NotificationTemplate{
-			EventType: eventType,
-			Channel:   channel,
-			Subject:   "Project scan failed",
-			Body:      "Scan failed for {{.payload.project}}. Error: {{.payload.error}}",
+// Channels lists the channels this service can deliver to: the outbound
+// channels with a registered provider, and the inbox when it is stored.
+func (s *NotificationService) Channels() []string {
+	var out []string
+	if s.Email != nil {
+		out = append(out, ChannelEmail)
+	}
+	if s.Slack != nil {
+		out = append(out, ChannelSlack)
+	}
+	if s.Teams != nil {
+		out = append(out, ChannelTeams)
+	}
+	if s.Webhook != nil {
+		out = append(out, ChannelWebhook)
+	}
+	if s.Inbox != nil {
+		out = append(out, ChannelInbox)
+	}
+	return out
+}
+
+// send formats rendered output for the target's channel and hands it to the provider.
+func (s *NotificationService) send(ctx context.Context, evt NotificationEvent, target DeliveryTarget, format, subject, body string) error {
+	msg := FormatMessage(format, target.Channel, subject, body)
+	switch target.Channel {
+	case ChannelEmail:
+		recipients := filterNonEmpty(strings.Split(target.Target, ","))
+		if htmlEmail, ok := s.Email.(HTMLEmailProvider); ok && msg.HTML != "" {
+			return htmlEmail.SendHTMLEmail(ctx, recipients, msg.Subject, msg.Body, msg.HTML)
 		}
-	case "sbom.scan.completed":
-		return NotificationTemplate{
-			EventType: eventType,
-			Channel:   channel,
-			Subject:   "SBOM scan completed",
-			Body:      "Manual SBOM scan finished for {{.payload.project}}. Findings: {{.payload.vulns}} vulns, {{.payload.code_findings}} code findings.",
+		return s.Email.SendEmail(ctx, recipients, msg.Subject, msg.Body)
+	case ChannelSlack:
+		return s.Slack.SendSlackMessage(ctx, target.Target, msg.Body)
+	case ChannelTeams:
+		if s.Teams == nil {
+			return errors.New("teams provider not configured")
 		}
-	case "sbom.scan.failed":
-		return NotificationTemplate{
-			EventType: eventType,
-			Channel:   channel,
-			Subject:   "SBOM scan failed",
-			Body:      "Manual SBOM scan failed for {{.payload.project}}. Error: {{.payload.error}}",
+		return s.Teams.SendTeamsMessage(ctx, target.Target, msg.Body)
+	case ChannelWebhook:
+		payload := map[string]interface{}{
+			"event":            evt,
+			"rendered_subject": msg.Subject,
+			"rendered_body":    msg.Body,
 		}
-	case "project.scan.summary":
-		return NotificationTemplate{
-			EventType: eventType,
-			Channel:   channel,
-			Subject:   "Project scan summary",
-			Body:      "{{.payload.project}} scan complete: {{.payload.vulns}} vulns, {{.payload.code_findings}} code findings.",
+		if format == FormatMarkdown {
+			payload["rendered_markdown"] = body
 		}
-	case "vulnerability.critical":
-		return NotificationTemplate{
-			EventType: eventType,
-			Channel:   channel,
-			Subject:   "Critical vulnerability detected",
-			Body:      "{{.payload.project}} reported {{.payload.critical_count}} critical vulnerabilities. Please review immediately.",
+		return s.Webhook.SendWebhook(ctx, target.Target, payload)
+	default:
+		return ErrUnsupportedChannel
+	}
+}
+
+// resolveTargets returns the active outbound destinations for evt; see
+// effectivePreferences for the precedence between users, the organization
+// and defaults. A failed lookup only keeps the event's own targets.
+func (s *NotificationService) resolveTargets(ctx context.Context, evt NotificationEvent, settings *OrgSettings) []DeliveryTarget {
+	prefs, err := s.Preferences.List(ctx, evt.OrganizationID, evt.UserID, evt.EventType)
+	if err != nil {
+		// Without the rows, defaults could reach channels someone opted out
+		// of, so only the targets the event carries are used.
+		log.Printf("[NOTIFY] preference lookup failed, using event targets only: %v", err)
+	}
+
+	resolved := make([]DeliveryTarget, 0)
+	verified := s.verifiedDestinations(ctx, evt.OrganizationID)
+	snoozed := s.activeSnoozes(ctx, evt.OrganizationID)
+	for _, e := range s.effectivePreferences(prefs, evt, settings, verified, snoozed) {
+		if e.Active && (err == nil || e.Source == SourceEvent) {
+			resolved = append(resolved, DeliveryTarget{Channel: e.Channel, Target: e.Target, Digest: e.Digest, UserID: e.UserID})
 		}
-	case "weekly.report.generated":
-		return NotificationTemplate{
-			EventType: eventType,
-			Channel:   channel,
-			Subject:   "Weekly security summary",
-			Body:      "Summary for {{.payload.organization_name}} ({{.payload.report_week}}): {{.payload.metrics.active_vulnerabilities}} active vulns, {{.payload.metrics.critical_vulnerabilities}} critical, avg risk {{printf \"%.2f\" .payload.metrics.average_risk_score}}.",
+	}
+	return resolved
+}
+
+// resolveTemplate picks the template for an event, channel and locale. Each tag in
+// the locale chain is tried in turn with precedence org override > built-in
+// default > global stored template; the generic built-in message is the last resort.
+func (s *NotificationService) resolveTemplate(ctx context.Context, orgID int64, eventType, channel, locale string) NotificationTemplate {
+	chain := LocaleChain(locale)
+	for _, loc := range chain {
+		tpl, err := s.Templates.FindByEventAndChannel(ctx, orgID, eventType, channel, loc)
+		if err != nil {
+			log.Printf("[NOTIFY] template lookup failed: %v", err)
 		}
-	case "user.activity.suspicious-login":
-		return NotificationTemplate{
-			EventType: eventType,
-			Channel:   channel,
-			Subject:   "Suspicious login detected",
-			Body:      "User {{.payload.user.email}} logged in from {{.payload.current_ip}} (previous {{.payload.previous_ip}}). Verify this activity.",
+		if tpl != nil && tpl.OrganizationID != nil {
+			return *tpl
 		}
-	case "sbom.scan.summary":
-		return NotificationTemplate{
-			EventType: eventType,
-			Channel:   channel,
-			Subject:   "SBOM scan summary",
-			Body:      "SBOM uploaded for {{.payload.project}}: {{.payload.components}} components, {{.payload.vulns}} vulns found.",
+		if builtin, ok := builtinFor(loc, eventType); ok {
+			return NotificationTemplate{EventType: eventType, Channel: channel, Locale: loc, Subject: builtin.Subject, Body: builtin.Body}
 		}
-	case "payment.success":
-		return NotificationTemplate{
-			EventType: eventType,
-			Channel:   channel,
-			Subject:   "Payment received",
-			Body:      "Your payment for {{.payload.plan_name}} succeeded. Amount: {{.payload.amount}}. Thank you!",
+		if tpl != nil {
+			return *tpl
 		}
-	case "payment.failed":
-		return NotificationTemplate{
-			EventType: eventType,
-			Channel:   channel,
-			Subject:   "Payment failed",
-			Body:      "A payment attempt for {{.payload.plan_name}} failed. Please update billing details.",
+	}
+
+	for _, loc := range chain {
+		if builtin, ok := builtinFor(loc, genericEvent); ok {
+			return NotificationTemplate{Channel: channel, Locale: loc, Subject: builtin.Subject, Body: builtin.Body}
 		}
 	}
+	return NotificationTemplate{Channel: channel}
+}
 
-	tpl, err := s.Templates.FindByEventAndChannel(ctx, eventType, channel)
-	if err != nil {
-		log.Printf("[NOTIFY] template lookup failed: %v", err)
+// renderTemplate renders the subject with the partials only; the channel layout
+// wraps the body alone.
+func (s *NotificationService) renderTemplate(tpl NotificationTemplate, set templates.Set, data map[string]interface{}) (string, string) {
+	subjectSet := templates.Set{Partials: set.Partials}
+	fallback := fallbackTemplate(tpl)
+	return s.renderField("subject", tpl.Subject, fallback.Subject, subjectSet, data),
+		s.renderField("body", tpl.Body, fallback.Body, set, data)
+}
+
+// renderField renders strictly and, when fields are missing, logs them and
+// falls back to a lenient render so recipients never see raw template markup
+// or "<no value>". A broken partial or layout degrades to rendering the template
+// on its own; if the template cannot run at all the built-in fallback is used.
+func (s *NotificationService) renderField(field, src, fallback string, set templates.Set, data map[string]interface{}) string {
+	out, err := s.Renderer.RenderWith(set, src, data)
+	if err == nil {
+		return out
 	}
-	if tpl != nil {
-		return *tpl
+	log.Printf("[NOTIFY] render %s: %v", field, err)
+	if out, err = s.Renderer.RenderLenientWith(set, src, data); err == nil {
+		return out
 	}
-
-	// Default fallback
-	return NotificationTemplate{
-		Subject: "MyESI update",
-		Body:    "You have a new update: {{.event.type}}.",
-		Channel: channel,
+	if !set.IsZero() {
+		if out, err = s.Renderer.RenderLenient(src, data); err == nil {
+			log.Printf("[NOTIFY] render %s without partials and layout", field)
+			return out
+		}
+	}
+	log.Printf("[NOTIFY] render %s failed, using built-in fallback: %v", field, err)
+	if out, err = s.Renderer.RenderLenient(fallback, data); err == nil {
+		return out
 	}
+	return ""
 }
 
-func (s *NotificationService) renderTemplate(tpl NotificationTemplate, data map[string]interface{}) (string, string) {
-	subj, err := s.Renderer.Render(tpl.Subject, data)
-	if err != nil {
-		log.Printf("[NOTIFY] render subject failed, using fallback: %v", err)
-		subj = tpl.Subject
-	}
-	body, err := s.Renderer.Render(tpl.Body, data)
-	if err != nil {
-		log.Printf("[NOTIFY] render body failed, using fallback: %v", err)
-		body = tpl.Body
+// fallbackTemplate is the built-in message for tpl's event type, or the
+// generic one, in tpl's locale.
+func fallbackTemplate(tpl NotificationTemplate) builtinTemplate {
+	chain := LocaleChain(tpl.Locale)
+	for _, eventType := range []string{tpl.EventType, genericEvent} {
+		for _, loc := range chain {
+			if builtin, ok := builtinFor(loc, eventType); ok {
+				return builtin
+			}
+		}
 	}
-	return subj, body
+	return builtinTemplate{}
 }
 
 func (s *NotificationService) logAttempt(ctx context.Context, evt NotificationEvent, target DeliveryTarget, status string, sendErr error) error {
@@ -369,7 +464,8 @@ func (s *NotificationService) logAttempt(ctx context.Context, evt NotificationEv
 	return s.Logs.Insert(ctx, logEntry)
 }
 
-func buildTemplateData(evt NotificationEvent) map[string]interface{} {
+// BuildTemplateData exposes an event to templates as {{.event.*}} and {{.payload.*}}.
+func BuildTemplateData(evt NotificationEvent) map[string]interface{} {
 	data := map[string]interface{}{
 		"event": map[string]interface{}{
 			"type":            evt.EventType,
@@ -399,6 +495,16 @@ func eventEnabled(eventType string, settings *OrgSettings) bool {
 	}
 }
 
+// ValidSeverity reports whether severity is on the ladder low < medium < high
+// < critical. Empty means no floor.
+func ValidSeverity(severity string) bool {
+	switch strings.ToLower(severity) {
+	case "", "low", "medium", "high", "critical":
+		return true
+	}
+	return false
+}
+
 func shouldSendForSeverity(min, actual string) bool {
 	if min == "" || actual == "" {
 		return true
@@ -42,16 +42,19 @@ func (s *stubInboxRepo) Delete(ctx Context, id int64, userID int64) error   { re
 
 type stubTemplateRepoAlways struct{ tpl NotificationTemplate }
 
-func (r *stubTemplateRepoAlways) List(ctx Context, limit, offset int) ([]NotificationTemplate, error) {
+func (r *stubTemplateRepoAlways) List(ctx Context, orgID int64, limit, offset int) ([]NotificationTemplate, error) {
 	return []NotificationTemplate{r.tpl}, nil
 }
 func (r *stubTemplateRepoAlways) Upsert(ctx Context, tpl NotificationTemplate) (NotificationTemplate, error) {
 	r.tpl = tpl
 	return tpl, nil
 }
-func (r *stubTemplateRepoAlways) FindByEventAndChannel(ctx Context, eventType, channel string) (*NotificationTemplate, error) {
+func (r *stubTemplateRepoAlways) FindByEventAndChannel(ctx Context, orgID int64, eventType, channel, locale string) (*NotificationTemplate, error) {
 	return &r.tpl, nil
 }
+func (r *stubTemplateRepoAlways) Get(ctx Context, orgID, id int64) (*NotificationTemplate, error) {
+	return nil, nil
+}
 
 type stubPrefRepoStatic struct{ prefs []NotificationPreference }
 
@@ -62,6 +65,16 @@ func (r *stubPrefRepoStatic) Save(ctx Context, pref NotificationPreference) (Not
 	r.prefs = append(r.prefs, pref)
 	return pref, nil
 }
+func (r *stubPrefRepoStatic) Get(ctx Context, id int64) (*NotificationPreference, error) {
+	return nil, nil
+}
+func (r *stubPrefRepoStatic) Delete(ctx Context, orgID, id int64) error { return nil }
+func (r *stubPrefRepoStatic) Replace(ctx Context, orgID int64, userID *int64, prefs []NotificationPreference) ([]NotificationPreference, error) {
+	return prefs, nil
+}
+func (r *stubPrefRepoStatic) ReplaceMatrix(ctx Context, orgID, userID int64, eventTypes, channels []string, prefs []NotificationPreference) ([]NotificationPreference, error) {
+	return prefs, nil
+}
 
 func (r *stubLogRepo) Insert(ctx Context, log NotificationLog) error {
 	r.entries = append(r.entries, log)
@@ -94,26 +107,6 @@ func TestHandleEvent_RespectsOrgSettings_DisableVulnAlerts(t *testing.T) {
 	}
 }
 
-func TestResolveTargets_FallbackSuppressedWhenUserSpecified(t *testing.T) {
-	uid := int64(9)
-	svc := &NotificationService{
-		Templates:   &stubTemplateRepoAlways{tpl: NotificationTemplate{Subject: "s", Body: "b"}},
-		Preferences: &stubPrefRepoStatic{prefs: nil}, // none
-		Logs:        &stubLogRepo{},
-		Email:       &stubEmail{},
-		Slack:       &stubSlack{},
-		Webhook:     &stubWebhook{},
-		Renderer:    templates.Renderer{},
-		Defaults:    Defaults{Emails: []string{"default@x.com"}, SlackWebhook: "slack", WebhookURL: "wh"},
-	}
-
-	evt := NotificationEvent{EventType: "payment.success", OrganizationID: 1, UserID: &uid}
-	targets := svc.resolveTargets(context.Background(), evt, &OrgSettings{EmailNotifications: true})
-	if len(targets) != 0 {
-		t.Fatalf("expected no fallback targets for user-specific event, got %v", targets)
-	}
-}
-
 func TestInbox_BroadcastPaymentToAllOrgUsers(t *testing.T) {
 	inbox := &stubInboxRepo{}
 	orgUsers := &stubOrgUsers{all: []int64{1, 2, 3}}
@@ -139,3 +132,67 @@ func TestInbox_BroadcastPaymentToAllOrgUsers(t *testing.T) {
 		t.Fatalf("expected 3 inbox saves, got %d", len(inbox.saved))
 	}
 }
+
+type stubTemplateRepoScoped struct {
+	tpl       *NotificationTemplate
+	lastOrgID int64
+}
+
+func (r *stubTemplateRepoScoped) List(ctx Context, orgID int64, limit, offset int) ([]NotificationTemplate, error) {
+	return nil, nil
+}
+func (r *stubTemplateRepoScoped) Upsert(ctx Context, tpl NotificationTemplate) (NotificationTemplate, error) {
+	return tpl, nil
+}
+func (r *stubTemplateRepoScoped) FindByEventAndChannel(ctx Context, orgID int64, eventType, channel, locale string) (*NotificationTemplate, error) {
+	r.lastOrgID = orgID
+	return r.tpl, nil
+}
+func (r *stubTemplateRepoScoped) Get(ctx Context, orgID, id int64) (*NotificationTemplate, error) {
+	return nil, nil
+}
+
+func TestResolveTemplate_OrgOverrideBeatsBuiltin(t *testing.T) {
+	org := int64(3)
+	repo := &stubTemplateRepoScoped{tpl: &NotificationTemplate{OrganizationID: &org, Subject: "Custom", Body: "Org body"}}
+	svc := &NotificationService{Templates: repo}
+
+	tpl := svc.resolveTemplate(context.Background(), 3, "payment.success", ChannelEmail, DefaultLocale)
+	if tpl.Subject != "Custom" {
+		t.Fatalf("expected org override, got %q", tpl.Subject)
+	}
+	if repo.lastOrgID != 3 {
+		t.Fatalf("expected lookup scoped to org 3, got %d", repo.lastOrgID)
+	}
+}
+
+func TestResolveTemplate_BuiltinBeatsGlobal(t *testing.T) {
+	repo := &stubTemplateRepoScoped{tpl: &NotificationTemplate{Subject: "Global", Body: "Global body"}}
+	svc := &NotificationService{Templates: repo}
+
+	tpl := svc.resolveTemplate(context.Background(), 3, "payment.success", ChannelEmail, DefaultLocale)
+	if tpl.Subject != "Payment received" {
+		t.Fatalf("expected builtin, got %q", tpl.Subject)
+	}
+
+	tpl = svc.resolveTemplate(context.Background(), 3, "custom.event", ChannelEmail, DefaultLocale)
+	if tpl.Subject != "Global" {
+		t.Fatalf("expected global template, got %q", tpl.Subject)
+	}
+}
+
+func TestRenderTemplate_MissingFieldsRenderEmpty(t *testing.T) {
+	svc := &NotificationService{Renderer: templates.Renderer{}}
+	data := BuildTemplateData(NotificationEvent{EventType: "project.scan.failed", Payload: map[string]interface{}{"project": "api"}})
+
+	subj, body := svc.renderTemplate(NotificationTemplate{
+		Subject: "{{upper .payload.project}}",
+		Body:    "Scan failed for {{.payload.project}}. Error: {{.payload.error}}",
+	}, templates.Set{}, data)
+	if subj != "API" {
+		t.Fatalf("unexpected subject %q", subj)
+	}
+	if body != "Scan failed for api. Error: " {
+		t.Fatalf("expected missing field rendered empty, got %q", body)
+	}
+}
@@ -10,16 +10,19 @@ import (
 // ---- stubs ----
 type tplRepoStub struct{ tpl NotificationTemplate }
 
-func (r *tplRepoStub) List(ctx Context, limit, offset int) ([]NotificationTemplate, error) {
+func (r *tplRepoStub) List(ctx Context, orgID int64, limit, offset int) ([]NotificationTemplate, error) {
 	return []NotificationTemplate{r.tpl}, nil
 }
 func (r *tplRepoStub) Upsert(ctx Context, tpl NotificationTemplate) (NotificationTemplate, error) {
 	r.tpl = tpl
 	return tpl, nil
 }
-func (r *tplRepoStub) FindByEventAndChannel(ctx Context, eventType, channel string) (*NotificationTemplate, error) {
+func (r *tplRepoStub) FindByEventAndChannel(ctx Context, orgID int64, eventType, channel, locale string) (*NotificationTemplate, error) {
 	return &r.tpl, nil
 }
+func (r *tplRepoStub) Get(ctx Context, orgID, id int64) (*NotificationTemplate, error) {
+	return nil, nil
+}
 
 type prefRepoNone struct{}
 
@@ -29,6 +32,16 @@ func (r *prefRepoNone) List(ctx Context, orgID int64, userID *int64, eventType s
 func (r *prefRepoNone) Save(ctx Context, pref NotificationPreference) (NotificationPreference, error) {
 	return pref, nil
 }
+func (r *prefRepoNone) Get(ctx Context, id int64) (*NotificationPreference, error) {
+	return nil, nil
+}
+func (r *prefRepoNone) Delete(ctx Context, orgID, id int64) error { return nil }
+func (r *prefRepoNone) Replace(ctx Context, orgID int64, userID *int64, prefs []NotificationPreference) ([]NotificationPreference, error) {
+	return prefs, nil
+}
+func (r *prefRepoNone) ReplaceMatrix(ctx Context, orgID, userID int64, eventTypes, channels []string, prefs []NotificationPreference) ([]NotificationPreference, error) {
+	return prefs, nil
+}
 
 type logRepoNoop struct{}
 
@@ -34,16 +34,19 @@ type stubWebhook struct {
 
 type stubMetrics struct{}
 
-func (r *stubTemplateRepo) List(ctx Context, limit, offset int) ([]NotificationTemplate, error) {
+func (r *stubTemplateRepo) List(ctx Context, orgID int64, limit, offset int) ([]NotificationTemplate, error) {
 	return []NotificationTemplate{r.tpl}, nil
 }
 func (r *stubTemplateRepo) Upsert(ctx Context, tpl NotificationTemplate) (NotificationTemplate, error) {
 	r.tpl = tpl
 	return tpl, nil
 }
-func (r *stubTemplateRepo) FindByEventAndChannel(ctx Context, eventType, channel string) (*NotificationTemplate, error) {
+func (r *stubTemplateRepo) FindByEventAndChannel(ctx Context, orgID int64, eventType, channel, locale string) (*NotificationTemplate, error) {
 	return &r.tpl, nil
 }
+func (r *stubTemplateRepo) Get(ctx Context, orgID, id int64) (*NotificationTemplate, error) {
+	return nil, nil
+}
 
 func (r *stubPrefRepo) List(ctx Context, orgID int64, userID *int64, eventType string) ([]NotificationPreference, error) {
 	return r.prefs, nil
@@ -52,6 +55,16 @@ func (r *stubPrefRepo) Save(ctx Context, pref NotificationPreference) (Notificat
 	r.prefs = append(r.prefs, pref)
 	return pref, nil
 }
+func (r *stubPrefRepo) Get(ctx Context, id int64) (*NotificationPreference, error) {
+	return nil, nil
+}
+func (r *stubPrefRepo) Delete(ctx Context, orgID, id int64) error { return nil }
+func (r *stubPrefRepo) Replace(ctx Context, orgID int64, userID *int64, prefs []NotificationPreference) ([]NotificationPreference, error) {
+	return prefs, nil
+}
+func (r *stubPrefRepo) ReplaceMatrix(ctx Context, orgID, userID int64, eventTypes, channels []string, prefs []NotificationPreference) ([]NotificationPreference, error) {
+	return prefs, nil
+}
 
 func (s *stubEmail) SendEmail(ctx Context, to []string, subject, body string) error {
 	if s.err != nil {
@@ -0,0 +1,253 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ChannelPush is a column of the settings page. No push provider exists yet,
+// so it is never available and stores no preferences.
+const ChannelPush = "push"
+
+// ErrInvalidSettings wraps validation errors from SaveUserSettings.
+var ErrInvalidSettings = errors.New("invalid settings")
+
+// SettingsCategory groups event types into a row of the settings page.
+type SettingsCategory struct {
+	Key       string `json:"key"`
+	EventType string `json:"event_type"`
+}
+
+// SettingsCategories are the rows of the user settings matrix. Each maps to
+// user preferences on its event pattern.
+var SettingsCategories = []SettingsCategory{
+	{Key: "vulnerabilities", EventType: "vulnerability.*"},
+	{Key: "code_findings", EventType: "code_finding.*"},
+	{Key: "project_scans", EventType: "project.scan.*"},
+	{Key: "sbom_scans", EventType: "sbom.scan.*"},
+	{Key: "billing", EventType: "payment.*"},
+	{Key: "reports", EventType: "weekly.report.*"},
+	{Key: "account_activity", EventType: "user.activity.*"},
+}
+
+// SettingsChannels are the columns of the user settings matrix.
+var SettingsChannels = []string{ChannelInbox, ChannelEmail, ChannelSlack, ChannelPush}
+
+// UserSettings is one user's preferences as a matrix of event categories and
+// channels.
+type UserSettings struct {
+	Channels   []ChannelSettings  `json:"channels"`
+	Categories []CategorySettings `json:"categories"`
+}
+
+// ChannelSettings is a settings column: whether the service can deliver on
+// the channel and the user's destination there.
+type ChannelSettings struct {
+	Channel      string `json:"channel"`
+	Available    bool   `json:"available"`
+	Target       string `json:"target,omitempty"`
+	Verification string `json:"verification,omitempty"`
+}
+
+// CategorySettings is a settings row with a cell per channel.
+type CategorySettings struct {
+	Category  string                  `json:"category"`
+	EventType string                  `json:"event_type,omitempty"`
+	Cells     map[string]SettingsCell `json:"cells"`
+}
+
+// SettingsCell is the user's own preference for a category on a channel.
+// Inherited cells have no preference of their own: they show the default,
+// inbox on and everything else off, and follow the organization's rows.
+type SettingsCell struct {
+	Enabled     bool   `json:"enabled"`
+	SeverityMin string `json:"severity_min,omitempty"`
+	Digest      string `json:"digest,omitempty"`
+	Inherited   bool   `json:"inherited,omitempty"`
+}
+
+// UserSettings builds the settings matrix from the user's preferences.
+func (s *NotificationService) UserSettings(ctx context.Context, orgID, userID int64) (UserSettings, error) {
+	rows, err := s.userPreferences(ctx, orgID, userID)
+	if err != nil {
+		return UserSettings{}, err
+	}
+	out := UserSettings{}
+	for _, ch := range SettingsChannels {
+		cs := ChannelSettings{Channel: ch, Available: s.hasChannel(ch), Target: channelTarget(rows, ch)}
+		if cs.Target != "" && NeedsVerification(ch) && s.Destinations != nil {
+			cs.Verification = DestinationPending
+			if s.destinationVerified(ctx, orgID, ch, cs.Target) {
+				cs.Verification = DestinationVerified
+			}
+		}
+		out.Channels = append(out.Channels, cs)
+	}
+	for _, cat := range SettingsCategories {
+		row := CategorySettings{Category: cat.Key, EventType: cat.EventType, Cells: map[string]SettingsCell{}}
+		for _, ch := range SettingsChannels {
+			cell := SettingsCell{Enabled: ch == ChannelInbox, Inherited: true}
+			for _, p := range rows {
+				if p.EventType == cat.EventType && p.Channel == ch {
+					cell = SettingsCell{Enabled: p.Enabled, SeverityMin: p.SeverityMin, Digest: p.Digest}
+					break
+				}
+			}
+			row.Cells[ch] = cell
+		}
+		out.Categories = append(out.Categories, row)
+	}
+	return out, nil
+}
+
+// SaveUserSettings replaces the user's matrix preferences with in, in one
+// transaction. Categories and cells left out, and inherited cells, lose their
+// preference; the user's other preferences are kept. New destinations are
+// sent a confirmation code.
+func (s *NotificationService) SaveUserSettings(ctx context.Context, orgID, userID int64, in UserSettings) (UserSettings, error) {
+	targets, err := s.settingsTargets(in.Channels)
+	if err != nil {
+		return UserSettings{}, err
+	}
+	var rows []NotificationPreference
+	used := map[string]bool{}
+	seen := map[string]bool{}
+	for _, row := range in.Categories {
+		cat, ok := settingsCategory(row.Category)
+		if !ok {
+			return UserSettings{}, fmt.Errorf("%w: unknown category %q", ErrInvalidSettings, row.Category)
+		}
+		if seen[cat.Key] {
+			return UserSettings{}, fmt.Errorf("%w: duplicate category %q", ErrInvalidSettings, cat.Key)
+		}
+		seen[cat.Key] = true
+		for ch, cell := range row.Cells {
+			if !settingsChannel(ch) {
+				return UserSettings{}, fmt.Errorf("%w: unknown channel %q", ErrInvalidSettings, ch)
+			}
+			if cell.Inherited {
+				continue
+			}
+			if cell.Enabled && !s.hasChannel(ch) {
+				return UserSettings{}, fmt.Errorf("%w: %s is not available", ErrInvalidSettings, ch)
+			}
+			if ch == ChannelPush {
+				continue
+			}
+			p := NotificationPreference{OrganizationID: orgID, UserID: &userID, EventType: cat.EventType, Channel: ch,
+				Target: targets[ch], Enabled: cell.Enabled, SeverityMin: cell.SeverityMin, Digest: cell.Digest}
+			if err := p.Validate(); err != nil {
+				return UserSettings{}, fmt.Errorf("%w: %s %s: %v", ErrInvalidSettings, cat.Key, ch, err)
+			}
+			if p.Enabled && p.Target != "" {
+				used[ch] = true
+			}
+			rows = append(rows, p)
+		}
+	}
+
+	eventTypes := make([]string, 0, len(SettingsCategories))
+	for _, cat := range SettingsCategories {
+		eventTypes = append(eventTypes, cat.EventType)
+	}
+	if _, err := s.Preferences.ReplaceMatrix(ctx, orgID, userID, eventTypes, SettingsChannels, rows); err != nil {
+		return UserSettings{}, err
+	}
+	for ch := range used {
+		for _, addr := range VerificationTargets(ch, targets[ch]) {
+			if _, err := s.RequestVerification(ctx, orgID, ch, addr); err != nil {
+				log.Printf("[NOTIFY][%s] verification request failed: %v", ch, err)
+			}
+		}
+	}
+	return s.UserSettings(ctx, orgID, userID)
+}
+
+// settingsTargets validates the destinations set on the channel columns.
+func (s *NotificationService) settingsTargets(channels []ChannelSettings) (map[string]string, error) {
+	targets := map[string]string{}
+	for _, cs := range channels {
+		if !settingsChannel(cs.Channel) {
+			return nil, fmt.Errorf("%w: unknown channel %q", ErrInvalidSettings, cs.Channel)
+		}
+		if cs.Target == "" {
+			continue
+		}
+		if cs.Channel == ChannelInbox || cs.Channel == ChannelPush {
+			return nil, fmt.Errorf("%w: %s takes no target", ErrInvalidSettings, cs.Channel)
+		}
+		if err := ValidateTarget(cs.Channel, cs.Target); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+		}
+		targets[cs.Channel] = cs.Target
+	}
+	return targets, nil
+}
+
+// userPreferences returns the user's own preference rows.
+func (s *NotificationService) userPreferences(ctx context.Context, orgID, userID int64) ([]NotificationPreference, error) {
+	prefs, err := s.Preferences.List(ctx, orgID, &userID, "")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]NotificationPreference, 0, len(prefs))
+	for _, p := range prefs {
+		if p.UserID != nil && *p.UserID == userID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// channelTarget returns the user's destination on channel, preferring the
+// one their matrix preferences use.
+func channelTarget(rows []NotificationPreference, channel string) string {
+	fallback := ""
+	for _, p := range rows {
+		if p.Channel != channel || p.Target == "" {
+			continue
+		}
+		if matrixPreference(p) {
+			return p.Target
+		}
+		if fallback == "" {
+			fallback = p.Target
+		}
+	}
+	return fallback
+}
+
+// matrixPreference reports whether p is a cell of the settings matrix.
+func matrixPreference(p NotificationPreference) bool {
+	_, ok := settingsCategoryFor(p.EventType)
+	return ok && settingsChannel(p.Channel)
+}
+
+func settingsCategory(key string) (SettingsCategory, bool) {
+	for _, c := range SettingsCategories {
+		if c.Key == key {
+			return c, true
+		}
+	}
+	return SettingsCategory{}, false
+}
+
+func settingsCategoryFor(eventType string) (SettingsCategory, bool) {
+	for _, c := range SettingsCategories {
+		if c.EventType == eventType {
+			return c, true
+		}
+	}
+	return SettingsCategory{}, false
+}
+
+func settingsChannel(channel string) bool {
+	for _, c := range SettingsChannels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
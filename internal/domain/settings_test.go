@@ -0,0 +1,170 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"myesi-notification-service/internal/templates"
+)
+
+// stubPrefStore keeps preferences so Replace and ReplaceMatrix are visible to
+// later lookups.
+type stubPrefStore struct{ stubPrefRepoStatic }
+
+func (r *stubPrefStore) Replace(ctx Context, orgID int64, userID *int64, prefs []NotificationPreference) ([]NotificationPreference, error) {
+	kept := []NotificationPreference{}
+	for _, p := range r.prefs {
+		if (p.UserID == nil) != (userID == nil) || (p.UserID != nil && *p.UserID != *userID) {
+			kept = append(kept, p)
+		}
+	}
+	for _, p := range prefs {
+		if p.Digest == "" {
+			p.Digest = DigestImmediate
+		}
+		kept = append(kept, p)
+	}
+	r.prefs = kept
+	return prefs, nil
+}
+
+func (r *stubPrefStore) ReplaceMatrix(ctx Context, orgID, userID int64, eventTypes, channels []string, prefs []NotificationPreference) ([]NotificationPreference, error) {
+	in := func(v string, list []string) bool {
+		for _, x := range list {
+			if x == v {
+				return true
+			}
+		}
+		return false
+	}
+	kept := []NotificationPreference{}
+	for _, p := range r.prefs {
+		if p.UserID == nil || *p.UserID != userID || !in(p.EventType, eventTypes) || !in(p.Channel, channels) {
+			kept = append(kept, p)
+		}
+	}
+	for _, p := range prefs {
+		if p.Digest == "" {
+			p.Digest = DigestImmediate
+		}
+		kept = append(kept, p)
+	}
+	r.prefs = kept
+	return prefs, nil
+}
+
+func TestUserSettings_MatrixFromPreferences(t *testing.T) {
+	uid := int64(9)
+	svc := &NotificationService{
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{OrganizationID: 1, UserID: &uid, EventType: "vulnerability.*", Channel: ChannelEmail, Target: "me@x.io", Enabled: true, SeverityMin: "high", Digest: DigestDaily},
+			{OrganizationID: 1, UserID: &uid, EventType: "payment.*", Channel: ChannelInbox, Enabled: false, Digest: DigestImmediate},
+			{OrganizationID: 1, EventType: "vulnerability.*", Channel: ChannelSlack, Target: "https://hooks/org", Enabled: true},
+		}},
+		Email: &stubEmail{},
+		Inbox: &stubInboxRepo{},
+	}
+
+	got, err := svc.UserSettings(context.Background(), 1, uid)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(got.Channels) != 4 || got.Channels[1].Target != "me@x.io" || !got.Channels[0].Available ||
+		got.Channels[2].Available || got.Channels[3].Available {
+		t.Fatalf("unexpected channels %#v", got.Channels)
+	}
+	if len(got.Categories) != len(SettingsCategories) {
+		t.Fatalf("expected every category, got %d", len(got.Categories))
+	}
+	vulns, billing := got.Categories[0].Cells, got.Categories[4].Cells
+	if c := vulns[ChannelEmail]; !c.Enabled || c.Inherited || c.SeverityMin != "high" || c.Digest != DigestDaily {
+		t.Fatalf("unexpected vulnerabilities email cell %#v", c)
+	}
+	if c := vulns[ChannelSlack]; c.Enabled || !c.Inherited {
+		t.Fatalf("org rows must not show as the user's own, got %#v", c)
+	}
+	if c := vulns[ChannelInbox]; !c.Enabled || !c.Inherited {
+		t.Fatalf("inbox defaults to on, got %#v", c)
+	}
+	if c := billing[ChannelInbox]; c.Enabled || c.Inherited {
+		t.Fatalf("unexpected billing inbox cell %#v", c)
+	}
+}
+
+func TestSaveUserSettings_ReplacesMatrixRowsAndKeepsOthers(t *testing.T) {
+	uid := int64(9)
+	email := &stubEmail{}
+	custom := NotificationPreference{ID: 7, OrganizationID: 1, UserID: &uid, EventType: "payment.failed", Channel: ChannelEmail, Target: "me@x.io", Enabled: true}
+	store := &stubPrefStore{stubPrefRepoStatic{prefs: []NotificationPreference{
+		custom,
+		{OrganizationID: 1, UserID: &uid, EventType: "vulnerability.*", Channel: ChannelEmail, Target: "me@x.io", Enabled: true},
+		{OrganizationID: 1, EventType: "*", Channel: ChannelEmail, Target: "org@x.io", Enabled: true},
+	}}}
+	svc := &NotificationService{
+		Preferences:  store,
+		Email:        email,
+		Inbox:        &stubInboxRepo{},
+		Destinations: &stubDestinations{byKey: map[string]*Destination{}},
+	}
+
+	got, err := svc.SaveUserSettings(context.Background(), 1, uid, UserSettings{
+		Channels: []ChannelSettings{{Channel: ChannelEmail, Target: "new@x.io"}},
+		Categories: []CategorySettings{
+			{Category: "code_findings", Cells: map[string]SettingsCell{ChannelEmail: {Enabled: true, Digest: DigestDaily}}},
+			{Category: "billing", Cells: map[string]SettingsCell{ChannelInbox: {Enabled: false}, ChannelPush: {Enabled: false}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(store.prefs) != 4 || store.prefs[0].ID != 7 {
+		t.Fatalf("expected org row, untouched custom row and two matrix rows, got %#v", store.prefs)
+	}
+	if got.Channels[1].Target != "new@x.io" || got.Channels[1].Verification != DestinationPending || len(email.to) != 1 {
+		t.Fatalf("expected new email target pending verification, got %#v sent to %v", got.Channels[1], email.to)
+	}
+	if c := got.Categories[1].Cells[ChannelEmail]; !c.Enabled || c.Inherited || c.Digest != DigestDaily {
+		t.Fatalf("unexpected code findings email cell %#v", c)
+	}
+	if c := got.Categories[0].Cells[ChannelEmail]; !c.Inherited {
+		t.Fatalf("omitted cells lose their preference, got %#v", c)
+	}
+
+	for _, bad := range []UserSettings{
+		{Categories: []CategorySettings{{Category: "weather"}}},
+		{Categories: []CategorySettings{{Category: "billing", Cells: map[string]SettingsCell{ChannelPush: {Enabled: true}}}}},
+		{Categories: []CategorySettings{{Category: "billing", Cells: map[string]SettingsCell{ChannelSlack: {Enabled: true}}}}},
+		{Categories: []CategorySettings{{Category: "billing", Cells: map[string]SettingsCell{ChannelEmail: {Enabled: true, SeverityMin: "urgent"}}}},
+			Channels: []ChannelSettings{{Channel: ChannelEmail, Target: "me@x.io"}}},
+		{Channels: []ChannelSettings{{Channel: ChannelEmail, Target: "nope"}}},
+	} {
+		if _, err := svc.SaveUserSettings(context.Background(), 1, uid, bad); !errors.Is(err, ErrInvalidSettings) {
+			t.Fatalf("expected ErrInvalidSettings for %#v, got %v", bad, err)
+		}
+	}
+}
+
+func TestHandleEvent_InboxOptOut(t *testing.T) {
+	muted, other := int64(3), int64(4)
+	inbox := &stubInboxRepo{}
+	svc := &NotificationService{
+		Templates: &stubTemplateRepo{},
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{OrganizationID: 1, UserID: &muted, EventType: "payment.*", Channel: ChannelInbox, Enabled: false},
+			{OrganizationID: 1, UserID: &other, EventType: "payment.*", Channel: ChannelInbox, Enabled: true, SeverityMin: "high"},
+		}},
+		Logs:     &stubLogRepo{},
+		Inbox:    inbox,
+		OrgUsers: &stubOrgUsers{all: []int64{muted, other, 5}},
+		Renderer: templates.Renderer{},
+	}
+
+	evt := NotificationEvent{EventType: "payment.success", OrganizationID: 1, Severity: "low"}
+	if err := svc.HandleEvent(context.Background(), evt); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(inbox.saved) != 1 || inbox.saved[0].UserID != 5 {
+		t.Fatalf("expected only user 5 to get the inbox item, got %#v", inbox.saved)
+	}
+}
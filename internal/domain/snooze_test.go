@@ -0,0 +1,127 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/templates"
+)
+
+type stubSnoozes struct {
+	active []Snooze
+}
+
+func (s *stubSnoozes) Active(ctx Context, orgID int64, now time.Time) ([]Snooze, error) {
+	return s.active, nil
+}
+func (s *stubSnoozes) ListForUser(ctx Context, orgID, userID int64, now time.Time) ([]Snooze, error) {
+	return s.active, nil
+}
+func (s *stubSnoozes) Create(ctx Context, z Snooze) (Snooze, error) { return z, nil }
+func (s *stubSnoozes) Delete(ctx Context, orgID, userID, id int64) error {
+	return nil
+}
+func (s *stubSnoozes) DeleteExpired(ctx Context, now time.Time) (int, error) { return 0, nil }
+
+func TestSnooze_Validate(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	cases := []struct {
+		z  Snooze
+		ok bool
+	}{
+		{Snooze{Scope: SnoozeAll, Until: later}, true},
+		{Snooze{Scope: SnoozeEventType, EventType: "vulnerability.*", Until: later}, true},
+		{Snooze{Scope: SnoozeProject, ProjectID: 4, Until: later}, true},
+		{Snooze{Scope: SnoozeProject, Project: "api", Until: later}, true},
+		{Snooze{Scope: SnoozeAll, Until: now}, false},
+		{Snooze{Scope: SnoozeAll, EventType: "payment.*", Until: later}, false},
+		{Snooze{Scope: SnoozeEventType, EventType: "vuln*", Until: later}, false},
+		{Snooze{Scope: SnoozeProject, Until: later}, false},
+		{Snooze{Scope: "forever", Until: later}, false},
+	}
+	for _, c := range cases {
+		if err := c.z.Validate(now); (err == nil) != c.ok {
+			t.Fatalf("%+v: expected ok=%v, got %v", c.z, c.ok, err)
+		}
+	}
+}
+
+func TestSnooze_Matches(t *testing.T) {
+	evt := NotificationEvent{EventType: "vulnerability.critical", Payload: map[string]interface{}{"project_id": 4, "project": "api"}}
+	cases := []struct {
+		z    Snooze
+		want bool
+	}{
+		{Snooze{Scope: SnoozeAll}, true},
+		{Snooze{Scope: SnoozeEventType, EventType: "vulnerability.*"}, true},
+		{Snooze{Scope: SnoozeEventType, EventType: "payment.*"}, false},
+		{Snooze{Scope: SnoozeProject, ProjectID: 4}, true},
+		{Snooze{Scope: SnoozeProject, ProjectID: 5, Project: "api"}, false},
+		{Snooze{Scope: SnoozeProject, Project: "api"}, true},
+		{Snooze{Scope: SnoozeProject, Project: "web"}, false},
+	}
+	for _, c := range cases {
+		if got := c.z.Matches(evt); got != c.want {
+			t.Fatalf("%+v: expected %v got %v", c.z, c.want, got)
+		}
+	}
+}
+
+func TestHandleEvent_SnoozedUserSkipsInboxAndOwnTargets(t *testing.T) {
+	snoozed, other := int64(3), int64(4)
+	inbox := &stubInboxRepo{}
+	email := &stubEmail{}
+	svc := &NotificationService{
+		Templates: &stubTemplateRepo{},
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{OrganizationID: 1, UserID: &snoozed, EventType: "*", Channel: ChannelEmail, Target: "snoozed@x.io", Enabled: true},
+			{OrganizationID: 1, UserID: &other, EventType: "*", Channel: ChannelEmail, Target: "other@x.io", Enabled: true},
+		}},
+		Snoozes: &stubSnoozes{active: []Snooze{
+			{OrganizationID: 1, UserID: snoozed, Scope: SnoozeEventType, EventType: "payment.*", Until: time.Now().Add(time.Hour)},
+		}},
+		Logs:     &stubLogRepo{},
+		Inbox:    inbox,
+		OrgUsers: &stubOrgUsers{all: []int64{snoozed, other}},
+		Email:    email,
+		Renderer: templates.Renderer{},
+	}
+
+	evt := NotificationEvent{EventType: "payment.success", OrganizationID: 1, Severity: "low"}
+	if err := svc.HandleEvent(context.Background(), evt); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(inbox.saved) != 1 || inbox.saved[0].UserID != other {
+		t.Fatalf("expected only user %d to get the inbox item, got %#v", other, inbox.saved)
+	}
+	if strings.Join(email.to, ",") != "other@x.io" {
+		t.Fatalf("expected only the other user's email, got %v", email.to)
+	}
+}
+
+func TestEffectivePreferences_ExplainsSnooze(t *testing.T) {
+	user := int64(9)
+	until := time.Date(2030, 1, 2, 3, 4, 0, 0, time.UTC)
+	svc := &NotificationService{
+		Preferences: &stubPrefRepoStatic{prefs: []NotificationPreference{
+			{ID: 1, EventType: "*", Channel: ChannelEmail, Target: "me@x.io", Enabled: true, UserID: &user},
+		}},
+		Snoozes: &stubSnoozes{active: []Snooze{{UserID: user, Scope: SnoozeAll, Until: until}}},
+	}
+	evt := NotificationEvent{EventType: "payment.success", OrganizationID: 1, UserID: &user, SlackWebhook: "https://hooks/event"}
+	got, err := svc.EffectivePreferences(context.Background(), evt)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected the user row and the event target, got %#v", got)
+	}
+	for _, e := range got {
+		if e.Active || e.Reason != "snoozed until 2030-01-02T03:04:00Z" {
+			t.Fatalf("expected %s to be snoozed, got %#v", e.Channel, e)
+		}
+	}
+}
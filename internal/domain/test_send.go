@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+// StatusTest marks notification_logs rows written by test sends, so they are
+// never mistaken for real deliveries or failures.
+const StatusTest = "test"
+
+// ErrMissingTarget is returned by SendTest when there is no destination.
+var ErrMissingTarget = errors.New("missing target")
+
+// ErrUnverifiedDestination is returned by SendTest for targets that are not
+// verified destinations of the organization.
+var ErrUnverifiedDestination = errors.New("target is not a verified destination")
+
+// TestDelivery reports the outcome of a test send.
+type TestDelivery struct {
+	Channel    string `json:"channel"`
+	Target     string `json:"target"`
+	EventType  string `json:"event_type"`
+	Subject    string `json:"subject"`
+	Body       string `json:"body"`
+	Delivered  bool   `json:"delivered"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// SendTest renders tpl with a sample event and delivers it to target through the
+// real provider. A nil tpl uses the template a real event would resolve to.
+// Target must be a verified destination of the organization, so test sends
+// cannot reach arbitrary addresses. The attempt is logged with StatusTest
+// whether or not delivery succeeds; only invalid input is returned as an error.
+func (s *NotificationService) SendTest(ctx context.Context, orgID int64, eventType string, tpl *NotificationTemplate, target DeliveryTarget) (TestDelivery, error) {
+	switch target.Channel {
+	case ChannelEmail, ChannelSlack, ChannelTeams, ChannelWebhook:
+	default:
+		return TestDelivery{}, ErrUnsupportedChannel
+	}
+	if strings.TrimSpace(target.Target) == "" {
+		return TestDelivery{}, ErrMissingTarget
+	}
+	if !s.destinationVerified(ctx, orgID, target.Channel, target.Target) {
+		return TestDelivery{}, ErrUnverifiedDestination
+	}
+
+	evt := SampleEvent(eventType, orgID)
+	evt.Payload["test"] = true
+
+	var settings *OrgSettings
+	if s.OrgSettings != nil && orgID != 0 {
+		if st, err := s.OrgSettings.Get(ctx, orgID); err == nil {
+			settings = st
+		}
+	}
+	locale := localeFor(evt, "", settings)
+	if tpl == nil {
+		resolved := s.resolveTemplate(ctx, orgID, eventType, target.Channel, locale)
+		tpl = &resolved
+	}
+
+	set := PartialSet(s.loadPartials(ctx, orgID), target.Channel)
+	subject, body := s.renderTemplate(*tpl, set, withLocale(BuildTemplateData(evt), locale))
+
+	start := time.Now()
+	sendErr := s.send(ctx, evt, target, tpl.Format, subject, body)
+	result := TestDelivery{
+		Channel:    target.Channel,
+		Target:     target.Target,
+		EventType:  eventType,
+		Subject:    subject,
+		Body:       body,
+		Delivered:  sendErr == nil,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if sendErr != nil {
+		result.Error = sendErr.Error()
+		log.Printf("[NOTIFY][%s] test send failed: %v", target.Channel, sendErr)
+	}
+
+	_ = s.logAttempt(ctx, evt, target, StatusTest, sendErr)
+	return result, nil
+}
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"myesi-notification-service/internal/templates"
+)
+
+func TestSendTest_RendersAndLogsTestStatus(t *testing.T) {
+	logs := &stubLogRepo{}
+	slack := &stubSlack{}
+	svc := &NotificationService{
+		Templates:    &stubTemplateRepo{tpl: NotificationTemplate{Channel: ChannelSlack, Body: "Scan for {{.project_name}}"}},
+		Logs:         logs,
+		Slack:        slack,
+		Renderer:     templates.Renderer{},
+		Destinations: verifiedDestinations(DeliveryTarget{Channel: ChannelSlack, Target: "https://hooks.slack"}),
+	}
+
+	got, err := svc.SendTest(context.Background(), 1, "scan.done", nil, DeliveryTarget{Channel: ChannelSlack, Target: "https://hooks.slack"})
+	if err != nil {
+		t.Fatalf("send test: %v", err)
+	}
+	if !got.Delivered || slack.url != "https://hooks.slack" || !strings.HasPrefix(slack.msg, "Scan for ") {
+		t.Fatalf("unexpected delivery %#v, slack %#v", got, slack)
+	}
+	if len(logs.entries) != 1 || logs.entries[0].Status != StatusTest || logs.entries[0].Payload["test"] != true {
+		t.Fatalf("expected one test log entry, got %#v", logs.entries)
+	}
+}
+
+func TestSendTest_RecordsProviderFailure(t *testing.T) {
+	logs := &stubLogRepo{}
+	svc := &NotificationService{
+		Logs:         logs,
+		Email:        &stubEmail{err: errors.New("550 mailbox unavailable")},
+		Renderer:     templates.Renderer{},
+		Destinations: verifiedDestinations(DeliveryTarget{Channel: ChannelEmail, Target: "a@b.c"}),
+	}
+	tpl := &NotificationTemplate{Subject: "Hi", Body: "Body"}
+
+	got, err := svc.SendTest(context.Background(), 1, "scan.done", tpl, DeliveryTarget{Channel: ChannelEmail, Target: "a@b.c"})
+	if err != nil {
+		t.Fatalf("send test: %v", err)
+	}
+	if got.Delivered || got.Error != "550 mailbox unavailable" {
+		t.Fatalf("expected failed delivery, got %#v", got)
+	}
+	if len(logs.entries) != 1 || logs.entries[0].Status != StatusTest || logs.entries[0].Error == "" {
+		t.Fatalf("expected test log with error, got %#v", logs.entries)
+	}
+}
+
+func verifiedDestinations(targets ...DeliveryTarget) *stubDestinations {
+	dests := &stubDestinations{byKey: map[string]*Destination{}}
+	for i, t := range targets {
+		dests.byKey[destinationKey(t.Channel, t.Target)] = &Destination{ID: int64(i + 1), OrganizationID: 1,
+			Channel: t.Channel, Target: t.Target, Status: DestinationVerified}
+	}
+	return dests
+}
+
+func TestSendTest_OnlyToVerifiedDestinations(t *testing.T) {
+	slack := &stubSlack{}
+	dests := verifiedDestinations()
+	dests.byKey[destinationKey(ChannelSlack, "https://pending")] = &Destination{ID: 1, OrganizationID: 1,
+		Channel: ChannelSlack, Target: "https://pending", Status: DestinationPending}
+	svc := &NotificationService{Logs: &stubLogRepo{}, Slack: slack, Renderer: templates.Renderer{}, Destinations: dests}
+
+	for _, target := range []string{"http://169.254.169.254/latest/meta-data", "https://pending"} {
+		_, err := svc.SendTest(context.Background(), 1, "x", &NotificationTemplate{Body: "b"}, DeliveryTarget{Channel: ChannelSlack, Target: target})
+		if !errors.Is(err, ErrUnverifiedDestination) {
+			t.Fatalf("%s: expected ErrUnverifiedDestination, got %v", target, err)
+		}
+	}
+	if slack.url != "" {
+		t.Fatalf("nothing should be sent, got %q", slack.url)
+	}
+}
+
+func TestSendTest_RejectsInvalidInput(t *testing.T) {
+	svc := &NotificationService{Logs: &stubLogRepo{}}
+	if _, err := svc.SendTest(context.Background(), 1, "x", nil, DeliveryTarget{Channel: ChannelInbox, Target: "1"}); !errors.Is(err, ErrUnsupportedChannel) {
+		t.Fatalf("expected ErrUnsupportedChannel, got %v", err)
+	}
+	if _, err := svc.SendTest(context.Background(), 1, "x", nil, DeliveryTarget{Channel: ChannelEmail, Target: " "}); !errors.Is(err, ErrMissingTarget) {
+		t.Fatalf("expected ErrMissingTarget, got %v", err)
+	}
+}
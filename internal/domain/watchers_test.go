@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"myesi-notification-service/internal/templates"
+)
+
+type stubWatchers struct {
+	byName map[string][]int64
+	asked  []string
+}
+
+func (s *stubWatchers) Watch(ctx Context, orgID, projectID, userID int64, watching bool) error {
+	return nil
+}
+func (s *stubWatchers) ListForUser(ctx Context, orgID, userID int64) ([]ProjectWatch, error) {
+	return nil, nil
+}
+func (s *stubWatchers) Watchers(ctx Context, orgID, projectID int64, project string) ([]int64, error) {
+	s.asked = append(s.asked, project)
+	return s.byName[project], nil
+}
+
+func TestHandleEvent_ProjectEventsGoToWatchers(t *testing.T) {
+	inbox := &stubInboxRepo{}
+	watchers := &stubWatchers{byName: map[string][]int64{"core-api": {2, 9}}}
+	svc := &NotificationService{
+		Templates:   &stubTemplateRepo{},
+		Preferences: &stubPrefRepoStatic{},
+		Logs:        &stubLogRepo{},
+		Inbox:       inbox,
+		OrgUsers:    &stubOrgUsers{all: []int64{1, 2, 3}, byRole: map[string][]int64{"developer": {1, 2, 3}}},
+		Watchers:    watchers,
+		Renderer:    templates.Renderer{},
+	}
+
+	evt := NotificationEvent{EventType: "project.scan.completed", OrganizationID: 1,
+		Payload: map[string]interface{}{"project": "core-api"}}
+	_ = svc.HandleEvent(context.Background(), evt)
+	if len(inbox.saved) != 2 || inbox.saved[0].UserID != 2 || inbox.saved[1].UserID != 9 {
+		t.Fatalf("expected the watchers, got %#v", inbox.saved)
+	}
+
+	// A project nobody watches keeps the role broadcast.
+	inbox.saved = nil
+	evt.Payload["project"] = "web"
+	_ = svc.HandleEvent(context.Background(), evt)
+	if len(inbox.saved) != 3 {
+		t.Fatalf("expected developer broadcast, got %d", len(inbox.saved))
+	}
+
+	// Configured audiences take precedence over watchers.
+	inbox.saved, watchers.asked = nil, nil
+	svc.Audiences = &stubAudiences{list: []Audience{{EventType: "project.scan.*", UserIDs: []int64{3}}}}
+	evt.Payload["project"] = "core-api"
+	_ = svc.HandleEvent(context.Background(), evt)
+	if len(inbox.saved) != 1 || inbox.saved[0].UserID != 3 || len(watchers.asked) != 0 {
+		t.Fatalf("expected the configured audience, got %#v", inbox.saved)
+	}
+}
@@ -16,8 +16,9 @@ import (
 
 // Collector holds counters used by the service.
 type Collector struct {
-	sent     metric.Int64Counter
-	duration metric.Float64Histogram
+	sent      metric.Int64Counter
+	duration  metric.Float64Histogram
+	throttled metric.Int64Counter
 }
 
 // Init initializes OpenTelemetry metrics with a basic SDK provider.
@@ -48,8 +49,13 @@ func Init() (*Collector, error) {
 		return nil, fmt.Errorf("histogram init failed: %w", err)
 	}
 
+	throttled, err := meter.Int64Counter("notifications_throttled_total")
+	if err != nil {
+		return nil, fmt.Errorf("counter init failed: %w", err)
+	}
+
 	log.Println("[METRICS] Collector initialized")
-	return &Collector{sent: sent, duration: duration}, nil
+	return &Collector{sent: sent, duration: duration, throttled: throttled}, nil
 }
 
 // ObserveSend records the result of a send attempt.
@@ -64,3 +70,16 @@ func (c *Collector) ObserveSend(ctx context.Context, channel, status string, too
 	c.sent.Add(ctx, 1, attrs)
 	c.duration.Record(ctx, took.Seconds(), metric.WithAttributes(attribute.String("channel", channel)))
 }
+
+// ObserveThrottled records a delivery held back by a rate limit; action is
+// "delay" or "summarize".
+func (c *Collector) ObserveThrottled(ctx context.Context, channel, eventType, action string) {
+	if c == nil {
+		return
+	}
+	c.throttled.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("channel", channel),
+		attribute.String("event_type", eventType),
+		attribute.String("action", action),
+	))
+}
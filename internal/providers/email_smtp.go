@@ -3,8 +3,13 @@ package providers
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
 	"net/smtp"
+	"net/textproto"
 	"strings"
 )
 
@@ -17,7 +22,21 @@ type SMTPProvider struct {
 	From string
 }
 
-func (p SMTPProvider) SendEmail(_ context.Context, to []string, subject string, body string) error {
+func (p SMTPProvider) SendEmail(ctx context.Context, to []string, subject string, body string) error {
+	return p.send(ctx, to, subject, "text/plain; charset=\"utf-8\"", []byte(body))
+}
+
+// SendHTMLEmail sends a multipart/alternative message so clients without HTML
+// support show the plain-text part.
+func (p SMTPProvider) SendHTMLEmail(ctx context.Context, to []string, subject, text, html string) error {
+	body, contentType, err := multipartAlternative(text, html)
+	if err != nil {
+		return err
+	}
+	return p.send(ctx, to, subject, contentType, body)
+}
+
+func (p SMTPProvider) send(ctx context.Context, to []string, subject, contentType string, body []byte) error {
 	if len(to) == 0 {
 		return nil
 	}
@@ -28,9 +47,9 @@ func (p SMTPProvider) SendEmail(_ context.Context, to []string, subject string,
 	headers := map[string]string{
 		"From":         p.From,
 		"To":           strings.Join(to, ","),
-		"Subject":      subject,
+		"Subject":      encodeSubject(subject),
 		"MIME-Version": "1.0",
-		"Content-Type": "text/plain; charset=\"utf-8\"",
+		"Content-Type": contentType,
 	}
 
 	var msg bytes.Buffer
@@ -38,7 +57,7 @@ func (p SMTPProvider) SendEmail(_ context.Context, to []string, subject string,
 		msg.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
 	}
 	msg.WriteString("\r\n")
-	msg.WriteString(body)
+	msg.Write(body)
 
 	addr := fmt.Sprintf("%s:%d", p.Host, p.Port)
 	var auth smtp.Auth
@@ -46,5 +65,50 @@ func (p SMTPProvider) SendEmail(_ context.Context, to []string, subject string,
 		auth = smtp.PlainAuth("", p.User, p.Pass, p.Host)
 	}
 
-	return smtp.SendMail(addr, auth, p.From, to, msg.Bytes())
+	err := smtp.SendMail(addr, auth, p.From, to, msg.Bytes())
+	var protoErr *textproto.Error
+	switch {
+	case err == nil:
+		recordResponse(ctx, 250, nil)
+	case errors.As(err, &protoErr):
+		recordResponse(ctx, protoErr.Code, nil)
+	}
+	return err
+}
+
+// encodeSubject keeps a rendered subject on one header line and encodes
+// non-ASCII text so clients display it and templates cannot inject headers.
+func encodeSubject(subject string) string {
+	subject = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(subject)
+	return mime.QEncoding.Encode("utf-8", subject)
+}
+
+// multipartAlternative encodes the text and HTML parts, least preferred first.
+func multipartAlternative(text, html string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	parts := []struct{ contentType, content string }{
+		{"text/plain; charset=\"utf-8\"", text},
+		{"text/html; charset=\"utf-8\"", html},
+	}
+	for _, part := range parts {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, "", err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "multipart/alternative; boundary=\"" + mw.Boundary() + "\"", nil
 }
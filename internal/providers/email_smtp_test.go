@@ -1,7 +1,12 @@
 package providers
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
 	"testing"
 )
 
@@ -18,3 +23,46 @@ func TestSMTPProvider_MissingHost_Error(t *testing.T) {
 		t.Fatalf("expected error")
 	}
 }
+
+func TestMultipartAlternative_TextThenHTML(t *testing.T) {
+	body, contentType, err := multipartAlternative("plain", "<p>rich</p>")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatalf("bad content type %q: %v", contentType, err)
+	}
+	r := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+
+	for _, want := range []struct{ ct, content string }{
+		{"text/plain", "plain"},
+		{"text/html", "<p>rich</p>"},
+	} {
+		part, err := r.NextPart()
+		if err != nil {
+			t.Fatalf("missing part %s: %v", want.ct, err)
+		}
+		if !strings.HasPrefix(part.Header.Get("Content-Type"), want.ct) {
+			t.Fatalf("expected %s part, got %q", want.ct, part.Header.Get("Content-Type"))
+		}
+		content, _ := io.ReadAll(part)
+		if string(content) != want.content {
+			t.Fatalf("unexpected %s content %q", want.ct, content)
+		}
+	}
+}
+
+func TestEncodeSubject_OneEncodedLine(t *testing.T) {
+	got := encodeSubject("Lỗ hổng mới\r\nBcc: evil@x.io")
+	if strings.ContainsAny(got, "\r\n") {
+		t.Fatalf("subject must stay on one line, got %q", got)
+	}
+	decoded, err := new(mime.WordDecoder).DecodeHeader(got)
+	if err != nil || decoded != "Lỗ hổng mới Bcc: evil@x.io" {
+		t.Fatalf("unexpected decoded subject %q %v", decoded, err)
+	}
+	if plain := encodeSubject("Scan done"); plain != "Scan done" {
+		t.Fatalf("ASCII subjects are sent as is, got %q", plain)
+	}
+}
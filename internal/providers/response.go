@@ -0,0 +1,47 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+)
+
+// Response captures what a provider's remote endpoint answered. Providers only
+// fill it in when the caller attached one with WithResponse, e.g. for test sends.
+// The reply body is never kept: targets are user-supplied and a body would
+// reflect whatever the endpoint returned.
+type Response struct {
+	StatusCode int               `json:"status_code,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+}
+
+type responseKey struct{}
+
+// WithResponse returns a context that makes providers record their reply in r.
+func WithResponse(ctx context.Context, r *Response) context.Context {
+	return context.WithValue(ctx, responseKey{}, r)
+}
+
+// ResponseFrom returns the Response attached to ctx, or nil.
+func ResponseFrom(ctx context.Context) *Response {
+	r, _ := ctx.Value(responseKey{}).(*Response)
+	return r
+}
+
+func recordResponse(ctx context.Context, status int, header http.Header) {
+	r := ResponseFrom(ctx)
+	if r == nil {
+		return
+	}
+	r.StatusCode = status
+	if len(header) > 0 {
+		r.Headers = make(map[string]string, len(header))
+		for k := range header {
+			r.Headers[k] = header.Get(k)
+		}
+	}
+}
+
+// recordHTTPResponse stores the status and headers of resp.
+func recordHTTPResponse(ctx context.Context, resp *http.Response) {
+	recordResponse(ctx, resp.StatusCode, resp.Header)
+}
@@ -38,6 +38,7 @@ func (p SlackWebhookProvider) SendSlackMessage(ctx context.Context, webhookURL s
 		return err
 	}
 	defer resp.Body.Close()
+	recordHTTPResponse(ctx, resp)
 
 	if resp.StatusCode >= 300 {
 		return fmt.Errorf("slack webhook returned %d", resp.StatusCode)
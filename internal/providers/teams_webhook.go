@@ -0,0 +1,47 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// TeamsWebhookProvider posts messages to Microsoft Teams incoming webhook URLs.
+type TeamsWebhookProvider struct {
+	Client *http.Client
+}
+
+func (p TeamsWebhookProvider) SendTeamsMessage(ctx context.Context, webhookURL string, text string) error {
+	if webhookURL == "" {
+		return fmt.Errorf("missing webhook url")
+	}
+
+	payload := map[string]string{"text": text}
+	body, _ := json.Marshal(payload)
+
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	recordHTTPResponse(ctx, resp)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("teams webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTeamsWebhookProvider_MissingURL(t *testing.T) {
+	p := TeamsWebhookProvider{}
+	if err := p.SendTeamsMessage(context.Background(), "", "hi"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestTeamsWebhookProvider_PostsText(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(200)
+	}))
+	defer srv.Close()
+
+	p := TeamsWebhookProvider{Client: srv.Client()}
+	if err := p.SendTeamsMessage(context.Background(), srv.URL, "**hi**"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["text"] != "**hi**" {
+		t.Fatalf("unexpected payload %#v", got)
+	}
+}
+
+func TestTeamsWebhookProvider_StatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+	}))
+	defer srv.Close()
+
+	p := TeamsWebhookProvider{Client: srv.Client()}
+	if err := p.SendTeamsMessage(context.Background(), srv.URL, "hi"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
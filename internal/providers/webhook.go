@@ -40,6 +40,7 @@ func (p GenericWebhookProvider) SendWebhook(ctx context.Context, url string, pay
 		return err
 	}
 	defer resp.Body.Close()
+	recordHTTPResponse(ctx, resp)
 
 	if resp.StatusCode >= 300 {
 		return fmt.Errorf("webhook returned %d", resp.StatusCode)
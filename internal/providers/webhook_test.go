@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -27,3 +28,25 @@ func TestGenericWebhookProvider(t *testing.T) {
 		t.Fatalf("payload mismatch, got %v", received)
 	}
 }
+
+func TestGenericWebhookProvider_RecordsResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "r1")
+		w.WriteHeader(422)
+		_, _ = w.Write([]byte(`{"error":"bad channel"}`))
+	}))
+	defer srv.Close()
+
+	var resp Response
+	ctx := WithResponse(context.Background(), &resp)
+	p := GenericWebhookProvider{Client: srv.Client()}
+	if err := p.SendWebhook(ctx, srv.URL, map[string]string{"a": "b"}); err == nil {
+		t.Fatalf("expected error")
+	}
+	if resp.StatusCode != 422 || resp.Headers["X-Request-Id"] != "r1" {
+		t.Fatalf("unexpected recorded response %#v", resp)
+	}
+	if out, _ := json.Marshal(resp); strings.Contains(string(out), "bad channel") {
+		t.Fatalf("the reply body must not be recorded: %s", out)
+	}
+}
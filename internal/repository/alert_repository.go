@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/lib/pq"
+)
+
+// AlertRepositoryPG persists alert lifecycles in PostgreSQL.
+type AlertRepositoryPG struct {
+	DB *sql.DB
+}
+
+const alertColumns = `id, organization_id, correlation_key, event_type, severity, summary, state,
+        acknowledged_at, acknowledged_by, resolved_at, resolved_by, created_at, updated_at`
+
+// Open inserts the alert, or reopens a resolved one with the same key.
+// Repeats of an open or acknowledged alert only refresh its details.
+func (r *AlertRepositoryPG) Open(ctx context.Context, a domain.Alert) (domain.Alert, error) {
+	row := r.DB.QueryRowContext(ctx, `
+        INSERT INTO notification_alerts (organization_id, correlation_key, event_type, severity, summary, state)
+        VALUES ($1,$2,$3,$4,$5,'open')
+        ON CONFLICT (organization_id, correlation_key) DO UPDATE SET
+            event_type=EXCLUDED.event_type, severity=EXCLUDED.severity, summary=EXCLUDED.summary,
+            state=CASE WHEN notification_alerts.state='resolved' THEN 'open' ELSE notification_alerts.state END,
+            acknowledged_at=CASE WHEN notification_alerts.state='resolved' THEN NULL ELSE notification_alerts.acknowledged_at END,
+            acknowledged_by=CASE WHEN notification_alerts.state='resolved' THEN '' ELSE notification_alerts.acknowledged_by END,
+            resolved_at=NULL, resolved_by='', updated_at=NOW()
+        RETURNING `+alertColumns, a.OrganizationID, a.CorrelationKey, a.EventType, a.Severity, a.Summary)
+	return scanAlert(row)
+}
+
+// Get returns the alert with key, or nil.
+func (r *AlertRepositoryPG) Get(ctx context.Context, orgID int64, key string) (*domain.Alert, error) {
+	row := r.DB.QueryRowContext(ctx, `
+        SELECT `+alertColumns+`
+        FROM notification_alerts
+        WHERE organization_id=$1 AND correlation_key=$2`, orgID, key)
+	a, err := scanAlert(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+// List returns the organization's alerts, most recently changed first. An
+// empty state returns every state.
+func (r *AlertRepositoryPG) List(ctx context.Context, orgID int64, state string, limit, offset int) ([]domain.Alert, error) {
+	if limit == 0 {
+		limit = 50
+	}
+	rows, err := r.DB.QueryContext(ctx, `
+        SELECT `+alertColumns+`
+        FROM notification_alerts
+        WHERE organization_id=$1 AND ($2='' OR state=$2)
+        ORDER BY updated_at DESC, id DESC
+        LIMIT $3 OFFSET $4`, orgID, state, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Alert, 0)
+	for rows.Next() {
+		a, err := scanAlert(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// Transition moves the alert and, in the same statement, updates its inbox
+// items and acknowledges escalations started for its key.
+func (r *AlertRepositoryPG) Transition(ctx context.Context, orgID int64, key, state, by string, from []string) (*domain.Alert, error) {
+	row := r.DB.QueryRowContext(ctx, `
+        WITH moved AS (
+            UPDATE notification_alerts
+            SET state=$3::text,
+                acknowledged_at=CASE WHEN $3::text='acknowledged' THEN NOW() ELSE acknowledged_at END,
+                acknowledged_by=CASE WHEN $3::text='acknowledged' THEN $4 ELSE acknowledged_by END,
+                resolved_at=CASE WHEN $3::text='resolved' THEN NOW() ELSE resolved_at END,
+                resolved_by=CASE WHEN $3::text='resolved' THEN $4 ELSE resolved_by END,
+                updated_at=NOW()
+            WHERE organization_id=$1 AND correlation_key=$2 AND state = ANY($5)
+            RETURNING `+alertColumns+`
+        ), inbox AS (
+            UPDATE user_notifications SET alert_state=$3::text
+            WHERE organization_id=$1 AND correlation_key=$2 AND EXISTS (SELECT 1 FROM moved)
+        ), escalations AS (
+            UPDATE notification_escalations SET acknowledged_at=NOW(), acknowledged_by=$4, next_at=NULL
+            WHERE organization_id=$1 AND event->>'correlation_key'=$2 AND acknowledged_at IS NULL
+              AND EXISTS (SELECT 1 FROM moved)
+        )
+        SELECT `+alertColumns+` FROM moved`, orgID, key, state, by, pq.Array(from))
+	a, err := scanAlert(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+func scanAlert(row rowScanner) (domain.Alert, error) {
+	var a domain.Alert
+	var ackAt, resolvedAt sql.NullTime
+	if err := row.Scan(&a.ID, &a.OrganizationID, &a.CorrelationKey, &a.EventType, &a.Severity, &a.Summary, &a.State,
+		&ackAt, &a.AcknowledgedBy, &resolvedAt, &a.ResolvedBy, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return a, err
+	}
+	if ackAt.Valid {
+		a.AcknowledgedAt = &ackAt.Time
+	}
+	if resolvedAt.Valid {
+		a.ResolvedAt = &resolvedAt.Time
+	}
+	return a, nil
+}
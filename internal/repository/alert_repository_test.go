@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var alertCols = []string{"id", "organization_id", "correlation_key", "event_type", "severity", "summary", "state",
+	"acknowledged_at", "acknowledged_by", "resolved_at", "resolved_by", "created_at", "updated_at"}
+
+func TestAlertRepositoryPG_OpenReopensResolved(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &AlertRepositoryPG{DB: db}
+	now := time.Now()
+
+	mock.ExpectQuery("ON CONFLICT \\(organization_id, correlation_key\\)(.|\n)*WHEN notification_alerts.state='resolved' THEN 'open'").
+		WithArgs(int64(1), "vuln:42", "vulnerability.critical", "critical", "CVE in api").
+		WillReturnRows(sqlmock.NewRows(alertCols).AddRow(int64(3), int64(1), "vuln:42", "vulnerability.critical", "critical", "CVE in api",
+			"open", nil, "", nil, "", now, now))
+
+	a, err := repo.Open(context.Background(), domain.Alert{
+		OrganizationID: 1, CorrelationKey: "vuln:42", EventType: "vulnerability.critical", Severity: "critical", Summary: "CVE in api",
+	})
+	if err != nil || a.ID != 3 || a.State != domain.AlertOpen || a.AcknowledgedAt != nil {
+		t.Fatalf("unexpected alert %#v %v", a, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestAlertRepositoryPG_TransitionPropagates(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &AlertRepositoryPG{DB: db}
+	now := time.Now()
+
+	mock.ExpectQuery("WITH moved AS(.|\n)*state = ANY\\(\\$5\\)(.|\n)*UPDATE user_notifications SET alert_state(.|\n)*UPDATE notification_escalations").
+		WithArgs(int64(1), "vuln:42", "resolved", "lead@x.io", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(alertCols).AddRow(int64(3), int64(1), "vuln:42", "vulnerability.critical", "critical", "CVE in api",
+			"resolved", now, "lead@x.io", now, "lead@x.io", now, now))
+
+	a, err := repo.Transition(context.Background(), 1, "vuln:42", domain.AlertResolved, "lead@x.io",
+		[]string{domain.AlertOpen, domain.AlertAcknowledged})
+	if err != nil || a == nil || a.State != domain.AlertResolved || a.ResolvedAt == nil || a.ResolvedBy != "lead@x.io" {
+		t.Fatalf("unexpected alert %#v %v", a, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestAlertRepositoryPG_TransitionNoMatch(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &AlertRepositoryPG{DB: db}
+	mock.ExpectQuery("WITH moved AS").WillReturnRows(sqlmock.NewRows(alertCols))
+
+	a, err := repo.Transition(context.Background(), 1, "vuln:42", domain.AlertAcknowledged, "me", []string{domain.AlertOpen})
+	if err != nil || a != nil {
+		t.Fatalf("expected no alert, got %#v %v", a, err)
+	}
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/lib/pq"
+)
+
+// AudienceRepositoryPG persists recipient audiences in PostgreSQL.
+type AudienceRepositoryPG struct {
+	DB *sql.DB
+}
+
+const audienceColumns = `id, organization_id, event_type, all_users, roles, user_ids, project_members, exclude_actor, updated_at`
+
+// List returns the organization's audiences ordered by event type.
+func (r *AudienceRepositoryPG) List(ctx context.Context, orgID int64) ([]domain.Audience, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        SELECT `+audienceColumns+`
+        FROM notification_audiences
+        WHERE organization_id=$1
+        ORDER BY event_type`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Audience, 0)
+	for rows.Next() {
+		a, err := scanAudience(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// Save creates or replaces the audience for (organization, event type).
+func (r *AudienceRepositoryPG) Save(ctx context.Context, a domain.Audience) (domain.Audience, error) {
+	row := r.DB.QueryRowContext(ctx, `
+        INSERT INTO notification_audiences (organization_id, event_type, all_users, roles, user_ids, project_members, exclude_actor)
+        VALUES ($1,$2,$3,COALESCE($4::text[], '{}'),COALESCE($5::bigint[], '{}'),$6,$7)
+        ON CONFLICT (organization_id, event_type)
+        DO UPDATE SET all_users=EXCLUDED.all_users, roles=EXCLUDED.roles, user_ids=EXCLUDED.user_ids,
+            project_members=EXCLUDED.project_members, exclude_actor=EXCLUDED.exclude_actor, updated_at=NOW()
+        RETURNING `+audienceColumns,
+		a.OrganizationID, a.EventType, a.AllUsers, pq.Array(a.Roles), pq.Array(a.UserIDs), a.ProjectMembers, a.ExcludeActor)
+	return scanAudience(row)
+}
+
+// Delete removes an audience; its event type falls back to the built-in one.
+func (r *AudienceRepositoryPG) Delete(ctx context.Context, orgID, id int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+        DELETE FROM notification_audiences
+        WHERE organization_id=$1 AND id=$2`, orgID, id)
+	return err
+}
+
+func scanAudience(row rowScanner) (domain.Audience, error) {
+	var a domain.Audience
+	err := row.Scan(&a.ID, &a.OrganizationID, &a.EventType, &a.AllUsers, pq.Array(&a.Roles), pq.Array(&a.UserIDs),
+		&a.ProjectMembers, &a.ExcludeActor, &a.UpdatedAt)
+	return a, err
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAudienceRepositoryPG_SaveUpserts(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &AudienceRepositoryPG{DB: db}
+	mock.ExpectQuery("INSERT INTO notification_audiences(.|\n)*ON CONFLICT \\(organization_id, event_type\\)").
+		WithArgs(int64(1), "project.scan.*", false, sqlmock.AnyArg(), sqlmock.AnyArg(), true, true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "event_type", "all_users", "roles", "user_ids", "project_members", "exclude_actor", "updated_at"}).
+			AddRow(int64(2), int64(1), "project.scan.*", false, "{developer,security}", "{9}", true, true, time.Now()))
+
+	a, err := repo.Save(context.Background(), domain.Audience{OrganizationID: 1, EventType: "project.scan.*",
+		Roles: []string{"developer", "security"}, UserIDs: []int64{9}, ProjectMembers: true, ExcludeActor: true})
+	if err != nil || a.ID != 2 || len(a.Roles) != 2 || len(a.UserIDs) != 1 || a.UserIDs[0] != 9 {
+		t.Fatalf("unexpected audience %#v %v", a, err)
+	}
+}
+
+func TestOrgUserRepositoryPG_ListUserIDsByProject(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &OrgUserRepositoryPG{DB: db}
+	mock.ExpectQuery("FROM project_members pm(.|\n)*pm.project_id=\\$2 AND u.organization_id=\\$1").
+		WithArgs(int64(1), int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(7)).AddRow(int64(8)))
+
+	ids, err := repo.ListUserIDsByProject(context.Background(), 1, 4)
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("unexpected ids %v %v", ids, err)
+	}
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+)
+
+// DestinationRepositoryPG persists destination verification state in
+// PostgreSQL.
+type DestinationRepositoryPG struct {
+	DB *sql.DB
+}
+
+const destinationColumns = `id, organization_id, channel, target, status, code_hash, attempts, expires_at, sent_at,
+        verified_at, created_at`
+
+// List returns the organization's destinations, newest first.
+func (r *DestinationRepositoryPG) List(ctx context.Context, orgID int64) ([]domain.Destination, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        SELECT `+destinationColumns+`
+        FROM notification_destinations
+        WHERE organization_id=$1
+        ORDER BY created_at DESC, id DESC`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Destination, 0)
+	for rows.Next() {
+		d, err := scanDestination(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// Get returns one of the organization's destinations or nil.
+func (r *DestinationRepositoryPG) Get(ctx context.Context, orgID, id int64) (*domain.Destination, error) {
+	return optionalDestination(scanDestination(r.DB.QueryRowContext(ctx, `
+        SELECT `+destinationColumns+`
+        FROM notification_destinations
+        WHERE organization_id=$1 AND id=$2`, orgID, id)))
+}
+
+// Find returns the organization's destination for a channel and target or nil.
+func (r *DestinationRepositoryPG) Find(ctx context.Context, orgID int64, channel, target string) (*domain.Destination, error) {
+	return optionalDestination(scanDestination(r.DB.QueryRowContext(ctx, `
+        SELECT `+destinationColumns+`
+        FROM notification_destinations
+        WHERE organization_id=$1 AND channel=$2 AND target=$3`, orgID, channel, target)))
+}
+
+// Save upserts the destination on organization, channel and target.
+func (r *DestinationRepositoryPG) Save(ctx context.Context, d domain.Destination) (domain.Destination, error) {
+	return scanDestination(r.DB.QueryRowContext(ctx, `
+        INSERT INTO notification_destinations
+            (organization_id, channel, target, status, code_hash, attempts, expires_at, sent_at, verified_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+        ON CONFLICT (organization_id, channel, target) DO UPDATE SET
+            status=EXCLUDED.status, code_hash=EXCLUDED.code_hash, attempts=EXCLUDED.attempts,
+            expires_at=EXCLUDED.expires_at, sent_at=EXCLUDED.sent_at, verified_at=EXCLUDED.verified_at
+        RETURNING `+destinationColumns,
+		d.OrganizationID, d.Channel, d.Target, d.Status, d.CodeHash, d.Attempts, d.ExpiresAt, d.SentAt, d.VerifiedAt))
+}
+
+// UseAttempt counts one confirmation attempt in the same statement that reads
+// the code hash, so concurrent guesses cannot exceed max.
+func (r *DestinationRepositoryPG) UseAttempt(ctx context.Context, orgID, id int64, max int) (string, bool, error) {
+	var hash string
+	err := r.DB.QueryRowContext(ctx, `
+        UPDATE notification_destinations
+        SET attempts=attempts+1
+        WHERE organization_id=$1 AND id=$2 AND status=$3 AND attempts < $4
+        RETURNING code_hash`, orgID, id, domain.DestinationPending, max).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return hash, true, nil
+}
+
+// Reissue stores a resent code for a pending destination.
+func (r *DestinationRepositoryPG) Reissue(ctx context.Context, orgID, id int64, codeHash string, sentAt time.Time) (*domain.Destination, error) {
+	return optionalDestination(scanDestination(r.DB.QueryRowContext(ctx, `
+        UPDATE notification_destinations
+        SET code_hash=$4, sent_at=$5
+        WHERE organization_id=$1 AND id=$2 AND status=$3
+        RETURNING `+destinationColumns, orgID, id, domain.DestinationPending, codeHash, sentAt)))
+}
+
+func optionalDestination(d domain.Destination, err error) (*domain.Destination, error) {
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func scanDestination(row rowScanner) (domain.Destination, error) {
+	var d domain.Destination
+	var expires, sent, verified sql.NullTime
+	err := row.Scan(&d.ID, &d.OrganizationID, &d.Channel, &d.Target, &d.Status, &d.CodeHash, &d.Attempts,
+		&expires, &sent, &verified, &d.CreatedAt)
+	if expires.Valid {
+		d.ExpiresAt = &expires.Time
+	}
+	if sent.Valid {
+		d.SentAt = &sent.Time
+	}
+	if verified.Valid {
+		d.VerifiedAt = &verified.Time
+	}
+	return d, err
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var destinationCols = []string{"id", "organization_id", "channel", "target", "status", "code_hash", "attempts",
+	"expires_at", "sent_at", "verified_at", "created_at"}
+
+func TestDestinationRepositoryPG_FindMissing(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &DestinationRepositoryPG{DB: db}
+	mock.ExpectQuery("FROM notification_destinations(.|\n)*channel=\\$2 AND target=\\$3").
+		WithArgs(int64(1), "email", "a@x.io").
+		WillReturnError(sql.ErrNoRows)
+
+	d, err := repo.Find(context.Background(), 1, "email", "a@x.io")
+	if err != nil || d != nil {
+		t.Fatalf("expected nil, got %#v %v", d, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDestinationRepositoryPG_Save(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &DestinationRepositoryPG{DB: db}
+	now := time.Now()
+	expires := now.Add(time.Hour)
+	mock.ExpectQuery("INSERT INTO notification_destinations(.|\n)*ON CONFLICT \\(organization_id, channel, target\\)").
+		WithArgs(int64(1), "slack", "https://hooks/x", domain.DestinationPending, "hash", 0, &expires, &now, nil).
+		WillReturnRows(sqlmock.NewRows(destinationCols).
+			AddRow(int64(4), int64(1), "slack", "https://hooks/x", "pending", "hash", 0, expires, now, nil, now))
+
+	d, err := repo.Save(context.Background(), domain.Destination{OrganizationID: 1, Channel: "slack", Target: "https://hooks/x",
+		Status: domain.DestinationPending, CodeHash: "hash", ExpiresAt: &expires, SentAt: &now})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if d.ID != 4 || d.ExpiresAt == nil || d.VerifiedAt != nil {
+		t.Fatalf("unexpected destination %#v", d)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDestinationRepositoryPG_UseAttemptCountsAtomically(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &DestinationRepositoryPG{DB: db}
+	mock.ExpectQuery("UPDATE notification_destinations(.|\n)*SET attempts=attempts\\+1(.|\n)*attempts < \\$4(.|\n)*RETURNING code_hash").
+		WithArgs(int64(1), int64(4), domain.DestinationPending, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"code_hash"}).AddRow("hash"))
+	mock.ExpectQuery("UPDATE notification_destinations").
+		WithArgs(int64(1), int64(4), domain.DestinationPending, 5).
+		WillReturnError(sql.ErrNoRows)
+
+	hash, ok, err := repo.UseAttempt(context.Background(), 1, 4, 5)
+	if err != nil || !ok || hash != "hash" {
+		t.Fatalf("unexpected attempt %q %v %v", hash, ok, err)
+	}
+	if _, ok, err := repo.UseAttempt(context.Background(), 1, 4, 5); err != nil || ok {
+		t.Fatalf("expected the attempts to be used up, got %v %v", ok, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/lib/pq"
+)
+
+// DigestRepositoryPG buffers digest items in PostgreSQL.
+type DigestRepositoryPG struct {
+	DB *sql.DB
+}
+
+func (r *DigestRepositoryPG) Add(ctx context.Context, item domain.DigestItem) error {
+	payloadJSON, _ := json.Marshal(item.Payload)
+	var userID interface{}
+	if item.UserID != nil {
+		userID = *item.UserID
+	}
+	_, err := r.DB.ExecContext(ctx, `
+        INSERT INTO notification_digest_items
+            (organization_id, user_id, channel, target, cadence, event_type, severity, subject, body, payload, occurred_at, due_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+    `, item.OrganizationID, userID, item.Channel, item.Target, item.Cadence, item.EventType, item.Severity,
+		item.Subject, item.Body, payloadJSON, item.OccurredAt, item.DueAt)
+	return err
+}
+
+// ClaimDue leases the due items of up to limit recipients by stamping
+// claimed_at, so a recipient's items are summarized together. Items whose
+// lease is older than lease are claimed again; the row locks taken by the
+// update keep concurrent flushes from claiming the same item twice.
+func (r *DigestRepositoryPG) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.DigestItem, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        UPDATE notification_digest_items d
+        SET claimed_at=$1, attempts=d.attempts+1
+        FROM (
+            SELECT organization_id, user_id, channel, target, cadence
+            FROM notification_digest_items
+            WHERE due_at <= $1 AND (claimed_at IS NULL OR claimed_at <= $2)
+            GROUP BY organization_id, user_id, channel, target, cadence
+            ORDER BY organization_id, user_id, channel, target, cadence
+            LIMIT $3
+        ) g
+        WHERE d.organization_id=g.organization_id AND d.user_id IS NOT DISTINCT FROM g.user_id
+          AND d.channel=g.channel AND d.target=g.target AND d.cadence=g.cadence
+          AND d.due_at <= $1 AND (d.claimed_at IS NULL OR d.claimed_at <= $2)
+        RETURNING d.id, d.organization_id, d.user_id, d.channel, d.target, d.cadence, d.event_type, d.severity,
+            d.subject, d.body, d.payload, d.occurred_at, d.due_at, d.attempts
+    `, now, now.Add(-lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]domain.DigestItem, 0)
+	for rows.Next() {
+		var item domain.DigestItem
+		var user sql.NullInt64
+		var payload []byte
+		if err := rows.Scan(&item.ID, &item.OrganizationID, &user, &item.Channel, &item.Target, &item.Cadence, &item.EventType,
+			&item.Severity, &item.Subject, &item.Body, &payload, &item.OccurredAt, &item.DueAt, &item.Attempts); err != nil {
+			return nil, err
+		}
+		if user.Valid {
+			val := user.Int64
+			item.UserID = &val
+		}
+		if len(payload) > 0 {
+			_ = json.Unmarshal(payload, &item.Payload)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Complete deletes delivered items.
+func (r *DigestRepositoryPG) Complete(ctx context.Context, ids []int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+        DELETE FROM notification_digest_items
+        WHERE id = ANY($1) AND claimed_at IS NOT NULL`, pq.Array(ids))
+	return err
+}
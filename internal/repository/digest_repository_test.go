@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestDigestRepositoryPG_Add(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &DigestRepositoryPG{DB: db}
+	now := time.Now()
+	uid := int64(4)
+
+	mock.ExpectExec("INSERT INTO notification_digest_items").
+		WithArgs(int64(1), int64(4), "inbox", "", "daily", "sbom.scan.summary", "low", "s", "b", sqlmock.AnyArg(), now, now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := repo.Add(context.Background(), domain.DigestItem{
+		OrganizationID: 1, UserID: &uid, Channel: "inbox", Cadence: "daily", EventType: "sbom.scan.summary",
+		Severity: "low", Subject: "s", Body: "b", OccurredAt: now, DueAt: now,
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDigestRepositoryPG_ClaimDueLeasesWholeRecipients(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &DigestRepositoryPG{DB: db}
+	now := time.Now()
+
+	mock.ExpectQuery("UPDATE notification_digest_items d(.|\n)*SET claimed_at=\\$1(.|\n)*GROUP BY organization_id, user_id, channel, target, cadence(.|\n)*LIMIT \\$3").
+		WithArgs(now, now.Add(-time.Minute), 50).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "organization_id", "user_id", "channel", "target", "cadence", "event_type", "severity", "subject", "body", "payload", "occurred_at", "due_at", "attempts",
+		}).AddRow(int64(1), int64(2), nil, "email", "a@b.c", "hourly", "project.scan.completed", "low", "s", "b", []byte(`{"project":"api"}`), now, now, 1))
+	mock.ExpectExec("DELETE FROM notification_digest_items(.|\n)*id = ANY\\(\\$1\\) AND claimed_at IS NOT NULL").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	items, err := repo.ClaimDue(context.Background(), now, time.Minute, 50)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(items) != 1 || items[0].UserID != nil || items[0].Payload["project"] != "api" || items[0].Attempts != 1 {
+		t.Fatalf("unexpected items: %#v", items)
+	}
+	if err := repo.Complete(context.Background(), []int64{1}); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/lib/pq"
+)
+
+// EscalationPolicyRepositoryPG persists escalation policies in PostgreSQL.
+type EscalationPolicyRepositoryPG struct {
+	DB *sql.DB
+}
+
+const escalationPolicyColumns = `id, organization_id, event_type, severity_min, tiers, enabled, updated_at`
+
+// List returns the organization's policies ordered by event type.
+func (r *EscalationPolicyRepositoryPG) List(ctx context.Context, orgID int64) ([]domain.EscalationPolicy, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        SELECT `+escalationPolicyColumns+`
+        FROM notification_escalation_policies
+        WHERE organization_id=$1
+        ORDER BY event_type`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.EscalationPolicy, 0)
+	for rows.Next() {
+		p, err := scanEscalationPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// Get returns a policy by id, or nil when it does not exist.
+func (r *EscalationPolicyRepositoryPG) Get(ctx context.Context, orgID, id int64) (*domain.EscalationPolicy, error) {
+	row := r.DB.QueryRowContext(ctx, `
+        SELECT `+escalationPolicyColumns+`
+        FROM notification_escalation_policies
+        WHERE organization_id=$1 AND id=$2`, orgID, id)
+	return optionalEscalationPolicy(row)
+}
+
+// FindForEvent returns the enabled policy for an event type, or nil.
+func (r *EscalationPolicyRepositoryPG) FindForEvent(ctx context.Context, orgID int64, eventType string) (*domain.EscalationPolicy, error) {
+	row := r.DB.QueryRowContext(ctx, `
+        SELECT `+escalationPolicyColumns+`
+        FROM notification_escalation_policies
+        WHERE organization_id=$1 AND event_type=$2 AND enabled`, orgID, eventType)
+	return optionalEscalationPolicy(row)
+}
+
+// Save creates or replaces the policy for (organization, event type).
+func (r *EscalationPolicyRepositoryPG) Save(ctx context.Context, p domain.EscalationPolicy) (domain.EscalationPolicy, error) {
+	tiers, err := json.Marshal(p.Tiers)
+	if err != nil {
+		return domain.EscalationPolicy{}, err
+	}
+	row := r.DB.QueryRowContext(ctx, `
+        INSERT INTO notification_escalation_policies (organization_id, event_type, severity_min, tiers, enabled)
+        VALUES ($1,$2,$3,$4,$5)
+        ON CONFLICT (organization_id, event_type)
+        DO UPDATE SET severity_min=EXCLUDED.severity_min, tiers=EXCLUDED.tiers, enabled=EXCLUDED.enabled, updated_at=NOW()
+        RETURNING `+escalationPolicyColumns, p.OrganizationID, p.EventType, p.SeverityMin, tiers, p.Enabled)
+	return scanEscalationPolicy(row)
+}
+
+// Delete removes a policy. Running escalations end at their next step.
+func (r *EscalationPolicyRepositoryPG) Delete(ctx context.Context, orgID, id int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+        DELETE FROM notification_escalation_policies
+        WHERE organization_id=$1 AND id=$2`, orgID, id)
+	return err
+}
+
+func optionalEscalationPolicy(row rowScanner) (*domain.EscalationPolicy, error) {
+	p, err := scanEscalationPolicy(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+func scanEscalationPolicy(row rowScanner) (domain.EscalationPolicy, error) {
+	var p domain.EscalationPolicy
+	var tiers []byte
+	if err := row.Scan(&p.ID, &p.OrganizationID, &p.EventType, &p.SeverityMin, &tiers, &p.Enabled, &p.UpdatedAt); err != nil {
+		return p, err
+	}
+	if len(tiers) > 0 {
+		_ = json.Unmarshal(tiers, &p.Tiers)
+	}
+	return p, nil
+}
+
+// EscalationRepositoryPG tracks running escalations in PostgreSQL.
+type EscalationRepositoryPG struct {
+	DB *sql.DB
+}
+
+const escalationColumns = `id, organization_id, policy_id, event, summary, inbox_ids, next_tier, next_at,
+        acknowledged_at, acknowledged_by, created_at`
+
+// Start inserts a new escalation.
+func (r *EscalationRepositoryPG) Start(ctx context.Context, e domain.Escalation) (domain.Escalation, error) {
+	eventJSON, err := json.Marshal(e.Event)
+	if err != nil {
+		return domain.Escalation{}, err
+	}
+	row := r.DB.QueryRowContext(ctx, `
+        INSERT INTO notification_escalations (organization_id, policy_id, event, summary, inbox_ids, next_tier, next_at)
+        VALUES ($1,$2,$3,$4,COALESCE($5::bigint[], '{}'),$6,$7)
+        RETURNING `+escalationColumns,
+		e.OrganizationID, e.PolicyID, eventJSON, e.Summary, pq.Array(e.InboxIDs), e.NextTier, e.NextAt)
+	return scanEscalation(row)
+}
+
+// ClaimDue acknowledges escalations whose inbox items were read, then leases
+// the remaining due ones by pushing next_at to now+lease.
+func (r *EscalationRepositoryPG) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.Escalation, error) {
+	if _, err := r.DB.ExecContext(ctx, `
+        UPDATE notification_escalations e
+        SET acknowledged_at=$1, acknowledged_by='inbox', next_at=NULL
+        WHERE e.next_at <= $1 AND e.acknowledged_at IS NULL
+          AND EXISTS (SELECT 1 FROM user_notifications n WHERE n.id = ANY(e.inbox_ids) AND n.read)`, now); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `
+        UPDATE notification_escalations
+        SET next_at=$2
+        WHERE id IN (
+            SELECT id FROM notification_escalations
+            WHERE next_at <= $1 AND acknowledged_at IS NULL
+            ORDER BY next_at, id
+            LIMIT $3
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING `+escalationColumns, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Escalation, 0)
+	for rows.Next() {
+		e, err := scanEscalation(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// Advance records the next tier and appends inbox items. An escalation
+// acknowledged meanwhile keeps next_at NULL.
+func (r *EscalationRepositoryPG) Advance(ctx context.Context, id int64, tier int, nextAt *time.Time, inboxIDs []int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+        UPDATE notification_escalations
+        SET next_tier=$2,
+            next_at=CASE WHEN acknowledged_at IS NULL THEN $3::timestamptz END,
+            inbox_ids=inbox_ids || COALESCE($4::bigint[], '{}')
+        WHERE id=$1`, id, tier, nextAt, pq.Array(inboxIDs))
+	return err
+}
+
+// Acknowledge stops an open escalation and reports whether one was found.
+func (r *EscalationRepositoryPG) Acknowledge(ctx context.Context, orgID, id int64, by string) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `
+        UPDATE notification_escalations
+        SET acknowledged_at=NOW(), acknowledged_by=$3, next_at=NULL
+        WHERE organization_id=$1 AND id=$2 AND acknowledged_at IS NULL`, orgID, id, by)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListOpen returns the organization's unacknowledged escalations, newest first.
+func (r *EscalationRepositoryPG) ListOpen(ctx context.Context, orgID int64) ([]domain.Escalation, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        SELECT `+escalationColumns+`
+        FROM notification_escalations
+        WHERE organization_id=$1 AND acknowledged_at IS NULL
+        ORDER BY created_at DESC, id DESC
+        LIMIT 100`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Escalation, 0)
+	for rows.Next() {
+		e, err := scanEscalation(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func scanEscalation(row rowScanner) (domain.Escalation, error) {
+	var e domain.Escalation
+	var event []byte
+	var nextAt, ackAt sql.NullTime
+	if err := row.Scan(&e.ID, &e.OrganizationID, &e.PolicyID, &event, &e.Summary, pq.Array(&e.InboxIDs),
+		&e.NextTier, &nextAt, &ackAt, &e.AcknowledgedBy, &e.CreatedAt); err != nil {
+		return e, err
+	}
+	if len(event) > 0 {
+		_ = json.Unmarshal(event, &e.Event)
+	}
+	if nextAt.Valid {
+		e.NextAt = &nextAt.Time
+	}
+	if ackAt.Valid {
+		e.AcknowledgedAt = &ackAt.Time
+	}
+	return e, nil
+}
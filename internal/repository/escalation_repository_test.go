@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var (
+	escalationPolicyCols = []string{"id", "organization_id", "event_type", "severity_min", "tiers", "enabled", "updated_at"}
+	escalationCols       = []string{"id", "organization_id", "policy_id", "event", "summary", "inbox_ids", "next_tier", "next_at",
+		"acknowledged_at", "acknowledged_by", "created_at"}
+)
+
+func TestEscalationPolicyRepositoryPG_SaveUpsertsByEventType(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &EscalationPolicyRepositoryPG{DB: db}
+	now := time.Now()
+	tiers := `[{"after_minutes":15,"role":"team_lead"}]`
+
+	mock.ExpectQuery("ON CONFLICT \\(organization_id, event_type\\)").
+		WithArgs(int64(1), "vulnerability.critical", "critical", []byte(tiers), true).
+		WillReturnRows(sqlmock.NewRows(escalationPolicyCols).AddRow(int64(4), int64(1), "vulnerability.critical", "critical", []byte(tiers), true, now))
+
+	p, err := repo.Save(context.Background(), domain.EscalationPolicy{
+		OrganizationID: 1, EventType: "vulnerability.critical", SeverityMin: "critical", Enabled: true,
+		Tiers: []domain.EscalationTier{{AfterMinutes: 15, Role: "team_lead"}},
+	})
+	if err != nil || p.ID != 4 || len(p.Tiers) != 1 || p.Tiers[0].Role != "team_lead" {
+		t.Fatalf("unexpected policy %#v %v", p, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestEscalationPolicyRepositoryPG_FindForEventMissing(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &EscalationPolicyRepositoryPG{DB: db}
+	mock.ExpectQuery("FROM notification_escalation_policies(.|\n)*event_type=\\$2 AND enabled").
+		WithArgs(int64(1), "scan.failed").
+		WillReturnRows(sqlmock.NewRows(escalationPolicyCols))
+
+	p, err := repo.FindForEvent(context.Background(), 1, "scan.failed")
+	if err != nil || p != nil {
+		t.Fatalf("expected no policy, got %#v %v", p, err)
+	}
+}
+
+func TestEscalationRepositoryPG_ClaimDueAcknowledgesReadItems(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &EscalationRepositoryPG{DB: db}
+	now := time.Now()
+
+	mock.ExpectExec("UPDATE notification_escalations e(.|\n)*acknowledged_by='inbox'(.|\n)*n.id = ANY\\(e.inbox_ids\\) AND n.read").
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("UPDATE notification_escalations(.|\n)*acknowledged_at IS NULL(.|\n)*FOR UPDATE SKIP LOCKED").
+		WithArgs(now, now.Add(time.Minute), 10).
+		WillReturnRows(sqlmock.NewRows(escalationCols).AddRow(int64(2), int64(1), int64(4), []byte(`{"type":"vulnerability.critical"}`),
+			"Critical CVE", "{11,12}", 1, now.Add(time.Minute), nil, "", now))
+
+	due, err := repo.ClaimDue(context.Background(), now, time.Minute, 10)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("unexpected claim %#v %v", due, err)
+	}
+	e := due[0]
+	if e.Event.EventType != "vulnerability.critical" || len(e.InboxIDs) != 2 || e.InboxIDs[1] != 12 || e.NextAt == nil || e.AcknowledgedAt != nil {
+		t.Fatalf("unexpected escalation %#v", e)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestEscalationRepositoryPG_Acknowledge(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &EscalationRepositoryPG{DB: db}
+	mock.ExpectExec("SET acknowledged_at=NOW\\(\\), acknowledged_by=\\$3, next_at=NULL(.|\n)*acknowledged_at IS NULL").
+		WithArgs(int64(1), int64(2), "lead@x.io").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ok, err := repo.Acknowledge(context.Background(), 1, 2, "lead@x.io")
+	if err != nil || ok {
+		t.Fatalf("expected already acknowledged, got %v %v", ok, err)
+	}
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/lib/pq"
+)
+
+// GroupingRuleRepositoryPG persists grouping rules in PostgreSQL.
+type GroupingRuleRepositoryPG struct {
+	DB *sql.DB
+}
+
+const groupingRuleColumns = `id, organization_id, event_type, group_by, window_seconds, enabled, updated_at`
+
+// List returns the organization's rules ordered by event type.
+func (r *GroupingRuleRepositoryPG) List(ctx context.Context, orgID int64) ([]domain.GroupingRule, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        SELECT `+groupingRuleColumns+`
+        FROM notification_grouping_rules
+        WHERE organization_id=$1
+        ORDER BY event_type`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.GroupingRule, 0)
+	for rows.Next() {
+		g, err := scanGroupingRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
+
+// Save creates or replaces the rule for (organization, event type).
+func (r *GroupingRuleRepositoryPG) Save(ctx context.Context, g domain.GroupingRule) (domain.GroupingRule, error) {
+	row := r.DB.QueryRowContext(ctx, `
+        INSERT INTO notification_grouping_rules (organization_id, event_type, group_by, window_seconds, enabled)
+        VALUES ($1,$2,COALESCE($3::text[], '{}'),$4,$5)
+        ON CONFLICT (organization_id, event_type)
+        DO UPDATE SET group_by=EXCLUDED.group_by, window_seconds=EXCLUDED.window_seconds, enabled=EXCLUDED.enabled, updated_at=NOW()
+        RETURNING `+groupingRuleColumns, g.OrganizationID, g.EventType, pq.Array(g.GroupBy), g.Window, g.Enabled)
+	return scanGroupingRule(row)
+}
+
+// Delete removes a rule. Groups it opened still close at their window end.
+func (r *GroupingRuleRepositoryPG) Delete(ctx context.Context, orgID, id int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+        DELETE FROM notification_grouping_rules
+        WHERE organization_id=$1 AND id=$2`, orgID, id)
+	return err
+}
+
+// Match returns the enabled rule for the event type, falling back to the
+// catch-all rule, or nil when neither exists.
+func (r *GroupingRuleRepositoryPG) Match(ctx context.Context, orgID int64, eventType string) (*domain.GroupingRule, error) {
+	row := r.DB.QueryRowContext(ctx, `
+        SELECT `+groupingRuleColumns+`
+        FROM notification_grouping_rules
+        WHERE organization_id=$1 AND event_type IN ('', $2) AND enabled
+        ORDER BY (event_type <> '') DESC
+        LIMIT 1`, orgID, eventType)
+	g, err := scanGroupingRule(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &g, nil
+}
+
+func scanGroupingRule(row rowScanner) (domain.GroupingRule, error) {
+	var g domain.GroupingRule
+	err := row.Scan(&g.ID, &g.OrganizationID, &g.EventType, pq.Array(&g.GroupBy), &g.Window, &g.Enabled, &g.UpdatedAt)
+	return g, err
+}
+
+// GroupRepositoryPG tracks open notification groups in PostgreSQL.
+type GroupRepositoryPG struct {
+	DB *sql.DB
+}
+
+const groupColumns = `id, organization_id, rule_id, group_key, event, events, count, inbox_ids, first_at, last_at, window_end`
+
+// Add opens a group or counts the event into the open one in a single
+// upsert. Counting pushes the new total onto the group's inbox items and
+// marks them unread so the bell shows the update. A group whose window has
+// ended is left for ClaimDue and reported as domain.ErrGroupClosed.
+func (r *GroupRepositoryPG) Add(ctx context.Context, g domain.NotificationGroup) (domain.NotificationGroup, bool, error) {
+	eventJSON, err := json.Marshal(g.Event)
+	if err != nil {
+		return domain.NotificationGroup{}, false, err
+	}
+	row := r.DB.QueryRowContext(ctx, `
+        WITH g AS (
+            INSERT INTO notification_groups (organization_id, rule_id, group_key, event, events, count, first_at, last_at, window_end)
+            VALUES ($1,$2,$3,$4,'[]',1,$5,$5,$6)
+            ON CONFLICT (organization_id, rule_id, group_key)
+            DO UPDATE SET count=notification_groups.count + 1, event=EXCLUDED.event, last_at=EXCLUDED.last_at,
+                events=CASE WHEN jsonb_array_length(notification_groups.events) < $7
+                    THEN notification_groups.events || jsonb_build_array(EXCLUDED.event)
+                    ELSE notification_groups.events END
+            WHERE notification_groups.window_end > EXCLUDED.last_at
+            RETURNING `+groupColumns+`, (xmax = 0) AS opened
+        ), bump AS (
+            UPDATE user_notifications n
+            SET group_count=g.count, read=false, read_at=NULL
+            FROM g
+            WHERE n.id = ANY(g.inbox_ids) AND NOT g.opened
+        )
+        SELECT `+groupColumns+`, opened FROM g`,
+		g.OrganizationID, g.RuleID, g.Key, eventJSON, g.FirstAt, g.WindowEnd, domain.MaxGroupEvents)
+
+	var opened bool
+	out, err := scanGroup(row, &opened)
+	if err == sql.ErrNoRows {
+		return domain.NotificationGroup{}, false, domain.ErrGroupClosed
+	}
+	return out, opened, err
+}
+
+// AttachInbox records the inbox items of the group's first event. Events
+// counted before the items existed are applied to them here.
+func (r *GroupRepositoryPG) AttachInbox(ctx context.Context, id int64, inboxIDs []int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+        WITH g AS (
+            UPDATE notification_groups
+            SET inbox_ids=inbox_ids || $2::bigint[]
+            WHERE id=$1
+            RETURNING count
+        )
+        UPDATE user_notifications n
+        SET group_count=g.count
+        FROM g
+        WHERE n.id = ANY($2::bigint[]) AND g.count > 1`, id, pq.Array(inboxIDs))
+	return err
+}
+
+// ClaimDue deletes and returns groups whose window has ended. Concurrent
+// workers skip each other's rows.
+func (r *GroupRepositoryPG) ClaimDue(ctx context.Context, now time.Time, limit int) ([]domain.NotificationGroup, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        DELETE FROM notification_groups
+        WHERE id IN (
+            SELECT id FROM notification_groups
+            WHERE window_end <= $1
+            ORDER BY window_end, id
+            LIMIT $2
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING `+groupColumns, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.NotificationGroup, 0)
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
+
+func scanGroup(row rowScanner, extra ...interface{}) (domain.NotificationGroup, error) {
+	var g domain.NotificationGroup
+	var event, events []byte
+	dest := []interface{}{&g.ID, &g.OrganizationID, &g.RuleID, &g.Key, &event, &events, &g.Count, pq.Array(&g.InboxIDs),
+		&g.FirstAt, &g.LastAt, &g.WindowEnd}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return g, err
+	}
+	if len(event) > 0 {
+		_ = json.Unmarshal(event, &g.Event)
+	}
+	if len(events) > 0 {
+		_ = json.Unmarshal(events, &g.Events)
+	}
+	return g, nil
+}
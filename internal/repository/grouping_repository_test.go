@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var groupCols = []string{"id", "organization_id", "rule_id", "group_key", "event", "events", "count", "inbox_ids", "first_at", "last_at", "window_end"}
+
+func TestGroupingRuleRepositoryPG_MatchPrefersSpecific(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &GroupingRuleRepositoryPG{DB: db}
+	mock.ExpectQuery("event_type IN \\('', \\$2\\) AND enabled(.|\n)*ORDER BY \\(event_type <> ''\\) DESC").
+		WithArgs(int64(1), "vulnerability.critical").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "event_type", "group_by", "window_seconds", "enabled", "updated_at"}).
+			AddRow(int64(4), int64(1), "vulnerability.critical", "{project,event_type}", 60, true, time.Now()))
+
+	r, err := repo.Match(context.Background(), 1, "vulnerability.critical")
+	if err != nil || r == nil || r.ID != 4 || len(r.GroupBy) != 2 || r.GroupBy[0] != "project" {
+		t.Fatalf("unexpected rule %#v %v", r, err)
+	}
+}
+
+func TestGroupRepositoryPG_AddCountsIntoOpenGroup(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &GroupRepositoryPG{DB: db}
+	now := time.Now()
+	mock.ExpectQuery("INSERT INTO notification_groups(.|\n)*count=notification_groups.count \\+ 1(.|\n)*WHERE notification_groups.window_end > EXCLUDED.last_at(.|\n)*UPDATE user_notifications n(.|\n)*SET group_count=g.count, read=false").
+		WithArgs(int64(1), int64(4), "project=payments-api", sqlmock.AnyArg(), now, now.Add(time.Minute), domain.MaxGroupEvents).
+		WillReturnRows(sqlmock.NewRows(append(groupCols, "opened")).
+			AddRow(int64(9), int64(1), int64(4), "project=payments-api", []byte(`{"type":"vulnerability.critical"}`),
+				[]byte(`[{"type":"vulnerability.critical"},{"type":"vulnerability.critical"}]`), 3, "{11,12}", now, now, now.Add(time.Minute), false))
+
+	g, opened, err := repo.Add(context.Background(), domain.NotificationGroup{
+		OrganizationID: 1, RuleID: 4, Key: "project=payments-api", Count: 1, FirstAt: now, LastAt: now, WindowEnd: now.Add(time.Minute),
+	})
+	if err != nil || opened || g.Count != 3 || len(g.InboxIDs) != 2 || g.Event.EventType != "vulnerability.critical" || len(g.Events) != 2 {
+		t.Fatalf("unexpected group %#v opened=%v err=%v", g, opened, err)
+	}
+}
+
+func TestGroupRepositoryPG_AddToEndedWindowReportsClosed(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &GroupRepositoryPG{DB: db}
+	now := time.Now()
+	mock.ExpectQuery("INSERT INTO notification_groups").
+		WillReturnRows(sqlmock.NewRows(append(groupCols, "opened")))
+
+	_, opened, err := repo.Add(context.Background(), domain.NotificationGroup{
+		OrganizationID: 1, RuleID: 4, Key: "project=payments-api", Count: 1, FirstAt: now, LastAt: now, WindowEnd: now.Add(time.Minute),
+	})
+	if err != domain.ErrGroupClosed || opened {
+		t.Fatalf("expected ErrGroupClosed, got opened=%v err=%v", opened, err)
+	}
+}
+
+func TestGroupRepositoryPG_ClaimDue(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &GroupRepositoryPG{DB: db}
+	now := time.Now()
+	mock.ExpectQuery("DELETE FROM notification_groups(.|\n)*window_end <= \\$1(.|\n)*FOR UPDATE SKIP LOCKED").
+		WithArgs(now, 10).
+		WillReturnRows(sqlmock.NewRows(groupCols).
+			AddRow(int64(9), int64(1), int64(4), "project=web", []byte(`{}`), []byte(`[]`), 1, "{}", now, now, now))
+
+	groups, err := repo.ClaimDue(context.Background(), now, 10)
+	if err != nil || len(groups) != 1 || groups[0].Key != "project=web" {
+		t.Fatalf("unexpected groups %#v %v", groups, err)
+	}
+}
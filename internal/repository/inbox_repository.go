@@ -15,12 +15,15 @@ type InboxRepositoryPG struct {
 
 func (r *InboxRepositoryPG) Save(ctx context.Context, n domain.UserNotification) (domain.UserNotification, error) {
 	payloadJSON, _ := json.Marshal(n.Payload)
+	if n.Format == "" {
+		n.Format = domain.FormatText
+	}
 
 	row := r.DB.QueryRowContext(ctx, `
-        INSERT INTO user_notifications (user_id, organization_id, title, message, type, severity, action_url, payload, read)
-        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+        INSERT INTO user_notifications (user_id, organization_id, title, message, format, type, severity, action_url, payload, read, correlation_key, alert_state)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
         RETURNING id, created_at, read_at
-    `, n.UserID, n.OrganizationID, n.Title, n.Message, n.Type, n.Severity, n.ActionURL, payloadJSON, n.Read)
+    `, n.UserID, n.OrganizationID, n.Title, n.Message, n.Format, n.Type, n.Severity, n.ActionURL, payloadJSON, n.Read, n.CorrelationKey, n.AlertState)
 
 	if err := row.Scan(&n.ID, &n.CreatedAt, &n.ReadAt); err != nil {
 		return n, err
@@ -34,7 +37,8 @@ func (r *InboxRepositoryPG) List(ctx context.Context, userID int64, orgID int64,
 	}
 
 	rows, err := r.DB.QueryContext(ctx, `
-        SELECT id, user_id, organization_id, title, message, type, severity, action_url, payload, read, created_at, read_at
+        SELECT id, user_id, organization_id, title, message, COALESCE(format, 'text'), type, severity, action_url, payload, read, created_at, read_at,
+               correlation_key, alert_state, group_count
         FROM user_notifications
         WHERE user_id=$1 AND ($2 = 0 OR organization_id=$2) AND ($3::bool = false OR read = false)
         ORDER BY created_at DESC
@@ -50,7 +54,8 @@ func (r *InboxRepositoryPG) List(ctx context.Context, userID int64, orgID int64,
 		var n domain.UserNotification
 		var payload []byte
 		var readAt sql.NullTime
-		if err := rows.Scan(&n.ID, &n.UserID, &n.OrganizationID, &n.Title, &n.Message, &n.Type, &n.Severity, &n.ActionURL, &payload, &n.Read, &n.CreatedAt, &readAt); err != nil {
+		if err := rows.Scan(&n.ID, &n.UserID, &n.OrganizationID, &n.Title, &n.Message, &n.Format, &n.Type, &n.Severity, &n.ActionURL, &payload, &n.Read, &n.CreatedAt, &readAt,
+			&n.CorrelationKey, &n.AlertState, &n.GroupCount); err != nil {
 			return nil, 0, 0, err
 		}
 		if len(payload) > 0 {
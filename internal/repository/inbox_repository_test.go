@@ -47,11 +47,12 @@ func TestInboxRepositoryPG_List_ScansPayloadAndCounts(t *testing.T) {
 	payload, _ := json.Marshal(map[string]interface{}{"k": "v"})
 
 	// list query
-	mock.ExpectQuery("SELECT id, user_id, organization_id, title, message, type, severity, action_url, payload, read, created_at, read_at").
+	mock.ExpectQuery("SELECT id, user_id, organization_id, title, message, COALESCE\\(format, 'text'\\), type, severity, action_url, payload, read, created_at, read_at").
 		WithArgs(int64(11), int64(0), false, 50, 0).
 		WillReturnRows(sqlmock.NewRows([]string{
-			"id", "user_id", "organization_id", "title", "message", "type", "severity", "action_url", "payload", "read", "created_at", "read_at",
-		}).AddRow(int64(1), int64(11), int64(0), "t", "m", "x", "low", "", payload, false, now, nil))
+			"id", "user_id", "organization_id", "title", "message", "format", "type", "severity", "action_url", "payload", "read", "created_at", "read_at",
+			"correlation_key", "alert_state", "group_count",
+		}).AddRow(int64(1), int64(11), int64(0), "t", "m", "text", "x", "low", "", payload, false, now, nil, "vuln:1", "acknowledged", 3))
 
 	// total count
 	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM user_notifications").
@@ -70,6 +71,9 @@ func TestInboxRepositoryPG_List_ScansPayloadAndCounts(t *testing.T) {
 	if total != 1 || unread != 1 || len(items) != 1 {
 		t.Fatalf("unexpected results total=%d unread=%d items=%d", total, unread, len(items))
 	}
+	if items[0].AlertState != domain.AlertAcknowledged || items[0].GroupCount != 3 {
+		t.Fatalf("expected alert state and group count, got %q %d", items[0].AlertState, items[0].GroupCount)
+	}
 	if items[0].Payload["k"] != "v" {
 		t.Fatalf("expected payload k=v got %v", items[0].Payload)
 	}
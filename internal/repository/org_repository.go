@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"database/sql"
+
+	"github.com/lib/pq"
 )
 
 // OrgUserRepositoryPG resolves users for an organization for broadcast notifications.
@@ -45,3 +47,45 @@ func (r *OrgUserRepositoryPG) ListUserIDsByOrgWithRole(ctx context.Context, orgI
 	}
 	return ids, nil
 }
+
+// ListUserIDsByProject returns the active org members of a project.
+func (r *OrgUserRepositoryPG) ListUserIDsByProject(ctx context.Context, orgID, projectID int64) ([]int64, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        SELECT u.id FROM project_members pm
+        JOIN users u ON u.id = pm.user_id
+        WHERE pm.project_id=$2 AND u.organization_id=$1 AND u.is_active=true`, orgID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// UserLocales returns the profile locale of each user that has one set.
+func (r *OrgUserRepositoryPG) UserLocales(ctx context.Context, userIDs []int64) (map[int64]string, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, locale FROM users WHERE id = ANY($1) AND COALESCE(locale, '') <> ''`, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locales := make(map[int64]string, len(userIDs))
+	for rows.Next() {
+		var id int64
+		var locale string
+		if err := rows.Scan(&id, &locale); err != nil {
+			return nil, err
+		}
+		locales[id] = locale
+	}
+	return locales, nil
+}
@@ -45,7 +45,8 @@ func (r *OrgSettingsRepositoryPG) Get(ctx context.Context, orgID int64) (*domain
                COALESCE(vulnerability_alerts, TRUE),
                COALESCE(weekly_reports, TRUE),
                COALESCE(user_activity_alerts, FALSE),
-               COALESCE(admin_email, '')
+               COALESCE(admin_email, ''),
+               COALESCE(locale, '')
         FROM organization_settings
         WHERE organization_id = $1
     `
@@ -67,6 +68,7 @@ func (r *OrgSettingsRepositoryPG) Get(ctx context.Context, orgID int64) (*domain
 		&settings.WeeklyReports,
 		&settings.UserActivityAlerts,
 		&settings.AdminEmail,
+		&settings.Locale,
 	); err != nil {
 		if err != sql.ErrNoRows {
 			log.Printf("[ORG_SETTINGS] query failed: %v", err)
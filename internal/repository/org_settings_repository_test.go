@@ -26,8 +26,8 @@ func TestOrgSettingsRepositoryPG_Get_Caches(t *testing.T) {
 	mock.ExpectQuery("SELECT organization_id").
 		WithArgs(int64(1)).
 		WillReturnRows(sqlmock.NewRows([]string{
-			"organization_id", "email_notifications", "vulnerability_alerts", "weekly_reports", "user_activity_alerts", "admin_email",
-		}).AddRow(int64(1), true, true, true, false, "admin@x.com"))
+			"organization_id", "email_notifications", "vulnerability_alerts", "weekly_reports", "user_activity_alerts", "admin_email", "locale",
+		}).AddRow(int64(1), true, true, true, false, "admin@x.com", "vi"))
 
 	// first call hits db
 	st1, err := repo.Get(context.Background(), 1)
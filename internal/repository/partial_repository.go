@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"myesi-notification-service/internal/domain"
+)
+
+// PartialRepositoryPG persists template partials and layouts in PostgreSQL.
+type PartialRepositoryPG struct {
+	DB *sql.DB
+}
+
+const partialColumns = `id, organization_id, name, kind, channel, body, updated_at`
+
+// List returns global partials followed by the org's own, so callers that
+// layer them see overrides last.
+func (r *PartialRepositoryPG) List(ctx context.Context, orgID int64) ([]domain.NotificationPartial, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        SELECT `+partialColumns+`
+        FROM notification_partials
+        WHERE organization_id IS NULL OR organization_id = $1
+        ORDER BY organization_id NULLS FIRST, kind, name, channel`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parts := make([]domain.NotificationPartial, 0)
+	for rows.Next() {
+		p, err := scanPartial(rows)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+	return parts, nil
+}
+
+// Upsert creates or replaces the partial for (organization, kind, name, channel).
+func (r *PartialRepositoryPG) Upsert(ctx context.Context, p domain.NotificationPartial) (domain.NotificationPartial, error) {
+	var orgID interface{}
+	if p.OrganizationID != nil && *p.OrganizationID > 0 {
+		orgID = *p.OrganizationID
+	}
+	row := r.DB.QueryRowContext(ctx, `
+        INSERT INTO notification_partials (organization_id, name, kind, channel, body)
+        VALUES ($1,$2,$3,$4,$5)
+        ON CONFLICT ((COALESCE(organization_id, 0)), kind, name, channel)
+        DO UPDATE SET body=EXCLUDED.body, updated_at=NOW()
+        RETURNING `+partialColumns+`
+    `, orgID, p.Name, p.Kind, p.Channel, p.Body)
+	return scanPartial(row)
+}
+
+// Delete removes a partial owned by orgID (0 = global) and reports ErrNotFound otherwise.
+func (r *PartialRepositoryPG) Delete(ctx context.Context, orgID, id int64) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM notification_partials WHERE id=$1 AND COALESCE(organization_id, 0)=$2`, id, orgID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func scanPartial(row rowScanner) (domain.NotificationPartial, error) {
+	var p domain.NotificationPartial
+	var org sql.NullInt64
+	err := row.Scan(&p.ID, &org, &p.Name, &p.Kind, &p.Channel, &p.Body, &p.UpdatedAt)
+	if org.Valid {
+		val := org.Int64
+		p.OrganizationID = &val
+	}
+	return p, err
+}
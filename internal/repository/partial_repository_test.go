@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var partialCols = []string{"id", "organization_id", "name", "kind", "channel", "body", "updated_at"}
+
+func TestPartialRepositoryPG_List(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &PartialRepositoryPG{DB: db}
+	now := time.Now()
+
+	mock.ExpectQuery("FROM notification_partials").
+		WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows(partialCols).
+			AddRow(int64(1), nil, "footer", "partial", "", "global", now).
+			AddRow(int64(2), int64(4), "footer", "partial", "", "org", now))
+
+	parts, err := repo.List(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(parts) != 2 || parts[0].OrganizationID != nil || parts[1].OrganizationID == nil || *parts[1].OrganizationID != 4 {
+		t.Fatalf("unexpected partials: %#v", parts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPartialRepositoryPG_Upsert(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &PartialRepositoryPG{DB: db}
+	now := time.Now()
+
+	mock.ExpectQuery("ON CONFLICT \\(\\(COALESCE\\(organization_id, 0\\)\\), kind, name, channel\\)").
+		WithArgs(nil, "layout", "layout", "email", "<{{template \"content\" .}}>").
+		WillReturnRows(sqlmock.NewRows(partialCols).AddRow(int64(3), nil, "layout", "layout", "email", "<{{template \"content\" .}}>", now))
+
+	saved, err := repo.Upsert(context.Background(), domain.NotificationPartial{
+		Name: "layout", Kind: "layout", Channel: "email", Body: "<{{template \"content\" .}}>",
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if saved.ID != 3 || saved.OrganizationID != nil {
+		t.Fatalf("unexpected saved: %#v", saved)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPartialRepositoryPG_Delete_NotFound(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &PartialRepositoryPG{DB: db}
+	mock.ExpectExec("DELETE FROM notification_partials").
+		WithArgs(int64(9), int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := repo.Delete(context.Background(), 4, 9); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
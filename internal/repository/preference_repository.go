@@ -7,6 +7,8 @@ import (
 	"strings"
 
 	"myesi-notification-service/internal/domain"
+
+	"github.com/lib/pq"
 )
 
 // PreferenceRepositoryPG persists preferences in PostgreSQL.
@@ -14,6 +16,9 @@ type PreferenceRepositoryPG struct {
 	DB *sql.DB
 }
 
+// List returns the preferences that apply to eventType, including wildcard
+// rows, or all of them when eventType is empty. Callers pick the most
+// specific rows.
 func (r *PreferenceRepositoryPG) List(ctx context.Context, orgID int64, userID *int64, eventType string) ([]domain.NotificationPreference, error) {
 	conditions := []string{"1=1"}
 	args := []interface{}{}
@@ -24,8 +29,8 @@ func (r *PreferenceRepositoryPG) List(ctx context.Context, orgID int64, userID *
 	}
 
 	if eventType != "" {
-		conditions = append(conditions, fmt.Sprintf("event_type=$%d", len(args)+1))
-		args = append(args, eventType)
+		conditions = append(conditions, fmt.Sprintf("event_type = ANY($%d)", len(args)+1))
+		args = append(args, pq.Array(domain.EventPatternCandidates(eventType)))
 	}
 
 	if userID != nil {
@@ -34,7 +39,7 @@ func (r *PreferenceRepositoryPG) List(ctx context.Context, orgID int64, userID *
 	}
 
 	query := fmt.Sprintf(`
-        SELECT id, organization_id, user_id, event_type, channel, target, enabled, severity_min, created_at, updated_at
+        SELECT id, organization_id, user_id, event_type, channel, target, enabled, severity_min, COALESCE(digest, 'immediate'), created_at, updated_at
         FROM notification_preferences
         WHERE %s
         ORDER BY updated_at DESC
@@ -48,15 +53,10 @@ func (r *PreferenceRepositoryPG) List(ctx context.Context, orgID int64, userID *
 
 	results := make([]domain.NotificationPreference, 0)
 	for rows.Next() {
-		var pref domain.NotificationPreference
-		var user sql.NullInt64
-		if err := rows.Scan(&pref.ID, &pref.OrganizationID, &user, &pref.EventType, &pref.Channel, &pref.Target, &pref.Enabled, &pref.SeverityMin, &pref.CreatedAt, &pref.UpdatedAt); err != nil {
+		pref, err := scanPreference(rows)
+		if err != nil {
 			return nil, err
 		}
-		if user.Valid {
-			val := user.Int64
-			pref.UserID = &val
-		}
 		results = append(results, pref)
 	}
 	return results, nil
@@ -69,40 +69,126 @@ func (r *PreferenceRepositoryPG) Save(ctx context.Context, pref domain.Notificat
 	} else {
 		userID = nil
 	}
+	if pref.Digest == "" {
+		pref.Digest = domain.DigestImmediate
+	}
 
 	// When an ID is provided, update that record directly to honor the REST contract.
 	if pref.ID > 0 {
 		row := r.DB.QueryRowContext(ctx, `
             UPDATE notification_preferences
-            SET organization_id=$2, user_id=$3, event_type=$4, channel=$5, target=$6, enabled=$7, severity_min=$8, updated_at=NOW()
+            SET organization_id=$2, user_id=$3, event_type=$4, channel=$5, target=$6, enabled=$7, severity_min=$8, digest=$9, updated_at=NOW()
             WHERE id=$1
-            RETURNING id, organization_id, user_id, event_type, channel, target, enabled, severity_min, created_at, updated_at
-        `, pref.ID, pref.OrganizationID, userID, pref.EventType, pref.Channel, pref.Target, pref.Enabled, pref.SeverityMin)
-
-		var saved domain.NotificationPreference
-		var user sql.NullInt64
-		err := row.Scan(&saved.ID, &saved.OrganizationID, &user, &saved.EventType, &saved.Channel, &saved.Target, &saved.Enabled, &saved.SeverityMin, &saved.CreatedAt, &saved.UpdatedAt)
-		if user.Valid {
-			val := user.Int64
-			saved.UserID = &val
+            RETURNING id, organization_id, user_id, event_type, channel, target, enabled, severity_min, COALESCE(digest, 'immediate'), created_at, updated_at
+        `, pref.ID, pref.OrganizationID, userID, pref.EventType, pref.Channel, pref.Target, pref.Enabled, pref.SeverityMin, pref.Digest)
+
+		saved, err := scanPreference(row)
+		if err == sql.ErrNoRows {
+			return saved, domain.ErrNotFound
 		}
 		return saved, err
 	}
 
 	row := r.DB.QueryRowContext(ctx, `
-        INSERT INTO notification_preferences (organization_id, user_id, event_type, channel, target, enabled, severity_min)
-        VALUES ($1,$2,$3,$4,$5,$6,$7)
+        INSERT INTO notification_preferences (organization_id, user_id, event_type, channel, target, enabled, severity_min, digest)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
         ON CONFLICT ON CONSTRAINT ux_notification_preferences_scope
-        DO UPDATE SET target=EXCLUDED.target, enabled=EXCLUDED.enabled, severity_min=EXCLUDED.severity_min, updated_at=NOW()
-        RETURNING id, organization_id, user_id, event_type, channel, target, enabled, severity_min, created_at, updated_at
-    `, pref.OrganizationID, userID, pref.EventType, pref.Channel, pref.Target, pref.Enabled, pref.SeverityMin)
+        DO UPDATE SET target=EXCLUDED.target, enabled=EXCLUDED.enabled, severity_min=EXCLUDED.severity_min, digest=EXCLUDED.digest, updated_at=NOW()
+        RETURNING id, organization_id, user_id, event_type, channel, target, enabled, severity_min, COALESCE(digest, 'immediate'), created_at, updated_at
+    `, pref.OrganizationID, userID, pref.EventType, pref.Channel, pref.Target, pref.Enabled, pref.SeverityMin, pref.Digest)
+
+	return scanPreference(row)
+}
+
+// Get returns a single preference or nil when it does not exist.
+func (r *PreferenceRepositoryPG) Get(ctx context.Context, id int64) (*domain.NotificationPreference, error) {
+	row := r.DB.QueryRowContext(ctx, `
+        SELECT id, organization_id, user_id, event_type, channel, target, enabled, severity_min, COALESCE(digest, 'immediate'), created_at, updated_at
+        FROM notification_preferences
+        WHERE id=$1
+    `, id)
+
+	pref, err := scanPreference(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &pref, nil
+}
+
+// Delete removes a preference of the organization.
+func (r *PreferenceRepositoryPG) Delete(ctx context.Context, orgID, id int64) error {
+	res, err := r.DB.ExecContext(ctx, `
+        DELETE FROM notification_preferences
+        WHERE organization_id=$1 AND id=$2`, orgID, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// Replace deletes every preference of the scope and inserts prefs in its place.
+func (r *PreferenceRepositoryPG) Replace(ctx context.Context, orgID int64, userID *int64, prefs []domain.NotificationPreference) ([]domain.NotificationPreference, error) {
+	return r.replace(ctx, orgID, userID, prefs, `
+        DELETE FROM notification_preferences
+        WHERE organization_id=$1 AND user_id IS NOT DISTINCT FROM $2`, orgID, nullableID(userID))
+}
+
+// ReplaceMatrix deletes the user's preferences for eventTypes on channels and
+// inserts prefs in their place; rows outside the matrix keep their ids.
+func (r *PreferenceRepositoryPG) ReplaceMatrix(ctx context.Context, orgID, userID int64, eventTypes, channels []string, prefs []domain.NotificationPreference) ([]domain.NotificationPreference, error) {
+	return r.replace(ctx, orgID, &userID, prefs, `
+        DELETE FROM notification_preferences
+        WHERE organization_id=$1 AND user_id=$2 AND event_type = ANY($3) AND channel = ANY($4)`,
+		orgID, userID, pq.Array(eventTypes), pq.Array(channels))
+}
+
+// replace runs deleteQuery and inserts prefs for the scope in one transaction.
+func (r *PreferenceRepositoryPG) replace(ctx context.Context, orgID int64, userID *int64, prefs []domain.NotificationPreference,
+	deleteQuery string, args ...interface{}) ([]domain.NotificationPreference, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, deleteQuery, args...); err != nil {
+		return nil, err
+	}
+	out := make([]domain.NotificationPreference, 0, len(prefs))
+	for _, p := range prefs {
+		if p.Digest == "" {
+			p.Digest = domain.DigestImmediate
+		}
+		row := tx.QueryRowContext(ctx, `
+            INSERT INTO notification_preferences (organization_id, user_id, event_type, channel, target, enabled, severity_min, digest)
+            VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+            RETURNING id, organization_id, user_id, event_type, channel, target, enabled, severity_min, COALESCE(digest, 'immediate'), created_at, updated_at
+        `, orgID, nullableID(userID), p.EventType, p.Channel, p.Target, p.Enabled, p.SeverityMin, p.Digest)
+		saved, err := scanPreference(row)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, saved)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
 
-	var saved domain.NotificationPreference
+func scanPreference(row rowScanner) (domain.NotificationPreference, error) {
+	var pref domain.NotificationPreference
 	var user sql.NullInt64
-	err := row.Scan(&saved.ID, &saved.OrganizationID, &user, &saved.EventType, &saved.Channel, &saved.Target, &saved.Enabled, &saved.SeverityMin, &saved.CreatedAt, &saved.UpdatedAt)
+	err := row.Scan(&pref.ID, &pref.OrganizationID, &user, &pref.EventType, &pref.Channel, &pref.Target, &pref.Enabled, &pref.SeverityMin, &pref.Digest, &pref.CreatedAt, &pref.UpdatedAt)
 	if user.Valid {
 		val := user.Int64
-		saved.UserID = &val
+		pref.UserID = &val
 	}
-	return saved, err
+	return pref, err
 }
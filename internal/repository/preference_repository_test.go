@@ -9,6 +9,7 @@ import (
 	"myesi-notification-service/internal/domain"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 )
 
 func TestPreferenceRepositoryPG_List(t *testing.T) {
@@ -19,10 +20,11 @@ func TestPreferenceRepositoryPG_List(t *testing.T) {
 	now := time.Now()
 	user := sql.NullInt64{Int64: 9, Valid: true}
 
-	mock.ExpectQuery("SELECT id, organization_id, user_id, event_type, channel, target, enabled, severity_min, created_at, updated_at").
+	mock.ExpectQuery("SELECT id, organization_id, user_id, event_type, channel, target, enabled, severity_min, COALESCE\\(digest, 'immediate'\\), created_at, updated_at(.|\n)*event_type = ANY\\(\\$2\\)").
+		WithArgs(int64(2), pq.Array([]string{"payment.success", "*", "payment.*", "*.success"})).
 		WillReturnRows(sqlmock.NewRows([]string{
-			"id", "organization_id", "user_id", "event_type", "channel", "target", "enabled", "severity_min", "created_at", "updated_at",
-		}).AddRow(int64(1), int64(2), user, "payment.success", "email", "a@b.com", true, "low", now, now))
+			"id", "organization_id", "user_id", "event_type", "channel", "target", "enabled", "severity_min", "digest", "created_at", "updated_at",
+		}).AddRow(int64(1), int64(2), user, "payment.success", "email", "a@b.com", true, "low", "immediate", now, now))
 
 	out, err := repo.List(context.Background(), 2, nil, "payment.success")
 	if err != nil {
@@ -47,8 +49,8 @@ func TestPreferenceRepositoryPG_Save_UpdateByID(t *testing.T) {
 
 	mock.ExpectQuery("UPDATE notification_preferences").
 		WillReturnRows(sqlmock.NewRows([]string{
-			"id", "organization_id", "user_id", "event_type", "channel", "target", "enabled", "severity_min", "created_at", "updated_at",
-		}).AddRow(int64(7), int64(1), user, "x", "email", "t", true, "low", now, now))
+			"id", "organization_id", "user_id", "event_type", "channel", "target", "enabled", "severity_min", "digest", "created_at", "updated_at",
+		}).AddRow(int64(7), int64(1), user, "x", "email", "t", true, "low", "immediate", now, now))
 
 	uid := int64(9)
 	out, err := repo.Save(context.Background(), domain.NotificationPreference{
@@ -75,9 +77,10 @@ func TestPreferenceRepositoryPG_Save_InsertOnConflict(t *testing.T) {
 	user := sql.NullInt64{Valid: false}
 
 	mock.ExpectQuery("INSERT INTO notification_preferences").
+		WithArgs(int64(1), nil, "x", "email", "t", true, "low", domain.DigestImmediate).
 		WillReturnRows(sqlmock.NewRows([]string{
-			"id", "organization_id", "user_id", "event_type", "channel", "target", "enabled", "severity_min", "created_at", "updated_at",
-		}).AddRow(int64(10), int64(1), user, "x", "email", "t", true, "low", now, now))
+			"id", "organization_id", "user_id", "event_type", "channel", "target", "enabled", "severity_min", "digest", "created_at", "updated_at",
+		}).AddRow(int64(10), int64(1), user, "x", "email", "t", true, "low", "immediate", now, now))
 
 	out, err := repo.Save(context.Background(), domain.NotificationPreference{
 		OrganizationID: 1, UserID: nil, EventType: "x", Channel: "email", Target: "t", Enabled: true, SeverityMin: "low",
@@ -93,3 +96,113 @@ func TestPreferenceRepositoryPG_Save_InsertOnConflict(t *testing.T) {
 		t.Fatalf("unmet expectations: %v", err)
 	}
 }
+
+func TestPreferenceRepositoryPG_Get(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &PreferenceRepositoryPG{DB: db}
+	now := time.Now()
+
+	mock.ExpectQuery("FROM notification_preferences WHERE id=\\$1").
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "organization_id", "user_id", "event_type", "channel", "target", "enabled", "severity_min", "digest", "created_at", "updated_at",
+		}).AddRow(int64(7), int64(1), nil, "x", "slack", "https://hooks", true, "", "immediate", now, now))
+	mock.ExpectQuery("FROM notification_preferences").
+		WithArgs(int64(8)).
+		WillReturnError(sql.ErrNoRows)
+
+	out, err := repo.Get(context.Background(), 7)
+	if err != nil || out == nil || out.Channel != "slack" || out.UserID != nil {
+		t.Fatalf("unexpected out: %#v %v", out, err)
+	}
+	out, err = repo.Get(context.Background(), 8)
+	if err != nil || out != nil {
+		t.Fatalf("expected nil pref and err, got %#v %v", out, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPreferenceRepositoryPG_Delete(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &PreferenceRepositoryPG{DB: db}
+	mock.ExpectExec("DELETE FROM notification_preferences").WithArgs(int64(1), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM notification_preferences").WithArgs(int64(1), int64(8)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := repo.Delete(context.Background(), 1, 7); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if err := repo.Delete(context.Background(), 1, 8); err != domain.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPreferenceRepositoryPG_Replace(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &PreferenceRepositoryPG{DB: db}
+	now := time.Now()
+	uid := int64(9)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM notification_preferences(.|\n)*user_id IS NOT DISTINCT FROM \\$2").
+		WithArgs(int64(1), uid).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery("INSERT INTO notification_preferences").
+		WithArgs(int64(1), uid, "*", "email", "me@x.io", true, "", domain.DigestImmediate).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "organization_id", "user_id", "event_type", "channel", "target", "enabled", "severity_min", "digest", "created_at", "updated_at",
+		}).AddRow(int64(11), int64(1), uid, "*", "email", "me@x.io", true, "", "immediate", now, now))
+	mock.ExpectCommit()
+
+	out, err := repo.Replace(context.Background(), 1, &uid, []domain.NotificationPreference{
+		{EventType: "*", Channel: "email", Target: "me@x.io", Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(out) != 1 || out[0].ID != 11 || out[0].UserID == nil || *out[0].UserID != 9 {
+		t.Fatalf("unexpected out: %#v", out)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPreferenceRepositoryPG_ReplaceMatrixOnlyDeletesMatrixRows(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &PreferenceRepositoryPG{DB: db}
+	now := time.Now()
+	uid := int64(9)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM notification_preferences(.|\n)*user_id=\\$2 AND event_type = ANY\\(\\$3\\) AND channel = ANY\\(\\$4\\)").
+		WithArgs(int64(1), uid, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery("INSERT INTO notification_preferences").
+		WithArgs(int64(1), uid, "payment.*", "email", "me@x.io", true, "", domain.DigestImmediate).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "organization_id", "user_id", "event_type", "channel", "target", "enabled", "severity_min", "digest", "created_at", "updated_at",
+		}).AddRow(int64(12), int64(1), uid, "payment.*", "email", "me@x.io", true, "", "immediate", now, now))
+	mock.ExpectCommit()
+
+	out, err := repo.ReplaceMatrix(context.Background(), 1, uid, []string{"payment.*"}, []string{"inbox", "email"},
+		[]domain.NotificationPreference{{EventType: "payment.*", Channel: "email", Target: "me@x.io", Enabled: true}})
+	if err != nil || len(out) != 1 || out[0].ID != 12 {
+		t.Fatalf("unexpected out: %#v %v", out, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
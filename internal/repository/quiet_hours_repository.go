@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"myesi-notification-service/internal/domain"
+)
+
+// QuietHoursRepositoryPG persists quiet hours in PostgreSQL.
+type QuietHoursRepositoryPG struct {
+	DB *sql.DB
+}
+
+const quietHoursColumns = `id, organization_id, user_id, time_zone, start_time, end_time, defer_critical, updated_at`
+
+// Find prefers the user's own row over the org-wide one.
+func (r *QuietHoursRepositoryPG) Find(ctx context.Context, orgID int64, userID *int64) (*domain.QuietHours, error) {
+	row := r.DB.QueryRowContext(ctx, `
+        SELECT `+quietHoursColumns+`
+        FROM notification_quiet_hours
+        WHERE organization_id=$1 AND (user_id IS NULL OR user_id=$2)
+        ORDER BY user_id NULLS LAST
+        LIMIT 1`, orgID, nullableID(userID))
+	q, err := scanQuietHours(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &q, nil
+}
+
+// Save creates or replaces the window for (organization, user).
+func (r *QuietHoursRepositoryPG) Save(ctx context.Context, q domain.QuietHours) (domain.QuietHours, error) {
+	row := r.DB.QueryRowContext(ctx, `
+        INSERT INTO notification_quiet_hours (organization_id, user_id, time_zone, start_time, end_time, defer_critical)
+        VALUES ($1,$2,$3,$4,$5,$6)
+        ON CONFLICT (organization_id, (COALESCE(user_id, 0)))
+        DO UPDATE SET time_zone=EXCLUDED.time_zone, start_time=EXCLUDED.start_time, end_time=EXCLUDED.end_time,
+                      defer_critical=EXCLUDED.defer_critical, updated_at=NOW()
+        RETURNING `+quietHoursColumns, q.OrganizationID, nullableID(q.UserID), q.TimeZone, q.Start, q.End, q.DeferCritical)
+	return scanQuietHours(row)
+}
+
+// Delete removes the window for (organization, user); a nil userID removes the org-wide one.
+func (r *QuietHoursRepositoryPG) Delete(ctx context.Context, orgID int64, userID *int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+        DELETE FROM notification_quiet_hours
+        WHERE organization_id=$1 AND COALESCE(user_id, 0)=COALESCE($2::bigint, 0)`, orgID, nullableID(userID))
+	return err
+}
+
+func scanQuietHours(row rowScanner) (domain.QuietHours, error) {
+	var q domain.QuietHours
+	var user sql.NullInt64
+	err := row.Scan(&q.ID, &q.OrganizationID, &user, &q.TimeZone, &q.Start, &q.End, &q.DeferCritical, &q.UpdatedAt)
+	if user.Valid {
+		val := user.Int64
+		q.UserID = &val
+	}
+	return q, err
+}
+
+// nullableID maps an optional ID to a SQL NULL when absent.
+func nullableID(id *int64) interface{} {
+	if id == nil {
+		return nil
+	}
+	return *id
+}
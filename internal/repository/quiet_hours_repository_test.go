@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var quietHoursCols = []string{"id", "organization_id", "user_id", "time_zone", "start_time", "end_time", "defer_critical", "updated_at"}
+
+func TestQuietHoursRepositoryPG_FindPrefersUser(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &QuietHoursRepositoryPG{DB: db}
+	uid := int64(7)
+
+	mock.ExpectQuery("ORDER BY user_id NULLS LAST").
+		WithArgs(int64(1), int64(7)).
+		WillReturnRows(sqlmock.NewRows(quietHoursCols).AddRow(int64(2), int64(1), int64(7), "Asia/Ho_Chi_Minh", "22:00", "07:00", false, time.Now()))
+	mock.ExpectQuery("FROM notification_quiet_hours").
+		WithArgs(int64(1), nil).
+		WillReturnError(sql.ErrNoRows)
+
+	q, err := repo.Find(context.Background(), 1, &uid)
+	if err != nil || q == nil || q.UserID == nil || *q.UserID != 7 || q.TimeZone != "Asia/Ho_Chi_Minh" {
+		t.Fatalf("unexpected quiet hours %#v %v", q, err)
+	}
+	q, err = repo.Find(context.Background(), 1, nil)
+	if err != nil || q != nil {
+		t.Fatalf("expected nil quiet hours, got %#v %v", q, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestQuietHoursRepositoryPG_SaveAndDelete(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &QuietHoursRepositoryPG{DB: db}
+
+	mock.ExpectQuery("ON CONFLICT \\(organization_id, \\(COALESCE\\(user_id, 0\\)\\)\\)").
+		WithArgs(int64(1), nil, "Europe/Berlin", "22:00", "07:00", true).
+		WillReturnRows(sqlmock.NewRows(quietHoursCols).AddRow(int64(3), int64(1), nil, "Europe/Berlin", "22:00", "07:00", true, time.Now()))
+	mock.ExpectExec("DELETE FROM notification_quiet_hours").
+		WithArgs(int64(1), nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	saved, err := repo.Save(context.Background(), domain.QuietHours{OrganizationID: 1, TimeZone: "Europe/Berlin", Start: "22:00", End: "07:00", DeferCritical: true})
+	if err != nil || saved.ID != 3 || saved.UserID != nil {
+		t.Fatalf("unexpected saved %#v %v", saved, err)
+	}
+	if err := repo.Delete(context.Background(), 1, nil); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
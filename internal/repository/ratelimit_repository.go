@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+)
+
+// RateLimitRepositoryPG stores rate limits and their token buckets in PostgreSQL.
+type RateLimitRepositoryPG struct {
+	DB *sql.DB
+}
+
+const rateLimitColumns = `id, organization_id, channel, event_type, burst, per_minute, overflow, updated_at`
+
+func (r *RateLimitRepositoryPG) List(ctx context.Context, orgID int64) ([]domain.RateLimit, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        SELECT `+rateLimitColumns+`
+        FROM notification_rate_limits
+        WHERE organization_id=$1
+        ORDER BY channel, event_type`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.RateLimit, 0)
+	for rows.Next() {
+		l, err := scanRateLimit(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+// Save creates or replaces the limit for (organization, channel, event type).
+func (r *RateLimitRepositoryPG) Save(ctx context.Context, l domain.RateLimit) (domain.RateLimit, error) {
+	row := r.DB.QueryRowContext(ctx, `
+        INSERT INTO notification_rate_limits (organization_id, channel, event_type, burst, per_minute, overflow)
+        VALUES ($1,$2,$3,$4,$5,$6)
+        ON CONFLICT (organization_id, channel, event_type)
+        DO UPDATE SET burst=EXCLUDED.burst, per_minute=EXCLUDED.per_minute, overflow=EXCLUDED.overflow, updated_at=NOW()
+        RETURNING `+rateLimitColumns, l.OrganizationID, l.Channel, l.EventType, l.Burst, l.PerMinute, l.Overflow)
+	return scanRateLimit(row)
+}
+
+// Delete removes a limit; its buckets go with it.
+func (r *RateLimitRepositoryPG) Delete(ctx context.Context, orgID, id int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+        DELETE FROM notification_rate_limits
+        WHERE organization_id=$1 AND id=$2`, orgID, id)
+	return err
+}
+
+func (r *RateLimitRepositoryPG) Match(ctx context.Context, orgID int64, channel, eventType string) (*domain.RateLimit, error) {
+	row := r.DB.QueryRowContext(ctx, `
+        SELECT `+rateLimitColumns+`
+        FROM notification_rate_limits
+        WHERE organization_id=$1 AND channel IN ('', $2) AND event_type IN ('', $3)
+        ORDER BY (channel <> '') DESC, (event_type <> '') DESC
+        LIMIT 1`, orgID, channel, eventType)
+	l, err := scanRateLimit(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Take locks the target's bucket, spends a token and counts suppressed
+// deliveries. A missing bucket starts full.
+func (r *RateLimitRepositoryPG) Take(ctx context.Context, l domain.RateLimit, target domain.DeliveryTarget, eventType string, now time.Time) (time.Duration, bool, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	key := bucketKey(target)
+	var tokens float64
+	var last time.Time
+	err = tx.QueryRowContext(ctx, `
+        SELECT tokens, refreshed_at FROM notification_rate_buckets
+        WHERE limit_id=$1 AND bucket_key=$2
+        FOR UPDATE`, l.ID, key).Scan(&tokens, &last)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, false, err
+	}
+
+	left, wait, ok := l.Take(tokens, last, now)
+	suppressed := 0
+	if !ok && l.Overflow != domain.OverflowDelay {
+		suppressed = 1
+	}
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO notification_rate_buckets
+            (limit_id, bucket_key, organization_id, channel, target, user_id, event_type, tokens, refreshed_at, suppressed, suppressed_since)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10, CASE WHEN $10::int > 0 THEN $9::timestamptz END)
+        ON CONFLICT (limit_id, bucket_key) DO UPDATE SET
+            tokens=EXCLUDED.tokens, refreshed_at=EXCLUDED.refreshed_at, event_type=EXCLUDED.event_type,
+            suppressed=notification_rate_buckets.suppressed + EXCLUDED.suppressed,
+            suppressed_since=COALESCE(notification_rate_buckets.suppressed_since, EXCLUDED.suppressed_since)`,
+		l.ID, key, l.OrganizationID, target.Channel, target.Target, nullableID(target.UserID), eventType,
+		left, now, suppressed); err != nil {
+		return 0, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, false, err
+	}
+	return wait, ok, nil
+}
+
+// ClaimSuppressed resets and returns the suppressed counts of buckets that hold
+// a token again, oldest first.
+func (r *RateLimitRepositoryPG) ClaimSuppressed(ctx context.Context, now time.Time, limit int) ([]domain.SuppressedBatch, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        WITH due AS (
+            SELECT b.limit_id, b.bucket_key, b.suppressed, b.suppressed_since
+            FROM notification_rate_buckets b
+            JOIN notification_rate_limits l ON l.id = b.limit_id
+            WHERE b.suppressed > 0
+              AND b.tokens + EXTRACT(EPOCH FROM ($1::timestamptz - b.refreshed_at)) / 60 * l.per_minute >= 1
+            ORDER BY b.suppressed_since
+            LIMIT $2
+            FOR UPDATE OF b SKIP LOCKED
+        )
+        UPDATE notification_rate_buckets b
+        SET suppressed=0, suppressed_since=NULL
+        FROM due
+        WHERE b.limit_id=due.limit_id AND b.bucket_key=due.bucket_key
+        RETURNING b.organization_id, b.channel, b.target, b.user_id, b.event_type, due.suppressed, due.suppressed_since`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.SuppressedBatch, 0)
+	for rows.Next() {
+		var b domain.SuppressedBatch
+		var user sql.NullInt64
+		if err := rows.Scan(&b.OrganizationID, &b.Channel, &b.Target, &user, &b.EventType, &b.Count, &b.Since); err != nil {
+			return nil, err
+		}
+		if user.Valid {
+			val := user.Int64
+			b.UserID = &val
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// bucketKey names a destination: the inbox of a user or a channel target.
+func bucketKey(target domain.DeliveryTarget) string {
+	if target.Channel == domain.ChannelInbox && target.UserID != nil {
+		return target.Channel + "|user:" + strconv.FormatInt(*target.UserID, 10)
+	}
+	return target.Channel + "|" + target.Target
+}
+
+func scanRateLimit(row rowScanner) (domain.RateLimit, error) {
+	var l domain.RateLimit
+	err := row.Scan(&l.ID, &l.OrganizationID, &l.Channel, &l.EventType, &l.Burst, &l.PerMinute, &l.Overflow, &l.UpdatedAt)
+	return l, err
+}
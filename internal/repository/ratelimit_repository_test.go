@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var rateLimitCols = []string{"id", "organization_id", "channel", "event_type", "burst", "per_minute", "overflow", "updated_at"}
+
+func TestRateLimitRepositoryPG_MatchPrefersSpecific(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &RateLimitRepositoryPG{DB: db}
+	mock.ExpectQuery("channel IN \\('', \\$2\\) AND event_type IN \\('', \\$3\\)(.|\n)*ORDER BY \\(channel <> ''\\) DESC, \\(event_type <> ''\\) DESC").
+		WithArgs(int64(1), "slack", "code_finding.assignment").
+		WillReturnRows(sqlmock.NewRows(rateLimitCols).AddRow(int64(2), int64(1), "slack", "", 10, 5.0, "summarize", time.Now()))
+
+	l, err := repo.Match(context.Background(), 1, "slack", "code_finding.assignment")
+	if err != nil || l == nil || l.ID != 2 || l.PerMinute != 5 {
+		t.Fatalf("unexpected limit %#v %v", l, err)
+	}
+}
+
+func TestRateLimitRepositoryPG_TakeCountsSuppressed(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &RateLimitRepositoryPG{DB: db}
+	now := time.Now()
+	l := domain.RateLimit{ID: 2, OrganizationID: 1, Channel: "slack", Burst: 10, PerMinute: 5, Overflow: domain.OverflowSummarize}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT tokens, refreshed_at FROM notification_rate_buckets(.|\n)*FOR UPDATE").
+		WithArgs(int64(2), "slack|https://hooks/1").
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "refreshed_at"}).AddRow(0.0, now))
+	mock.ExpectExec("INSERT INTO notification_rate_buckets(.|\n)*suppressed=notification_rate_buckets.suppressed \\+ EXCLUDED.suppressed").
+		WithArgs(int64(2), "slack|https://hooks/1", int64(1), "slack", "https://hooks/1", nil, "code_finding.assignment", 0.0, now, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, ok, err := repo.Take(context.Background(), l, domain.DeliveryTarget{Channel: "slack", Target: "https://hooks/1"}, "code_finding.assignment", now)
+	if err != nil || ok {
+		t.Fatalf("expected throttled, got %v %v", ok, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRateLimitRepositoryPG_ClaimSuppressed(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &RateLimitRepositoryPG{DB: db}
+	now := time.Now()
+	mock.ExpectQuery("WITH due AS(.|\n)*FOR UPDATE OF b SKIP LOCKED(.|\n)*SET suppressed=0").
+		WithArgs(now, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"organization_id", "channel", "target", "user_id", "event_type", "suppressed", "suppressed_since"}).
+			AddRow(int64(1), "inbox", "", int64(3), "code_finding.assignment", 42, now.Add(-time.Hour)))
+
+	batches, err := repo.ClaimSuppressed(context.Background(), now, 10)
+	if err != nil || len(batches) != 1 || batches[0].Count != 42 || batches[0].UserID == nil || *batches[0].UserID != 3 {
+		t.Fatalf("unexpected batches %#v %v", batches, err)
+	}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"myesi-notification-service/internal/domain"
+)
+
+// RoutingRuleRepositoryPG persists routing rules in PostgreSQL.
+type RoutingRuleRepositoryPG struct {
+	DB *sql.DB
+}
+
+const routingRuleColumns = `id, organization_id, position, name, event_pattern, severity_min, condition, actions,
+        continue_matching, enabled, updated_at`
+
+// List returns the organization's rules in evaluation order.
+func (r *RoutingRuleRepositoryPG) List(ctx context.Context, orgID int64) ([]domain.RoutingRule, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        SELECT `+routingRuleColumns+`
+        FROM notification_routing_rules
+        WHERE organization_id=$1
+        ORDER BY position, id`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.RoutingRule, 0)
+	for rows.Next() {
+		rule, err := scanRoutingRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rule)
+	}
+	return out, rows.Err()
+}
+
+// Save inserts a rule without an id and otherwise replaces the organization's
+// rule with that id, reporting ErrNotFound when there is none.
+func (r *RoutingRuleRepositoryPG) Save(ctx context.Context, rule domain.RoutingRule) (domain.RoutingRule, error) {
+	actions, err := json.Marshal(rule.Actions)
+	if err != nil {
+		return domain.RoutingRule{}, err
+	}
+	args := []interface{}{rule.OrganizationID, rule.Position, rule.Name, rule.EventPattern, rule.SeverityMin,
+		rule.Condition, actions, rule.Continue, rule.Enabled}
+	if rule.ID == 0 {
+		return scanRoutingRule(r.DB.QueryRowContext(ctx, `
+            INSERT INTO notification_routing_rules (organization_id, position, name, event_pattern, severity_min, condition, actions, continue_matching, enabled)
+            VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+            RETURNING `+routingRuleColumns, args...))
+	}
+	saved, err := scanRoutingRule(r.DB.QueryRowContext(ctx, `
+        UPDATE notification_routing_rules
+        SET position=$2, name=$3, event_pattern=$4, severity_min=$5, condition=$6, actions=$7,
+            continue_matching=$8, enabled=$9, updated_at=NOW()
+        WHERE organization_id=$1 AND id=$10
+        RETURNING `+routingRuleColumns, append(args, rule.ID)...))
+	if err == sql.ErrNoRows {
+		return saved, domain.ErrNotFound
+	}
+	return saved, err
+}
+
+// Delete removes a rule and reports ErrNotFound when the org has no such rule.
+func (r *RoutingRuleRepositoryPG) Delete(ctx context.Context, orgID, id int64) error {
+	res, err := r.DB.ExecContext(ctx, `
+        DELETE FROM notification_routing_rules
+        WHERE organization_id=$1 AND id=$2`, orgID, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func scanRoutingRule(row rowScanner) (domain.RoutingRule, error) {
+	var rule domain.RoutingRule
+	var actions []byte
+	if err := row.Scan(&rule.ID, &rule.OrganizationID, &rule.Position, &rule.Name, &rule.EventPattern, &rule.SeverityMin,
+		&rule.Condition, &actions, &rule.Continue, &rule.Enabled, &rule.UpdatedAt); err != nil {
+		return rule, err
+	}
+	if len(actions) > 0 {
+		_ = json.Unmarshal(actions, &rule.Actions)
+	}
+	return rule, nil
+}
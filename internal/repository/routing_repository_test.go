@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var routingRuleCols = []string{"id", "organization_id", "position", "name", "event_pattern", "severity_min", "condition", "actions",
+	"continue_matching", "enabled", "updated_at"}
+
+func TestRoutingRuleRepositoryPG_ListOrdered(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &RoutingRuleRepositoryPG{DB: db}
+	mock.ExpectQuery("FROM notification_routing_rules(.|\n)*ORDER BY position, id").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows(routingRuleCols).
+			AddRow(int64(3), int64(1), 0, "core", "vulnerability.*", "", `payload.project == "core-api"`,
+				[]byte(`{"roles":["security"],"channels":["inbox","slack"]}`), false, true, time.Now()))
+
+	list, err := repo.List(context.Background(), 1)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("unexpected rules %#v %v", list, err)
+	}
+	if a := list[0].Actions; len(a.Roles) != 1 || a.Roles[0] != "security" || len(a.Channels) != 2 {
+		t.Fatalf("unexpected actions %#v", a)
+	}
+}
+
+func TestRoutingRuleRepositoryPG_SaveUnknownID(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &RoutingRuleRepositoryPG{DB: db}
+	mock.ExpectQuery("UPDATE notification_routing_rules(.|\n)*WHERE organization_id=\\$1 AND id=\\$10").
+		WillReturnRows(sqlmock.NewRows(routingRuleCols))
+
+	_, err := repo.Save(context.Background(), domain.RoutingRule{ID: 9, OrganizationID: 1, Actions: domain.RouteActions{Drop: true}})
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
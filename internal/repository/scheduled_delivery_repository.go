@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+)
+
+// ScheduledDeliveryRepositoryPG stores deferred deliveries in PostgreSQL.
+type ScheduledDeliveryRepositoryPG struct {
+	DB *sql.DB
+}
+
+func (r *ScheduledDeliveryRepositoryPG) Add(ctx context.Context, d domain.ScheduledDelivery) error {
+	eventJSON, err := json.Marshal(d.Event)
+	if err != nil {
+		return err
+	}
+	_, err = r.DB.ExecContext(ctx, `
+        INSERT INTO notification_scheduled_deliveries
+            (organization_id, user_id, channel, target, format, subject, body, event, reason, deliver_at)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+    `, d.Event.OrganizationID, nullableID(d.Event.UserID), d.Channel, d.Target, d.Format, d.Subject, d.Body,
+		eventJSON, d.Reason, d.DeliverAt)
+	return err
+}
+
+// ClaimDue leases due deliveries that are not held by another worker and
+// counts the attempt. SKIP LOCKED lets several instances drain the table
+// without sending anything twice.
+func (r *ScheduledDeliveryRepositoryPG) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.ScheduledDelivery, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        UPDATE notification_scheduled_deliveries
+        SET locked_until=$2, attempts=attempts + 1
+        WHERE id IN (
+            SELECT id FROM notification_scheduled_deliveries
+            WHERE deliver_at <= $1 AND (locked_until IS NULL OR locked_until <= $1)
+            ORDER BY deliver_at, id
+            LIMIT $3
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING id, channel, target, format, subject, body, event, reason, deliver_at, attempts
+    `, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.ScheduledDelivery, 0)
+	for rows.Next() {
+		var d domain.ScheduledDelivery
+		var event []byte
+		if err := rows.Scan(&d.ID, &d.Channel, &d.Target, &d.Format, &d.Subject, &d.Body, &event, &d.Reason, &d.DeliverAt, &d.Attempts); err != nil {
+			return nil, err
+		}
+		if len(event) > 0 {
+			_ = json.Unmarshal(event, &d.Event)
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// Complete deletes a handled delivery.
+func (r *ScheduledDeliveryRepositoryPG) Complete(ctx context.Context, id int64) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM notification_scheduled_deliveries WHERE id=$1`, id)
+	return err
+}
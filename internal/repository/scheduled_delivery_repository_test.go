@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestScheduledDeliveryRepositoryPG_AddAndClaim(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &ScheduledDeliveryRepositoryPG{DB: db}
+	now := time.Now()
+	uid := int64(4)
+
+	mock.ExpectExec("INSERT INTO notification_scheduled_deliveries").
+		WithArgs(int64(1), int64(4), "slack", "https://hooks", "text", "s", "b", sqlmock.AnyArg(), domain.ReasonQuietHours, now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("UPDATE notification_scheduled_deliveries(.|\n)*locked_until IS NULL OR locked_until <= \\$1(.|\n)*FOR UPDATE SKIP LOCKED").
+		WithArgs(now, now.Add(time.Minute), 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "channel", "target", "format", "subject", "body", "event", "reason", "deliver_at", "attempts"}).
+			AddRow(int64(1), "slack", "https://hooks", "text", "s", "b", []byte(`{"type":"scan.done","organization_id":1,"user_id":4}`), domain.ReasonQuietHours, now, 1))
+	mock.ExpectExec("DELETE FROM notification_scheduled_deliveries WHERE id=\\$1").
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.Add(context.Background(), domain.ScheduledDelivery{
+		Event:   domain.NotificationEvent{EventType: "scan.done", OrganizationID: 1, UserID: &uid},
+		Channel: "slack", Target: "https://hooks", Format: "text", Subject: "s", Body: "b",
+		Reason: domain.ReasonQuietHours, DeliverAt: now,
+	})
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	due, err := repo.ClaimDue(context.Background(), now, time.Minute, 10)
+	if err != nil || len(due) != 1 || due[0].Event.EventType != "scan.done" || due[0].Event.UserID == nil || due[0].Attempts != 1 {
+		t.Fatalf("unexpected due %#v %v", due, err)
+	}
+	if err := repo.Complete(context.Background(), 1); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
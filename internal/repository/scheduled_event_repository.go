@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+)
+
+// ScheduledEventRepositoryPG stores delayed events in PostgreSQL.
+type ScheduledEventRepositoryPG struct {
+	DB *sql.DB
+}
+
+const scheduledEventColumns = `id, organization_id, schedule_key, event, deliver_at, attempts`
+
+// Schedule inserts the event, or replaces the pending one with the same key
+// and resets its lease and attempts.
+func (r *ScheduledEventRepositoryPG) Schedule(ctx context.Context, e domain.ScheduledEvent) (domain.ScheduledEvent, error) {
+	eventJSON, err := json.Marshal(e.Event)
+	if err != nil {
+		return domain.ScheduledEvent{}, err
+	}
+	row := r.DB.QueryRowContext(ctx, `
+        INSERT INTO notification_scheduled_events (organization_id, schedule_key, event, deliver_at)
+        VALUES ($1,$2,$3,$4)
+        ON CONFLICT (organization_id, schedule_key) WHERE schedule_key <> ''
+        DO UPDATE SET event=EXCLUDED.event, deliver_at=EXCLUDED.deliver_at, attempts=0, locked_until=NULL, updated_at=NOW()
+        RETURNING `+scheduledEventColumns, e.OrganizationID, e.Key, eventJSON, e.DeliverAt)
+	return scanScheduledEvent(row)
+}
+
+// Claim leases due events that are not held by another worker.
+func (r *ScheduledEventRepositoryPG) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.ScheduledEvent, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        UPDATE notification_scheduled_events
+        SET locked_until=$2, attempts=attempts + 1
+        WHERE id IN (
+            SELECT id FROM notification_scheduled_events
+            WHERE deliver_at <= $1 AND (locked_until IS NULL OR locked_until <= $1)
+            ORDER BY deliver_at, id
+            LIMIT $3
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING `+scheduledEventColumns, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.ScheduledEvent, 0)
+	for rows.Next() {
+		e, err := scanScheduledEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// Complete deletes a processed event. Rescheduling clears the lease, so a row
+// replaced while it was being processed survives.
+func (r *ScheduledEventRepositoryPG) Complete(ctx context.Context, id int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+        DELETE FROM notification_scheduled_events
+        WHERE id=$1 AND locked_until IS NOT NULL`, id)
+	return err
+}
+
+// Cancel deletes the pending event with key and reports whether one existed.
+func (r *ScheduledEventRepositoryPG) Cancel(ctx context.Context, orgID int64, key string) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `
+        DELETE FROM notification_scheduled_events
+        WHERE organization_id=$1 AND schedule_key=$2`, orgID, key)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func scanScheduledEvent(row rowScanner) (domain.ScheduledEvent, error) {
+	var e domain.ScheduledEvent
+	var event []byte
+	if err := row.Scan(&e.ID, &e.OrganizationID, &e.Key, &event, &e.DeliverAt, &e.Attempts); err != nil {
+		return e, err
+	}
+	if len(event) > 0 {
+		_ = json.Unmarshal(event, &e.Event)
+	}
+	return e, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var scheduledEventCols = []string{"id", "organization_id", "schedule_key", "event", "deliver_at", "attempts"}
+
+func TestScheduledEventRepositoryPG_ScheduleReplacesByKey(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &ScheduledEventRepositoryPG{DB: db}
+	at := time.Now().Add(48 * time.Hour)
+
+	mock.ExpectQuery("ON CONFLICT \\(organization_id, schedule_key\\) WHERE schedule_key <> ''(.|\n)*attempts=0, locked_until=NULL").
+		WithArgs(int64(1), "sla:42", sqlmock.AnyArg(), at).
+		WillReturnRows(sqlmock.NewRows(scheduledEventCols).AddRow(int64(9), int64(1), "sla:42", []byte(`{"type":"vulnerability.sla_due"}`), at, 0))
+
+	e, err := repo.Schedule(context.Background(), domain.ScheduledEvent{
+		OrganizationID: 1, Key: "sla:42", DeliverAt: at,
+		Event: domain.NotificationEvent{EventType: "vulnerability.sla_due", OrganizationID: 1},
+	})
+	if err != nil || e.ID != 9 || e.Event.EventType != "vulnerability.sla_due" {
+		t.Fatalf("unexpected scheduled event %#v %v", e, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestScheduledEventRepositoryPG_ClaimLeases(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &ScheduledEventRepositoryPG{DB: db}
+	now := time.Now()
+
+	mock.ExpectQuery("UPDATE notification_scheduled_events(.|\n)*locked_until IS NULL OR locked_until <= \\$1(.|\n)*FOR UPDATE SKIP LOCKED").
+		WithArgs(now, now.Add(time.Minute), 10).
+		WillReturnRows(sqlmock.NewRows(scheduledEventCols).AddRow(int64(9), int64(1), "", []byte(`{"type":"trial.expiring"}`), now, 1))
+	mock.ExpectExec("DELETE FROM notification_scheduled_events(.|\n)*locked_until IS NOT NULL").
+		WithArgs(int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	due, err := repo.Claim(context.Background(), now, time.Minute, 10)
+	if err != nil || len(due) != 1 || due[0].Attempts != 1 {
+		t.Fatalf("unexpected claim %#v %v", due, err)
+	}
+	if err := repo.Complete(context.Background(), 9); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestScheduledEventRepositoryPG_Cancel(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &ScheduledEventRepositoryPG{DB: db}
+	mock.ExpectExec("DELETE FROM notification_scheduled_events").
+		WithArgs(int64(1), "sla:42").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ok, err := repo.Cancel(context.Background(), 1, "sla:42")
+	if err != nil || ok {
+		t.Fatalf("expected nothing cancelled, got %v %v", ok, err)
+	}
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+)
+
+// SnoozeRepositoryPG persists user snoozes in PostgreSQL.
+type SnoozeRepositoryPG struct {
+	DB *sql.DB
+}
+
+const snoozeColumns = `id, organization_id, user_id, scope, event_type, project_id, project, until, created_at`
+
+// Active returns the organization's snoozes that end after now.
+func (r *SnoozeRepositoryPG) Active(ctx context.Context, orgID int64, now time.Time) ([]domain.Snooze, error) {
+	return r.query(ctx, `
+        SELECT `+snoozeColumns+`
+        FROM notification_snoozes
+        WHERE organization_id=$1 AND until > $2
+        ORDER BY until, id`, orgID, now)
+}
+
+// ListForUser returns the user's snoozes that end after now, soonest first.
+func (r *SnoozeRepositoryPG) ListForUser(ctx context.Context, orgID, userID int64, now time.Time) ([]domain.Snooze, error) {
+	return r.query(ctx, `
+        SELECT `+snoozeColumns+`
+        FROM notification_snoozes
+        WHERE organization_id=$1 AND user_id=$2 AND until > $3
+        ORDER BY until, id`, orgID, userID, now)
+}
+
+// Create inserts a snooze.
+func (r *SnoozeRepositoryPG) Create(ctx context.Context, z domain.Snooze) (domain.Snooze, error) {
+	return scanSnooze(r.DB.QueryRowContext(ctx, `
+        INSERT INTO notification_snoozes (organization_id, user_id, scope, event_type, project_id, project, until)
+        VALUES ($1,$2,$3,$4,$5,$6,$7)
+        RETURNING `+snoozeColumns,
+		z.OrganizationID, z.UserID, z.Scope, z.EventType, z.ProjectID, z.Project, z.Until))
+}
+
+// Delete cancels one of the user's snoozes and reports ErrNotFound when the
+// user has no snooze with that id.
+func (r *SnoozeRepositoryPG) Delete(ctx context.Context, orgID, userID, id int64) error {
+	res, err := r.DB.ExecContext(ctx, `
+        DELETE FROM notification_snoozes
+        WHERE organization_id=$1 AND user_id=$2 AND id=$3`, orgID, userID, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteExpired purges snoozes that ended at or before now.
+func (r *SnoozeRepositoryPG) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM notification_snoozes WHERE until <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (r *SnoozeRepositoryPG) query(ctx context.Context, query string, args ...interface{}) ([]domain.Snooze, error) {
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Snooze, 0)
+	for rows.Next() {
+		z, err := scanSnooze(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, z)
+	}
+	return out, rows.Err()
+}
+
+func scanSnooze(row rowScanner) (domain.Snooze, error) {
+	var z domain.Snooze
+	err := row.Scan(&z.ID, &z.OrganizationID, &z.UserID, &z.Scope, &z.EventType, &z.ProjectID, &z.Project,
+		&z.Until, &z.CreatedAt)
+	return z, err
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var snoozeRowColumns = []string{"id", "organization_id", "user_id", "scope", "event_type", "project_id", "project", "until", "created_at"}
+
+func TestSnoozeRepositoryPG_ActiveSkipsExpired(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	now := time.Now()
+	repo := &SnoozeRepositoryPG{DB: db}
+	mock.ExpectQuery("FROM notification_snoozes(.|\n)*WHERE organization_id=\\$1 AND until > \\$2").
+		WithArgs(int64(1), now).
+		WillReturnRows(sqlmock.NewRows(snoozeRowColumns).
+			AddRow(int64(3), int64(1), int64(7), domain.SnoozeProject, "", int64(4), "", now.Add(time.Hour), now))
+
+	list, err := repo.Active(context.Background(), 1, now)
+	if err != nil || len(list) != 1 || list[0].ProjectID != 4 || list[0].UserID != 7 {
+		t.Fatalf("unexpected snoozes %#v %v", list, err)
+	}
+}
+
+func TestSnoozeRepositoryPG_DeleteScopedToUser(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &SnoozeRepositoryPG{DB: db}
+	mock.ExpectExec("DELETE FROM notification_snoozes(.|\n)*organization_id=\\$1 AND user_id=\\$2 AND id=\\$3").
+		WithArgs(int64(1), int64(7), int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := repo.Delete(context.Background(), 1, 7, 3); err != domain.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSnoozeRepositoryPG_DeleteExpired(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	now := time.Now()
+	repo := &SnoozeRepositoryPG{DB: db}
+	mock.ExpectExec("DELETE FROM notification_snoozes WHERE until <= \\$1").
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	n, err := repo.DeleteExpired(context.Background(), now)
+	if err != nil || n != 2 {
+		t.Fatalf("unexpected purge %d %v", n, err)
+	}
+}
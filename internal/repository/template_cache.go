@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+)
+
+// CachedTemplateRepository decorates template persistence with a short-lived
+// cache of FindByEventAndChannel lookups, including misses, so bursts of events
+// don't query the same templates per target. Writes through this repository
+// invalidate only this process's cache; other replicas keep serving the old
+// template for up to TTL (TemplateCacheTTL).
+type CachedTemplateRepository struct {
+	Inner    domain.TemplateRepository
+	Versions domain.TemplateVersionRepository
+	TTL      time.Duration
+
+	mu      sync.RWMutex
+	entries map[templateCacheKey]templateCacheEntry
+	// generation counts invalidations; a lookup that started before one
+	// does not store its possibly stale result.
+	generation uint64
+}
+
+// templateCacheMaxEntries bounds the cache; event types and locales come from
+// callers, so the key space is open-ended.
+const templateCacheMaxEntries = 10000
+
+type templateCacheKey struct {
+	orgID     int64
+	eventType string
+	channel   string
+	locale    string
+}
+
+type templateCacheEntry struct {
+	value   *domain.NotificationTemplate
+	expires time.Time
+}
+
+// NewCachedTemplateRepository wraps inner; versions may be nil when history is not needed.
+func NewCachedTemplateRepository(inner domain.TemplateRepository, versions domain.TemplateVersionRepository, ttl time.Duration) *CachedTemplateRepository {
+	return &CachedTemplateRepository{
+		Inner:    inner,
+		Versions: versions,
+		TTL:      ttl,
+		entries:  map[templateCacheKey]templateCacheEntry{},
+	}
+}
+
+func (r *CachedTemplateRepository) List(ctx context.Context, orgID int64, limit, offset int) ([]domain.NotificationTemplate, error) {
+	return r.Inner.List(ctx, orgID, limit, offset)
+}
+
+func (r *CachedTemplateRepository) Upsert(ctx context.Context, tpl domain.NotificationTemplate) (domain.NotificationTemplate, error) {
+	saved, err := r.Inner.Upsert(ctx, tpl)
+	r.Invalidate()
+	return saved, err
+}
+
+func (r *CachedTemplateRepository) FindByEventAndChannel(ctx context.Context, orgID int64, eventType, channel, locale string) (*domain.NotificationTemplate, error) {
+	key := templateCacheKey{orgID: orgID, eventType: eventType, channel: channel, locale: locale}
+
+	r.mu.RLock()
+	if entry, ok := r.entries[key]; ok && time.Now().Before(entry.expires) {
+		r.mu.RUnlock()
+		return entry.value, nil
+	}
+	generation := r.generation
+	r.mu.RUnlock()
+
+	tpl, err := r.Inner.FindByEventAndChannel(ctx, orgID, eventType, channel, locale)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if r.generation == generation {
+		now := time.Now()
+		if len(r.entries) >= templateCacheMaxEntries {
+			r.evictExpired(now)
+		}
+		if len(r.entries) < templateCacheMaxEntries {
+			r.entries[key] = templateCacheEntry{value: tpl, expires: now.Add(r.TTL)}
+		}
+	}
+	r.mu.Unlock()
+	return tpl, nil
+}
+
+func (r *CachedTemplateRepository) Get(ctx context.Context, orgID, id int64) (*domain.NotificationTemplate, error) {
+	return r.Inner.Get(ctx, orgID, id)
+}
+
+func (r *CachedTemplateRepository) ListVersions(ctx context.Context, orgID, templateID int64) ([]domain.TemplateVersion, error) {
+	return r.Versions.ListVersions(ctx, orgID, templateID)
+}
+
+func (r *CachedTemplateRepository) GetVersion(ctx context.Context, orgID, templateID int64, version int) (*domain.TemplateVersion, error) {
+	return r.Versions.GetVersion(ctx, orgID, templateID, version)
+}
+
+func (r *CachedTemplateRepository) Rollback(ctx context.Context, orgID, templateID int64, version int, author string) (domain.NotificationTemplate, error) {
+	saved, err := r.Versions.Rollback(ctx, orgID, templateID, version, author)
+	r.Invalidate()
+	return saved, err
+}
+
+func (r *CachedTemplateRepository) Sources(ctx context.Context, orgID int64, text string) ([]domain.TemplateSource, error) {
+	return r.Versions.Sources(ctx, orgID, text)
+}
+
+// evictExpired drops entries past their TTL. Callers hold r.mu.
+func (r *CachedTemplateRepository) evictExpired(now time.Time) {
+	for key, entry := range r.entries {
+		if !now.Before(entry.expires) {
+			delete(r.entries, key)
+		}
+	}
+}
+
+// Invalidate drops every cached lookup. A global default change affects all
+// orgs, so a full flush is simpler than tracking dependents.
+func (r *CachedTemplateRepository) Invalidate() {
+	r.mu.Lock()
+	r.entries = map[templateCacheKey]templateCacheEntry{}
+	r.generation++
+	r.mu.Unlock()
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"myesi-notification-service/internal/domain"
+)
+
+type countingTemplates struct {
+	finds  int
+	tpl    *domain.NotificationTemplate
+	onFind func()
+}
+
+func (c *countingTemplates) List(ctx context.Context, orgID int64, limit, offset int) ([]domain.NotificationTemplate, error) {
+	return nil, nil
+}
+func (c *countingTemplates) Upsert(ctx context.Context, tpl domain.NotificationTemplate) (domain.NotificationTemplate, error) {
+	c.tpl = &tpl
+	return tpl, nil
+}
+func (c *countingTemplates) FindByEventAndChannel(ctx context.Context, orgID int64, eventType, channel, locale string) (*domain.NotificationTemplate, error) {
+	c.finds++
+	tpl := c.tpl
+	if c.onFind != nil {
+		c.onFind()
+	}
+	return tpl, nil
+}
+func (c *countingTemplates) Get(ctx context.Context, orgID, id int64) (*domain.NotificationTemplate, error) {
+	return nil, nil
+}
+
+func TestCachedTemplateRepository_CachesHitsAndMisses(t *testing.T) {
+	inner := &countingTemplates{}
+	repo := NewCachedTemplateRepository(inner, nil, time.Minute)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		tpl, err := repo.FindByEventAndChannel(ctx, 1, "x", "email", "en")
+		if err != nil || tpl != nil {
+			t.Fatalf("expected cached miss, got %#v %v", tpl, err)
+		}
+	}
+	if inner.finds != 1 {
+		t.Fatalf("expected 1 inner lookup got %d", inner.finds)
+	}
+
+	// A different org is a different key.
+	_, _ = repo.FindByEventAndChannel(ctx, 2, "x", "email", "en")
+	if inner.finds != 2 {
+		t.Fatalf("expected 2 inner lookups got %d", inner.finds)
+	}
+}
+
+func TestCachedTemplateRepository_UpsertInvalidates(t *testing.T) {
+	inner := &countingTemplates{}
+	repo := NewCachedTemplateRepository(inner, nil, time.Minute)
+	ctx := context.Background()
+
+	_, _ = repo.FindByEventAndChannel(ctx, 1, "x", "email", "en")
+	_, _ = repo.Upsert(ctx, domain.NotificationTemplate{EventType: "x", Channel: "email", Subject: "new"})
+
+	tpl, _ := repo.FindByEventAndChannel(ctx, 1, "x", "email", "en")
+	if tpl == nil || tpl.Subject != "new" {
+		t.Fatalf("expected fresh template after upsert, got %#v", tpl)
+	}
+	if inner.finds != 2 {
+		t.Fatalf("expected lookup after invalidation, got %d", inner.finds)
+	}
+}
+
+func TestCachedTemplateRepository_LookupRacingInvalidationIsNotStored(t *testing.T) {
+	inner := &countingTemplates{tpl: &domain.NotificationTemplate{Subject: "old"}}
+	repo := NewCachedTemplateRepository(inner, nil, time.Minute)
+	ctx := context.Background()
+
+	// The upsert lands while the lookup is reading the old row.
+	inner.onFind = func() {
+		inner.onFind = nil
+		_, _ = repo.Upsert(ctx, domain.NotificationTemplate{Subject: "new"})
+	}
+	if tpl, _ := repo.FindByEventAndChannel(ctx, 1, "x", "email", "en"); tpl == nil || tpl.Subject != "old" {
+		t.Fatalf("expected the in-flight lookup to return the old row, got %#v", tpl)
+	}
+	if tpl, _ := repo.FindByEventAndChannel(ctx, 1, "x", "email", "en"); tpl == nil || tpl.Subject != "new" {
+		t.Fatalf("expected the stale lookup not to be cached, got %#v", tpl)
+	}
+}
+
+func TestCachedTemplateRepository_Expires(t *testing.T) {
+	inner := &countingTemplates{}
+	repo := NewCachedTemplateRepository(inner, nil, time.Nanosecond)
+	ctx := context.Background()
+
+	_, _ = repo.FindByEventAndChannel(ctx, 1, "x", "email", "en")
+	time.Sleep(time.Millisecond)
+	_, _ = repo.FindByEventAndChannel(ctx, 1, "x", "email", "en")
+	if inner.finds != 2 {
+		t.Fatalf("expected expired entry to be refetched, got %d lookups", inner.finds)
+	}
+}
+
+func TestCachedTemplateRepository_BoundedAndSweepsExpired(t *testing.T) {
+	inner := &countingTemplates{}
+	repo := NewCachedTemplateRepository(inner, nil, time.Minute)
+	ctx := context.Background()
+
+	past := time.Now().Add(-time.Second)
+	for i := 0; i < templateCacheMaxEntries-1; i++ {
+		repo.entries[templateCacheKey{orgID: int64(i), eventType: "old"}] = templateCacheEntry{expires: past}
+	}
+	repo.entries[templateCacheKey{orgID: 1, eventType: "live"}] = templateCacheEntry{expires: time.Now().Add(time.Minute)}
+
+	_, _ = repo.FindByEventAndChannel(ctx, 1, "x", "email", "en")
+	if len(repo.entries) != 2 {
+		t.Fatalf("expected expired entries swept, got %d entries", len(repo.entries))
+	}
+
+	for i := 0; i < templateCacheMaxEntries; i++ {
+		_, _ = repo.FindByEventAndChannel(ctx, int64(i), "y", "email", "en")
+	}
+	if len(repo.entries) > templateCacheMaxEntries {
+		t.Fatalf("cache grew past its bound: %d entries", len(repo.entries))
+	}
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
 	"myesi-notification-service/internal/domain"
 )
@@ -12,15 +13,18 @@ type TemplateRepositoryPG struct {
 	DB *sql.DB
 }
 
-func (r *TemplateRepositoryPG) List(ctx context.Context, limit, offset int) ([]domain.NotificationTemplate, error) {
+const templateColumns = `id, organization_id, name, event_type, channel, locale, format, subject, body, is_default, version, created_at, updated_at`
+
+func (r *TemplateRepositoryPG) List(ctx context.Context, orgID int64, limit, offset int) ([]domain.NotificationTemplate, error) {
 	if limit == 0 {
 		limit = 50
 	}
 	rows, err := r.DB.QueryContext(ctx, `
-        SELECT id, name, event_type, channel, subject, body, is_default, created_at, updated_at
+        SELECT `+templateColumns+`
         FROM notification_templates
+        WHERE ($1 = 0 AND organization_id IS NULL) OR organization_id = $1
         ORDER BY updated_at DESC
-        LIMIT $1 OFFSET $2`, limit, offset)
+        LIMIT $2 OFFSET $3`, orgID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -28,8 +32,8 @@ func (r *TemplateRepositoryPG) List(ctx context.Context, limit, offset int) ([]d
 
 	templates := make([]domain.NotificationTemplate, 0)
 	for rows.Next() {
-		var t domain.NotificationTemplate
-		if err := rows.Scan(&t.ID, &t.Name, &t.EventType, &t.Channel, &t.Subject, &t.Body, &t.IsDefault, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		t, err := scanTemplate(rows)
+		if err != nil {
 			return nil, err
 		}
 		templates = append(templates, t)
@@ -37,31 +41,64 @@ func (r *TemplateRepositoryPG) List(ctx context.Context, limit, offset int) ([]d
 	return templates, nil
 }
 
+// Upsert creates or replaces the template for (organization, event_type, channel, locale)
+// and records the new content as an immutable version in the same transaction.
+// Global defaults use a NULL organization_id, so the conflict target coalesces it.
 func (r *TemplateRepositoryPG) Upsert(ctx context.Context, tpl domain.NotificationTemplate) (domain.NotificationTemplate, error) {
-	row := r.DB.QueryRowContext(ctx, `
-        INSERT INTO notification_templates (name, event_type, channel, subject, body, is_default)
-        VALUES ($1,$2,$3,$4,$5,$6)
-        ON CONFLICT (event_type, channel)
-        DO UPDATE SET name=EXCLUDED.name, subject=EXCLUDED.subject, body=EXCLUDED.body, is_default=EXCLUDED.is_default, updated_at=NOW()
-        RETURNING id, name, event_type, channel, subject, body, is_default, created_at, updated_at
-    `, tpl.Name, tpl.EventType, tpl.Channel, tpl.Subject, tpl.Body, tpl.IsDefault)
-
-	var saved domain.NotificationTemplate
-	err := row.Scan(&saved.ID, &saved.Name, &saved.EventType, &saved.Channel, &saved.Subject, &saved.Body, &saved.IsDefault, &saved.CreatedAt, &saved.UpdatedAt)
-	return saved, err
+	var orgID interface{}
+	if tpl.OrganizationID != nil && *tpl.OrganizationID > 0 {
+		orgID = *tpl.OrganizationID
+	}
+	locale := domain.NormalizeLocale(tpl.Locale)
+	if locale == "" {
+		locale = domain.DefaultLocale
+	}
+	format := tpl.Format
+	if format == "" {
+		format = domain.FormatText
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.NotificationTemplate{}, err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(ctx, `
+        INSERT INTO notification_templates (organization_id, name, event_type, channel, locale, format, subject, body, is_default, version)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,1)
+        ON CONFLICT ((COALESCE(organization_id, 0)), event_type, channel, locale)
+        DO UPDATE SET name=EXCLUDED.name, format=EXCLUDED.format, subject=EXCLUDED.subject, body=EXCLUDED.body, is_default=EXCLUDED.is_default,
+                      version=notification_templates.version + 1, updated_at=NOW()
+        RETURNING `+templateColumns+`
+    `, orgID, tpl.Name, tpl.EventType, tpl.Channel, locale, format, tpl.Subject, tpl.Body, tpl.IsDefault)
+
+	saved, err := scanTemplate(row)
+	if err != nil {
+		return saved, err
+	}
+	saved.Author = tpl.Author
+	saved.ChangeNote = tpl.ChangeNote
+
+	if err := insertTemplateVersion(ctx, tx, saved); err != nil {
+		return saved, err
+	}
+	return saved, tx.Commit()
 }
 
-func (r *TemplateRepositoryPG) FindByEventAndChannel(ctx context.Context, eventType, channel string) (*domain.NotificationTemplate, error) {
+// FindByEventAndChannel resolves the org override first and falls back to the global default.
+// The locale must match exactly; callers walk the fallback chain themselves.
+func (r *TemplateRepositoryPG) FindByEventAndChannel(ctx context.Context, orgID int64, eventType, channel, locale string) (*domain.NotificationTemplate, error) {
 	row := r.DB.QueryRowContext(ctx, `
-        SELECT id, name, event_type, channel, subject, body, is_default, created_at, updated_at
+        SELECT `+templateColumns+`
         FROM notification_templates
-        WHERE event_type=$1 AND channel=$2
-        ORDER BY is_default DESC, updated_at DESC
+        WHERE event_type=$1 AND channel=$2 AND locale=$4 AND (organization_id IS NULL OR organization_id=$3)
+        ORDER BY organization_id NULLS LAST, is_default DESC, updated_at DESC
         LIMIT 1
-    `, eventType, channel)
+    `, eventType, channel, orgID, locale)
 
-	var tpl domain.NotificationTemplate
-	if err := row.Scan(&tpl.ID, &tpl.Name, &tpl.EventType, &tpl.Channel, &tpl.Subject, &tpl.Body, &tpl.IsDefault, &tpl.CreatedAt, &tpl.UpdatedAt); err != nil {
+	tpl, err := scanTemplate(row)
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -69,3 +106,169 @@ func (r *TemplateRepositoryPG) FindByEventAndChannel(ctx context.Context, eventT
 	}
 	return &tpl, nil
 }
+
+// Get returns a template visible to orgID: its own overrides and the global
+// defaults (globals only when orgID is 0). It returns nil when there is none.
+func (r *TemplateRepositoryPG) Get(ctx context.Context, orgID, id int64) (*domain.NotificationTemplate, error) {
+	row := r.DB.QueryRowContext(ctx, `
+        SELECT `+templateColumns+`
+        FROM notification_templates
+        WHERE id=$1 AND (organization_id IS NULL OR organization_id=$2)
+    `, id, orgID)
+
+	tpl, err := scanTemplate(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+const templateVersionColumns = `v.id, v.template_id, v.version, v.name, v.format, v.subject, v.body, v.is_default, v.author, v.change_note, v.created_at`
+
+// templateScope restricts version queries to templates owned by orgID (0 = global).
+const templateScope = `COALESCE(t.organization_id, 0) = $1`
+
+// ListVersions returns the history of a template, newest first.
+func (r *TemplateRepositoryPG) ListVersions(ctx context.Context, orgID, templateID int64) ([]domain.TemplateVersion, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        SELECT `+templateVersionColumns+`
+        FROM notification_template_versions v
+        JOIN notification_templates t ON t.id = v.template_id
+        WHERE `+templateScope+` AND v.template_id = $2
+        ORDER BY v.version DESC`, orgID, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make([]domain.TemplateVersion, 0)
+	for rows.Next() {
+		v, err := scanTemplateVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// GetVersion returns a single version or nil when it does not exist in the caller's scope.
+func (r *TemplateRepositoryPG) GetVersion(ctx context.Context, orgID, templateID int64, version int) (*domain.TemplateVersion, error) {
+	row := r.DB.QueryRowContext(ctx, `
+        SELECT `+templateVersionColumns+`
+        FROM notification_template_versions v
+        JOIN notification_templates t ON t.id = v.template_id
+        WHERE `+templateScope+` AND v.template_id = $2 AND v.version = $3`, orgID, templateID, version)
+
+	v, err := scanTemplateVersion(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Rollback promotes an earlier version to active. History stays append-only:
+// the restored content is written as a new version rather than rewinding the counter.
+func (r *TemplateRepositoryPG) Rollback(ctx context.Context, orgID, templateID int64, version int, author string) (domain.NotificationTemplate, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.NotificationTemplate{}, err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(ctx, `
+        UPDATE notification_templates t
+        SET name=v.name, format=v.format, subject=v.subject, body=v.body, is_default=v.is_default,
+            version=t.version + 1, updated_at=NOW()
+        FROM notification_template_versions v
+        WHERE v.template_id = t.id AND `+templateScope+` AND t.id = $2 AND v.version = $3
+        RETURNING t.id, t.organization_id, t.name, t.event_type, t.channel, t.locale, t.format, t.subject, t.body, t.is_default, t.version, t.created_at, t.updated_at
+    `, orgID, templateID, version)
+
+	saved, err := scanTemplate(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return saved, domain.ErrNotFound
+		}
+		return saved, err
+	}
+	saved.Author = author
+	saved.ChangeNote = fmt.Sprintf("rollback to version %d", version)
+
+	if err := insertTemplateVersion(ctx, tx, saved); err != nil {
+		return saved, err
+	}
+	return saved, tx.Commit()
+}
+
+// Sources returns the templates and versions whose subject or body contains
+// text. A template's current content is normally also its latest version, so
+// identical rows are merged.
+func (r *TemplateRepositoryPG) Sources(ctx context.Context, orgID int64, text string) ([]domain.TemplateSource, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        SELECT t.id, t.organization_id, t.channel, t.version, t.subject, t.body
+        FROM notification_templates t
+        WHERE ($1 = 0 OR t.organization_id IS NULL OR t.organization_id = $1)
+            AND (strpos(t.subject, $2) > 0 OR strpos(t.body, $2) > 0)
+        UNION
+        SELECT t.id, t.organization_id, t.channel, v.version, v.subject, v.body
+        FROM notification_template_versions v
+        JOIN notification_templates t ON t.id = v.template_id
+        WHERE ($1 = 0 OR t.organization_id IS NULL OR t.organization_id = $1)
+            AND (strpos(v.subject, $2) > 0 OR strpos(v.body, $2) > 0)
+        ORDER BY 1, 4`, orgID, text)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.TemplateSource, 0)
+	for rows.Next() {
+		var src domain.TemplateSource
+		var org sql.NullInt64
+		if err := rows.Scan(&src.TemplateID, &org, &src.Channel, &src.Version, &src.Subject, &src.Body); err != nil {
+			return nil, err
+		}
+		if org.Valid {
+			val := org.Int64
+			src.OrganizationID = &val
+		}
+		out = append(out, src)
+	}
+	return out, rows.Err()
+}
+
+func insertTemplateVersion(ctx context.Context, tx *sql.Tx, tpl domain.NotificationTemplate) error {
+	_, err := tx.ExecContext(ctx, `
+        INSERT INTO notification_template_versions (template_id, version, name, format, subject, body, is_default, author, change_note)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+    `, tpl.ID, tpl.Version, tpl.Name, tpl.Format, tpl.Subject, tpl.Body, tpl.IsDefault, tpl.Author, tpl.ChangeNote)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTemplate(row rowScanner) (domain.NotificationTemplate, error) {
+	var t domain.NotificationTemplate
+	var org sql.NullInt64
+	err := row.Scan(&t.ID, &org, &t.Name, &t.EventType, &t.Channel, &t.Locale, &t.Format, &t.Subject, &t.Body, &t.IsDefault, &t.Version, &t.CreatedAt, &t.UpdatedAt)
+	if org.Valid {
+		val := org.Int64
+		t.OrganizationID = &val
+	}
+	return t, err
+}
+
+func scanTemplateVersion(row rowScanner) (domain.TemplateVersion, error) {
+	var v domain.TemplateVersion
+	err := row.Scan(&v.ID, &v.TemplateID, &v.Version, &v.Name, &v.Format, &v.Subject, &v.Body, &v.IsDefault, &v.Author, &v.ChangeNote, &v.CreatedAt)
+	return v, err
+}
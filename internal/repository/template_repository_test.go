@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -10,6 +11,14 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 )
 
+var templateCols = []string{
+	"id", "organization_id", "name", "event_type", "channel", "locale", "format", "subject", "body", "is_default", "version", "created_at", "updated_at",
+}
+
+var templateVersionCols = []string{
+	"id", "template_id", "version", "name", "format", "subject", "body", "is_default", "author", "change_note", "created_at",
+}
+
 func TestTemplateRepositoryPG_List(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -20,15 +29,14 @@ func TestTemplateRepositoryPG_List(t *testing.T) {
 	repo := &TemplateRepositoryPG{DB: db}
 
 	now := time.Now()
-	rows := sqlmock.NewRows([]string{
-		"id", "name", "event_type", "channel", "subject", "body", "is_default", "created_at", "updated_at",
-	}).AddRow(int64(1), "tpl", "payment.success", "email", "sub", "body", true, now, now)
+	rows := sqlmock.NewRows(templateCols).
+		AddRow(int64(1), nil, "tpl", "payment.success", "email", "en", "text", "sub", "body", true, 1, now, now)
 
-	mock.ExpectQuery("SELECT id, name, event_type, channel, subject, body, is_default, created_at, updated_at").
-		WithArgs(50, 0).
+	mock.ExpectQuery("SELECT id, organization_id, name, event_type, channel, locale, format, subject, body, is_default, version, created_at, updated_at").
+		WithArgs(int64(0), 50, 0).
 		WillReturnRows(rows)
 
-	res, err := repo.List(context.Background(), 0, 0)
+	res, err := repo.List(context.Background(), 0, 0, 0)
 	if err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
@@ -47,23 +55,229 @@ func TestTemplateRepositoryPG_Upsert(t *testing.T) {
 	repo := &TemplateRepositoryPG{DB: db}
 	now := time.Now()
 
+	mock.ExpectBegin()
 	mock.ExpectQuery("INSERT INTO notification_templates").
-		WithArgs("n", "e", "email", "s", "b", true).
-		WillReturnRows(sqlmock.NewRows([]string{
-			"id", "name", "event_type", "channel", "subject", "body", "is_default", "created_at", "updated_at",
-		}).AddRow(int64(7), "n", "e", "email", "s", "b", true, now, now))
+		WithArgs(nil, "n", "e", "email", "en", "text", "s", "b", true).
+		WillReturnRows(sqlmock.NewRows(templateCols).AddRow(int64(7), nil, "n", "e", "email", "en", "text", "s", "b", true, 2, now, now))
+	mock.ExpectExec("INSERT INTO notification_template_versions").
+		WithArgs(int64(7), 2, "n", "text", "s", "b", true, "alice@x.com", "tweak wording").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	out, err := repo.Upsert(context.Background(), domain.NotificationTemplate{
 		Name: "n", EventType: "e", Channel: "email", Subject: "s", Body: "b", IsDefault: true,
+		Author: "alice@x.com", ChangeNote: "tweak wording",
 	})
 	if err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
-	if out.ID != 7 {
-		t.Fatalf("expected id 7 got %d", out.ID)
+	if out.ID != 7 || out.Version != 2 {
+		t.Fatalf("expected id 7 version 2 got %d/%d", out.ID, out.Version)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestTemplateRepositoryPG_Upsert_OrgOverride(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &TemplateRepositoryPG{DB: db}
+	now := time.Now()
+	org := int64(5)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("ON CONFLICT \\(\\(COALESCE\\(organization_id, 0\\)\\), event_type, channel, locale\\)").
+		WithArgs(int64(5), "n", "e", "email", "en", "text", "s", "b", false).
+		WillReturnRows(sqlmock.NewRows(templateCols).AddRow(int64(8), int64(5), "n", "e", "email", "en", "text", "s", "b", false, 1, now, now))
+	mock.ExpectExec("INSERT INTO notification_template_versions").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	out, err := repo.Upsert(context.Background(), domain.NotificationTemplate{
+		OrganizationID: &org, Name: "n", EventType: "e", Channel: "email", Subject: "s", Body: "b",
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if out.OrganizationID == nil || *out.OrganizationID != 5 {
+		t.Fatalf("expected org override, got %#v", out.OrganizationID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestTemplateRepositoryPG_FindByEventAndChannel_PrefersOrg(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &TemplateRepositoryPG{DB: db}
+	now := time.Now()
+
+	mock.ExpectQuery("ORDER BY organization_id NULLS LAST").
+		WithArgs("payment.success", "email", int64(5), "en").
+		WillReturnRows(sqlmock.NewRows(templateCols).AddRow(int64(3), int64(5), "n", "payment.success", "email", "en", "text", "s", "b", false, 1, now, now))
+
+	tpl, err := repo.FindByEventAndChannel(context.Background(), 5, "payment.success", "email", "en")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if tpl == nil || tpl.OrganizationID == nil || *tpl.OrganizationID != 5 {
+		t.Fatalf("unexpected tpl: %#v", tpl)
 	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
 
+func TestTemplateRepositoryPG_FindByEventAndChannel_NoRows(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &TemplateRepositoryPG{DB: db}
+	mock.ExpectQuery("FROM notification_templates").
+		WillReturnRows(sqlmock.NewRows(templateCols))
+
+	tpl, err := repo.FindByEventAndChannel(context.Background(), 5, "x", "email", "en")
+	if err != nil || tpl != nil {
+		t.Fatalf("expected nil tpl and err, got %#v %v", tpl, err)
+	}
+}
+
+func TestTemplateRepositoryPG_Get_ScopedToOrg(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &TemplateRepositoryPG{DB: db}
+	now := time.Now()
+
+	mock.ExpectQuery(`organization_id IS NULL OR organization_id=\$2`).
+		WithArgs(int64(3), int64(5)).
+		WillReturnRows(sqlmock.NewRows(templateCols).AddRow(int64(3), nil, "n", "payment.success", "email", "en", "text", "s", "b", true, 1, now, now))
+	mock.ExpectQuery("FROM notification_templates").
+		WithArgs(int64(4), int64(5)).
+		WillReturnRows(sqlmock.NewRows(templateCols))
+
+	tpl, err := repo.Get(context.Background(), 5, 3)
+	if err != nil || tpl == nil || tpl.ID != 3 {
+		t.Fatalf("unexpected tpl: %#v %v", tpl, err)
+	}
+	tpl, err = repo.Get(context.Background(), 5, 4)
+	if err != nil || tpl != nil {
+		t.Fatalf("expected nil tpl and err, got %#v %v", tpl, err)
+	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("unmet expectations: %v", err)
 	}
 }
+
+func TestTemplateRepositoryPG_Upsert_VersionInsertFailsRollsBack(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &TemplateRepositoryPG{DB: db}
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO notification_templates").
+		WillReturnRows(sqlmock.NewRows(templateCols).AddRow(int64(7), nil, "n", "e", "email", "en", "text", "s", "b", true, 2, now, now))
+	mock.ExpectExec("INSERT INTO notification_template_versions").
+		WillReturnError(errors.New("boom"))
+	mock.ExpectRollback()
+
+	if _, err := repo.Upsert(context.Background(), domain.NotificationTemplate{EventType: "e", Channel: "email"}); err == nil {
+		t.Fatalf("expected error")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestTemplateRepositoryPG_ListVersions(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &TemplateRepositoryPG{DB: db}
+	now := time.Now()
+
+	mock.ExpectQuery("FROM notification_template_versions v").
+		WithArgs(int64(5), int64(7)).
+		WillReturnRows(sqlmock.NewRows(templateVersionCols).
+			AddRow(int64(2), int64(7), 2, "n", "text", "s2", "b2", false, "bob", "fix", now).
+			AddRow(int64(1), int64(7), 1, "n", "text", "s1", "b1", false, "alice", "", now))
+
+	out, err := repo.ListVersions(context.Background(), 5, 7)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(out) != 2 || out[0].Version != 2 || out[1].Author != "alice" {
+		t.Fatalf("unexpected versions: %#v", out)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestTemplateRepositoryPG_SourcesIncludeVersions(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &TemplateRepositoryPG{DB: db}
+	mock.ExpectQuery("FROM notification_templates t(.|\n)*UNION(.|\n)*FROM notification_template_versions v").
+		WithArgs(int64(5), `"footer"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "channel", "version", "subject", "body"}).
+			AddRow(int64(7), int64(5), "email", 1, "s", `{{template "footer" .}}`).
+			AddRow(int64(8), nil, "", 3, "s", `{{template "footer" .}}`))
+
+	out, err := repo.Sources(context.Background(), 5, `"footer"`)
+	if err != nil || len(out) != 2 || out[0].OrganizationID == nil || out[1].OrganizationID != nil || out[1].Version != 3 {
+		t.Fatalf("unexpected sources %#v %v", out, err)
+	}
+}
+
+func TestTemplateRepositoryPG_Rollback(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &TemplateRepositoryPG{DB: db}
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE notification_templates t").
+		WithArgs(int64(0), int64(7), 1).
+		WillReturnRows(sqlmock.NewRows(templateCols).AddRow(int64(7), nil, "n", "e", "email", "en", "text", "s1", "b1", false, 3, now, now))
+	mock.ExpectExec("INSERT INTO notification_template_versions").
+		WithArgs(int64(7), 3, "n", "text", "s1", "b1", false, "bob", "rollback to version 1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	out, err := repo.Rollback(context.Background(), 0, 7, 1, "bob")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if out.Version != 3 || out.Subject != "s1" {
+		t.Fatalf("unexpected rollback result: %#v", out)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestTemplateRepositoryPG_Rollback_NotFound(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &TemplateRepositoryPG{DB: db}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE notification_templates t").
+		WillReturnRows(sqlmock.NewRows(templateCols))
+	mock.ExpectRollback()
+
+	if _, err := repo.Rollback(context.Background(), 5, 7, 9, "bob"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
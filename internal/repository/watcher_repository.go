@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"myesi-notification-service/internal/domain"
+)
+
+// WatcherRepositoryPG stores project watches in PostgreSQL. Owners of a
+// project (projects.owner_id) watch it without a row; an unwatch row with
+// watching=false opts them out.
+type WatcherRepositoryPG struct {
+	DB *sql.DB
+}
+
+// Watch records that the user watches or stops watching an org project.
+func (r *WatcherRepositoryPG) Watch(ctx context.Context, orgID, projectID, userID int64, watching bool) error {
+	res, err := r.DB.ExecContext(ctx, `
+        INSERT INTO notification_project_watchers (organization_id, project_id, user_id, watching)
+        SELECT $1, p.id, $3, $4 FROM projects p
+        WHERE p.id=$2 AND p.organization_id=$1
+        ON CONFLICT (project_id, user_id)
+        DO UPDATE SET watching=EXCLUDED.watching, updated_at=NOW()`, orgID, projectID, userID, watching)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// ListForUser returns the projects the user watches, owned ones included.
+func (r *WatcherRepositoryPG) ListForUser(ctx context.Context, orgID, userID int64) ([]domain.ProjectWatch, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        SELECT p.id, p.name, COALESCE(p.owner_id = $2, false), COALESCE(w.updated_at, p.created_at)
+        FROM projects p
+        LEFT JOIN notification_project_watchers w ON w.project_id = p.id AND w.user_id = $2
+        WHERE p.organization_id=$1
+          AND (w.watching OR (w.watching IS NULL AND p.owner_id = $2))
+        ORDER BY p.name`, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.ProjectWatch, 0)
+	for rows.Next() {
+		w := domain.ProjectWatch{OrganizationID: orgID, UserID: userID}
+		if err := rows.Scan(&w.ProjectID, &w.ProjectName, &w.Owner, &w.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+// Watchers lists the active users watching a project identified by id, or
+// by name when projectID is 0.
+func (r *WatcherRepositoryPG) Watchers(ctx context.Context, orgID, projectID int64, project string) ([]int64, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+        WITH p AS (
+            SELECT id, owner_id FROM projects
+            WHERE organization_id=$1 AND (id=$2 OR ($2 = 0 AND name=$3))
+        ), w AS (
+            SELECT w.user_id FROM notification_project_watchers w JOIN p ON p.id = w.project_id
+            WHERE w.watching
+            UNION
+            SELECT p.owner_id FROM p
+            WHERE p.owner_id IS NOT NULL AND NOT EXISTS (
+                SELECT 1 FROM notification_project_watchers x
+                WHERE x.project_id = p.id AND x.user_id = p.owner_id AND NOT x.watching)
+        )
+        SELECT u.id FROM users u JOIN w ON w.user_id = u.id
+        WHERE u.organization_id=$1 AND u.is_active=true
+        ORDER BY u.id`, orgID, projectID, project)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"myesi-notification-service/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestWatcherRepositoryPG_WatchUnknownProject(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &WatcherRepositoryPG{DB: db}
+	mock.ExpectExec("INSERT INTO notification_project_watchers(.|\n)*FROM projects p(.|\n)*p.organization_id=\\$1").
+		WithArgs(int64(1), int64(4), int64(7), false).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := repo.Watch(context.Background(), 1, 4, 7, false); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestWatcherRepositoryPG_WatchersIncludeOwners(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	repo := &WatcherRepositoryPG{DB: db}
+	mock.ExpectQuery("name=\\$3(.|\n)*SELECT p.owner_id FROM p(.|\n)*NOT x.watching").
+		WithArgs(int64(1), int64(0), "core-api").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(3)).AddRow(int64(8)))
+
+	ids, err := repo.Watchers(context.Background(), 1, 0, "core-api")
+	if err != nil || len(ids) != 2 || ids[1] != 8 {
+		t.Fatalf("unexpected watchers %v %v", ids, err)
+	}
+}
@@ -0,0 +1,365 @@
+// Package rules evaluates routing conditions such as
+// `payload.project == "core-api" && payload.critical_count > 3` against
+// event data shaped like the template data ({{.event.*}}, {{.payload.*}}).
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a compiled condition. The zero value and an empty source always
+// match.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile parses a condition. Supported are dotted field paths, string,
+// number, true/false/null literals, == != < <= > >=, &&, ||, ! and
+// parentheses.
+func Compile(src string) (*Expr, error) {
+	e := &Expr{src: src}
+	if strings.TrimSpace(src) == "" {
+		return e, nil
+	}
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", p.peek().text, p.peek().pos)
+	}
+	e.root = root
+	return e, nil
+}
+
+// String returns the source the expression was compiled from.
+func (e *Expr) String() string { return e.src }
+
+// Match evaluates the condition against env. Missing fields are null;
+// ordering comparisons between values of different types are false.
+func (e *Expr) Match(env map[string]interface{}) bool {
+	if e == nil || e.root == nil {
+		return true
+	}
+	return truthy(e.root.eval(env))
+}
+
+type node interface {
+	eval(env map[string]interface{}) interface{}
+}
+
+type literal struct{ v interface{} }
+
+func (n literal) eval(map[string]interface{}) interface{} { return n.v }
+
+type field struct{ path []string }
+
+func (n field) eval(env map[string]interface{}) interface{} {
+	var cur interface{} = env
+	for _, part := range n.path {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return normalize(cur)
+}
+
+type not struct{ x node }
+
+func (n not) eval(env map[string]interface{}) interface{} { return !truthy(n.x.eval(env)) }
+
+type logical struct {
+	and  bool
+	l, r node
+}
+
+func (n logical) eval(env map[string]interface{}) interface{} {
+	if truthy(n.l.eval(env)) != n.and {
+		return !n.and
+	}
+	return truthy(n.r.eval(env))
+}
+
+type compare struct {
+	op   string
+	l, r node
+}
+
+func (n compare) eval(env map[string]interface{}) interface{} {
+	l, r := n.l.eval(env), n.r.eval(env)
+	switch n.op {
+	case "==":
+		return equal(l, r)
+	case "!=":
+		return !equal(l, r)
+	}
+	c, ok := order(l, r)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// normalize folds the numeric types payloads may carry into float64.
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int:
+		return float64(x)
+	case int32:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case *int64:
+		if x == nil {
+			return nil
+		}
+		return float64(*x)
+	case float32:
+		return float64(x)
+	}
+	return v
+}
+
+func equal(l, r interface{}) bool {
+	if c, ok := order(l, r); ok {
+		return c == 0
+	}
+	if lb, ok := l.(bool); ok {
+		rb, ok := r.(bool)
+		return ok && lb == rb
+	}
+	return l == nil && r == nil
+}
+
+func order(l, r interface{}) (int, bool) {
+	switch lv := l.(type) {
+	case float64:
+		rv, ok := r.(float64)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case lv < rv:
+			return -1, true
+		case lv > rv:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		rv, ok := r.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(lv, rv), true
+	}
+	return 0, false
+}
+
+func truthy(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	case string:
+		return x != ""
+	}
+	return true
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+func lex(src string) ([]token, error) {
+	var out []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			var b strings.Builder
+			for ; j < len(src) && rune(src[j]) != c; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+				}
+				b.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			out = append(out, token{tokString, b.String(), i})
+			i = j + 1
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1]))):
+			j := i + 1
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+				j++
+			}
+			out = append(out, token{tokNumber, src[i:j], i})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(src) && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])) || src[j] == '_' || src[j] == '.') {
+				j++
+			}
+			out = append(out, token{tokIdent, src[i:j], i})
+			i = j
+		default:
+			op := ""
+			for _, cand := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")"} {
+				if strings.HasPrefix(src[i:], cand) {
+					op = cand
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			out = append(out, token{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(out, token{tokEOF, "end of expression", len(src)}), nil
+}
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokOp && t.text == op {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) or() (node, error) {
+	l, err := p.and()
+	for err == nil && p.accept("||") {
+		var r node
+		if r, err = p.and(); err == nil {
+			l = logical{and: false, l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *parser) and() (node, error) {
+	l, err := p.unary()
+	for err == nil && p.accept("&&") {
+		var r node
+		if r, err = p.unary(); err == nil {
+			l = logical{and: true, l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+func (p *parser) unary() (node, error) {
+	if p.accept("!") {
+		x, err := p.unary()
+		return not{x}, err
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	l, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			r, err := p.primary()
+			if err != nil {
+				return nil, err
+			}
+			return compare{op: op, l: l, r: r}, nil
+		}
+	}
+	return l, nil
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return literal{t.text}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at %d", t.text, t.pos)
+		}
+		return literal{f}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		case "null":
+			return literal{nil}, nil
+		}
+		return field{strings.Split(t.text, ".")}, nil
+	case tokOp:
+		if t.text == "(" {
+			x, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			if !p.accept(")") {
+				return nil, fmt.Errorf("missing ) at %d", p.peek().pos)
+			}
+			return x, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
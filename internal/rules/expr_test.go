@@ -0,0 +1,52 @@
+package rules
+
+import "testing"
+
+func TestExprMatch(t *testing.T) {
+	env := map[string]interface{}{
+		"event": map[string]interface{}{"type": "vulnerability.critical", "severity": "critical"},
+		"payload": map[string]interface{}{
+			"project":        "core-api",
+			"critical_count": 5,
+			"fixed":          false,
+			"sbom":           map[string]interface{}{"score": 7.5},
+		},
+	}
+	cases := map[string]bool{
+		``: true,
+		`payload.project == "core-api" && payload.critical_count > 3`: true,
+		`payload.project == 'web' || payload.critical_count >= 5`:     true,
+		`payload.critical_count < 5`:                                  false,
+		`payload.sbom.score <= 7.5 && !payload.fixed`:                 true,
+		`!(event.severity == "critical")`:                             false,
+		`payload.missing == null`:                                     true,
+		`payload.missing != null`:                                     false,
+		`payload.missing`:                                             false,
+		`payload.project > 3`:                                         false,
+		`payload.fixed == false && payload.project != "web"`:          true,
+		`event.type == "vulnerability.critical" && payload.critical_count == -1 || true`: true,
+	}
+	for src, want := range cases {
+		e, err := Compile(src)
+		if err != nil {
+			t.Fatalf("%q: compile: %v", src, err)
+		}
+		if got := e.Match(env); got != want {
+			t.Fatalf("%q: got %v want %v", src, got, want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, src := range []string{
+		`payload.project ==`,
+		`(payload.a == 1`,
+		`payload.a = 1`,
+		`"unterminated`,
+		`payload.a == 1 payload.b`,
+	} {
+		if _, err := Compile(src); err == nil {
+			t.Fatalf("%q: expected error", src)
+		}
+	}
+}
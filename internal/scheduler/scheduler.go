@@ -0,0 +1,31 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Job is periodic work; now is the time of the tick that triggered it.
+type Job func(ctx context.Context, now time.Time) error
+
+// Start runs job every interval in the background until ctx is cancelled.
+// Errors are logged and the next tick runs as usual.
+func Start(ctx context.Context, name string, interval time.Duration, job Job) {
+	log.Printf("[SCHEDULER] %s running every %s", name, interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := job(ctx, now.UTC()); err != nil && ctx.Err() == nil {
+					log.Printf("[SCHEDULER] %s failed: %v", name, err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,38 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStart_RunsUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ticks := make(chan time.Time, 10)
+	Start(ctx, "test", 5*time.Millisecond, func(ctx context.Context, now time.Time) error {
+		ticks <- now
+		return errors.New("keep going")
+	})
+
+	for i := 0; i < 2; i++ {
+		select {
+		case now := <-ticks:
+			if now.Location() != time.UTC {
+				t.Fatalf("expected UTC tick, got %v", now.Location())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("job did not run again after an error")
+		}
+	}
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	for len(ticks) > 0 {
+		<-ticks
+	}
+	select {
+	case <-ticks:
+		t.Fatalf("job ran after cancel")
+	case <-time.After(30 * time.Millisecond):
+	}
+}
@@ -0,0 +1,63 @@
+package templates
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"text/template"
+)
+
+// parseCache is a concurrency-safe LRU of parsed templates keyed by a hash of
+// their source, so identical templates share one parse regardless of origin.
+type parseCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[[sha256.Size]byte]*list.Element
+}
+
+type parseCacheEntry struct {
+	key [sha256.Size]byte
+	tpl *template.Template
+}
+
+func newParseCache(size int) *parseCache {
+	return &parseCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[[sha256.Size]byte]*list.Element, size),
+	}
+}
+
+func (c *parseCache) get(key [sha256.Size]byte) (*template.Template, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*parseCacheEntry).tpl, true
+}
+
+func (c *parseCache) add(key [sha256.Size]byte, tpl *template.Template) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		el.Value.(*parseCacheEntry).tpl = tpl
+		return
+	}
+	c.entries[key] = c.order.PushFront(&parseCacheEntry{key: key, tpl: tpl})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*parseCacheEntry).key)
+	}
+}
+
+func (c *parseCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
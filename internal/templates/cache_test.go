@@ -0,0 +1,66 @@
+package templates
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRenderer_CachesParsedTemplates(t *testing.T) {
+	r := NewRenderer(2)
+	data := map[string]interface{}{"name": "MyESI"}
+
+	for i := 0; i < 3; i++ {
+		if out, err := r.Render("Hello {{.name}}", data); err != nil || out != "Hello MyESI" {
+			t.Fatalf("unexpected render: %q %v", out, err)
+		}
+	}
+	if n := r.cache.len(); n != 1 {
+		t.Fatalf("expected 1 cached template got %d", n)
+	}
+
+	_, _ = r.Render("A {{.name}}", data)
+	_, _ = r.Render("B {{.name}}", data)
+	if n := r.cache.len(); n != 2 {
+		t.Fatalf("expected LRU bounded at 2 got %d", n)
+	}
+	if _, ok := r.cache.get(hashOf("Hello {{.name}}")); ok {
+		t.Fatalf("expected least recently used entry to be evicted")
+	}
+}
+
+func TestRenderer_LenientDoesNotMutateCachedTemplate(t *testing.T) {
+	r := NewRenderer(4)
+	data := map[string]interface{}{}
+
+	if out, err := r.RenderLenient("x{{.missing}}", data); err != nil || out != "x" {
+		t.Fatalf("unexpected lenient render: %q %v", out, err)
+	}
+	if _, err := r.Render("x{{.missing}}", data); err == nil {
+		t.Fatalf("expected strict render to still report the missing key")
+	}
+}
+
+func TestRenderer_ConcurrentRender(t *testing.T) {
+	r := NewRenderer(8)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tpl := fmt.Sprintf("n=%d {{.v}}", i%10)
+			if _, err := r.Render(tpl, map[string]interface{}{"v": i}); err != nil {
+				t.Errorf("render: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if n := r.cache.len(); n > 8 {
+		t.Fatalf("cache exceeded bound: %d", n)
+	}
+}
+
+func hashOf(s string) [32]byte {
+	return sha256.Sum256([]byte(s))
+}
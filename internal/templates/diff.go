@@ -0,0 +1,107 @@
+package templates
+
+import "strings"
+
+// Diff operations emitted by Diff.
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// DiffLine is a single line of a line-oriented diff.
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// MaxDiffLines caps how many differing lines per side Diff aligns with its
+// quadratic LCS table. Larger changes are reported as a plain replacement.
+const MaxDiffLines = 2000
+
+// Diff computes a line diff between two template sources. Common leading and
+// trailing lines are matched directly and the rest is aligned with an LCS
+// table, unless either side's remainder exceeds MaxDiffLines; then it is shown
+// as deleted and re-inserted, which is correct but not minimal.
+func Diff(from, to string) []DiffLine {
+	a := splitLines(from)
+	b := splitLines(to)
+	out := make([]DiffLine, 0, len(a)+len(b))
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		out = append(out, DiffLine{Op: DiffEqual, Text: a[prefix]})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	out = append(out, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		out = append(out, DiffLine{Op: DiffEqual, Text: line})
+	}
+	return out
+}
+
+// diffMiddle aligns a and b with an LCS table, or replaces a with b when
+// either is longer than MaxDiffLines.
+func diffMiddle(a, b []string) []DiffLine {
+	out := make([]DiffLine, 0, len(a)+len(b))
+	if len(a) > MaxDiffLines || len(b) > MaxDiffLines {
+		for _, line := range a {
+			out = append(out, DiffLine{Op: DiffDelete, Text: line})
+		}
+		for _, line := range b {
+			out = append(out, DiffLine{Op: DiffInsert, Text: line})
+		}
+		return out
+	}
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			out = append(out, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, DiffLine{Op: DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		out = append(out, DiffLine{Op: DiffInsert, Text: b[j]})
+	}
+	return out
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,52 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff_InsertAndDelete(t *testing.T) {
+	out := Diff("hello\nold line\nbye", "hello\nnew line\nbye")
+
+	want := []DiffLine{
+		{Op: DiffEqual, Text: "hello"},
+		{Op: DiffDelete, Text: "old line"},
+		{Op: DiffInsert, Text: "new line"},
+		{Op: DiffEqual, Text: "bye"},
+	}
+	if len(out) != len(want) {
+		t.Fatalf("expected %d lines got %d: %#v", len(want), len(out), out)
+	}
+	for i := range want {
+		if out[i] != want[i] {
+			t.Fatalf("line %d: expected %#v got %#v", i, want[i], out[i])
+		}
+	}
+}
+
+func TestDiff_EmptySides(t *testing.T) {
+	if out := Diff("", "a\nb"); len(out) != 2 || out[0].Op != DiffInsert {
+		t.Fatalf("expected two inserts, got %#v", out)
+	}
+	if out := Diff("a", ""); len(out) != 1 || out[0].Op != DiffDelete {
+		t.Fatalf("expected one delete, got %#v", out)
+	}
+}
+
+func TestDiff_LargeBodiesAreBounded(t *testing.T) {
+	n := MaxDiffLines + 1
+	from := strings.Repeat("a\n", n) + "head\n" + strings.Repeat("x\n", n) + "tail"
+	to := strings.Repeat("a\n", n) + "head\n" + strings.Repeat("y\n", n) + "tail"
+
+	out := Diff(from, to)
+	counts := map[string]int{}
+	for _, l := range out {
+		counts[l.Op]++
+	}
+	if counts[DiffEqual] != n+2 || counts[DiffDelete] != n || counts[DiffInsert] != n {
+		t.Fatalf("unexpected op counts %v", counts)
+	}
+	if out[len(out)-1] != (DiffLine{Op: DiffEqual, Text: "tail"}) {
+		t.Fatalf("expected the common suffix last, got %#v", out[len(out)-1])
+	}
+}
@@ -0,0 +1,264 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// funcMap is the helper library available to every notification template.
+// Helpers take the piped value as their last argument, e.g.
+// {{.event.occurred_at | date "2006-01-02 15:04" "Asia/Ho_Chi_Minh"}}.
+//
+// Rendering is strict about missing map keys, so optional fields should be read
+// with index or dig before piping into default:
+// {{dig "metrics" "average_risk_score" .payload | default 0}}.
+var funcMap = template.FuncMap{
+	"date":          formatDate,
+	"default":       defaultValue,
+	"dig":           dig,
+	"pluralize":     pluralize,
+	"upper":         func(v interface{}) string { return strings.ToUpper(toString(v)) },
+	"lower":         func(v interface{}) string { return strings.ToLower(toString(v)) },
+	"title":         title,
+	"truncate":      truncate,
+	"join":          join,
+	"number":        formatNumber,
+	"localeNumber":  localeNumber,
+	"localeDate":    localeDate,
+	"severityEmoji": severityEmoji,
+	"escape":        escapeFor,
+}
+
+// formatDate renders a time.Time, RFC3339 string or unix timestamp in the given
+// IANA time zone. Unknown zones fall back to UTC.
+func formatDate(layout, tz string, v interface{}) string {
+	t, ok := toTime(v)
+	if !ok {
+		return ""
+	}
+	loc := time.UTC
+	if tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	return t.In(loc).Format(layout)
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, !t.IsZero()
+	case *time.Time:
+		if t == nil {
+			return time.Time{}, false
+		}
+		return *t, !t.IsZero()
+	case string:
+		parsed, err := time.Parse(time.RFC3339, t)
+		return parsed, err == nil
+	}
+	if f, ok := toFloat(v); ok {
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// defaultValue returns def when v is nil, empty or the zero value of its type.
+func defaultValue(def, v interface{}) interface{} {
+	if v == nil {
+		return def
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if rv.Len() == 0 {
+			return def
+		}
+	default:
+		if rv.IsZero() {
+			return def
+		}
+	}
+	return v
+}
+
+// dig walks nested maps by key and returns nil instead of failing on a missing key.
+// The map is the last argument so it can be piped.
+func dig(args ...interface{}) interface{} {
+	if len(args) < 2 {
+		return nil
+	}
+	cur := args[len(args)-1]
+	for _, k := range args[:len(args)-1] {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[toString(k)]
+	}
+	return cur
+}
+
+func pluralize(singular, plural string, count interface{}) string {
+	if n, ok := toFloat(count); ok && n == 1 {
+		return singular
+	}
+	return plural
+}
+
+func title(v interface{}) string {
+	words := strings.Fields(toString(v))
+	for i, w := range words {
+		r, size := utf8.DecodeRuneInString(w)
+		words[i] = strings.ToUpper(string(r)) + strings.ToLower(w[size:])
+	}
+	return strings.Join(words, " ")
+}
+
+// truncate shortens v to at most n runes, marking the cut with an ellipsis.
+func truncate(n int, v interface{}) string {
+	s := toString(v)
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	if n == 1 {
+		return "…"
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+func join(sep string, v interface{}) string {
+	switch list := v.(type) {
+	case nil:
+		return ""
+	case []string:
+		return strings.Join(list, sep)
+	case []interface{}:
+		parts := make([]string, 0, len(list))
+		for _, item := range list {
+			parts = append(parts, toString(item))
+		}
+		return strings.Join(parts, sep)
+	default:
+		return toString(v)
+	}
+}
+
+// formatNumber groups thousands and keeps at most two decimals.
+func formatNumber(v interface{}) string {
+	f, ok := toFloat(v)
+	if !ok {
+		return toString(v)
+	}
+	return groupDigits(f, ",", ".")
+}
+
+func groupDigits(f float64, thousands, decimal string) string {
+	neg := f < 0
+	if neg {
+		f = -f
+	}
+	s := strconv.FormatFloat(f, 'f', 2, 64)
+	intPart, frac, _ := strings.Cut(s, ".")
+	frac = strings.TrimRight(frac, "0")
+
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(thousands)
+		}
+		b.WriteRune(r)
+	}
+	if frac != "" {
+		b.WriteString(decimal)
+		b.WriteString(frac)
+	}
+	return b.String()
+}
+
+func severityEmoji(v interface{}) string {
+	switch strings.ToLower(toString(v)) {
+	case "critical":
+		return "🔴"
+	case "high":
+		return "🟠"
+	case "medium":
+		return "🟡"
+	case "low":
+		return "🔵"
+	default:
+		return "⚪"
+	}
+}
+
+var (
+	slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	mdEscaper    = strings.NewReplacer(
+		`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "~", `\~`,
+		"[", `\[`, "]", `\]`, "#", `\#`, "|", `\|`, ">", `\>`,
+	)
+)
+
+// escapeFor escapes user-supplied text for the formatting rules of a channel.
+func escapeFor(channel string, v interface{}) string {
+	s := toString(v)
+	switch strings.ToLower(channel) {
+	case "slack":
+		return slackEscaper.Replace(s)
+	case "email", "html", "inbox":
+		return html.EscapeString(s)
+	case "markdown", "teams":
+		return mdEscaper.Replace(s)
+	default:
+		return s
+	}
+}
+
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	case fmt.Stringer:
+		return s.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
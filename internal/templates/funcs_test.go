@@ -0,0 +1,130 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func renderOK(t *testing.T, tpl string, data map[string]interface{}) string {
+	t.Helper()
+	out, err := Renderer{}.Render(tpl, data)
+	if err != nil {
+		t.Fatalf("render %q: %v", tpl, err)
+	}
+	return out
+}
+
+func TestFuncs_Date(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 20, 30, 0, 0, time.UTC)
+	data := map[string]interface{}{"at": ts, "str": "2024-03-01T20:30:00Z", "unix": float64(ts.Unix())}
+
+	if out := renderOK(t, `{{.at | date "2006-01-02 15:04" "Asia/Ho_Chi_Minh"}}`, data); out != "2024-03-02 03:30" {
+		t.Fatalf("unexpected tz conversion: %s", out)
+	}
+	if out := renderOK(t, `{{.str | date "15:04" ""}}`, data); out != "20:30" {
+		t.Fatalf("unexpected string date: %s", out)
+	}
+	if out := renderOK(t, `{{.unix | date "2006-01-02" "Bad/Zone"}}`, data); out != "2024-03-01" {
+		t.Fatalf("unexpected unix date: %s", out)
+	}
+}
+
+func TestFuncs_DefaultAndDig(t *testing.T) {
+	data := map[string]interface{}{
+		"payload": map[string]interface{}{"project": "", "metrics": map[string]interface{}{"score": 7}},
+	}
+	if out := renderOK(t, `{{.payload.project | default "n/a"}}`, data); out != "n/a" {
+		t.Fatalf("expected default for empty string, got %q", out)
+	}
+	if out := renderOK(t, `{{index .payload "owner" | default "nobody"}}`, data); out != "nobody" {
+		t.Fatalf("expected default for missing key, got %q", out)
+	}
+	if out := renderOK(t, `{{dig "metrics" "score" .payload}}/{{dig "metrics" "nope" "deeper" .payload | default 0}}`, data); out != "7/0" {
+		t.Fatalf("unexpected dig output %q", out)
+	}
+}
+
+func TestFuncs_TextHelpers(t *testing.T) {
+	data := map[string]interface{}{
+		"n":    1,
+		"many": 3,
+		"sev":  "critical",
+		"err":  "connection reset by peer while downloading",
+		"tags": []interface{}{"a", "b", "c"},
+		"big":  1234567.5,
+	}
+	cases := map[string]string{
+		`{{.n | pluralize "vuln" "vulns"}} {{.many | pluralize "vuln" "vulns"}}`: "vuln vulns",
+		`{{upper .sev}} {{lower "HIGH"}} {{title "hello wORLD"}}`:                "CRITICAL high Hello World",
+		`{{.err | truncate 10}}`:                            "connectio…",
+		`{{.tags | join ", "}}`:                             "a, b, c",
+		`{{number .big}} {{number 1000}} {{number -42}}`:    "1,234,567.5 1,000 -42",
+		`{{severityEmoji .sev}}{{severityEmoji "unknown"}}`: "🔴⚪",
+	}
+	for tpl, want := range cases {
+		if out := renderOK(t, tpl, data); out != want {
+			t.Fatalf("%s: expected %q got %q", tpl, want, out)
+		}
+	}
+}
+
+func TestFuncs_EscapePerChannel(t *testing.T) {
+	data := map[string]interface{}{"v": "<b>*x*</b> & _y_"}
+	if out := renderOK(t, `{{escape "slack" .v}}`, data); out != "&lt;b&gt;*x*&lt;/b&gt; &amp; _y_" {
+		t.Fatalf("unexpected slack escape %q", out)
+	}
+	if out := renderOK(t, `{{escape "email" .v}}`, data); !strings.Contains(out, "&lt;b&gt;") {
+		t.Fatalf("unexpected html escape %q", out)
+	}
+	if out := renderOK(t, `{{escape "markdown" .v}}`, data); !strings.Contains(out, `\*x\*`) || !strings.Contains(out, `\_y\_`) {
+		t.Fatalf("unexpected markdown escape %q", out)
+	}
+}
+
+func TestRender_MissingKeySurfaced(t *testing.T) {
+	data := map[string]interface{}{"payload": map[string]interface{}{}}
+	_, err := Renderer{}.Render("Scan for {{.payload.project}}", data)
+	if err == nil || !strings.Contains(err.Error(), `"project"`) {
+		t.Fatalf("expected missing key error naming project, got %v", err)
+	}
+
+	out, err := Renderer{}.RenderLenient("Scan for {{.payload.project}} {{.payload.metrics.score}}", data)
+	if err != nil {
+		t.Fatalf("unexpected lenient error: %v", err)
+	}
+	if out != "Scan for  " {
+		t.Fatalf("expected missing fields rendered empty, got %q", out)
+	}
+}
+
+func TestRenderLenient_KeepsLiteralNoValue(t *testing.T) {
+	data := map[string]interface{}{"payload": map[string]interface{}{"note": "<no value>", "n": 0}}
+	tpl := `a <no value> {{.payload.note}} {{.payload.missing}}{{if .payload.n}}x{{else}}{{.payload.n}}{{end}}{{with .payload.gone}}{{.}}{{end}}`
+	out, err := Renderer{}.RenderLenientWith(Set{Partials: map[string]string{"p": "{{.payload.missing}}"}}, tpl+`{{template "p" .}}`, data)
+	if err != nil {
+		t.Fatalf("unexpected lenient error: %v", err)
+	}
+	if out != "a <no value> <no value> 0" {
+		t.Fatalf("unexpected lenient render %q", out)
+	}
+}
+
+func TestFuncs_LocaleFormatting(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 20, 30, 0, 0, time.UTC)
+	data := map[string]interface{}{"at": ts, "n": 1234567.25}
+
+	cases := map[string]string{
+		`{{localeNumber "vi-VN" .n}}`:                  "1.234.567,25",
+		`{{localeNumber "ja" .n}}`:                     "1,234,567.25",
+		`{{localeNumber "xx" .n}}`:                     "1,234,567.25",
+		`{{.at | localeDate "vi" "Asia/Ho_Chi_Minh"}}`: "02/03/2024 03:30",
+		`{{.at | localeDate "ja-JP" "Asia/Tokyo"}}`:    "2024年3月2日 05:30",
+		`{{.at | localeDate "en" ""}}`:                 "Mar 1, 2024 20:30",
+	}
+	for tpl, want := range cases {
+		if out := renderOK(t, tpl, data); out != want {
+			t.Fatalf("%s: expected %q got %q", tpl, want, out)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package templates
+
+import "strings"
+
+// localeFormat holds per-language number separators and a date layout.
+type localeFormat struct {
+	thousands, decimal string
+	dateLayout         string
+}
+
+var localeFormats = map[string]localeFormat{
+	"en": {thousands: ",", decimal: ".", dateLayout: "Jan 2, 2006 15:04"},
+	"vi": {thousands: ".", decimal: ",", dateLayout: "02/01/2006 15:04"},
+	"ja": {thousands: ",", decimal: ".", dateLayout: "2006年1月2日 15:04"},
+	"de": {thousands: ".", decimal: ",", dateLayout: "02.01.2006 15:04"},
+	"fr": {thousands: " ", decimal: ",", dateLayout: "02/01/2006 15:04"},
+}
+
+func formatFor(locale string) localeFormat {
+	lang, _, _ := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+	if f, ok := localeFormats[strings.ToLower(lang)]; ok {
+		return f
+	}
+	return localeFormats["en"]
+}
+
+// localeNumber groups digits using the locale's separators: {{localeNumber .locale 1234.5}}.
+func localeNumber(locale string, v interface{}) string {
+	f, ok := toFloat(v)
+	if !ok {
+		return toString(v)
+	}
+	lf := formatFor(locale)
+	return groupDigits(f, lf.thousands, lf.decimal)
+}
+
+// localeDate renders a time in the locale's conventional layout and the given
+// IANA zone: {{.event.occurred_at | localeDate .locale "Asia/Tokyo"}}.
+func localeDate(locale, tz string, v interface{}) string {
+	return formatDate(formatFor(locale).dateLayout, tz, v)
+}
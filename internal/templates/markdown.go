@@ -0,0 +1,476 @@
+package templates
+
+import (
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Output formats understood by Markdown.
+const (
+	OutputHTML     = "html"      // email HTML part
+	OutputSafeHTML = "safe_html" // in-app inbox
+	OutputSlack    = "slack"     // Slack mrkdwn
+	OutputTeams    = "teams"     // Microsoft Teams markdown subset
+	OutputText     = "text"      // plain-text fallback
+)
+
+// Markdown converts the rendered output of a Markdown-authored template for a
+// channel. It supports the subset notifications need: headings, paragraphs,
+// bullet and numbered lists, block quotes, fenced code, rules, **bold**,
+// *italic*, ~~strike~~, `code` and [links](url). Raw HTML in the source is
+// always escaped, and links other than http(s) and mailto are dropped, so the
+// HTML outputs are safe to embed. Unknown outputs return src unchanged.
+func Markdown(src, output string) string {
+	var st mdStyle
+	switch output {
+	case OutputHTML:
+		st = htmlStyle(false)
+	case OutputSafeHTML:
+		st = htmlStyle(true)
+	case OutputSlack:
+		st = slackStyle
+	case OutputTeams:
+		st = teamsStyle
+	case OutputText:
+		st = textStyle
+	default:
+		return src
+	}
+
+	blocks := parseMarkdown(src)
+	out := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		out = append(out, st.block(st, b))
+	}
+	return strings.Join(out, st.blockSep)
+}
+
+const (
+	mdParagraph = iota
+	mdHeading
+	mdList
+	mdQuote
+	mdCode
+	mdRule
+)
+
+type mdBlock struct {
+	kind    int
+	level   int      // heading level
+	ordered bool     // numbered list
+	start   int      // first number of a numbered list
+	lines   []string // paragraph/quote/code lines, or list items
+}
+
+func parseMarkdown(src string) []mdBlock {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	blocks := make([]mdBlock, 0)
+	var cur *mdBlock
+	flush := func() {
+		if cur != nil {
+			blocks = append(blocks, *cur)
+			cur = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") {
+			flush()
+			code := mdBlock{kind: mdCode}
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code.lines = append(code.lines, lines[i])
+			}
+			blocks = append(blocks, code)
+			continue
+		}
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		if level, text, ok := mdHeadingLine(trimmed); ok {
+			flush()
+			blocks = append(blocks, mdBlock{kind: mdHeading, level: level, lines: []string{text}})
+			continue
+		}
+		if mdRuleLine(trimmed) {
+			flush()
+			blocks = append(blocks, mdBlock{kind: mdRule})
+			continue
+		}
+		if text, ok := strings.CutPrefix(trimmed, ">"); ok {
+			if cur == nil || cur.kind != mdQuote {
+				flush()
+				cur = &mdBlock{kind: mdQuote}
+			}
+			cur.lines = append(cur.lines, strings.TrimPrefix(text, " "))
+			continue
+		}
+		if ordered, n, text, ok := mdListItem(trimmed); ok {
+			if cur == nil || cur.kind != mdList || cur.ordered != ordered {
+				flush()
+				cur = &mdBlock{kind: mdList, ordered: ordered, start: n}
+			}
+			cur.lines = append(cur.lines, text)
+			continue
+		}
+		if cur != nil && cur.kind == mdList {
+			// Continuation of the previous list item.
+			cur.lines[len(cur.lines)-1] += " " + trimmed
+			continue
+		}
+		if cur == nil || cur.kind != mdParagraph {
+			flush()
+			cur = &mdBlock{kind: mdParagraph}
+		}
+		cur.lines = append(cur.lines, trimmed)
+	}
+	flush()
+	return blocks
+}
+
+func mdHeadingLine(s string) (int, string, bool) {
+	level := 0
+	for level < len(s) && s[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level >= len(s) || s[level] != ' ' {
+		return 0, "", false
+	}
+	return level, strings.TrimSpace(strings.TrimRight(s[level:], "#")), true
+}
+
+func mdRuleLine(s string) bool {
+	if len(s) < 3 || (s[0] != '-' && s[0] != '*' && s[0] != '_') {
+		return false
+	}
+	return strings.Count(s, s[:1]) == len(s)
+}
+
+func mdListItem(s string) (ordered bool, n int, text string, ok bool) {
+	if len(s) > 2 && (s[0] == '-' || s[0] == '*' || s[0] == '+') && s[1] == ' ' {
+		return false, 0, strings.TrimSpace(s[2:]), true
+	}
+	digits := 0
+	for digits < len(s) && s[digits] >= '0' && s[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 || digits > 9 || digits+1 >= len(s) || (s[digits] != '.' && s[digits] != ')') || s[digits+1] != ' ' {
+		return false, 0, "", false
+	}
+	n, _ = strconv.Atoi(s[:digits])
+	return true, n, strings.TrimSpace(s[digits+2:]), true
+}
+
+// mdStyle describes how one output renders inline spans and blocks.
+type mdStyle struct {
+	text     func(s string) string
+	bold     func(inner string) string
+	italic   func(inner string) string
+	strike   func(inner string) string
+	code     func(raw string) string
+	link     func(text, href string) string
+	block    func(st mdStyle, b mdBlock) string
+	blockSep string
+}
+
+// inline renders emphasis, code spans and links. Unmatched markers are literal.
+func (st mdStyle) inline(s string) string {
+	var b strings.Builder
+	var lit strings.Builder
+	emit := func(v string) {
+		b.WriteString(st.text(lit.String()))
+		lit.Reset()
+		b.WriteString(v)
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_~[]()#>|-+.!", s[i+1]) >= 0:
+			lit.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				emit(st.code(s[i+1 : i+1+end]))
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(s[i:], "**") || strings.HasPrefix(s[i:], "__"):
+			marker := s[i : i+2]
+			if end := strings.Index(s[i+2:], marker); end > 0 && mdOpens(s, i, 2) {
+				emit(st.bold(st.inline(s[i+2 : i+2+end])))
+				i += end + 4
+				continue
+			}
+		case strings.HasPrefix(s[i:], "~~"):
+			if end := strings.Index(s[i+2:], "~~"); end > 0 {
+				emit(st.strike(st.inline(s[i+2 : i+2+end])))
+				i += end + 4
+				continue
+			}
+		case c == '*' || c == '_':
+			if end := mdCloseSingle(s, i); end > 0 && mdOpens(s, i, 1) {
+				emit(st.italic(st.inline(s[i+1 : end])))
+				i = end + 1
+				continue
+			}
+		case c == '[':
+			if text, href, n, ok := mdLink(s[i:]); ok {
+				emit(st.link(st.inline(text), href))
+				i += n
+				continue
+			}
+		}
+		lit.WriteByte(c)
+		i++
+	}
+	emit("")
+	return b.String()
+}
+
+// mdOpens reports whether a marker of width n at i can open emphasis: it must
+// be followed by non-space and, for underscores, not sit inside a word.
+func mdOpens(s string, i, n int) bool {
+	if i+n >= len(s) || s[i+n] == ' ' {
+		return false
+	}
+	if s[i] == '_' && i > 0 && mdWordByte(s[i-1]) {
+		return false
+	}
+	return true
+}
+
+func mdCloseSingle(s string, i int) int {
+	marker := s[i]
+	for j := i + 1; j < len(s); j++ {
+		if s[j] != marker || s[j-1] == ' ' {
+			continue
+		}
+		if j+1 < len(s) && s[j+1] == marker {
+			j++
+			continue
+		}
+		if marker == '_' && j+1 < len(s) && mdWordByte(s[j+1]) {
+			continue
+		}
+		if j > i+1 {
+			return j
+		}
+	}
+	return -1
+}
+
+func mdWordByte(c byte) bool {
+	return c == '_' || c < 0x80 && (unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)))
+}
+
+// mdLink parses "[text](href)" at the start of s; parentheses inside href must balance.
+func mdLink(s string) (text, href string, n int, ok bool) {
+	closeText := strings.Index(s, "](")
+	if closeText < 0 {
+		return "", "", 0, false
+	}
+	depth := 0
+	for j := closeText + 2; j < len(s); j++ {
+		switch s[j] {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+				continue
+			}
+			text = s[1:closeText]
+			href = strings.TrimSpace(s[closeText+2 : j])
+			return text, href, j + 1, text != "" && href != ""
+		}
+	}
+	return "", "", 0, false
+}
+
+// safeURL allows only link schemes that cannot run script in a client.
+func safeURL(href string) bool {
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+func htmlStyle(safe bool) mdStyle {
+	linkAttrs := ""
+	if safe {
+		linkAttrs = ` rel="noopener noreferrer nofollow" target="_blank"`
+	}
+	return mdStyle{
+		text:   html.EscapeString,
+		bold:   func(s string) string { return "<strong>" + s + "</strong>" },
+		italic: func(s string) string { return "<em>" + s + "</em>" },
+		strike: func(s string) string { return "<del>" + s + "</del>" },
+		code:   func(s string) string { return "<code>" + html.EscapeString(s) + "</code>" },
+		link: func(text, href string) string {
+			if !safeURL(href) {
+				return text
+			}
+			return `<a href="` + html.EscapeString(href) + `"` + linkAttrs + `>` + text + `</a>`
+		},
+		block:    htmlBlock,
+		blockSep: "\n",
+	}
+}
+
+func htmlBlock(st mdStyle, b mdBlock) string {
+	switch b.kind {
+	case mdHeading:
+		tag := "h" + strconv.Itoa(b.level)
+		return "<" + tag + ">" + st.inline(b.lines[0]) + "</" + tag + ">"
+	case mdList:
+		tag, open := "ul", "<ul>"
+		if b.ordered {
+			tag, open = "ol", "<ol>"
+			if b.start > 1 {
+				open = `<ol start="` + strconv.Itoa(b.start) + `">`
+			}
+		}
+		var out strings.Builder
+		out.WriteString(open)
+		for _, item := range b.lines {
+			out.WriteString("<li>" + st.inline(item) + "</li>")
+		}
+		return out.String() + "</" + tag + ">"
+	case mdQuote:
+		return "<blockquote><p>" + joinInline(st, b.lines, "<br>") + "</p></blockquote>"
+	case mdCode:
+		return "<pre><code>" + html.EscapeString(strings.Join(b.lines, "\n")) + "</code></pre>"
+	case mdRule:
+		return "<hr>"
+	default:
+		return "<p>" + joinInline(st, b.lines, "<br>") + "</p>"
+	}
+}
+
+var slackStyle = mdStyle{
+	text:   slackEscaper.Replace,
+	bold:   func(s string) string { return "*" + s + "*" },
+	italic: func(s string) string { return "_" + s + "_" },
+	strike: func(s string) string { return "~" + s + "~" },
+	code:   func(s string) string { return "`" + slackEscaper.Replace(s) + "`" },
+	link: func(text, href string) string {
+		if !safeURL(href) {
+			return text
+		}
+		return "<" + slackEscaper.Replace(href) + "|" + strings.ReplaceAll(text, "|", "¦") + ">"
+	},
+	block: func(st mdStyle, b mdBlock) string {
+		switch b.kind {
+		case mdHeading:
+			return st.bold(st.inline(b.lines[0]))
+		case mdList:
+			return listLines(st, b, "• ")
+		case mdQuote:
+			return "> " + joinInline(st, b.lines, "\n> ")
+		case mdCode:
+			return "```\n" + slackEscaper.Replace(strings.Join(b.lines, "\n")) + "\n```"
+		case mdRule:
+			return "———"
+		default:
+			return joinInline(st, b.lines, "\n")
+		}
+	},
+	blockSep: "\n\n",
+}
+
+// teamsStyle targets the markdown Teams renders in connector cards, where a
+// single newline is ignored, so line breaks are doubled.
+var teamsStyle = mdStyle{
+	text:   mdEscaper.Replace,
+	bold:   func(s string) string { return "**" + s + "**" },
+	italic: func(s string) string { return "_" + s + "_" },
+	strike: func(s string) string { return "~~" + s + "~~" },
+	code:   func(s string) string { return "`" + s + "`" },
+	link: func(text, href string) string {
+		if !safeURL(href) {
+			return text
+		}
+		return "[" + text + "](" + href + ")"
+	},
+	block: func(st mdStyle, b mdBlock) string {
+		switch b.kind {
+		case mdHeading:
+			return st.bold(st.inline(b.lines[0]))
+		case mdList:
+			return listLines(st, b, "- ")
+		case mdQuote:
+			return "> " + joinInline(st, b.lines, "\n\n> ")
+		case mdCode:
+			return "```\n" + strings.Join(b.lines, "\n") + "\n```"
+		case mdRule:
+			return "---"
+		default:
+			return joinInline(st, b.lines, "\n\n")
+		}
+	},
+	blockSep: "\n\n",
+}
+
+var textStyle = mdStyle{
+	text:   func(s string) string { return s },
+	bold:   func(s string) string { return s },
+	italic: func(s string) string { return s },
+	strike: func(s string) string { return s },
+	code:   func(s string) string { return s },
+	link: func(text, href string) string {
+		if text == href || strings.TrimPrefix(href, "mailto:") == text {
+			return text
+		}
+		return text + " (" + href + ")"
+	},
+	block: func(st mdStyle, b mdBlock) string {
+		switch b.kind {
+		case mdHeading:
+			return st.inline(b.lines[0])
+		case mdList:
+			return listLines(st, b, "- ")
+		case mdQuote:
+			return "> " + joinInline(st, b.lines, "\n> ")
+		case mdCode:
+			return strings.Join(b.lines, "\n")
+		case mdRule:
+			return "----------"
+		default:
+			return joinInline(st, b.lines, "\n")
+		}
+	},
+	blockSep: "\n\n",
+}
+
+func joinInline(st mdStyle, lines []string, sep string) string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = st.inline(l)
+	}
+	return strings.Join(out, sep)
+}
+
+func listLines(st mdStyle, b mdBlock, bullet string) string {
+	out := make([]string, len(b.lines))
+	for i, item := range b.lines {
+		prefix := bullet
+		if b.ordered {
+			prefix = strconv.Itoa(b.start+i) + ". "
+		}
+		out[i] = prefix + st.inline(item)
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,60 @@
+package templates
+
+import "testing"
+
+const sampleMarkdown = `## Scan finished
+
+Project **api** has *3* new findings in ` + "`go.mod`" + `.
+See [details](https://myesi.io/p/1).
+
+- CVE-1 <script>
+- CVE_2 in snake_case_name
+
+> Fix before release`
+
+func TestMarkdown_Outputs(t *testing.T) {
+	cases := map[string]string{
+		OutputHTML: "<h2>Scan finished</h2>\n" +
+			`<p>Project <strong>api</strong> has <em>3</em> new findings in <code>go.mod</code>.<br>See <a href="https://myesi.io/p/1">details</a>.</p>` + "\n" +
+			"<ul><li>CVE-1 &lt;script&gt;</li><li>CVE_2 in snake_case_name</li></ul>\n" +
+			"<blockquote><p>Fix before release</p></blockquote>",
+		OutputSlack: "*Scan finished*\n\n" +
+			"Project *api* has _3_ new findings in `go.mod`.\nSee <https://myesi.io/p/1|details>.\n\n" +
+			"• CVE-1 &lt;script&gt;\n• CVE_2 in snake_case_name\n\n" +
+			"> Fix before release",
+		OutputTeams: "**Scan finished**\n\n" +
+			"Project **api** has _3_ new findings in `go.mod`.\n\nSee [details](https://myesi.io/p/1).\n\n" +
+			"- CVE-1 <script\\>\n- CVE\\_2 in snake\\_case\\_name\n\n" +
+			"> Fix before release",
+		OutputText: "Scan finished\n\n" +
+			"Project api has 3 new findings in go.mod.\nSee details (https://myesi.io/p/1).\n\n" +
+			"- CVE-1 <script>\n- CVE_2 in snake_case_name\n\n" +
+			"> Fix before release",
+	}
+	for output, want := range cases {
+		if got := Markdown(sampleMarkdown, output); got != want {
+			t.Fatalf("%s:\n got %q\nwant %q", output, got, want)
+		}
+	}
+}
+
+func TestMarkdown_SafeHTMLDropsUnsafeLinks(t *testing.T) {
+	got := Markdown("[click](javascript:alert(1)) [ok](https://x.io) <img src=x onerror=y>", OutputSafeHTML)
+	want := `<p>click <a href="https://x.io" rel="noopener noreferrer nofollow" target="_blank">ok</a> &lt;img src=x onerror=y&gt;</p>`
+	if got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestMarkdown_BlocksAndEscapes(t *testing.T) {
+	src := "3. third\n4. fourth\n\n```\nif a < b {}\n```\n\n---\n\n\\*not italic\\*"
+	got := Markdown(src, OutputHTML)
+	want := `<ol start="3"><li>third</li><li>fourth</li></ol>` + "\n" +
+		"<pre><code>if a &lt; b {}</code></pre>\n<hr>\n<p>*not italic*</p>"
+	if got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	if got := Markdown("x", "unknown"); got != "x" {
+		t.Fatalf("unknown output should be identity, got %q", got)
+	}
+}
@@ -0,0 +1,218 @@
+package templates
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// ContentTemplate is the name a layout uses to place the wrapped body:
+// <header>{{template "content" .}}<footer>.
+const ContentTemplate = "content"
+
+// rootTemplate is the name of the template being rendered (or of the layout).
+const rootTemplate = "tpl"
+
+// Set is the named partials and optional layout a template is rendered with.
+// Partials are invoked with {{template "footer" .}}.
+type Set struct {
+	Partials map[string]string
+	Layout   string
+}
+
+// IsZero reports whether the set adds nothing to a plain render.
+func (s Set) IsZero() bool {
+	return s.Layout == "" && len(s.Partials) == 0
+}
+
+// cacheKey hashes the template together with every partial and the layout,
+// so editing a partial never serves a stale parse.
+func (s Set) cacheKey(tpl string) [sha256.Size]byte {
+	if s.IsZero() {
+		return sha256.Sum256([]byte(tpl))
+	}
+	h := sha256.New()
+	write := func(v string) { fmt.Fprintf(h, "%d:%s", len(v), v) }
+	write(tpl)
+	write(s.Layout)
+	names := make([]string, 0, len(s.Partials))
+	for name := range s.Partials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		write(name)
+		write(s.Partials[name])
+	}
+	var key [sha256.Size]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+// ValidPartialName reports whether name can be used for a partial. Names are
+// referenced from templates, so they are restricted to simple identifiers.
+func ValidPartialName(name string) bool {
+	if name == "" || name == rootTemplate || name == ContentTemplate {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '_' || r == '-' || r == '.' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func newSetTemplate(set Set, tpl string) (*template.Template, error) {
+	if set.IsZero() {
+		root, err := newTemplate(tpl)
+		if err != nil {
+			return nil, err
+		}
+		return root, checkReferences(root)
+	}
+	root := template.New(rootTemplate).Funcs(funcMap).Option("missingkey=error")
+	if set.Layout != "" {
+		if _, err := root.Parse(set.Layout); err != nil {
+			return nil, fmt.Errorf("layout: %w", err)
+		}
+		if _, err := root.New(ContentTemplate).Parse(tpl); err != nil {
+			return nil, err
+		}
+	} else if _, err := root.Parse(tpl); err != nil {
+		return nil, err
+	}
+	for name, body := range set.Partials {
+		if !ValidPartialName(name) {
+			return nil, fmt.Errorf("invalid partial name %q", name)
+		}
+		if _, err := root.New(name).Parse(body); err != nil {
+			return nil, fmt.Errorf("partial %q: %w", name, err)
+		}
+	}
+	if err := checkReferences(root); err != nil {
+		return nil, err
+	}
+	if set.Layout != "" && !references(root, rootTemplate, ContentTemplate) {
+		return nil, fmt.Errorf("layout must include {{template %q .}}", ContentTemplate)
+	}
+	return root, nil
+}
+
+// checkReferences rejects {{template}} calls to partials that do not exist and
+// cycles between partials, which text/template would only report at execution
+// after recursing to its depth limit.
+func checkReferences(root *template.Template) error {
+	graph := referenceGraph(root)
+	names := make([]string, 0, len(graph))
+	for name := range graph {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, ref := range graph[name] {
+			if _, ok := graph[ref]; !ok {
+				return fmt.Errorf("template %q references undefined partial %q", name, ref)
+			}
+		}
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			start := 0
+			for i, p := range path {
+				if p == name {
+					start = i
+				}
+			}
+			return fmt.Errorf("partial cycle: %s -> %s", strings.Join(path[start:], " -> "), name)
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, ref := range graph[name] {
+			if err := visit(ref); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// references reports whether target is reachable from name through {{template}} calls.
+func references(root *template.Template, name, target string) bool {
+	graph := referenceGraph(root)
+	seen := map[string]bool{}
+	stack := []string{name}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, ref := range graph[cur] {
+			if ref == target {
+				return true
+			}
+			if !seen[ref] {
+				seen[ref] = true
+				stack = append(stack, ref)
+			}
+		}
+	}
+	return false
+}
+
+// referenceGraph maps each defined template to the templates it invokes.
+func referenceGraph(root *template.Template) map[string][]string {
+	graph := map[string][]string{}
+	for _, t := range root.Templates() {
+		var refs []string
+		if t.Tree != nil {
+			collectRefs(t.Tree.Root, &refs)
+		}
+		graph[t.Name()] = refs
+	}
+	return graph
+}
+
+func collectRefs(node parse.Node, refs *[]string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectRefs(child, refs)
+		}
+	case *parse.TemplateNode:
+		*refs = append(*refs, n.Name)
+	case *parse.IfNode:
+		collectRefs(n.List, refs)
+		collectRefs(n.ElseList, refs)
+	case *parse.RangeNode:
+		collectRefs(n.List, refs)
+		collectRefs(n.ElseList, refs)
+	case *parse.WithNode:
+		collectRefs(n.List, refs)
+		collectRefs(n.ElseList, refs)
+	}
+}
@@ -0,0 +1,94 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderWith_PartialsAndLayout(t *testing.T) {
+	r := NewRenderer(8)
+	set := Set{
+		Partials: map[string]string{"footer": "-- {{template \"link\" .}}", "link": "View in MyESI: {{.url}}"},
+		Layout:   "[{{.org}}]\n{{template \"content\" .}}\n{{template \"footer\" .}}",
+	}
+	data := map[string]interface{}{"org": "Acme", "url": "https://x", "name": "api"}
+
+	out, err := r.RenderWith(set, "Scan of {{.name}} finished", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "[Acme]\nScan of api finished\n-- View in MyESI: https://x"
+	if out != want {
+		t.Fatalf("got %q want %q", out, want)
+	}
+
+	// Without a layout partials are still callable from the template itself.
+	out, err = r.RenderWith(Set{Partials: set.Partials}, "Hi {{template \"footer\" .}}", data)
+	if err != nil || out != "Hi -- View in MyESI: https://x" {
+		t.Fatalf("unexpected partial render %q %v", out, err)
+	}
+}
+
+func TestRenderWith_CacheSeesPartialEdits(t *testing.T) {
+	r := NewRenderer(8)
+	tpl := "{{template \"footer\" .}}"
+
+	out, _ := r.RenderWith(Set{Partials: map[string]string{"footer": "v1"}}, tpl, nil)
+	if out != "v1" {
+		t.Fatalf("unexpected %q", out)
+	}
+	out, _ = r.RenderWith(Set{Partials: map[string]string{"footer": "v2"}}, tpl, nil)
+	if out != "v2" {
+		t.Fatalf("expected edited partial to be re-parsed, got %q", out)
+	}
+}
+
+func TestValidateWith_Errors(t *testing.T) {
+	r := Renderer{}
+	cases := map[string]struct {
+		set  Set
+		tpl  string
+		want string
+	}{
+		"cycle": {
+			set:  Set{Partials: map[string]string{"a": "{{template \"b\" .}}", "b": "{{template \"a\" .}}"}},
+			tpl:  "{{template \"a\" .}}",
+			want: "partial cycle: a -> b -> a",
+		},
+		"self reference": {
+			set:  Set{Partials: map[string]string{"a": "{{if .x}}{{template \"a\" .}}{{end}}"}},
+			tpl:  "x",
+			want: "partial cycle: a -> a",
+		},
+		"undefined partial": {
+			set:  Set{Partials: map[string]string{"a": "ok"}},
+			tpl:  "{{template \"missing\" .}}",
+			want: `undefined partial "missing"`,
+		},
+		"layout without content": {
+			set:  Set{Layout: "header only"},
+			tpl:  "body",
+			want: "layout must include",
+		},
+		"partial parse error": {
+			set:  Set{Partials: map[string]string{"a": "{{.x"}},
+			tpl:  "x",
+			want: `partial "a"`,
+		},
+		"reserved name": {
+			set:  Set{Partials: map[string]string{"content": "x"}},
+			tpl:  "x",
+			want: "invalid partial name",
+		},
+	}
+	for name, tc := range cases {
+		err := r.ValidateWith(tc.set, tc.tpl)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: expected error containing %q, got %v", name, tc.want, err)
+		}
+	}
+
+	if err := r.ValidateWith(Set{Layout: "<{{template \"content\" .}}>"}, ""); err != nil {
+		t.Fatalf("unexpected error for empty body in layout: %v", err)
+	}
+}
@@ -3,22 +3,151 @@ package templates
 import (
 	"bytes"
 	"text/template"
+	"text/template/parse"
 )
 
-// Renderer renders notification templates using text/template semantics.
-type Renderer struct{}
+// lenientFunc is appended to every printing action of a lenient render. It
+// receives nil for missing keys and prints them as empty strings.
+const lenientFunc = "lenientMissingValue"
 
-// Render applies the provided data to a template string.
-func (Renderer) Render(tpl string, data map[string]interface{}) (string, error) {
-	parsed, err := template.New("tpl").Parse(tpl)
+// Renderer renders notification templates using text/template semantics
+// extended with the helpers in funcMap. The zero value parses on every call;
+// use NewRenderer to share an LRU of parsed templates.
+type Renderer struct {
+	cache *parseCache
+}
+
+// NewRenderer returns a Renderer that caches up to size parsed templates.
+func NewRenderer(size int) Renderer {
+	if size <= 0 {
+		return Renderer{}
+	}
+	return Renderer{cache: newParseCache(size)}
+}
+
+// Render applies the provided data to a template string. Referencing a missing
+// map key is an error that names the key rather than printing "<no value>".
+func (r Renderer) Render(tpl string, data map[string]interface{}) (string, error) {
+	return r.RenderWith(Set{}, tpl, data)
+}
+
+// RenderWith is Render with partials available and, when set.Layout is not
+// empty, the output wrapped in the layout.
+func (r Renderer) RenderWith(set Set, tpl string, data map[string]interface{}) (string, error) {
+	parsed, err := r.parse(set, tpl)
 	if err != nil {
 		return "", err
 	}
+	return execute(parsed, data)
+}
 
-	var buf bytes.Buffer
-	if err := parsed.Execute(&buf, data); err != nil {
+// RenderLenient renders missing keys as empty strings. It is the delivery-time
+// fallback once Render has reported which fields were missing.
+func (r Renderer) RenderLenient(tpl string, data map[string]interface{}) (string, error) {
+	return r.RenderLenientWith(Set{}, tpl, data)
+}
+
+// RenderLenientWith is RenderLenient with partials and an optional layout.
+func (r Renderer) RenderLenientWith(set Set, tpl string, data map[string]interface{}) (string, error) {
+	parsed, err := r.parse(set, tpl)
+	if err != nil {
+		return "", err
+	}
+	lenient, err := newLenientTemplate(parsed)
+	if err != nil {
 		return "", err
 	}
+	return execute(lenient, data)
+}
+
+// Validate parses a template without executing it, reporting syntax errors
+// and references to functions that are not defined.
+func (r Renderer) Validate(tpl string) error {
+	return r.ValidateWith(Set{}, tpl)
+}
 
+// ValidateWith parses a template against a set of partials and a layout,
+// additionally reporting undefined partials and cycles between them.
+func (r Renderer) ValidateWith(set Set, tpl string) error {
+	_, err := r.parse(set, tpl)
+	return err
+}
+
+func (r Renderer) parse(set Set, tpl string) (*template.Template, error) {
+	if r.cache == nil {
+		return newSetTemplate(set, tpl)
+	}
+	key := set.cacheKey(tpl)
+	if cached, ok := r.cache.get(key); ok {
+		return cached, nil
+	}
+	parsed, err := newSetTemplate(set, tpl)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.add(key, parsed)
+	return parsed, nil
+}
+
+func newTemplate(tpl string) (*template.Template, error) {
+	return template.New(rootTemplate).Funcs(funcMap).Option("missingkey=error").Parse(tpl)
+}
+
+// newLenientTemplate copies the parse trees of parsed, so the shared cached
+// template stays strict, and pipes every printed value through lenientFunc.
+// Text that merely looks like "<no value>" in the template or data is kept.
+func newLenientTemplate(parsed *template.Template) (*template.Template, error) {
+	lenient := template.New(parsed.Name()).Funcs(funcMap).Funcs(template.FuncMap{
+		lenientFunc: func(v interface{}) interface{} {
+			if v == nil {
+				return ""
+			}
+			return v
+		},
+	}).Option("missingkey=default")
+	for _, t := range parsed.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		tree := t.Tree.Copy()
+		emptyMissing(tree.Root)
+		if _, err := lenient.AddParseTree(t.Name(), tree); err != nil {
+			return nil, err
+		}
+	}
+	return lenient, nil
+}
+
+func emptyMissing(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			emptyMissing(child)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) == 0 {
+			ident := parse.NewIdentifier(lenientFunc).SetPos(n.Pos)
+			n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{ident}})
+		}
+	case *parse.IfNode:
+		emptyMissing(n.List)
+		emptyMissing(n.ElseList)
+	case *parse.RangeNode:
+		emptyMissing(n.List)
+		emptyMissing(n.ElseList)
+	case *parse.WithNode:
+		emptyMissing(n.List)
+		emptyMissing(n.ElseList)
+	}
+}
+
+func execute(t *template.Template, data map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
 	return buf.String(), nil
 }
@@ -12,3 +12,16 @@ func TestRendererRender(t *testing.T) {
 		t.Fatalf("unexpected render output: %s", out)
 	}
 }
+
+func TestRendererValidate(t *testing.T) {
+	r := Renderer{}
+	if err := r.Validate("Hello {{.name}}"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Validate("Hello {{.name"); err == nil {
+		t.Fatalf("expected parse error for unclosed action")
+	}
+	if err := r.Validate("Hello {{shout .name}}"); err == nil {
+		t.Fatalf("expected error for undefined function")
+	}
+}